@@ -0,0 +1,72 @@
+package envi
+
+import (
+	"fmt"
+	"sort"
+)
+
+// WithAliases maps the deprecated keys of variables to their new keys, like
+// the `aliases` tag, e.g. for fields of structs that cannot be tagged:
+//
+//	envi.WithAliases(map[string]string{"REDIS_ADDR": "REDIS_URL"})
+//
+// If the new variable of a field is not set, the field is parsed from the
+// first of its aliases that is set, and a Warning tells operators which
+// variable to rename. The keys are not affected by `envPrefix` tags.
+func WithAliases(aliases map[string]string) Option {
+	return func(p *parser) {
+		if p.aliases == nil {
+			p.aliases = make(map[string]string)
+		}
+		for old, key := range aliases {
+			p.aliases[old] = key
+		}
+	}
+}
+
+// aliasesOf returns the deprecated keys of the variable of field, i.e. the
+// keys of its `aliases` tag followed by those of WithAliases.
+func (p *parser) aliasesOf(field *fieldSchema) []string {
+	var olds []string
+	for old, key := range p.aliases {
+		if key == field.key {
+			olds = append(olds, old)
+		}
+	}
+	if len(olds) == 0 {
+		return field.aliases
+	}
+	sort.Strings(olds)
+	return append(append([]string(nil), field.aliases...), olds...)
+}
+
+// lookupAlias looks up the aliases of the variable of field in order and
+// returns the first one that is set, warning about its use.
+func (p *parser) lookupAlias(field *fieldSchema) (alias, value string, source Source, set bool, err error) {
+	for _, alias := range p.aliasesOf(field) {
+		if value, source, set, err = p.lookupSource(alias); err != nil {
+			return "", "", nil, false, err
+		}
+		if !set {
+			continue
+		}
+
+		p.warn(Warning{
+			Field:       field.name,
+			Key:         alias,
+			Replacement: field.key,
+			Message:     fmt.Sprintf("deprecated; rename it to %s", field.key),
+		})
+		return alias, value, source, true, nil
+	}
+	return "", "", nil, false, nil
+}
+
+// addAliases adds the aliases of WithAliases whose new keys are in d.
+func (d *declaredKeys) addAliases(aliases map[string]string) {
+	for old, key := range aliases {
+		if d.keys[key] {
+			d.keys[old] = true
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package envi_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bounoable/envi"
+	"github.com/google/go-cmp/cmp"
+)
+
+type aliasEnv struct {
+	Redis string `env:"REDIS_URL" aliases:"REDIS_ADDR, CACHE_URL"`
+	Port  int    `env:"PORT" default:"8080"`
+	DB    struct {
+		Host string `env:"HOST" aliases:"HOSTNAME"`
+	} `envPrefix:"DB_"`
+	Rest map[string]string `env:",rest"`
+}
+
+// TestAliases verifies that fields are parsed from the first of their
+// deprecated aliases that is set if their variable is not set, with a warning
+// that names the variable to rename.
+func TestAliases(t *testing.T) {
+	tests := []struct {
+		name     string
+		vars     envi.Map
+		opts     []envi.Option
+		want     aliasEnv
+		warnings []envi.Warning
+	}{
+		{
+			name: "new key",
+			vars: envi.Map{"REDIS_URL": "redis://new", "REDIS_ADDR": "redis://old"},
+			want: aliasEnv{Redis: "redis://new", Port: 8080},
+		},
+		{
+			name: "tag",
+			vars: envi.Map{"CACHE_URL": "redis://cache", "DB_HOSTNAME": "db"},
+			want: aliasEnv{Redis: "redis://cache", Port: 8080, DB: struct {
+				Host string `env:"HOST" aliases:"HOSTNAME"`
+			}{Host: "db"}},
+			warnings: []envi.Warning{
+				{Field: "Redis", Key: "CACHE_URL", Replacement: "REDIS_URL", Message: "deprecated; rename it to REDIS_URL"},
+				{Field: "Host", Key: "DB_HOSTNAME", Replacement: "DB_HOST", Message: "deprecated; rename it to DB_HOST"},
+			},
+		},
+		{
+			name: "tag order",
+			vars: envi.Map{"CACHE_URL": "redis://cache", "REDIS_ADDR": "redis://addr"},
+			want: aliasEnv{Redis: "redis://addr", Port: 8080},
+			warnings: []envi.Warning{
+				{Field: "Redis", Key: "REDIS_ADDR", Replacement: "REDIS_URL", Message: "deprecated; rename it to REDIS_URL"},
+			},
+		},
+		{
+			name: "WithAliases",
+			vars: envi.Map{"LISTEN_PORT": "9000"},
+			opts: []envi.Option{envi.WithAliases(map[string]string{"LISTEN_PORT": "PORT"})},
+			want: aliasEnv{Port: 9000},
+			warnings: []envi.Warning{
+				{Field: "Port", Key: "LISTEN_PORT", Replacement: "PORT", Message: "deprecated; rename it to PORT"},
+			},
+		},
+		{
+			name: "without WithAliases",
+			vars: envi.Map{"LISTEN_PORT": "9000"},
+			want: aliasEnv{Port: 8080, Rest: map[string]string{"LISTEN_PORT": "9000"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var warnings []envi.Warning
+			opts := append([]envi.Option{
+				envi.WithSource(tt.vars),
+				envi.WithWarningHandler(func(w envi.Warning) { warnings = append(warnings, w) }),
+			}, tt.opts...)
+
+			e, err := envi.New[aliasEnv](opts...)
+			if err != nil {
+				t.Fatalf("New() failed: %v", err)
+			}
+			if !cmp.Equal(tt.want, e) {
+				t.Fatalf("env = %v, want = %v\n\n%s", e, tt.want, cmp.Diff(tt.want, e))
+			}
+			if !cmp.Equal(tt.warnings, warnings) {
+				t.Fatalf("warnings = %v, want = %v\n\n%s", warnings, tt.warnings, cmp.Diff(tt.warnings, warnings))
+			}
+		})
+	}
+}
+
+// TestAliases_trace verifies that traces and reports name the alias that
+// provided the value of a field next to its variable.
+func TestAliases_trace(t *testing.T) {
+	vars := envi.Map{"CACHE_URL": "redis://cache", "DB_HOST": "db"}
+
+	var events []envi.TraceEvent
+	var report envi.Report
+	_, err := envi.New[aliasEnv](
+		envi.WithSource(vars),
+		envi.WithTrace(func(e envi.TraceEvent) { events = append(events, e) }),
+		envi.WithReport(&report),
+		envi.WithWarningHandler(func(envi.Warning) {}),
+	)
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	if e := events[0]; e.Key != "REDIS_URL" || e.From != "CACHE_URL" {
+		t.Fatalf("trace of Redis = %+v; want Key = REDIS_URL, From = CACHE_URL", e)
+	}
+	if e := events[2]; e.Key != "DB_HOST" || e.From != "" {
+		t.Fatalf("trace of DB.Host = %+v; want Key = DB_HOST, From = \"\"", e)
+	}
+	if f := report.Fields[0]; f.From != "CACHE_URL" {
+		t.Fatalf("report of Redis = %+v; want From = CACHE_URL", f)
+	}
+	if table := report.String(); !strings.Contains(table, "REDIS_URL (from CACHE_URL)") {
+		t.Fatalf("String() should name the alias next to the key; got\n%s", table)
+	}
+}
@@ -0,0 +1,78 @@
+package envi
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// WithBlob decodes the variable with the given key, e.g. CONFIG_B64, as a
+// base64-encoded JSON object of variables and their values, for platforms that
+// limit the number of variables that can be set:
+//
+//	{"PORT": 8080, "DB_HOST": "db", "ORIGINS": ["a.example", "b.example"]}
+//
+// The variables of the blob have the lowest precedence, so variables that are
+// set in any Source override them. Strings are used as they are, and other
+// JSON values, such as numbers and arrays, are used as their JSON text.
+func WithBlob(key string) Option {
+	return func(p *parser) {
+		p.blob = key
+	}
+}
+
+// loadBlob adds the variables of the blob variable as the last Source of the
+// parser, if WithBlob is used and the variable is set.
+func (p *parser) loadBlob() error {
+	if p.blob == "" {
+		return nil
+	}
+
+	v, _, ok, err := p.lookupRaw(p.blob)
+	if err != nil || !ok || strings.TrimSpace(v) == "" {
+		return err
+	}
+
+	vars, err := decodeBlob(v)
+	if err != nil {
+		return fmt.Errorf("%s: %w", p.blob, err)
+	}
+	p.sources = append(p.sources, vars)
+
+	return nil
+}
+
+// decodeBlob decodes the base64-encoded JSON object s into the variables it
+// contains. Null values are left out.
+func decodeBlob(s string) (Map, error) {
+	data, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil {
+		return nil, fmt.Errorf("decode base64: %w", err)
+	}
+
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("decode JSON: %w", err)
+	}
+
+	vars := make(Map, len(raw))
+	for key, val := range raw {
+		val = bytes.TrimSpace(val)
+		switch {
+		case bytes.Equal(val, []byte("null")):
+			continue
+		case len(val) > 0 && val[0] == '"':
+			var s string
+			if err := json.Unmarshal(val, &s); err != nil {
+				return nil, fmt.Errorf("decode JSON: %w", err)
+			}
+			vars[key] = s
+		default:
+			vars[key] = string(val)
+		}
+	}
+
+	return vars, nil
+}
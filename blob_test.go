@@ -0,0 +1,64 @@
+package envi_test
+
+import (
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/bounoable/envi"
+	"github.com/google/go-cmp/cmp"
+)
+
+// TestWithBlob verifies that the variables of a blob variable are parsed with
+// the lowest precedence.
+func TestWithBlob(t *testing.T) {
+	type blobEnv struct {
+		Host    string            `env:"BLOB_HOST" default:"localhost"`
+		Port    int               `env:"BLOB_PORT"`
+		Timeout time.Duration     `env:"BLOB_TIMEOUT" default:"5s"`
+		Origins []string          `env:"BLOB_ORIGINS"`
+		Debug   bool              `env:"BLOB_DEBUG"`
+		Extra   map[string]string `env:",rest"`
+	}
+
+	blob := base64.StdEncoding.EncodeToString([]byte(`{
+		"BLOB_HOST": "db",
+		"BLOB_PORT": 8080,
+		"BLOB_ORIGINS": ["a.example", "b.example"],
+		"BLOB_DEBUG": true,
+		"BLOB_TIMEOUT": null
+	}`))
+
+	e, err := envi.New[blobEnv](envi.WithSource(envi.Map{
+		"CONFIG_B64": blob,
+		"BLOB_PORT":  "9090",
+	}), envi.WithBlob("CONFIG_B64"))
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	want := blobEnv{
+		Host:    "db",
+		Port:    9090,
+		Timeout: 5 * time.Second,
+		Origins: []string{"a.example", "b.example"},
+		Debug:   true,
+	}
+	if !cmp.Equal(e, want) {
+		t.Fatalf("env = %v, want = %v\n\n%s", e, want, cmp.Diff(want, e))
+	}
+}
+
+// TestWithBlob_invalid verifies that parsing fails if the blob variable is
+// not valid base64-encoded JSON.
+func TestWithBlob_invalid(t *testing.T) {
+	type blobEnv struct {
+		Port int `env:"BLOB_PORT"`
+	}
+
+	for _, blob := range []string{"not base64!", base64.StdEncoding.EncodeToString([]byte(`["BLOB_PORT"]`))} {
+		if _, err := envi.New[blobEnv](envi.WithSource(envi.Map{"CONFIG_B64": blob}), envi.WithBlob("CONFIG_B64")); err == nil {
+			t.Fatalf("New() with blob %q should fail", blob)
+		}
+	}
+}
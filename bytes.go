@@ -0,0 +1,109 @@
+package envi
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Bytes is a byte size that is parsed from human-readable values with SI or
+// IEC suffixes, such as "512MB" (512 * 1000^2 bytes) or "2GiB" (2 * 1024^3
+// bytes). Suffixes are case-insensitive and may be separated from the number
+// by a space; values without suffix are bytes.
+//
+//	type Env struct {
+//		MemoryLimit envi.Bytes `env:"MEMORY_LIMIT" default:"512MiB"`
+//	}
+type Bytes uint64
+
+// Byte size units.
+const (
+	Byte Bytes = 1
+
+	KB Bytes = 1000 * Byte
+	MB Bytes = 1000 * KB
+	GB Bytes = 1000 * MB
+	TB Bytes = 1000 * GB
+	PB Bytes = 1000 * TB
+	EB Bytes = 1000 * PB
+
+	KiB Bytes = 1024 * Byte
+	MiB Bytes = 1024 * KiB
+	GiB Bytes = 1024 * MiB
+	TiB Bytes = 1024 * GiB
+	PiB Bytes = 1024 * TiB
+	EiB Bytes = 1024 * PiB
+)
+
+var byteUnits = map[string]Bytes{
+	"":  Byte,
+	"b": Byte,
+	"k": KB, "kb": KB, "kib": KiB,
+	"m": MB, "mb": MB, "mib": MiB,
+	"g": GB, "gb": GB, "gib": GiB,
+	"t": TB, "tb": TB, "tib": TiB,
+	"p": PB, "pb": PB, "pib": PiB,
+	"e": EB, "eb": EB, "eib": EiB,
+}
+
+// byteUnitNames are the units used by Bytes.String.
+var byteUnitNames = []struct {
+	size Bytes
+	name string
+}{
+	{EiB, "EiB"}, {PiB, "PiB"}, {TiB, "TiB"}, {GiB, "GiB"}, {MiB, "MiB"}, {KiB, "KiB"},
+	{EB, "EB"}, {PB, "PB"}, {TB, "TB"}, {GB, "GB"}, {MB, "MB"}, {KB, "KB"},
+}
+
+// ParseBytes parses a human-readable byte size, e.g. "512MB" or "1.5 GiB".
+func ParseBytes(s string) (Bytes, error) {
+	s = strings.TrimSpace(s)
+	i := strings.IndexFunc(s, func(r rune) bool {
+		return !unicode.IsDigit(r) && r != '.'
+	})
+	if i < 0 {
+		i = len(s)
+	}
+
+	num, unit := s[:i], strings.ToLower(strings.TrimSpace(s[i:]))
+	if num == "" {
+		return 0, fmt.Errorf("invalid byte size %q", s)
+	}
+
+	size, ok := byteUnits[unit]
+	if !ok {
+		return 0, fmt.Errorf("invalid byte size %q: unknown unit %q", s, s[i:])
+	}
+
+	if n, err := strconv.ParseUint(num, 10, 64); err == nil {
+		if n > math.MaxUint64/uint64(size) {
+			return 0, fmt.Errorf("byte size %q out of range", s)
+		}
+		return Bytes(n) * size, nil
+	}
+
+	f, err := strconv.ParseFloat(num, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid byte size %q: %w", s, err)
+	}
+	b := f * float64(size)
+	if b >= math.MaxUint64 {
+		return 0, fmt.Errorf("byte size %q out of range", s)
+	}
+
+	return Bytes(b), nil
+}
+
+// String formats b with the unit that divides it into the smallest number,
+// preferring IEC units, e.g. "512MiB", "512MB" or "1500B".
+func (b Bytes) String() string {
+	n, name := b, "B"
+	for _, u := range byteUnitNames {
+		if b%u.size == 0 && b/u.size < n {
+			n, name = b/u.size, u.name
+		}
+	}
+	return strconv.FormatUint(uint64(n), 10) + name
+}
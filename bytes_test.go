@@ -0,0 +1,88 @@
+package envi_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/bounoable/envi"
+)
+
+// TestParseBytes verifies that byte sizes with SI and IEC suffixes are
+// parsed.
+func TestParseBytes(t *testing.T) {
+	tests := map[string]envi.Bytes{
+		"0":         0,
+		"512":       512,
+		"512B":      512,
+		"1k":        1000,
+		"2KB":       2 * envi.KB,
+		"2KiB":      2 * envi.KiB,
+		"512MB":     512 * envi.MB,
+		"512 mib":   512 * envi.MiB,
+		"2GiB":      2 * envi.GiB,
+		"1.5GiB":    1536 * envi.MiB,
+		"3TB":       3 * envi.TB,
+		"1PiB":      envi.PiB,
+		" 10 GB ":   10 * envi.GB,
+		"0.5KiB":    512,
+		"100000000": 100000000,
+	}
+
+	for s, want := range tests {
+		got, err := envi.ParseBytes(s)
+		if err != nil {
+			t.Fatalf("ParseBytes(%q) failed: %v", s, err)
+		}
+		if got != want {
+			t.Fatalf("ParseBytes(%q) = %d, want %d", s, got, want)
+		}
+	}
+
+	for _, s := range []string{"", "MB", "-1MB", "5XB", "16EiB", "1.2.3MB"} {
+		if _, err := envi.ParseBytes(s); err == nil {
+			t.Fatalf("ParseBytes(%q) should fail", s)
+		}
+	}
+}
+
+// TestBytes_String verifies that byte sizes are formatted with the largest
+// unit that divides them.
+func TestBytes_String(t *testing.T) {
+	tests := map[envi.Bytes]string{
+		0:               "0B",
+		1500:            "1500B",
+		1000:            "1KB",
+		1024:            "1KiB",
+		512 * envi.MiB:  "512MiB",
+		512 * envi.MB:   "512MB",
+		1536 * envi.MiB: "1536MiB",
+	}
+
+	for b, want := range tests {
+		if got := b.String(); got != want {
+			t.Fatalf("Bytes(%d).String() = %q, want %q", uint64(b), got, want)
+		}
+	}
+}
+
+// TestParse_bytes verifies that Bytes fields are parsed from the environment.
+func TestParse_bytes(t *testing.T) {
+	type bytesEnv struct {
+		MemoryLimit envi.Bytes  `env:"MEMORY_LIMIT"`
+		BufferSize  *envi.Bytes `env:"BUFFER_SIZE" default:"64KiB"`
+	}
+
+	os.Clearenv()
+	os.Setenv("MEMORY_LIMIT", "512MB")
+
+	e, err := envi.New[bytesEnv]()
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	if e.MemoryLimit != 512*envi.MB {
+		t.Fatalf("MemoryLimit = %v, want %v", e.MemoryLimit, 512*envi.MB)
+	}
+	if e.BufferSize == nil || *e.BufferSize != 64*envi.KiB {
+		t.Fatalf("BufferSize = %v, want %v", e.BufferSize, 64*envi.KiB)
+	}
+}
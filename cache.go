@@ -0,0 +1,121 @@
+package envi
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Cache is a Source that caches the lookups of another Source for a fixed
+// time, so that repeated parsing, e.g. by a Watcher or of multiple structs,
+// doesn't query a remote store every time. Unset variables are cached as
+// well; errors are not.
+type Cache struct {
+	source Source
+	ttl    time.Duration
+
+	mux        sync.Mutex
+	entries    map[string]cacheEntry
+	keys       []string
+	keysExpire time.Time
+}
+
+type cacheEntry struct {
+	value   string
+	ok      bool
+	expires time.Time
+}
+
+// Cached returns a Cache for source. Lookups expire after ttl; a ttl of zero
+// or less caches lookups until they are invalidated:
+//
+//	vault := envi.Cached(vault, 5*time.Minute)
+//	err := envi.Parse(&env, envi.WithSource(envi.OS(), vault))
+//
+// The Cache implements Lister if source does.
+func Cached(source Source, ttl time.Duration) *Cache {
+	return &Cache{
+		source:  source,
+		ttl:     ttl,
+		entries: make(map[string]cacheEntry),
+	}
+}
+
+// Lookup implements Source.
+func (c *Cache) Lookup(ctx context.Context, key string) (string, bool, error) {
+	c.mux.Lock()
+	e, ok := c.entries[key]
+	c.mux.Unlock()
+	if ok && !c.expired(e.expires) {
+		return e.value, e.ok, nil
+	}
+
+	v, ok, err := c.source.Lookup(ctx, key)
+	if err != nil {
+		return "", false, err
+	}
+
+	c.mux.Lock()
+	c.entries[key] = cacheEntry{value: v, ok: ok, expires: c.expiry()}
+	c.mux.Unlock()
+
+	return v, ok, nil
+}
+
+// Keys implements Lister. It returns no keys if the cached Source does not
+// implement Lister.
+func (c *Cache) Keys(ctx context.Context) ([]string, error) {
+	l, ok := c.source.(Lister)
+	if !ok {
+		return nil, nil
+	}
+
+	c.mux.Lock()
+	keys, keysExpire := c.keys, c.keysExpire
+	c.mux.Unlock()
+	if keys != nil && !c.expired(keysExpire) {
+		return append([]string(nil), keys...), nil
+	}
+
+	keys, err := l.Keys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if keys == nil {
+		keys = []string{}
+	}
+
+	c.mux.Lock()
+	c.keys, c.keysExpire = keys, c.expiry()
+	c.mux.Unlock()
+
+	return append([]string(nil), keys...), nil
+}
+
+// Invalidate removes the cached lookups of the given keys, e.g. after a
+// secret was rotated, so that they are looked up again. Without keys, the
+// whole cache is cleared, including the keysExpire keys.
+func (c *Cache) Invalidate(keys ...string) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	if len(keys) == 0 {
+		c.entries = make(map[string]cacheEntry)
+		c.keys = nil
+		return
+	}
+	for _, key := range keys {
+		delete(c.entries, key)
+	}
+}
+
+func (c *Cache) expiry() time.Time {
+	if c.ttl <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(c.ttl)
+}
+
+func (c *Cache) expired(expires time.Time) bool {
+	return !expires.IsZero() && !time.Now().Before(expires)
+}
@@ -0,0 +1,390 @@
+package envi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// ErrRequired is returned for required fields whose variable is not set or
+// empty and that have no default. Fields are required if they have a
+// `required` tag, or a `required_if` or `required_unless` tag whose conditions
+// demand it.
+var ErrRequired = errors.New("required variable is not set")
+
+// ErrEmpty is returned with WithStrictEmpty for non-string fields whose
+// variable is set but empty.
+var ErrEmpty = errors.New("variable is set but empty")
+
+// ErrXor is returned if not exactly one field of an `xor` group is set.
+var ErrXor = errors.New("exactly one variable of the group must be set")
+
+// Problem is an invalid or missing variable that was found by Check.
+type Problem struct {
+	// Field is the path of the field, e.g. "Database.URL".
+	Field string
+
+	// Key is the environment variable of the field.
+	Key string
+
+	// Err is the error that occurred while parsing the field.
+	Err error
+}
+
+func (p Problem) Error() string {
+	if p.Key == "" {
+		return fmt.Sprintf("%s: %v", p.Field, p.Err)
+	}
+	return fmt.Sprintf("%s (%s): %v", p.Field, p.Key, p.Err)
+}
+
+func (p Problem) Unwrap() error {
+	return p.Err
+}
+
+// Check validates the environment against the struct type Env. Unlike Parse,
+// it does not stop at the first error, but returns a Problem for every field
+// whose variable is missing or invalid, e.g. to validate an environment before
+// deploying it:
+//
+//	for _, p := range envi.Check[Config]() {
+//		fmt.Println(p)
+//	}
+func Check[Env any](opts ...Option) []Problem {
+	return CheckContext[Env](context.Background(), opts...)
+}
+
+// CheckContext is like Check, but passes ctx to the configured sources. If ctx
+// is canceled, the context's error is returned as a Problem.
+func CheckContext[Env any](ctx context.Context, opts ...Option) []Problem {
+	var env Env
+	p := newParser(ctx, opts)
+	p.problems = []Problem{}
+
+	if err := p.parseInto(&env); err != nil {
+		p.problems = append(p.problems, Problem{Field: fmt.Sprintf("%T", env), Err: err})
+	}
+
+	if len(p.problems) == 0 {
+		return nil
+	}
+	return p.problems
+}
+
+// fail handles the error of the field with the given name and key. If the
+// parser checks the environment, the error is recorded as a Problem and fail
+// returns nil. Otherwise, it returns the error wrapped with the name of the
+// field.
+func (p *parser) fail(name, key string, err error) error {
+	if p.problems == nil {
+		if name == "" {
+			return err
+		}
+		return fmt.Errorf("parse %q field: %w", name, err)
+	}
+
+	// The key is part of the Problem, so it's not repeated in its error.
+	if errors.Is(err, ErrRequired) {
+		err = ErrRequired
+	}
+
+	path := strings.Join(p.path, ".")
+	if name != "" {
+		if path != "" {
+			path += "."
+		}
+		path += name
+	}
+
+	p.problems = append(p.problems, Problem{Field: path, Key: key, Err: err})
+
+	return nil
+}
+
+func requiredError(key string) error {
+	return fmt.Errorf("%s: %w", key, ErrRequired)
+}
+
+// WithStrictEmpty makes variables that are set but empty, e.g. `PORT=`, an
+// error wrapping ErrEmpty for all fields that are not strings, instead of
+// leaving the fields unset or falling back to their defaults. An empty value
+// is usually a templating bug. Slice fields that accept empty values with
+// WithEmptySlices are exempt.
+func WithStrictEmpty() Option {
+	return func(p *parser) {
+		p.strictEmpty = true
+	}
+}
+
+// checkEmpty returns an error wrapping ErrEmpty if strict empty handling is
+// enabled and field, whose variable is set but empty, is not a string.
+func (p *parser) checkEmpty(field *fieldSchema) error {
+	if !p.strictEmpty {
+		return nil
+	}
+	t := field.typ
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() == reflect.String {
+		return nil
+	}
+	return fmt.Errorf("%s: %w", field.key, ErrEmpty)
+}
+
+// condition is a condition of a `required_if` or `required_unless` tag, e.g.
+// "TLS_ENABLED=true". A condition without value, e.g. "TLS_CERT", holds if the
+// variable is set to a non-empty value.
+type condition struct {
+	key      string
+	value    string
+	hasValue bool
+}
+
+func (c condition) String() string {
+	if !c.hasValue {
+		return c.key
+	}
+	return c.key + "=" + c.value
+}
+
+func parseConditions(tag string) []condition {
+	var conds []condition
+	for _, part := range strings.Split(tag, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		key, value, hasValue := strings.Cut(part, "=")
+		conds = append(conds, condition{
+			key:      strings.TrimSpace(key),
+			value:    strings.TrimSpace(value),
+			hasValue: hasValue,
+		})
+	}
+	return conds
+}
+
+// WithDeferredRequired skips the checks of required fields and `xor` groups
+// while parsing, e.g. because command-line flags may set them afterwards. Use
+// CheckRequired to check the fields once they are complete.
+func WithDeferredRequired() Option {
+	return func(p *parser) {
+		p.deferRequired = true
+	}
+}
+
+// CheckRequired returns an error wrapping ErrRequired for the first required
+// field of env that has the zero value, including the fields of nested
+// structs, or wrapping ErrXor if the non-zero fields of an `xor` group
+// violate it. Conditions of `required_if` and `required_unless` tags are
+// evaluated against the sources of opts. It completes Parse with
+// WithDeferredRequired, e.g. after command-line flags were applied:
+//
+//	err := envi.BindFlags(flag.CommandLine, &cfg)
+//	flag.Parse()
+//	err = envi.CheckRequired(&cfg)
+func CheckRequired[Env any](env *Env, opts ...Option) error {
+	p := newParser(context.Background(), opts)
+	p.deferRequired = false
+
+	v := reflect.ValueOf(env).Elem()
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+	return p.checkRequiredFields(v, schemaOf(v.Type()))
+}
+
+// checkRequiredFields checks the required fields of the struct v with the
+// given schema; see CheckRequired.
+func (p *parser) checkRequiredFields(v reflect.Value, schema *structSchema) error {
+	results := make([]fieldResult, len(schema.fields))
+	for n := range schema.fields {
+		field := &schema.fields[n]
+		fv := v.Field(n)
+		results[n].skipped = p.skips(field)
+		results[n].found = field.settable() && !fv.IsZero()
+
+		switch {
+		case field.recursive || !field.settable() || results[n].skipped:
+		case field.isStruct:
+			if fv.Kind() == reflect.Pointer {
+				if fv.IsNil() {
+					fv = reflect.Zero(fv.Type().Elem())
+				} else {
+					fv = fv.Elem()
+				}
+			}
+
+			path, group := p.path, p.group
+			if !field.squash {
+				p.path = append(p.path, field.name)
+			}
+			p.group = p.fieldGroups(field)
+			err := p.checkRequiredFields(fv, field.nested())
+			p.path, p.group = path, group
+
+			if err != nil {
+				if field.squash {
+					return err
+				}
+				return fmt.Errorf("parse %q field: %w", field.name, err)
+			}
+		case field.lazy || field.rest || (!field.hasKey && !isPrefixMap(field.typ)):
+		case fv.IsZero():
+			if err := p.checkRequired(field); err != nil {
+				return fmt.Errorf("parse %q field: %w", field.name, err)
+			}
+		}
+	}
+	return checkGroups(schema, results)
+}
+
+// checkRequired returns an error wrapping ErrRequired if field, whose variable
+// is not set, is required. Fields with a `required_if` tag are required if all
+// of its conditions hold, and fields with a `required_unless` tag unless all of
+// its conditions hold. It returns nil with WithDeferredRequired.
+func (p *parser) checkRequired(field *fieldSchema) error {
+	if p.deferRequired {
+		return nil
+	}
+
+	if field.required {
+		return requiredError(field.key)
+	}
+
+	if len(field.requiredIf) > 0 {
+		ok, err := p.conditionsHold(field.requiredIf)
+		if err != nil {
+			return err
+		}
+		if ok {
+			return fmt.Errorf("%w (required if %s)", requiredError(field.key), joinConditions(field.requiredIf))
+		}
+	}
+
+	if len(field.requiredUnless) > 0 {
+		ok, err := p.conditionsHold(field.requiredUnless)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("%w (required unless %s)", requiredError(field.key), joinConditions(field.requiredUnless))
+		}
+	}
+
+	return nil
+}
+
+// conditionsHold reports whether all conds hold. Values that are booleans are
+// compared as booleans, so "TLS_ENABLED=true" also holds for TLS_ENABLED=1.
+func (p *parser) conditionsHold(conds []condition) (bool, error) {
+	for _, c := range conds {
+		v, err := p.getenv(c.key)
+		if err != nil {
+			return false, err
+		}
+
+		if !c.hasValue {
+			if v == "" {
+				return false, nil
+			}
+			continue
+		}
+
+		if want, err := strconv.ParseBool(c.value); err == nil && v != "" {
+			if parseBool(v) != want {
+				return false, nil
+			}
+			continue
+		}
+
+		if v != c.value {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func joinConditions(conds []condition) string {
+	s := make([]string, len(conds))
+	for i, c := range conds {
+		s[i] = c.String()
+	}
+	return strings.Join(s, ", ")
+}
+
+// xorGroup is a group of mutually exclusive fields, declared with an `xor`
+// tag, e.g. `xor:"auth"`. Exactly one field of the group must be set, or at
+// most one if any field of the group is tagged with `xor:"auth,optional"`. A
+// nested struct is set if any of its variables is set.
+type xorGroup struct {
+	name     string
+	fields   []int
+	optional bool
+}
+
+func xorGroups(fields []fieldSchema, t reflect.Type) []xorGroup {
+	var groups []xorGroup
+	index := make(map[string]int)
+	for n := range fields {
+		tag, ok := t.Field(n).Tag.Lookup("xor")
+		if !ok {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(tag, ",")
+		name = strings.TrimSpace(name)
+
+		i, ok := index[name]
+		if !ok {
+			i = len(groups)
+			index[name] = i
+			groups = append(groups, xorGroup{name: name})
+		}
+
+		groups[i].fields = append(groups[i].fields, n)
+		if strings.TrimSpace(opts) == "optional" {
+			groups[i].optional = true
+		}
+	}
+	return groups
+}
+
+// checkGroups returns an error wrapping ErrXor for the first `xor` group of
+// the struct in which not exactly one field is set.
+func checkGroups(schema *structSchema, results []fieldResult) error {
+groups:
+	for _, g := range schema.groups {
+		var set, all []string
+		for _, n := range g.fields {
+			if results[n].skipped {
+				// Groups are only checked if all of their fields are parsed.
+				continue groups
+			}
+			field := &schema.fields[n]
+			name := field.key
+			if field.isStruct || name == "" {
+				name = field.name
+			}
+			all = append(all, name)
+			if results[n].found {
+				set = append(set, name)
+			}
+		}
+
+		if len(set) == 1 || (len(set) == 0 && g.optional) {
+			continue
+		}
+
+		got := "none"
+		if len(set) > 0 {
+			got = strings.Join(set, ", ")
+		}
+		return fmt.Errorf("xor group %q (%s): %w, got %s", g.name, strings.Join(all, ", "), ErrXor, got)
+	}
+	return nil
+}
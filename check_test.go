@@ -0,0 +1,216 @@
+package envi_test
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/bounoable/envi"
+)
+
+type checkEnv struct {
+	Host    string            `env:"CHECK_HOST" required:"true"`
+	Port    int               `env:"CHECK_PORT" required:"true" default:"8080"`
+	Timeout int               `env:"CHECK_TIMEOUT"`
+	Labels  map[string]string `env:"CHECK_LABEL" required:""`
+	Addr    string            `env:"CHECK_ADDR" required:"true" defaultExpr:"{{.Host}}:{{.Port}}"`
+	Debug   bool              `env:"CHECK_DEBUG" required:"false"`
+	Nested  struct {
+		URL string `env:"CHECK_NESTED_URL" required:"true"`
+	}
+}
+
+// TestParse_required verifies that Parse fails with ErrRequired if the
+// variable of a required field without a default is not set.
+func TestParse_required(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("CHECK_LABEL_TEAM", "core")
+	os.Setenv("CHECK_NESTED_URL", "postgres://localhost")
+
+	_, err := envi.New[checkEnv]()
+	if !errors.Is(err, envi.ErrRequired) {
+		t.Fatalf("New() should fail with %q; got %v", envi.ErrRequired, err)
+	}
+
+	os.Setenv("CHECK_HOST", "localhost")
+	e, err := envi.New[checkEnv]()
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	if e.Port != 8080 || e.Addr != "localhost:8080" {
+		t.Fatalf("required fields should fall back to their defaults; got Port=%d Addr=%q", e.Port, e.Addr)
+	}
+}
+
+// TestCheck verifies that Check reports all missing and invalid variables
+// instead of stopping at the first one.
+func TestCheck(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("CHECK_TIMEOUT", "soon")
+
+	problems := envi.Check[checkEnv]()
+
+	want := []envi.Problem{
+		{Field: "Host", Key: "CHECK_HOST", Err: envi.ErrRequired},
+		{Field: "Timeout", Key: "CHECK_TIMEOUT", Err: strconv.ErrSyntax},
+		{Field: "Labels", Key: "CHECK_LABEL", Err: envi.ErrRequired},
+		{Field: "Nested.URL", Key: "CHECK_NESTED_URL", Err: envi.ErrRequired},
+	}
+	if len(problems) != len(want) {
+		t.Fatalf("Check() = %v, want = %v", problems, want)
+	}
+	for i, p := range problems {
+		if p.Field != want[i].Field || p.Key != want[i].Key || !errors.Is(p.Err, want[i].Err) {
+			t.Fatalf("Check()[%d] = %v, want = %v", i, p, want[i])
+		}
+	}
+
+	os.Setenv("CHECK_HOST", "localhost")
+	os.Setenv("CHECK_TIMEOUT", "10")
+	os.Setenv("CHECK_LABEL_TEAM", "core")
+	os.Setenv("CHECK_NESTED_URL", "postgres://localhost")
+	if problems := envi.Check[checkEnv](); len(problems) != 0 {
+		t.Fatalf("Check() should find no problems; got %v", problems)
+	}
+}
+
+// TestParse_requiredIf verifies that fields with a `required_if` or
+// `required_unless` tag are only required depending on other variables.
+func TestParse_requiredIf(t *testing.T) {
+	type tlsEnv struct {
+		TLSEnabled bool   `env:"TLS_ENABLED"`
+		CertFile   string `env:"TLS_CERT_FILE" required_if:"TLS_ENABLED=true"`
+		KeyFile    string `env:"TLS_KEY_FILE" required_if:"TLS_ENABLED=true, TLS_CERT_FILE"`
+		Token      string `env:"TOKEN" required_unless:"AUTH_MODE=none"`
+	}
+
+	tests := []struct {
+		name    string
+		env     map[string]string
+		wantErr bool
+	}{
+		{name: "tls disabled", env: map[string]string{"TOKEN": "t"}},
+		{name: "tls enabled", env: map[string]string{"TLS_ENABLED": "1", "TOKEN": "t"}, wantErr: true},
+		{name: "tls enabled with cert only", env: map[string]string{"TLS_ENABLED": "true", "TLS_CERT_FILE": "c", "TOKEN": "t"}, wantErr: true},
+		{name: "tls enabled with cert and key", env: map[string]string{"TLS_ENABLED": "true", "TLS_CERT_FILE": "c", "TLS_KEY_FILE": "k", "TOKEN": "t"}},
+		{name: "no token", env: map[string]string{}, wantErr: true},
+		{name: "no token without auth", env: map[string]string{"AUTH_MODE": "none"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Clearenv()
+			for k, v := range tt.env {
+				os.Setenv(k, v)
+			}
+
+			_, err := envi.New[tlsEnv]()
+			if tt.wantErr && !errors.Is(err, envi.ErrRequired) {
+				t.Fatalf("New() should fail with %q; got %v", envi.ErrRequired, err)
+			}
+			if !tt.wantErr && err != nil {
+				t.Fatalf("New() failed: %v", err)
+			}
+		})
+	}
+}
+
+// TestParse_xor verifies that Parse fails with ErrXor unless exactly one
+// field of an `xor` group is set, counting nested structs as one field.
+func TestParse_xor(t *testing.T) {
+	type authEnv struct {
+		Token string `env:"AUTH_TOKEN" xor:"auth"`
+		Basic struct {
+			Username string `env:"AUTH_USERNAME"`
+			Password string `env:"AUTH_PASSWORD"`
+		} `xor:"auth"`
+		Cert   string `env:"AUTH_CERT" xor:"client,optional"`
+		Secret string `env:"AUTH_SECRET" xor:"client"`
+	}
+
+	tests := []struct {
+		name    string
+		env     map[string]string
+		wantErr bool
+	}{
+		{name: "token", env: map[string]string{"AUTH_TOKEN": "t"}},
+		{name: "basic", env: map[string]string{"AUTH_USERNAME": "u", "AUTH_PASSWORD": "p"}},
+		{name: "empty token", env: map[string]string{"AUTH_TOKEN": "", "AUTH_USERNAME": "u"}},
+		{name: "none", env: map[string]string{}, wantErr: true},
+		{name: "both", env: map[string]string{"AUTH_TOKEN": "t", "AUTH_PASSWORD": "p"}, wantErr: true},
+		{name: "optional group", env: map[string]string{"AUTH_TOKEN": "t", "AUTH_CERT": "c"}},
+		{name: "optional group conflict", env: map[string]string{"AUTH_TOKEN": "t", "AUTH_CERT": "c", "AUTH_SECRET": "s"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Clearenv()
+			for k, v := range tt.env {
+				os.Setenv(k, v)
+			}
+
+			_, err := envi.New[authEnv]()
+			if tt.wantErr && !errors.Is(err, envi.ErrXor) {
+				t.Fatalf("New() should fail with %q; got %v", envi.ErrXor, err)
+			}
+			if !tt.wantErr && err != nil {
+				t.Fatalf("New() failed: %v", err)
+			}
+		})
+	}
+
+	os.Clearenv()
+	os.Setenv("AUTH_TOKEN", "t")
+	os.Setenv("AUTH_USERNAME", "u")
+	_, err := envi.New[authEnv]()
+	if want := `xor group "auth" (AUTH_TOKEN, Basic): exactly one variable of the group must be set, got AUTH_TOKEN, Basic`; err == nil || err.Error() != want {
+		t.Fatalf("New() should fail with %q; got %v", want, err)
+	}
+}
+
+// TestWithStrictEmpty verifies that non-string fields whose variable is set but
+// empty fail with ErrEmpty if WithStrictEmpty is used.
+func TestWithStrictEmpty(t *testing.T) {
+	type strictEnv struct {
+		Host  string `env:"STRICT_HOST"`
+		Port  int    `env:"STRICT_PORT" default:"8080"`
+		Debug *bool  `env:"STRICT_DEBUG"`
+	}
+
+	tests := []struct {
+		name    string
+		env     map[string]string
+		wantErr string
+	}{
+		{name: "unset", env: map[string]string{}},
+		{name: "empty string", env: map[string]string{"STRICT_HOST": ""}},
+		{name: "empty int", env: map[string]string{"STRICT_PORT": ""}, wantErr: "STRICT_PORT"},
+		{name: "empty pointer", env: map[string]string{"STRICT_DEBUG": ""}, wantErr: "STRICT_DEBUG"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Clearenv()
+			for k, v := range tt.env {
+				os.Setenv(k, v)
+			}
+
+			if _, err := envi.New[strictEnv](); err != nil {
+				t.Fatalf("New() without WithStrictEmpty failed: %v", err)
+			}
+
+			_, err := envi.New[strictEnv](envi.WithStrictEmpty())
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("New() failed: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, envi.ErrEmpty) || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("New() should fail with %q naming %s; got %v", envi.ErrEmpty, tt.wantErr, err)
+			}
+		})
+	}
+}
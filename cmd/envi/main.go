@@ -0,0 +1,219 @@
+// Command envi runs the commands of package envicli against the config struct
+// of a Go package.
+//
+// Usage:
+//
+//	envi <command> [-type Config] [-pkg .] [command flags]
+//
+// The commands are:
+//
+//	check    validate the environment, or a dotenv file given by -env-file
+//	decrypt  decrypt the age-encrypted values of a dotenv file, or a single value
+//	diff     compare the variables of two env files, or of an env file and the
+//	         environment, with the values of secrets redacted
+//	docs     print the documentation of the variables as Markdown or HTML (-format)
+//	encrypt  encrypt the values of secret fields in a dotenv file, or a single
+//	         value, for age recipients
+//	exec     run a command with the variables of env files (-env-file), after
+//	         validating the resulting environment
+//	lint     check dotenv files for unknown, duplicate, invalid and missing variables
+//	scaffold print a struct for the variables of a dotenv file, with types
+//	         inferred from the values
+//	template print a dotenv template of the variables, with their `desc` tags
+//	         as comments
+//
+// For example, to validate a dotenv file against the Config struct of the
+// package in ./config before deploying it:
+//
+//	envi check -pkg ./config -env-file prod.env
+//
+// exec runs a command like dotenv-cli, e.g. to start a server with the
+// variables of .env and .env.local:
+//
+//	envi exec -pkg ./config -env-file .env -env-file .env.local -- go run ./cmd/server
+//
+// scaffold doesn't need a package; it bootstraps the migration of a service to
+// envi by printing a struct whose fields have types inferred from the values
+// of a dotenv file:
+//
+//	envi scaffold -type Config -package config .env > config/config.go
+//
+// check, diff, exec and lint read env files with the syntax of the -dialect
+// flag: dotenv (the default), docker for files passed to `docker run
+// --env-file`, systemd for the EnvironmentFile= of systemd units, or shell for
+// scripts of export statements.
+//
+// envi generates a program that calls envicli.Main with the struct, builds it
+// using the go command in the module of the package, and runs it. The module
+// must therefore require github.com/bounoable/envi, and the package must not
+// be a main package.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:]))
+}
+
+func run(args []string) int {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		fmt.Fprintf(os.Stderr, "Usage: envi <command> [-type Config] [-pkg .] [command flags]\n")
+		return 2
+	}
+
+	command := args[0]
+	typeName, pkg, rest, err := splitArgs(args[1:])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "envi: %v\n", err)
+		return 2
+	}
+
+	// scaffold creates a struct instead of running against one.
+	if command == "scaffold" {
+		return scaffold(typeName, rest, os.Stdout, os.Stderr)
+	}
+
+	dir, err := os.MkdirTemp("", "envi-")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "envi: %v\n", err)
+		return 1
+	}
+	defer os.RemoveAll(dir)
+
+	bin, err := build(dir, pkg, typeName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "envi: %v\n", err)
+		return 1
+	}
+
+	cmd := exec.Command(bin, append([]string{command}, rest...)...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return exitErr.ExitCode()
+		}
+		fmt.Fprintf(os.Stderr, "envi: %v\n", err)
+		return 1
+	}
+
+	return 0
+}
+
+// splitArgs extracts the -type and -pkg flags from args and returns the
+// remaining arguments, which are passed to the command. Arguments after "--"
+// are passed as is.
+func splitArgs(args []string) (typeName, pkg string, rest []string, err error) {
+	typeName, pkg = "Config", "."
+
+	for i := 0; i < len(args); i++ {
+		if args[i] == "--" {
+			rest = append(rest, args[i:]...)
+			break
+		}
+
+		name, value, hasValue := strings.Cut(strings.TrimLeft(args[i], "-"), "=")
+		if !strings.HasPrefix(args[i], "-") || (name != "type" && name != "pkg") {
+			rest = append(rest, args[i])
+			continue
+		}
+
+		if !hasValue {
+			if i+1 >= len(args) {
+				return "", "", nil, fmt.Errorf("flag needs an argument: -%s", name)
+			}
+			i++
+			value = args[i]
+		}
+
+		if name == "type" {
+			typeName = value
+		} else {
+			pkg = value
+		}
+	}
+
+	return typeName, pkg, rest, nil
+}
+
+// build builds a program in dir that runs the envicli commands against the
+// struct typeName of the package pkg, and returns the path of the executable.
+func build(dir, pkg, typeName string) (string, error) {
+	out, err := goCommand("", "list", "-f", "{{.ImportPath}}\t{{.Name}}\t{{with .Module}}{{.Dir}}{{end}}", pkg)
+	if err != nil {
+		return "", err
+	}
+
+	fields := strings.Split(strings.TrimSpace(out), "\t")
+	if len(fields) != 3 || fields[2] == "" {
+		return "", fmt.Errorf("package %s is not part of a module", pkg)
+	}
+	importPath, name, modDir := fields[0], fields[1], fields[2]
+	if name == "main" {
+		return "", fmt.Errorf("package %s is a main package, which cannot be imported", pkg)
+	}
+
+	src := fmt.Sprintf(`package main
+
+import (
+	"os"
+
+	"github.com/bounoable/envi/envicli"
+
+	config %q
+)
+
+func main() {
+	envicli.Main[config.%s](os.Args[1:]...)
+}
+`, importPath, typeName)
+
+	srcPath := filepath.Join(dir, "main.go")
+	if err := os.WriteFile(srcPath, []byte(src), 0o644); err != nil {
+		return "", err
+	}
+
+	// The program is built within the module of the package, so it uses the
+	// module's dependencies. The overlay places it there without writing to
+	// the module directory.
+	mainPath := filepath.Join(modDir, "envi-cli-main", "main.go")
+	overlay, err := json.Marshal(map[string]any{
+		"Replace": map[string]string{mainPath: srcPath},
+	})
+	if err != nil {
+		return "", err
+	}
+	overlayPath := filepath.Join(dir, "overlay.json")
+	if err := os.WriteFile(overlayPath, overlay, 0o644); err != nil {
+		return "", err
+	}
+
+	bin := filepath.Join(dir, "envi-cli")
+	if _, err := goCommand(modDir, "build", "-overlay", overlayPath, "-o", bin, mainPath); err != nil {
+		return "", err
+	}
+
+	return bin, nil
+}
+
+func goCommand(dir string, args ...string) (string, error) {
+	cmd := exec.Command("go", args...)
+	cmd.Dir = dir
+
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("go %s: %w\n%s", args[0], err, stderr.String())
+	}
+
+	return string(out), nil
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// TestSplitArgs tests that the -type and -pkg flags are separated from the
+// flags of the command.
+func TestSplitArgs(t *testing.T) {
+	typeName, pkg, rest, err := splitArgs([]string{"-type", "Env", "-env-file", ".env", "--pkg=./config"})
+	if err != nil {
+		t.Fatalf("splitArgs() failed: %v", err)
+	}
+	if typeName != "Env" || pkg != "./config" {
+		t.Fatalf("splitArgs() = %q, %q; want %q, %q", typeName, pkg, "Env", "./config")
+	}
+	if want := []string{"-env-file", ".env"}; !cmp.Equal(want, rest) {
+		t.Fatalf("rest = %v, want = %v", rest, want)
+	}
+
+	_, pkg, rest, err = splitArgs([]string{"-env-file", ".env", "--", "go", "list", "-pkg", "x"})
+	if err != nil {
+		t.Fatalf("splitArgs() failed: %v", err)
+	}
+	if want := []string{"-env-file", ".env", "--", "go", "list", "-pkg", "x"}; pkg != "." || !cmp.Equal(want, rest) {
+		t.Fatalf("pkg = %q, rest = %v; want %q, %v", pkg, rest, ".", want)
+	}
+
+	if _, _, _, err := splitArgs([]string{"-type"}); err == nil {
+		t.Fatalf("splitArgs() should fail for a flag without argument")
+	}
+}
+
+// TestBuild tests that the generated program runs the commands against the
+// struct of a package in another module.
+func TestBuild(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go command not found")
+	}
+
+	root, err := filepath.Abs("../..")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod": "module example.com/app\n\ngo 1.18\n\n" +
+			"require github.com/bounoable/envi v0.0.0\n\n" +
+			"replace github.com/bounoable/envi => " + root + "\n",
+		"config/config.go": "package config\n\n" +
+			"type Config struct {\n\tHost string `env:\"HOST\" required:\"true\"`\n}\n",
+		"prod.env": "PORT=8080\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Setenv("GOFLAGS", "-mod=mod")
+	t.Setenv("GOPROXY", "off")
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	bin, err := build(t.TempDir(), "./config", "Config")
+	if err != nil {
+		t.Fatalf("build() failed: %v", err)
+	}
+
+	out, err := exec.Command(bin, "check", "-env-file", "prod.env").Output()
+	if code := exitCode(err); code != 1 {
+		t.Fatalf("exit code = %d, want 1 (%v)", code, err)
+	}
+	if want := "Host (HOST): required variable is not set"; strings.TrimSpace(string(out)) != want {
+		t.Fatalf("output = %q, want %q", out, want)
+	}
+}
+
+func exitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		return exitErr.ExitCode()
+	}
+	return -1
+}
+
+// TestScaffold tests that scaffold prints a struct with a field for every
+// variable of a dotenv file, with types inferred from the values.
+func TestScaffold(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".env")
+	env := "DB_HOST=localhost\nDB_PORT=5432\nDEBUG=true\nRATIO=0.5\nTIMEOUT=30s\n" +
+		"ALLOWED_ORIGINS=a.example.com,b.example.com\nPORTS=80,443\nAPI_KEY=abc\n" +
+		"DB_HOST=db.internal\nGREETING=\"hello, world\"\n"
+	if err := os.WriteFile(path, []byte(env), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr strings.Builder
+	if code := scaffold("Env", []string{"-package", "app", path}, &stdout, &stderr); code != 0 {
+		t.Fatalf("exit code = %d, want 0\n\n%s", code, stderr.String())
+	}
+
+	want := "package app\n\n" +
+		"import \"time\"\n\n" +
+		"type Env struct {\n" +
+		"\tDBHost         string        `env:\"DB_HOST\"`\n" +
+		"\tDBPort         int           `env:\"DB_PORT\"`\n" +
+		"\tDebug          bool          `env:\"DEBUG\"`\n" +
+		"\tRatio          float64       `env:\"RATIO\"`\n" +
+		"\tTimeout        time.Duration `env:\"TIMEOUT\"`\n" +
+		"\tAllowedOrigins []string      `env:\"ALLOWED_ORIGINS\"`\n" +
+		"\tPorts          []int         `env:\"PORTS\"`\n" +
+		"\tAPIKey         string        `env:\"API_KEY\" secret:\"true\"`\n" +
+		"\tGreeting       string        `env:\"GREETING\"`\n" +
+		"}\n"
+	if stdout.String() != want {
+		t.Fatalf("output =\n%s\nwant =\n%s", stdout.String(), want)
+	}
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bounoable/envi"
+)
+
+// scaffold prints a Go struct with a field for every variable of a dotenv
+// file, with types inferred from the values.
+func scaffold(typeName string, args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("scaffold", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	pkgName := fs.String("package", "config", "the `name` of the package of the struct")
+	dialectName := fs.String("dialect", envi.DotEnv.String(), "the `syntax` of the env file: dotenv, docker, systemd or shell")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: envi scaffold [-type Config] [flags] [file]\n\nThe file defaults to .env.\n\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() > 1 {
+		fs.Usage()
+		return 2
+	}
+
+	path := ".env"
+	if fs.NArg() == 1 {
+		path = fs.Arg(0)
+	}
+
+	var dialect envi.DotEnvDialect
+	var ok bool
+	for _, d := range []envi.DotEnvDialect{envi.DotEnv, envi.DockerEnvFile, envi.SystemdEnvFile, envi.ShellScript} {
+		if d.String() == *dialectName {
+			dialect, ok = d, true
+		}
+	}
+	if !ok {
+		fmt.Fprintf(stderr, "envi: unknown dialect %q\n", *dialectName)
+		return 2
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		fmt.Fprintf(stderr, "envi: %v\n", err)
+		return 1
+	}
+	defer f.Close()
+
+	vars, err := envi.ParseDotEnvDialect(f, dialect)
+	if err != nil {
+		fmt.Fprintf(stderr, "envi: parse %s: %v\n", path, err)
+		return 1
+	}
+
+	src, err := scaffoldStruct(*pkgName, typeName, vars)
+	if err != nil {
+		fmt.Fprintf(stderr, "envi: %v\n", err)
+		return 1
+	}
+	stdout.Write(src)
+
+	return 0
+}
+
+// scaffoldStruct returns the formatted source of a package with a struct for
+// the given variables. Duplicate keys are declared once.
+func scaffoldStruct(pkgName, typeName string, vars []envi.DotEnvVar) ([]byte, error) {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "package %s\n\n", pkgName)
+
+	var usesTime bool
+	var fields bytes.Buffer
+	seen := make(map[string]bool)
+	names := make(map[string]int)
+	for _, v := range vars {
+		if seen[v.Key] {
+			continue
+		}
+		seen[v.Key] = true
+
+		name := fieldName(v.Key)
+		if names[name]++; names[name] > 1 {
+			name += strconv.Itoa(names[name])
+		}
+
+		typ := inferType(v.Value)
+		usesTime = usesTime || strings.Contains(typ, "time.")
+
+		tag := fmt.Sprintf("env:%q", v.Key)
+		if isSecretKey(v.Key) {
+			tag += ` secret:"true"`
+		}
+		fmt.Fprintf(&fields, "\t%s %s `%s`\n", name, typ, tag)
+	}
+
+	if usesTime {
+		buf.WriteString("import \"time\"\n\n")
+	}
+	fmt.Fprintf(&buf, "type %s struct {\n%s}\n", typeName, fields.Bytes())
+
+	return format.Source(buf.Bytes())
+}
+
+// inferType returns the Go type of a field for the given value: bool, int,
+// float64, time.Duration, a slice of these for comma-separated lists, or
+// string. Comma-separated text with spaces is considered prose rather than a
+// list of strings.
+func inferType(value string) string {
+	if !strings.Contains(value, ",") || strings.ContainsAny(value, `"'[{`) {
+		return scalarType(value)
+	}
+
+	typ := ""
+	for _, e := range strings.Split(value, ",") {
+		t := scalarType(strings.TrimSpace(e))
+		if typ != "" && t != typ {
+			typ = "string"
+			break
+		}
+		typ = t
+	}
+	if typ == "string" && strings.ContainsAny(value, " \t") {
+		return "string"
+	}
+	return "[]" + typ
+}
+
+func scalarType(value string) string {
+	switch {
+	case value == "":
+		return "string"
+	case value == "true" || value == "false":
+		return "bool"
+	}
+	if _, err := strconv.Atoi(value); err == nil {
+		return "int"
+	}
+	if _, err := strconv.ParseFloat(value, 64); err == nil && strings.Contains(value, ".") {
+		return "float64"
+	}
+	if _, err := time.ParseDuration(value); err == nil {
+		return "time.Duration"
+	}
+	return "string"
+}
+
+// initialisms are the parts of keys that are written in upper case in field
+// names, following the Go naming conventions.
+var initialisms = map[string]bool{
+	"API": true, "AWS": true, "CA": true, "CPU": true, "DB": true, "DNS": true,
+	"DSN": true, "GCP": true, "GRPC": true, "HTML": true, "HTTP": true, "HTTPS": true,
+	"ID": true, "IP": true, "JSON": true, "JWT": true, "OS": true, "S3": true,
+	"SMTP": true, "SQL": true, "SSH": true, "SSL": true, "TCP": true, "TLS": true,
+	"TTL": true, "UDP": true, "UI": true, "URI": true, "URL": true, "UUID": true,
+}
+
+// fieldName returns the name of the field for the variable with the given
+// key, e.g. DBHost for DB_HOST.
+func fieldName(key string) string {
+	var b strings.Builder
+	for _, part := range strings.FieldsFunc(key, func(r rune) bool { return r == '_' || r == '-' || r == '.' }) {
+		upper := strings.ToUpper(part)
+		if initialisms[upper] {
+			b.WriteString(upper)
+			continue
+		}
+		b.WriteString(upper[:1] + strings.ToLower(part[1:]))
+	}
+
+	name := b.String()
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		name = "Var" + name
+	}
+	return name
+}
+
+// isSecretKey reports whether the variable with the given key likely holds a
+// secret.
+func isSecretKey(key string) bool {
+	key = strings.ToUpper(key)
+	for _, s := range []string{"PASSWORD", "PASSWD", "SECRET", "TOKEN", "PRIVATE_KEY", "API_KEY", "CREDENTIALS"} {
+		if strings.Contains(key, s) {
+			return true
+		}
+	}
+	return false
+}
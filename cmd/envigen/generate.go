@@ -0,0 +1,816 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"io/fs"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Generate parses the Go package in dir and returns the source of a file that
+// declares a function funcName, which parses the environment into the struct
+// type typeName without using reflection. The file skipFile, typically the
+// previous output of Generate, is not parsed.
+func Generate(dir, typeName, funcName, skipFile string) ([]byte, error) {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, dir, func(fi fs.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), "_test.go") && fi.Name() != skipFile
+	}, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(pkgs))
+	for name := range pkgs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		g := newGenerator(fset, pkgs[name])
+		if _, ok := g.decls[typeName]; !ok {
+			continue
+		}
+		return g.generate(typeName, funcName)
+	}
+
+	return nil, fmt.Errorf("type %s not found in %s", typeName, dir)
+}
+
+type typeDecl struct {
+	spec *ast.TypeSpec
+	file *ast.File
+}
+
+type generator struct {
+	fset    *token.FileSet
+	pkg     *ast.Package
+	decls   map[string]typeDecl
+	imports map[string]bool
+	helpers map[string]bool
+	order   []string
+	sources map[string]string
+
+	// enums are the types that are registered with envi.RegisterEnum, and
+	// texts are the types with an UnmarshalText method.
+	enums map[string]bool
+	texts map[string]bool
+}
+
+func newGenerator(fset *token.FileSet, pkg *ast.Package) *generator {
+	g := &generator{
+		fset:    fset,
+		pkg:     pkg,
+		decls:   make(map[string]typeDecl),
+		imports: make(map[string]bool),
+		helpers: make(map[string]bool),
+		sources: make(map[string]string),
+		enums:   make(map[string]bool),
+		texts:   make(map[string]bool),
+	}
+
+	for _, file := range pkg.Files {
+		for _, decl := range file.Decls {
+			switch decl := decl.(type) {
+			case *ast.GenDecl:
+				if decl.Tok != token.TYPE {
+					continue
+				}
+				for _, spec := range decl.Specs {
+					ts := spec.(*ast.TypeSpec)
+					g.decls[ts.Name.Name] = typeDecl{spec: ts, file: file}
+				}
+			case *ast.FuncDecl:
+				if decl.Recv != nil && len(decl.Recv.List) == 1 && decl.Name.Name == "UnmarshalText" {
+					g.texts[embeddedName(decl.Recv.List[0].Type)] = true
+				}
+			}
+		}
+
+		ast.Inspect(file, func(node ast.Node) bool {
+			if call, ok := node.(*ast.CallExpr); ok {
+				if name := registeredEnum(call, file); name != "" {
+					g.enums[name] = true
+				}
+			}
+			return true
+		})
+	}
+
+	return g
+}
+
+// registeredEnum returns the name of the type that call registers, if it is a
+// call of envi.RegisterEnum.
+func registeredEnum(call *ast.CallExpr, file *ast.File) string {
+	fun, typeArg := call.Fun, ast.Expr(nil)
+	if index, ok := fun.(*ast.IndexExpr); ok {
+		fun, typeArg = index.X, index.Index
+	}
+
+	sel, ok := fun.(*ast.SelectorExpr)
+	if !ok || sel.Sel.Name != "RegisterEnum" {
+		return ""
+	}
+	if x, ok := sel.X.(*ast.Ident); !ok || importPath(file, x.Name) != "github.com/bounoable/envi" {
+		return ""
+	}
+
+	if typeArg == nil && len(call.Args) == 1 {
+		if lit, ok := call.Args[0].(*ast.CompositeLit); ok {
+			if m, ok := lit.Type.(*ast.MapType); ok {
+				typeArg = m.Value
+			}
+		}
+	}
+	if ident, ok := typeArg.(*ast.Ident); ok {
+		return ident.Name
+	}
+	return ""
+}
+
+func (g *generator) generate(typeName, funcName string) ([]byte, error) {
+	decl := g.decls[typeName]
+	if decl.spec.TypeParams != nil && len(decl.spec.TypeParams.List) > 0 {
+		return nil, fmt.Errorf("%s: generic types are not supported", g.pos(decl.spec))
+	}
+	if _, ok := decl.spec.Type.(*ast.StructType); !ok {
+		return nil, fmt.Errorf("%s: %s is not a struct type", g.pos(decl.spec), typeName)
+	}
+
+	structHelper, err := g.structHelper(typeName)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by envigen. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&buf, "package %s\n\n", g.pkg.Name)
+
+	imports := make([]string, 0, len(g.imports))
+	for imp := range g.imports {
+		imports = append(imports, imp)
+	}
+	sort.Strings(imports)
+	fmt.Fprintf(&buf, "import (\n")
+	for _, imp := range imports {
+		fmt.Fprintf(&buf, "\t%q\n", imp)
+	}
+	fmt.Fprintf(&buf, ")\n\n")
+
+	fmt.Fprintf(&buf, "// %s populates cfg with the parsed values of the environment variables\n", funcName)
+	fmt.Fprintf(&buf, "// specified in the struct tags of %s. It behaves like envi.Parse, but does\n", typeName)
+	fmt.Fprintf(&buf, "// not use reflection.\n")
+	fmt.Fprintf(&buf, "func %s(cfg *%s) error {\n", funcName, typeName)
+	fmt.Fprintf(&buf, "\tvar out %s\n", typeName)
+	fmt.Fprintf(&buf, "\tif _, err := %s(&out); err != nil {\n\t\treturn err\n\t}\n", structHelper)
+	fmt.Fprintf(&buf, "\t*cfg = out\n\treturn nil\n}\n")
+
+	for _, name := range g.order {
+		buf.WriteString("\n")
+		buf.WriteString(g.sources[name])
+	}
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("format generated code: %w\n%s", err, buf.Bytes())
+	}
+
+	return src, nil
+}
+
+type kind int
+
+const (
+	kindBasic kind = iota
+	kindDuration
+	kindSlice
+	kindArray
+	kindPointer
+	kindMap
+	kindStruct
+)
+
+// typ describes a field type that envigen can generate code for.
+type typ struct {
+	kind kind
+
+	// basic is the underlying builtin type of basic types.
+	basic string
+
+	// expr is the Go source of the type.
+	expr string
+
+	// name is used to derive the names of helper functions.
+	name string
+
+	elem *typ
+	key  *typ
+}
+
+var basicTypes = map[string]string{
+	"string": "string", "bool": "bool",
+	"int": "int", "int8": "int8", "int16": "int16", "int32": "int32", "int64": "int64",
+	"uint": "uint", "uint8": "uint8", "uint16": "uint16", "uint32": "uint32", "uint64": "uint64",
+	"byte": "uint8", "rune": "int32",
+	"float32": "float32", "float64": "float64",
+	"complex64": "complex64", "complex128": "complex128",
+}
+
+// goosList are the known values of GOOS, which select the `default.<GOOS>`
+// tags of fields.
+var goosList = []string{
+	"aix", "android", "darwin", "dragonfly", "freebsd", "hurd", "illumos", "ios", "js",
+	"linux", "nacl", "netbsd", "openbsd", "plan9", "solaris", "wasip1", "windows", "zos",
+}
+
+// resolve returns the typ of the type expression expr in file.
+func (g *generator) resolve(expr ast.Expr, file *ast.File) (*typ, error) {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		if basic, ok := basicTypes[e.Name]; ok {
+			return &typ{kind: kindBasic, basic: basic, expr: e.Name, name: exported(e.Name)}, nil
+		}
+
+		decl, ok := g.decls[e.Name]
+		if !ok {
+			return nil, fmt.Errorf("unsupported type %s", e.Name)
+		}
+		if decl.spec.TypeParams != nil && len(decl.spec.TypeParams.List) > 0 {
+			return nil, fmt.Errorf("unsupported generic type %s", e.Name)
+		}
+
+		if g.enums[e.Name] {
+			return nil, fmt.Errorf("enum type %s is not supported", e.Name)
+		}
+
+		if _, ok := decl.spec.Type.(*ast.StructType); ok {
+			if g.texts[e.Name] {
+				return nil, fmt.Errorf("unsupported type %s: encoding.TextUnmarshaler structs are not supported", e.Name)
+			}
+			return &typ{kind: kindStruct, expr: e.Name, name: exported(e.Name)}, nil
+		}
+
+		underlying, err := g.resolve(decl.spec.Type, decl.file)
+		if err != nil {
+			return nil, fmt.Errorf("type %s: %w", e.Name, err)
+		}
+		if decl.spec.Assign.IsValid() {
+			return underlying, nil
+		}
+		if underlying.kind != kindBasic {
+			return nil, fmt.Errorf("unsupported type %s", e.Name)
+		}
+		return &typ{kind: kindBasic, basic: underlying.basic, expr: e.Name, name: exported(e.Name)}, nil
+
+	case *ast.SelectorExpr:
+		if x, ok := e.X.(*ast.Ident); ok && importPath(file, x.Name) == "time" && e.Sel.Name == "Duration" {
+			return &typ{kind: kindDuration, expr: "time.Duration", name: "Duration"}, nil
+		}
+		return nil, fmt.Errorf("unsupported type %s", g.source(e))
+
+	case *ast.StarExpr:
+		elem, err := g.resolve(e.X, file)
+		if err != nil {
+			return nil, err
+		}
+		return &typ{kind: kindPointer, expr: "*" + elem.expr, name: "Ptr" + elem.name, elem: elem}, nil
+
+	case *ast.ArrayType:
+		elem, err := g.resolve(e.Elt, file)
+		if err != nil {
+			return nil, err
+		}
+		if elem.kind == kindStruct || elem.kind == kindMap {
+			return nil, fmt.Errorf("unsupported element type %s", elem.expr)
+		}
+		if e.Len == nil {
+			return &typ{kind: kindSlice, expr: "[]" + elem.expr, name: "Slice" + elem.name, elem: elem}, nil
+		}
+		n := g.source(e.Len)
+		return &typ{kind: kindArray, expr: "[" + n + "]" + elem.expr, name: "Array" + identifier(n) + elem.name, elem: elem}, nil
+
+	case *ast.MapType:
+		key, err := g.resolve(e.Key, file)
+		if err != nil {
+			return nil, err
+		}
+		elem, err := g.resolve(e.Value, file)
+		if err != nil {
+			return nil, err
+		}
+		if key.kind == kindStruct || key.kind == kindMap || elem.kind == kindStruct || elem.kind == kindMap {
+			return nil, fmt.Errorf("unsupported map type map[%s]%s", key.expr, elem.expr)
+		}
+		return &typ{
+			kind: kindMap,
+			expr: "map[" + key.expr + "]" + elem.expr,
+			name: "Map" + key.name + elem.name,
+			key:  key,
+			elem: elem,
+		}, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported type %s", g.source(expr))
+	}
+}
+
+// structHelper generates the helper that parses the struct type name and
+// returns the name of the helper.
+func (g *generator) structHelper(name string) (string, error) {
+	helper := "envigenParseStruct" + exported(name)
+	if g.helpers[helper] {
+		// The helper is added once all of its fields were generated.
+		if _, done := g.sources[helper]; !done {
+			return "", fmt.Errorf("recursive struct type %s is not supported", name)
+		}
+		return helper, nil
+	}
+	g.helpers[helper] = true
+	g.imports["fmt"] = true
+
+	decl := g.decls[name]
+	st := decl.spec.Type.(*ast.StructType)
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "func %s(out *%s) (bool, error) {\n", helper, name)
+	fmt.Fprintf(&buf, "\tvar found bool\n")
+
+	for _, field := range st.Fields.List {
+		var tag reflect.StructTag
+		if field.Tag != nil {
+			s, err := strconv.Unquote(field.Tag.Value)
+			if err != nil {
+				return "", fmt.Errorf("%s: invalid struct tag: %w", g.pos(field), err)
+			}
+			tag = reflect.StructTag(s)
+		}
+
+		names := make([]string, 0, len(field.Names))
+		for _, n := range field.Names {
+			names = append(names, n.Name)
+		}
+		if len(names) == 0 {
+			names = append(names, embeddedName(field.Type))
+		}
+
+		key, tagged := tag.Lookup("env")
+		t, err := g.resolve(field.Type, decl.file)
+		if err != nil {
+			if tagged {
+				return "", fmt.Errorf("%s: field %s: %w", g.pos(field), names[0], err)
+			}
+			// envi ignores untagged fields that are not structs or maps.
+			continue
+		}
+
+		if _, opts, ok := strings.Cut(key, ","); ok {
+			return "", fmt.Errorf("%s: field %s: env tag option %q is not supported by envigen", g.pos(field), names[0], opts)
+		}
+
+		for _, unsupported := range []string{"defaultExpr", "required_if", "required_unless", "xor", "trim", "emptySlice", "init", "path", "validate", "dsn", "sep", "unescape", "group", "envPrefix", "transform", "aliases", "deprecated"} {
+			if _, ok := tag.Lookup(unsupported); ok {
+				return "", fmt.Errorf("%s: field %s: %s is not supported by envigen", g.pos(field), names[0], unsupported)
+			}
+		}
+
+		for _, fieldName := range names {
+			// envi ignores unexported fields, except for embedded structs.
+			if !ast.IsExported(fieldName) && len(field.Names) > 0 {
+				continue
+			}
+			if err := g.field(&buf, fieldName, t, key, tagged, tag); err != nil {
+				return "", fmt.Errorf("%s: field %s: %w", g.pos(field), fieldName, err)
+			}
+		}
+	}
+
+	fmt.Fprintf(&buf, "\treturn found, nil\n}\n")
+	g.add(helper, buf.String())
+
+	return helper, nil
+}
+
+func (g *generator) field(buf *bytes.Buffer, name string, t *typ, key string, tagged bool, tag reflect.StructTag) error {
+	wrap := fmt.Sprintf("fmt.Errorf(\"parse %%q field: %%w\", %q, err)", name)
+
+	switch {
+	case t.kind == kindStruct || (t.kind == kindPointer && t.elem.kind == kindStruct):
+		st := t
+		if t.kind == kindPointer {
+			st = t.elem
+		}
+		helper, err := g.structHelper(st.expr)
+		if err != nil {
+			return err
+		}
+
+		if t.kind == kindPointer {
+			fmt.Fprintf(buf, "\t{\n\t\tvar v %s\n", st.expr)
+			fmt.Fprintf(buf, "\t\tok, err := %s(&v)\n", helper)
+			fmt.Fprintf(buf, "\t\tif err != nil {\n\t\t\treturn false, %s\n\t\t}\n", wrap)
+			fmt.Fprintf(buf, "\t\tif ok {\n\t\t\tout.%s = &v\n\t\t\tfound = true\n\t\t}\n\t}\n", name)
+			return nil
+		}
+
+		fmt.Fprintf(buf, "\t{\n\t\tok, err := %s(&out.%s)\n", helper, name)
+		fmt.Fprintf(buf, "\t\tif err != nil {\n\t\t\treturn false, %s\n\t\t}\n", wrap)
+		fmt.Fprintf(buf, "\t\tfound = found || ok\n\t}\n")
+		return nil
+
+	case t.kind == kindMap:
+		helper := g.mapHelper(t)
+		fmt.Fprintf(buf, "\t{\n\t\tv, err := %s(%q)\n", helper, key)
+		fmt.Fprintf(buf, "\t\tif err != nil {\n\t\t\treturn false, %s\n\t\t}\n", wrap)
+		if boolTag(tag, "required") {
+			fmt.Fprintf(buf, "\t\tif v == nil {\n\t\t\treturn false, fmt.Errorf(\"parse %%q field: %%s: required variable is not set\", %q, %q)\n\t\t}\n", name, key)
+		}
+		fmt.Fprintf(buf, "\t\tif v != nil {\n\t\t\tout.%s = v\n\t\t\tfound = true\n\t\t}\n\t}\n", name)
+		return nil
+
+	case !tagged:
+		return nil
+
+	case t.kind == kindPointer && (t.elem.kind == kindMap || t.elem.kind == kindPointer):
+		return fmt.Errorf("unsupported type %s", t.expr)
+	}
+
+	helper := g.valueHelper(t)
+	g.imports["os"] = true
+
+	fmt.Fprintf(buf, "\t{\n\t\ts := os.Getenv(%q)\n", key)
+	if from, ok := tag.Lookup("defaultFrom"); ok {
+		for _, k := range strings.Split(from, ",") {
+			fmt.Fprintf(buf, "\t\tif s == \"\" {\n\t\t\ts = os.Getenv(%q)\n\t\t}\n", strings.TrimSpace(k))
+		}
+	}
+	for _, goos := range goosList {
+		if def, ok := tag.Lookup("default." + goos); ok {
+			g.imports["runtime"] = true
+			fmt.Fprintf(buf, "\t\tif s == \"\" && runtime.GOOS == %q {\n\t\t\ts = %q\n\t\t}\n", goos, def)
+		}
+	}
+	if def, ok := tag.Lookup("default"); ok {
+		fmt.Fprintf(buf, "\t\tif s == \"\" {\n\t\t\ts = %q\n\t\t}\n", def)
+	}
+	if boolTag(tag, "required") {
+		fmt.Fprintf(buf, "\t\tif s == \"\" {\n\t\t\treturn false, fmt.Errorf(\"parse %%q field: %%s: required variable is not set\", %q, %q)\n\t\t}\n", name, key)
+	}
+	fmt.Fprintf(buf, "\t\tv, ok, err := %s(s)\n", helper)
+	fmt.Fprintf(buf, "\t\tif err != nil {\n\t\t\treturn false, %s\n\t\t}\n", wrap)
+	fmt.Fprintf(buf, "\t\tif ok {\n\t\t\tout.%s = v\n\t\t\tfound = true\n\t\t}\n\t}\n", name)
+
+	return nil
+}
+
+// valueHelper generates the helper that converts a string to a value of type
+// t and returns the name of the helper. Like envi, helpers report false for
+// empty strings, except for booleans.
+func (g *generator) valueHelper(t *typ) string {
+	helper := "envigenParse" + t.name
+	if g.helpers[helper] {
+		return helper
+	}
+	g.helpers[helper] = true
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "func %s(s string) (%s, bool, error) {\n", helper, t.expr)
+	switch {
+	case t.kind == kindBasic && t.basic == "bool":
+	case t.kind == kindBasic && t.basic == "string":
+		fmt.Fprintf(&buf, "\tif s == \"\" {\n\t\treturn \"\", false, nil\n\t}\n")
+	default:
+		fmt.Fprintf(&buf, "\tvar zero %s\n", t.expr)
+		fmt.Fprintf(&buf, "\tif s == \"\" {\n\t\treturn zero, false, nil\n\t}\n")
+	}
+
+	switch t.kind {
+	case kindBasic:
+		g.basicBody(&buf, t)
+	case kindDuration:
+		g.imports["time"] = true
+		fmt.Fprintf(&buf, "\td, err := time.ParseDuration(s)\n")
+		fmt.Fprintf(&buf, "\tif err != nil {\n\t\treturn zero, false, err\n\t}\n")
+		fmt.Fprintf(&buf, "\treturn d, true, nil\n")
+	case kindPointer:
+		elem := g.valueHelper(t.elem)
+		fmt.Fprintf(&buf, "\tv, ok, err := %s(s)\n", elem)
+		fmt.Fprintf(&buf, "\tif err != nil || !ok {\n\t\treturn zero, false, err\n\t}\n")
+		fmt.Fprintf(&buf, "\treturn &v, true, nil\n")
+	case kindSlice, kindArray:
+		g.imports["fmt"] = true
+		g.imports["strings"] = true
+		elem := g.valueHelper(t.elem)
+		split := g.splitHelper()
+		fmt.Fprintf(&buf, "\tparts, err := %s(s)\n", split)
+		fmt.Fprintf(&buf, "\tif err != nil {\n\t\treturn zero, false, err\n\t}\n")
+		if t.kind == kindSlice {
+			fmt.Fprintf(&buf, "\tout := make(%s, len(parts))\n", t.expr)
+		} else {
+			fmt.Fprintf(&buf, "\tvar out %s\n", t.expr)
+		}
+		fmt.Fprintf(&buf, "\tfor i, part := range parts {\n")
+		if t.kind == kindArray {
+			fmt.Fprintf(&buf, "\t\tif i >= len(out) {\n\t\t\tbreak\n\t\t}\n")
+		}
+		fmt.Fprintf(&buf, "\t\tv, ok, err := %s(part)\n", elem)
+		fmt.Fprintf(&buf, "\t\tif err != nil {\n\t\t\treturn zero, false, fmt.Errorf(\"parse array value %%q: %%w\", part, err)\n\t\t}\n")
+		fmt.Fprintf(&buf, "\t\tif ok {\n\t\t\tout[i] = v\n\t\t}\n\t}\n")
+		fmt.Fprintf(&buf, "\treturn out, true, nil\n")
+	}
+
+	fmt.Fprintf(&buf, "}\n")
+	g.add(helper, buf.String())
+
+	return helper
+}
+
+func (g *generator) basicBody(buf *bytes.Buffer, t *typ) {
+	conv := func(v string) string {
+		if t.expr == t.basic {
+			return v
+		}
+		return t.expr + "(" + v + ")"
+	}
+
+	bits := strings.TrimLeft(t.basic, "abcdefghijklmnopqrstuvwxyz")
+	if bits == "" {
+		bits = "0"
+	}
+
+	var parse string
+	switch {
+	case t.basic == "string":
+		fmt.Fprintf(buf, "\treturn %s, true, nil\n", conv("s"))
+		return
+	case t.basic == "bool":
+		g.imports["strconv"] = true
+		fmt.Fprintf(buf, "\tif b, err := strconv.ParseBool(s); err == nil {\n\t\treturn %s, true, nil\n\t}\n", conv("b"))
+		fmt.Fprintf(buf, "\treturn %s, true, nil\n", conv("s != \"\""))
+		return
+	case strings.HasPrefix(t.basic, "int"):
+		fmt.Fprintf(buf, "\ttext, base := %s(s)\n", g.intHelper())
+		parse = fmt.Sprintf("strconv.ParseInt(text, base, %s)", bits)
+	case strings.HasPrefix(t.basic, "uint"):
+		fmt.Fprintf(buf, "\ttext, base := %s(s)\n", g.intHelper())
+		parse = fmt.Sprintf("strconv.ParseUint(text, base, %s)", bits)
+	case strings.HasPrefix(t.basic, "float"):
+		parse = fmt.Sprintf("strconv.ParseFloat(%s(s), %s)", g.digitsHelper(), bits)
+	case strings.HasPrefix(t.basic, "complex"):
+		parse = fmt.Sprintf("strconv.ParseComplex(s, %s)", bits)
+	}
+
+	g.imports["strconv"] = true
+	fmt.Fprintf(buf, "\tv, err := %s\n", parse)
+	fmt.Fprintf(buf, "\tif err != nil {\n")
+	if !strings.HasPrefix(t.basic, "complex") {
+		// envi reports the value as it was set, including underscores.
+		fmt.Fprintf(buf, "\t\tif ne, ok := err.(*strconv.NumError); ok {\n\t\t\tne.Num = s\n\t\t}\n")
+	}
+	fmt.Fprintf(buf, "\t\treturn zero, false, err\n\t}\n")
+	fmt.Fprintf(buf, "\treturn %s(v), true, nil\n", t.expr)
+}
+
+// digitsHelper generates the helper that removes the underscores between
+// the digits of numbers like envi, and returns its name.
+func (g *generator) digitsHelper() string {
+	const helper = "envigenDigits"
+	if g.helpers[helper] {
+		return helper
+	}
+	g.helpers[helper] = true
+	g.imports["strings"] = true
+	g.add(helper, digitsSource)
+	return helper
+}
+
+// intHelper generates the helper that returns the text and base of integers
+// like envi, and returns its name.
+func (g *generator) intHelper() string {
+	const helper = "envigenInt"
+	if g.helpers[helper] {
+		return helper
+	}
+	g.helpers[helper] = true
+	g.add(helper, fmt.Sprintf(intSource, g.digitsHelper()))
+	return helper
+}
+
+const intSource = `func envigenInt(s string) (string, int) {
+	unsigned := s
+	if len(s) > 0 && (s[0] == '+' || s[0] == '-') {
+		unsigned = s[1:]
+	}
+	if len(unsigned) > 2 && unsigned[0] == '0' && strings.IndexByte("xXoObB", unsigned[1]) >= 0 {
+		return s, 0
+	}
+	return %s(s), 10
+}
+`
+
+const digitsSource = `func envigenDigits(s string) string {
+	if !strings.Contains(s, "_") {
+		return s
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] == '_' && (i == 0 || i == len(s)-1 || s[i-1] < '0' || s[i-1] > '9' || s[i+1] < '0' || s[i+1] > '9') {
+			return s
+		}
+	}
+	return strings.ReplaceAll(s, "_", "")
+}
+`
+
+// splitHelper generates the helper that splits list values into their
+// elements like envi, including quoted elements, and returns its name.
+func (g *generator) splitHelper() string {
+	const helper = "envigenSplitList"
+	if g.helpers[helper] {
+		return helper
+	}
+	g.helpers[helper] = true
+	g.imports["encoding/json"] = true
+	g.imports["errors"] = true
+	g.imports["fmt"] = true
+	g.imports["strings"] = true
+	g.add(helper, splitListSource)
+	return helper
+}
+
+const splitListSource = `func envigenSplitList(s string) ([]string, error) {
+	if t := strings.TrimSpace(s); strings.HasPrefix(t, "[") && json.Valid([]byte(t)) {
+		var raw []json.RawMessage
+		if err := json.Unmarshal([]byte(t), &raw); err == nil {
+			elems := make([]string, len(raw))
+			for i, r := range raw {
+				if err := json.Unmarshal(r, &elems[i]); err != nil {
+					elems[i] = string(r)
+				}
+			}
+			return elems, nil
+		}
+	}
+	var elems []string
+	for {
+		s = strings.TrimLeft(s, " \t")
+		if !strings.HasPrefix(s, "\"") {
+			elem, rest, more := strings.Cut(s, ",")
+			for more && strings.HasSuffix(elem, "\\") {
+				var next string
+				next, rest, more = strings.Cut(rest, ",")
+				elem = elem[:len(elem)-1] + "," + next
+			}
+			elems = append(elems, strings.TrimSpace(elem))
+			if !more {
+				return elems, nil
+			}
+			s = rest
+			continue
+		}
+		var b strings.Builder
+		s = s[1:]
+		for {
+			i := strings.IndexByte(s, '"')
+			if i < 0 {
+				return nil, errors.New("missing closing quote")
+			}
+			b.WriteString(s[:i])
+			s = s[i+1:]
+			if !strings.HasPrefix(s, "\"") {
+				break
+			}
+			b.WriteByte('"')
+			s = s[1:]
+		}
+		elems = append(elems, b.String())
+		s = strings.TrimLeft(s, " \t")
+		if s == "" {
+			return elems, nil
+		}
+		if s[0] != ',' {
+			return nil, fmt.Errorf("unexpected %q after closing quote", s[0])
+		}
+		s = s[1:]
+	}
+}
+`
+
+// mapHelper generates the helper that collects the variables with a given
+// prefix into a map of type t and returns the name of the helper.
+func (g *generator) mapHelper(t *typ) string {
+	helper := "envigenParse" + t.name
+	if g.helpers[helper] {
+		return helper
+	}
+	g.helpers[helper] = true
+	g.imports["fmt"] = true
+	g.imports["os"] = true
+	g.imports["strings"] = true
+
+	key := g.valueHelper(t.key)
+	elem := g.valueHelper(t.elem)
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "func %s(prefix string) (%s, error) {\n", helper, t.expr)
+	fmt.Fprintf(&buf, "\tif prefix != \"\" {\n\t\tprefix += \"_\"\n\t}\n")
+	fmt.Fprintf(&buf, "\tout := make(%s)\n", t.expr)
+	fmt.Fprintf(&buf, "\tfor _, env := range os.Environ() {\n")
+	fmt.Fprintf(&buf, "\t\tkey, val, _ := strings.Cut(env, \"=\")\n")
+	fmt.Fprintf(&buf, "\t\tif key == \"\" || !strings.HasPrefix(key, prefix) {\n\t\t\tcontinue\n\t\t}\n")
+	fmt.Fprintf(&buf, "\t\tk, ok, err := %s(strings.TrimPrefix(key, prefix))\n", key)
+	fmt.Fprintf(&buf, "\t\tif err != nil {\n\t\t\treturn nil, fmt.Errorf(\"parse map key %%q: %%w\", key, err)\n\t\t}\n")
+	fmt.Fprintf(&buf, "\t\tif !ok {\n\t\t\tcontinue\n\t\t}\n")
+	fmt.Fprintf(&buf, "\t\tv, ok, err := %s(val)\n", elem)
+	fmt.Fprintf(&buf, "\t\tif err != nil {\n\t\t\treturn nil, fmt.Errorf(\"parse map value %%q [key=%%s]: %%w\", val, key, err)\n\t\t}\n")
+	fmt.Fprintf(&buf, "\t\tif !ok {\n\t\t\tcontinue\n\t\t}\n")
+	fmt.Fprintf(&buf, "\t\tout[k] = v\n\t}\n")
+	fmt.Fprintf(&buf, "\tif len(out) == 0 {\n\t\treturn nil, nil\n\t}\n")
+	fmt.Fprintf(&buf, "\treturn out, nil\n}\n")
+	g.add(helper, buf.String())
+
+	return helper
+}
+
+func (g *generator) add(helper, src string) {
+	g.order = append(g.order, helper)
+	g.sources[helper] = src
+}
+
+func (g *generator) pos(node ast.Node) token.Position {
+	return g.fset.Position(node.Pos())
+}
+
+func (g *generator) source(node ast.Node) string {
+	var buf bytes.Buffer
+	printer.Fprint(&buf, g.fset, node)
+	return buf.String()
+}
+
+// importPath returns the path of the import with the given name in file.
+func importPath(file *ast.File, name string) string {
+	for _, imp := range file.Imports {
+		path, _ := strconv.Unquote(imp.Path.Value)
+		if imp.Name != nil {
+			if imp.Name.Name == name {
+				return path
+			}
+			continue
+		}
+		if path[strings.LastIndex(path, "/")+1:] == name {
+			return path
+		}
+	}
+	return ""
+}
+
+// boolTag reports whether the struct tag has the given key and its value is
+// empty or parses as true, like envi does.
+func boolTag(tag reflect.StructTag, key string) bool {
+	v, ok := tag.Lookup(key)
+	if !ok {
+		return false
+	}
+	if v == "" {
+		return true
+	}
+	b, _ := strconv.ParseBool(v)
+	return b
+}
+
+func embeddedName(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.StarExpr:
+		return embeddedName(e.X)
+	case *ast.SelectorExpr:
+		return e.Sel.Name
+	case *ast.Ident:
+		return e.Name
+	default:
+		return ""
+	}
+}
+
+func exported(s string) string {
+	if s == "" {
+		return s
+	}
+	return string(unicode.ToUpper(rune(s[0]))) + s[1:]
+}
+
+func identifier(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		return '_'
+	}, s)
+}
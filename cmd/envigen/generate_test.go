@@ -0,0 +1,222 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const compareMain = `package main
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+
+	"example.com/gen/config"
+	"github.com/bounoable/envi"
+)
+
+func main() {
+	var want config.Config
+	wantErr := envi.Parse(&want)
+
+	var got config.Config
+	gotErr := config.ParseEnv(&got)
+
+	if wantErr != nil || gotErr != nil {
+		if fmt.Sprint(gotErr) != fmt.Sprint(wantErr) {
+			fmt.Printf("ParseEnv() error = %v\n\nenvi.Parse() error = %v\n", gotErr, wantErr)
+			os.Exit(1)
+		}
+		return
+	}
+
+	got.Ignored, want.Ignored = nil, nil
+	if !reflect.DeepEqual(got, want) {
+		fmt.Printf("ParseEnv() = %+v\n\nenvi.Parse() = %+v\n", got, want)
+		os.Exit(1)
+	}
+}
+`
+
+// TestGenerate tests that the generated code parses the environment like
+// envi.Parse does.
+func TestGenerate(t *testing.T) {
+	goBin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go command not found")
+	}
+
+	root, err := filepath.Abs("../..")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	src, err := Generate("testdata/config", "Config", "ParseEnv", "config_envigen.go")
+	if err != nil {
+		t.Fatalf("Generate() failed: %v", err)
+	}
+
+	dir := t.TempDir()
+	config, err := os.ReadFile("testdata/config/config.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := map[string]string{
+		"go.mod": "module example.com/gen\n\ngo 1.18\n\n" +
+			"require github.com/bounoable/envi v0.0.0\n\n" +
+			"replace github.com/bounoable/envi => " + root + "\n",
+		"main.go":                  compareMain,
+		"config/config.go":         string(config),
+		"config/config_envigen.go": string(src),
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		env  []string
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name: "values",
+			env: []string{
+				"HOST=example.com",
+				"PORT=9_000",
+				"DEBUG=yes",
+				"RATIO=0.5",
+				"TIMEOUT=1m",
+				"RETRIES=3",
+				"NAMES=a, \"b, c\" ,d\\,e,f\\g",
+				"WEIGHTS=[1, 2, 3, 4]",
+				"LABEL_TEAM=core",
+				"LABEL_TIER=1",
+				"LIMIT_CPU=2",
+				"HEADER_Accept=application/json, \"text/plain; q=0.5, x\"",
+				"HEADER_X_Trace=1",
+				"DATABASE_URL=postgres://localhost",
+				"CONNS=10",
+				"CACHE_ADDR=localhost:6379",
+				"REGION=eu",
+				"ZONE=eu-1",
+				"TOKEN=secret",
+			},
+		},
+		{
+			name: "invalid number",
+			env:  []string{"PORT=9__000"},
+		},
+		{
+			name: "base prefixes",
+			env:  []string{"PORT=0x1F_90", "RETRIES=-0b11", "CONNS=0o17", "WEIGHTS=010,0x7f,-0b1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := exec.Command(goBin, "run", ".")
+			cmd.Dir = dir
+			cmd.Env = append(filterEnv(os.Environ()), "GOFLAGS=-mod=mod", "GOPROXY=off")
+			cmd.Env = append(cmd.Env, tt.env...)
+			if out, err := cmd.CombinedOutput(); err != nil {
+				t.Fatalf("generated code does not match envi.Parse: %v\n\n%s\n\n%s", err, out, src)
+			}
+		})
+	}
+}
+
+// TestGenerate_unsupported tests that Generate reports fields it cannot
+// generate code for.
+func TestGenerate_unsupported(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{
+			name: "defaultExpr",
+			src:  "type Config struct {\n\tURL string `env:\"URL\" defaultExpr:\"{{.Host}}\"`\n\tHost string\n}\n",
+			want: "defaultExpr is not supported",
+		},
+		{
+			name: "deprecated",
+			src:  "type Config struct {\n\tHost string `env:\"HOST\" deprecated:\"use ADDR\"`\n}\n",
+			want: "deprecated is not supported",
+		},
+		{
+			name: "enum",
+			src: "import \"github.com/bounoable/envi\"\n\ntype Level int\n\nfunc init() {\n\tenvi.RegisterEnum(map[string]Level{\"debug\": 0})\n}\n\n" +
+				"type Config struct {\n\tLevel Level `env:\"LEVEL\"`\n}\n",
+			want: "enum type Level is not supported",
+		},
+		{
+			name: "enum type argument",
+			src: "import \"github.com/bounoable/envi\"\n\ntype Level int\n\nvar levels = map[string]Level{\"debug\": 0}\n\nfunc init() {\n\tenvi.RegisterEnum[Level](levels)\n}\n\n" +
+				"type Config struct {\n\tLevels []Level `env:\"LEVELS\"`\n}\n",
+			want: "enum type Level is not supported",
+		},
+		{
+			name: "text unmarshaler",
+			src: "type Addr struct {\n\tHost string\n}\n\nfunc (a *Addr) UnmarshalText(text []byte) error {\n\ta.Host = string(text)\n\treturn nil\n}\n\n" +
+				"type Config struct {\n\tAddr Addr `env:\"ADDR\"`\n}\n",
+			want: "encoding.TextUnmarshaler structs are not supported",
+		},
+		{
+			name: "foreign type",
+			src:  "import \"net/url\"\n\ntype Config struct {\n\tURL url.URL `env:\"URL\"`\n}\n",
+			want: "unsupported type url.URL",
+		},
+		{
+			name: "recursive type",
+			src:  "type Config struct {\n\tNode Node\n}\n\ntype Node struct {\n\tName string `env:\"NAME\"`\n\tNext *Node\n}\n",
+			want: "recursive struct type Node",
+		},
+		{
+			name: "not a struct",
+			src:  "type Config string\n",
+			want: "not a struct type",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			src := "package config\n\n" + tt.src
+			if err := os.WriteFile(filepath.Join(dir, "config.go"), []byte(src), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err := Generate(dir, "Config", "ParseEnv", "config_envigen.go")
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("Generate() should fail with %q; got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+// filterEnv removes the variables of the test configuration from env.
+func filterEnv(env []string) []string {
+	out := env[:0:0]
+	for _, e := range env {
+		key, _, _ := strings.Cut(e, "=")
+		switch key {
+		case "HOST", "PORT", "DEBUG", "GOFLAGS", "GOPROXY", "REGION":
+			continue
+		}
+		out = append(out, e)
+	}
+	return out
+}
@@ -0,0 +1,71 @@
+// Command envigen generates a reflection-free implementation of envi.Parse
+// for a struct type.
+//
+// Add a go:generate directive next to the struct:
+//
+//	//go:generate go run github.com/bounoable/envi/cmd/envigen -type Config
+//	type Config struct {
+//		Port int `env:"PORT" default:"8080"`
+//	}
+//
+// and run go generate. This writes config_envigen.go, which contains
+//
+//	func ParseEnv(cfg *Config) error
+//
+// The generated code only depends on the standard library and does not use
+// reflection. Fields that envigen cannot generate code for, such as fields
+// with a `defaultExpr` or `deprecated` tag, of enums registered with
+// envi.RegisterEnum, of structs that implement encoding.TextUnmarshaler or of
+// types from other packages (except time.Duration), are reported as errors at
+// generation time.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	var (
+		typeName = flag.String("type", "", "name of the struct type (required)")
+		funcName = flag.String("func", "ParseEnv", "name of the generated function")
+		output   = flag.String("output", "", "output file name (default <type>_envigen.go)")
+	)
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: envigen -type <name> [flags] [directory]\n\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *typeName == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	dir := "."
+	if flag.NArg() > 0 {
+		dir = flag.Arg(0)
+	}
+
+	out := *output
+	if out == "" {
+		out = strings.ToLower(*typeName) + "_envigen.go"
+	}
+	if !filepath.IsAbs(out) {
+		out = filepath.Join(dir, out)
+	}
+
+	src, err := Generate(dir, *typeName, *funcName, filepath.Base(out))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "envigen: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := os.WriteFile(out, src, 0o644); err != nil {
+		fmt.Fprintf(os.Stderr, "envigen: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,47 @@
+package config
+
+import (
+	"time"
+)
+
+type Config struct {
+	Host    string              `env:"HOST" default:"localhost"`
+	Port    uint16              `env:"PORT" default:"8080"`
+	Debug   bool                `env:"DEBUG"`
+	Ratio   float64             `env:"RATIO"`
+	Timeout time.Duration       `env:"TIMEOUT" default:"5s"`
+	Retries *int                `env:"RETRIES"`
+	Names   []string            `env:"NAMES"`
+	Weights [3]int8             `env:"WEIGHTS"`
+	Labels  map[string]string   `env:"LABEL"`
+	Limits  map[string]int      `env:"LIMIT"`
+	Headers map[string][]string `env:"HEADER"`
+	Socket  string              `env:"SOCKET" default:"/var/run/app.sock" default.linux:"/run/app.sock" default.windows:"\\\\.\\pipe\\app"`
+
+	Database Database
+	Cache    *Cache
+	Metrics  *Metrics
+
+	Embedded
+
+	Ignored func()
+	token   string `env:"TOKEN"`
+}
+
+type Database struct {
+	URL   string `env:"DATABASE_URL"`
+	Conns int    `env:"DATABASE_CONNS" defaultFrom:"CONNS"`
+}
+
+type Cache struct {
+	Addr string `env:"CACHE_ADDR"`
+}
+
+type Metrics struct {
+	Addr string `env:"METRICS_ADDR"`
+}
+
+type Embedded struct {
+	Region string `env:"REGION"`
+	Zone   string `env:"ZONE" required:"true"`
+}
@@ -0,0 +1,91 @@
+package envi
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// collisions caches the result of checkCollisions per struct type.
+var collisions sync.Map
+
+// binding is a variable key or map prefix that a field is bound to.
+type binding struct {
+	field  string
+	key    string
+	prefix bool
+}
+
+// checkCollisions returns an error if two fields of the struct type t,
+// including the fields of nested structs, are bound to the same variable, or
+// if the prefix of a map field shadows the variable of another field.
+func checkCollisions(t reflect.Type) error {
+	if err, ok := collisions.Load(t); ok {
+		return err.(errorOrNil).err
+	}
+
+	var bindings []binding
+	if t.Kind() == reflect.Struct {
+		collectBindings(&bindings, schemaOf(t), "")
+	}
+
+	var err error
+	for i, a := range bindings {
+		for _, b := range bindings[i+1:] {
+			if err = collides(a, b); err != nil {
+				break
+			}
+		}
+		if err != nil {
+			break
+		}
+	}
+
+	collisions.Store(t, errorOrNil{err})
+	return err
+}
+
+// errorOrNil allows nil errors to be stored in a sync.Map.
+type errorOrNil struct{ err error }
+
+func collides(a, b binding) error {
+	switch {
+	case !a.prefix && !b.prefix:
+		if a.key == b.key {
+			return fmt.Errorf("fields %s and %s are both bound to %s", a.field, b.field, a.key)
+		}
+	case a.prefix && b.prefix:
+		if strings.HasPrefix(a.key, b.key) || strings.HasPrefix(b.key, a.key) {
+			return fmt.Errorf("map fields %s and %s have overlapping prefixes %s and %s", a.field, b.field, a.key, b.key)
+		}
+	default:
+		if b.prefix {
+			a, b = b, a
+		}
+		if strings.HasPrefix(b.key, a.key) {
+			return fmt.Errorf("%s of field %s is shadowed by the prefix %s of map field %s", b.key, b.field, a.key, a.field)
+		}
+	}
+	return nil
+}
+
+func collectBindings(bindings *[]binding, schema *structSchema, path string) {
+	for n := range schema.fields {
+		field := &schema.fields[n]
+		switch {
+		case field.recursive || !field.settable():
+		case field.isStruct:
+			collectBindings(bindings, field.nested(), field.nestedPath(path))
+		case field.rest || !field.hasKey:
+		case isPrefixMap(field.typ):
+			prefix := field.key
+			if prefix != "" {
+				prefix += "_"
+			}
+			*bindings = append(*bindings, binding{field: path + field.name, key: prefix, prefix: true})
+		default:
+			*bindings = append(*bindings, binding{field: path + field.name, key: field.key})
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package envi_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/bounoable/envi"
+)
+
+type collisionDB struct {
+	URL string `env:"DB_URL"`
+}
+
+// TestParse_collisions verifies that Parse fails if two fields are bound to
+// the same variable or a map prefix shadows the variable of another field.
+func TestParse_collisions(t *testing.T) {
+	type sameKey struct {
+		Primary collisionDB
+		Replica collisionDB
+	}
+	type shadowed struct {
+		Labels map[string]string `env:"LABEL"`
+		Team   string            `env:"LABEL_TEAM"`
+	}
+	type overlapping struct {
+		Limits    map[string]int `env:"LIMIT"`
+		CPULimits map[string]int `env:"LIMIT_CPU"`
+	}
+	type distinct struct {
+		Labels map[string]string `env:"LABEL"`
+		Label  string            `env:"LABELS"`
+		DB     collisionDB
+		Extra  map[string]string `env:",rest"`
+	}
+	type skipped struct {
+		A      string            `env:"-"`
+		B      string            `env:"-"`
+		Labels map[string]string `env:"-"`
+		DB     collisionDB       `env:"-"`
+		URL    string            `env:"DB_URL"`
+	}
+
+	tests := []struct {
+		name    string
+		parse   func() error
+		wantErr string
+	}{
+		{
+			name:    "same key",
+			parse:   func() error { _, err := envi.New[sameKey](); return err },
+			wantErr: "fields Primary.URL and Replica.URL are both bound to DB_URL",
+		},
+		{
+			name:    "shadowed key",
+			parse:   func() error { _, err := envi.New[shadowed](); return err },
+			wantErr: "LABEL_TEAM of field Team is shadowed by the prefix LABEL_ of map field Labels",
+		},
+		{
+			name:    "overlapping prefixes",
+			parse:   func() error { _, err := envi.New[overlapping](); return err },
+			wantErr: "map fields Limits and CPULimits have overlapping prefixes LIMIT_ and LIMIT_CPU_",
+		},
+		{
+			name:  "distinct",
+			parse: func() error { _, err := envi.New[distinct](envi.WithSource(envi.Map{})); return err },
+		},
+		{
+			name: "skipped fields",
+			parse: func() error {
+				e, err := envi.New[skipped](envi.WithSource(envi.Map{"-": "x", "DB_URL": "postgres://"}))
+				if err == nil && (e.A != "" || e.B != "" || e.Labels != nil || e.URL != "postgres://") {
+					return fmt.Errorf("env = %v, want only URL set", e)
+				}
+				return err
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.parse()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("New() failed: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("New() should fail with %q; got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
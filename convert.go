@@ -0,0 +1,206 @@
+package envi
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// converters caches the converter of every type parsed so far, keyed by
+// reflect.Type, so the conversion of a type is planned once instead of on
+// every Parse call.
+var converters sync.Map
+
+// converter converts a value to the type it was planned for. It reports false
+// if the value is empty and the type needs a value.
+type converter func(p *parser, value string) (reflect.Value, bool, error)
+
+// converterOf returns the converter of type t.
+func converterOf(t reflect.Type) converter {
+	if c, ok := converters.Load(t); ok {
+		return c.(converter)
+	}
+	c, _ := converters.LoadOrStore(t, newConverter(t))
+	return c.(converter)
+}
+
+func newConverter(t reflect.Type) converter {
+	convert := planConversion(t)
+	required := valueRequired(t.Kind())
+
+	return func(p *parser, value string) (reflect.Value, bool, error) {
+		if value == "" {
+			if required {
+				return reflect.Value{}, false, nil
+			}
+			return convert(p, value)
+		}
+
+		// Enums may be registered after the conversion was planned.
+		if e, ok := enumOf(t); ok {
+			v, err := e.parse(value)
+			return v, err == nil, err
+		}
+		return convert(p, value)
+	}
+}
+
+// planConversion returns the function that converts values to type t.
+func planConversion(t reflect.Type) converter {
+	switch {
+	case t == durationType:
+		return func(_ *parser, value string) (reflect.Value, bool, error) {
+			d, err := time.ParseDuration(value)
+			return reflect.ValueOf(d), err == nil, err
+		}
+
+	case t == bytesType:
+		return func(_ *parser, value string) (reflect.Value, bool, error) {
+			b, err := ParseBytes(value)
+			return reflect.ValueOf(b), err == nil, err
+		}
+
+	case t == urlType:
+		return func(_ *parser, value string) (reflect.Value, bool, error) {
+			u, err := url.Parse(value)
+			if err != nil {
+				return reflect.Value{}, false, err
+			}
+			return reflect.ValueOf(*u), true, nil
+		}
+
+	case isText(t):
+		return func(_ *parser, value string) (reflect.Value, bool, error) {
+			out := reflect.New(t)
+			if err := out.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(value)); err != nil {
+				return reflect.Value{}, false, err
+			}
+			return out.Elem(), true, nil
+		}
+
+	case t == locationType:
+		return func(_ *parser, value string) (reflect.Value, bool, error) {
+			loc, err := time.LoadLocation(value)
+			if err != nil {
+				return reflect.Value{}, false, fmt.Errorf("unknown time zone %q: %w", value, err)
+			}
+			return reflect.ValueOf(loc), true, nil
+		}
+
+	case isJSONType(t):
+		return func(_ *parser, value string) (reflect.Value, bool, error) {
+			out := reflect.New(t)
+			if err := json.Unmarshal([]byte(value), out.Interface()); err != nil {
+				return reflect.Value{}, false, fmt.Errorf("decode JSON: %w", err)
+			}
+			return out.Elem(), true, nil
+		}
+
+	case t == rawMessageType:
+		return func(_ *parser, value string) (reflect.Value, bool, error) {
+			if !json.Valid([]byte(value)) {
+				return reflect.Value{}, false, fmt.Errorf("invalid JSON: %q", value)
+			}
+			return reflect.ValueOf(json.RawMessage(value)), true, nil
+		}
+	}
+
+	switch kind := t.Kind(); kind {
+	case reflect.String:
+		return func(_ *parser, value string) (reflect.Value, bool, error) {
+			out := reflect.New(t).Elem()
+			out.SetString(value)
+			return out, true, nil
+		}
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return func(_ *parser, value string) (reflect.Value, bool, error) {
+			n, err := parseInt(value, t.Bits())
+			if err != nil {
+				return reflect.Value{}, false, err
+			}
+			out := reflect.New(t).Elem()
+			out.SetInt(n)
+			return out, true, nil
+		}
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return func(_ *parser, value string) (reflect.Value, bool, error) {
+			n, err := parseUint(value, t.Bits())
+			if err != nil {
+				return reflect.Value{}, false, err
+			}
+			out := reflect.New(t).Elem()
+			out.SetUint(n)
+			return out, true, nil
+		}
+
+	case reflect.Float32, reflect.Float64:
+		return func(p *parser, value string) (reflect.Value, bool, error) {
+			f, err := p.parseFloat(value, t.Bits())
+			if err != nil {
+				return reflect.Value{}, false, err
+			}
+			out := reflect.New(t).Elem()
+			out.SetFloat(f)
+			return out, true, nil
+		}
+
+	case reflect.Complex64, reflect.Complex128:
+		return func(_ *parser, value string) (reflect.Value, bool, error) {
+			c, err := strconv.ParseComplex(value, t.Bits())
+			if err != nil {
+				return reflect.Value{}, false, err
+			}
+			out := reflect.New(t).Elem()
+			out.SetComplex(c)
+			return out, true, nil
+		}
+
+	case reflect.Bool:
+		return func(_ *parser, value string) (reflect.Value, bool, error) {
+			out := reflect.New(t).Elem()
+			out.SetBool(parseBool(value))
+			return out, true, nil
+		}
+
+	case reflect.Array:
+		return func(p *parser, value string) (reflect.Value, bool, error) {
+			vals, err := splitList(value, p.list)
+			if err != nil {
+				return reflect.Value{}, false, err
+			}
+			return p.parseArray(vals, t)
+		}
+
+	case reflect.Slice:
+		return func(p *parser, value string) (reflect.Value, bool, error) {
+			vals, err := splitList(value, p.list)
+			if err != nil {
+				return reflect.Value{}, false, err
+			}
+			return p.parseSlice(vals, t)
+		}
+
+	case reflect.Pointer:
+		return func(p *parser, value string) (reflect.Value, bool, error) {
+			v, ok, err := p.parseValue(value, t.Elem())
+			if err != nil || !ok {
+				return reflect.Value{}, false, err
+			}
+			ptr := reflect.New(v.Type())
+			ptr.Elem().Set(v)
+			return ptr, true, nil
+		}
+
+	default:
+		return func(*parser, string) (reflect.Value, bool, error) {
+			return reflect.Value{}, false, fmt.Errorf("unsupported Kind: %q", kind)
+		}
+	}
+}
@@ -0,0 +1,218 @@
+package envi
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Decryptor decrypts ciphertext with a key that is managed outside of envi,
+// e.g. by AWS KMS or an age identity.
+type Decryptor interface {
+	Decrypt(ctx context.Context, ciphertext []byte) ([]byte, error)
+}
+
+// DecryptorFunc is a function that implements Decryptor.
+type DecryptorFunc func(ctx context.Context, ciphertext []byte) ([]byte, error)
+
+// Decrypt implements Decryptor.
+func (fn DecryptorFunc) Decrypt(ctx context.Context, ciphertext []byte) ([]byte, error) {
+	return fn(ctx, ciphertext)
+}
+
+// WithDecryptor decrypts values of the form "<scheme>:<ciphertext>", e.g.
+// "age:-----BEGIN AGE ENCRYPTED FILE-----...", with d before they are
+// converted, so individual secrets can be committed in encrypted form:
+//
+//	envi.Parse(&env, envi.WithDecryptor("age", envi.AgeIdentityFile("key.txt")))
+//
+// d receives the ciphertext without the scheme. Values with other schemes
+// are used as is.
+func WithDecryptor(scheme string, d Decryptor) Option {
+	return func(p *parser) {
+		if p.decryptors == nil {
+			p.decryptors = make(map[string]Decryptor)
+		}
+		p.decryptors[scheme] = d
+	}
+}
+
+// WithKMS decrypts values of the form "kms:<ciphertext>", where ciphertext is
+// the base64-encoded output of a key management service such as AWS KMS,
+// with d before they are converted:
+//
+//	envi.WithKMS(envi.DecryptorFunc(func(ctx context.Context, blob []byte) ([]byte, error) {
+//		out, err := client.Decrypt(ctx, &kms.DecryptInput{CiphertextBlob: blob})
+//		if err != nil {
+//			return nil, err
+//		}
+//		return out.Plaintext, nil
+//	}))
+//
+// d receives the decoded ciphertext. Other providers can be added with
+// WithDecryptor and a scheme of their own, using Base64Decryptor.
+func WithKMS(d Decryptor) Option {
+	return WithDecryptor("kms", Base64Decryptor(d))
+}
+
+// Base64Decryptor returns a Decryptor that decodes base64-encoded ciphertext
+// before it is decrypted by d.
+func Base64Decryptor(d Decryptor) Decryptor {
+	return DecryptorFunc(func(ctx context.Context, ciphertext []byte) ([]byte, error) {
+		blob := make([]byte, base64.StdEncoding.DecodedLen(len(ciphertext)))
+		n, err := base64.StdEncoding.Decode(blob, bytes.TrimSpace(ciphertext))
+		if err != nil {
+			return nil, fmt.Errorf("decode ciphertext: %w", err)
+		}
+		return d.Decrypt(ctx, blob[:n])
+	})
+}
+
+// decrypt decrypts value, the value of the variable with the given key, if
+// it has the scheme of a Decryptor.
+func (p *parser) decrypt(key, value string) (string, error) {
+	scheme, ciphertext, ok := strings.Cut(value, ":")
+	if !ok {
+		return value, nil
+	}
+	d, ok := p.decryptors[scheme]
+	if !ok {
+		return value, nil
+	}
+
+	plaintext, err := d.Decrypt(p.ctx, []byte(ciphertext))
+	if err != nil {
+		return "", fmt.Errorf("decrypt %q: %w", key, err)
+	}
+	return string(plaintext), nil
+}
+
+// AgeIdentityFile returns a Decryptor that decrypts armored or binary age
+// ciphertext with the identities of the key file at path, such as one that
+// was created with age-keygen. Decryption is delegated to the age command,
+// which must be installed:
+//
+//	envi.WithDecryptor("age", envi.AgeIdentityFile(os.Getenv("AGE_KEY_FILE")))
+func AgeIdentityFile(path string) Decryptor {
+	return DecryptorFunc(func(ctx context.Context, ciphertext []byte) ([]byte, error) {
+		if err := lookAge(); err != nil {
+			return nil, err
+		}
+		return runCommand(ctx, ciphertext, nil, "age", "--decrypt", "--identity", path)
+	})
+}
+
+// AgeIdentity is like AgeIdentityFile, but decrypts with the given
+// identities, e.g. the contents of a key file that was read from a secret
+// store, without writing them to disk:
+//
+//	envi.WithDecryptor("age", envi.AgeIdentity(os.Getenv("AGE_SECRET_KEY")))
+//
+// The identities are passed to the age command through a pipe, which is not
+// supported on Windows.
+func AgeIdentity(identities string) Decryptor {
+	return DecryptorFunc(func(ctx context.Context, ciphertext []byte) ([]byte, error) {
+		if err := lookAge(); err != nil {
+			return nil, err
+		}
+
+		r, w, err := os.Pipe()
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		go func() {
+			io.WriteString(w, identities)
+			w.Close()
+		}()
+
+		// The first extra file of a command is its file descriptor 3.
+		return runCommand(ctx, ciphertext, []*os.File{r}, "age", "--decrypt", "--identity", "/dev/fd/3")
+	})
+}
+
+// lookAge returns a descriptive error if the age command is not installed.
+func lookAge() error {
+	if _, err := exec.LookPath("age"); err != nil {
+		return fmt.Errorf("age command not found; install it from https://age-encryption.org or use a Decryptor of your own: %w", err)
+	}
+	return nil
+}
+
+// CommandDecryptor returns a Decryptor that runs the command with the given
+// name and arguments, passing the ciphertext as its standard input and using
+// its standard output as the plaintext, e.g. for gpg or age.
+func CommandDecryptor(name string, args ...string) Decryptor {
+	return DecryptorFunc(func(ctx context.Context, ciphertext []byte) ([]byte, error) {
+		return runCommand(ctx, ciphertext, nil, name, args...)
+	})
+}
+
+// Encryptor encrypts plaintext with a key that is managed outside of envi. It
+// is the counterpart of Decryptor, e.g. for tools that author encrypted
+// values.
+type Encryptor interface {
+	Encrypt(ctx context.Context, plaintext []byte) ([]byte, error)
+}
+
+// EncryptorFunc is a function that implements Encryptor.
+type EncryptorFunc func(ctx context.Context, plaintext []byte) ([]byte, error)
+
+// Encrypt implements Encryptor.
+func (fn EncryptorFunc) Encrypt(ctx context.Context, plaintext []byte) ([]byte, error) {
+	return fn(ctx, plaintext)
+}
+
+// AgeRecipients returns an Encryptor that encrypts plaintext as armored age
+// ciphertext for the given recipients, e.g. public keys created with
+// age-keygen, which can be decrypted with AgeIdentityFile. Encryption is
+// delegated to the age command, which must be installed.
+func AgeRecipients(recipients ...string) Encryptor {
+	args := []string{"--encrypt", "--armor"}
+	for _, r := range recipients {
+		args = append(args, "--recipient", r)
+	}
+	return EncryptorFunc(func(ctx context.Context, plaintext []byte) ([]byte, error) {
+		if err := lookAge(); err != nil {
+			return nil, err
+		}
+		return runCommand(ctx, plaintext, nil, "age", args...)
+	})
+}
+
+// CommandEncryptor returns an Encryptor that runs the command with the given
+// name and arguments, passing the plaintext as its standard input and using
+// its standard output as the ciphertext.
+func CommandEncryptor(name string, args ...string) Encryptor {
+	return EncryptorFunc(func(ctx context.Context, plaintext []byte) ([]byte, error) {
+		return runCommand(ctx, plaintext, nil, name, args...)
+	})
+}
+
+// runCommand runs the command with the given name and arguments with input as
+// its standard input and the extra open files, and returns its standard
+// output.
+func runCommand(ctx context.Context, input []byte, extra []*os.File, name string, args ...string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.ExtraFiles = extra
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if msg := strings.TrimSpace(stderr.String()); msg != "" && errors.As(err, &exitErr) {
+			return nil, fmt.Errorf("%s: %w: %s", name, err, msg)
+		}
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,173 @@
+package envi_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/bounoable/envi"
+	"github.com/google/go-cmp/cmp"
+)
+
+// TestWithDecryptor verifies that values with the scheme of a Decryptor are
+// decrypted before they are converted, and that other values are used as is.
+func TestWithDecryptor(t *testing.T) {
+	type decryptEnv struct {
+		Password string `env:"DECRYPT_PASSWORD"`
+		Port     int    `env:"DECRYPT_PORT"`
+		URL      string `env:"DECRYPT_URL"`
+	}
+
+	upper := envi.DecryptorFunc(func(_ context.Context, ciphertext []byte) ([]byte, error) {
+		return []byte(strings.ToUpper(string(ciphertext))), nil
+	})
+	vars := envi.Map{
+		"DECRYPT_PASSWORD": "upper:s3cr3t",
+		"DECRYPT_PORT":     "upper:8080",
+		"DECRYPT_URL":      "https://example.com",
+	}
+
+	raw := make(map[string]string)
+	e, err := envi.New[decryptEnv](envi.WithSource(vars), envi.WithDecryptor("upper", upper), envi.WithRawValues(raw))
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	want := decryptEnv{Password: "S3CR3T", Port: 8080, URL: "https://example.com"}
+	if !cmp.Equal(want, e) {
+		t.Fatalf("env = %v, want = %v\n\n%s", e, want, cmp.Diff(want, e))
+	}
+	if raw["DECRYPT_PASSWORD"] != "upper:s3cr3t" {
+		t.Fatalf("raw value = %q, want ciphertext", raw["DECRYPT_PASSWORD"])
+	}
+
+	failed := errors.New("no identity matched")
+	_, err = envi.New[decryptEnv](envi.WithSource(vars), envi.WithDecryptor("upper", envi.DecryptorFunc(
+		func(context.Context, []byte) ([]byte, error) { return nil, failed },
+	)))
+	if !errors.Is(err, failed) {
+		t.Fatalf("New() should fail with %v; got %v", failed, err)
+	}
+}
+
+// TestWithKMS verifies that "kms:" values are decoded and decrypted with the
+// given Decryptor.
+func TestWithKMS(t *testing.T) {
+	kms := envi.DecryptorFunc(func(_ context.Context, blob []byte) ([]byte, error) {
+		if !bytes.HasPrefix(blob, []byte{0x01, 0x02}) {
+			return nil, errors.New("invalid ciphertext")
+		}
+		return blob[2:], nil
+	})
+
+	var e struct {
+		Password string `env:"KMS_PASSWORD"`
+		Timeout  int    `env:"KMS_TIMEOUT"`
+	}
+	err := envi.Parse(&e, envi.WithKMS(kms), envi.WithSource(envi.Map{
+		"KMS_PASSWORD": "kms:" + base64.StdEncoding.EncodeToString([]byte("\x01\x02s3cr3t")),
+		"KMS_TIMEOUT":  "kms:" + base64.StdEncoding.EncodeToString([]byte("\x01\x0230")),
+	}))
+	if err != nil {
+		t.Fatalf("Parse() failed: %v", err)
+	}
+	if e.Password != "s3cr3t" || e.Timeout != 30 {
+		t.Fatalf("Password = %q, Timeout = %d; want %q, 30", e.Password, e.Timeout, "s3cr3t")
+	}
+
+	for _, value := range []string{"kms:not base64", "kms:" + base64.StdEncoding.EncodeToString([]byte("plain"))} {
+		err := envi.Parse(&e, envi.WithKMS(kms), envi.WithSource(envi.Map{"KMS_PASSWORD": value}))
+		if err == nil {
+			t.Fatalf("Parse() should fail for %q", value)
+		}
+	}
+}
+
+// TestAgeIdentityFile verifies that age ciphertext is decrypted by the age
+// command with the given identity file.
+func TestAgeIdentityFile(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake age command is a shell script")
+	}
+
+	// The fake age command checks its arguments and "decrypts" by prefixing
+	// the ciphertext. It only uses builtins, as other tests clear PATH.
+	dir := t.TempDir()
+	script := "#!/bin/sh\n" +
+		`[ "$1 $2 $3" = "--decrypt --identity key.txt" ] || { echo "bad arguments: $*" >&2; exit 1; }` + "\n" +
+		`read -r line; printf 'plain-%s\n' "$line"` + "\n"
+	if err := os.WriteFile(filepath.Join(dir, "age"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	var e struct {
+		Token string `env:"AGE_TOKEN"`
+	}
+	err := envi.Parse(&e,
+		envi.WithSource(envi.Map{"AGE_TOKEN": "age:token\n"}),
+		envi.WithDecryptor("age", envi.AgeIdentityFile("key.txt")),
+	)
+	if err != nil {
+		t.Fatalf("Parse() failed: %v", err)
+	}
+	if e.Token != "plain-token\n" {
+		t.Fatalf("Token = %q, want %q", e.Token, "plain-token\n")
+	}
+
+	err = envi.Parse(&e,
+		envi.WithSource(envi.Map{"AGE_TOKEN": "age:token"}),
+		envi.WithDecryptor("age", envi.AgeIdentityFile("other.txt")),
+	)
+	if err == nil || !strings.Contains(err.Error(), "bad arguments") {
+		t.Fatalf("Parse() should fail with the output of age; got %v", err)
+	}
+}
+
+// TestAgeIdentity verifies that age ciphertext is decrypted with identities
+// that are passed to the age command without a key file, and that a missing
+// age command is reported as such.
+func TestAgeIdentity(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake age command is a shell script")
+	}
+
+	// The fake age command "decrypts" by prefixing the ciphertext with the
+	// identity that it reads from the file of its --identity flag.
+	dir := t.TempDir()
+	script := "#!/bin/sh\n" +
+		`[ "$1 $2" = "--decrypt --identity" ] || { echo "bad arguments: $*" >&2; exit 1; }` + "\n" +
+		`read -r identity < "$3"; read -r line; printf '%s-%s\n' "$identity" "$line"` + "\n"
+	if err := os.WriteFile(filepath.Join(dir, "age"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir)
+
+	var e struct {
+		Token string `env:"AGE_TOKEN"`
+	}
+	err := envi.Parse(&e,
+		envi.WithSource(envi.Map{"AGE_TOKEN": "age:token\n"}),
+		envi.WithDecryptor("age", envi.AgeIdentity("AGE-SECRET-KEY-1XYZ\n")),
+	)
+	if err != nil {
+		t.Fatalf("Parse() failed: %v", err)
+	}
+	if want := "AGE-SECRET-KEY-1XYZ-token\n"; e.Token != want {
+		t.Fatalf("Token = %q, want %q", e.Token, want)
+	}
+
+	t.Setenv("PATH", t.TempDir())
+	err = envi.Parse(&e,
+		envi.WithSource(envi.Map{"AGE_TOKEN": "age:token"}),
+		envi.WithDecryptor("age", envi.AgeIdentityFile("key.txt")),
+	)
+	if err == nil || !strings.Contains(err.Error(), "age command not found") {
+		t.Fatalf("Parse() should fail with a missing age command; got %v", err)
+	}
+}
@@ -0,0 +1,352 @@
+package envi
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+// defaultExprFuncs overrides the template builtins that could be used to
+// escape the sandbox of a `defaultExpr` tag.
+var defaultExprFuncs = template.FuncMap{
+	"call": func(...any) (any, error) {
+		return nil, errors.New("call is not allowed in defaultExpr")
+	},
+}
+
+// maxExprDepth is the depth up to which the values of nested structs, slices
+// and maps are visible to `defaultExpr` tags.
+const maxExprDepth = 8
+
+// parseDefaultExprs parses the `defaultExpr` tags of the fields of the struct
+// type t and returns them, keyed by field index, together with their
+// evaluation order.
+func parseDefaultExprs(t reflect.Type) (map[int]*template.Template, []int, error) {
+	exprs := make(map[int]*template.Template)
+	deps := make(map[int][]int)
+	for n := 0; n < t.NumField(); n++ {
+		field := t.Field(n)
+		expr, ok := field.Tag.Lookup("defaultExpr")
+		if !ok {
+			continue
+		}
+
+		tmpl, err := template.New(field.Name).
+			Option("missingkey=error").
+			Funcs(defaultExprFuncs).
+			Parse(expr)
+		if err == nil {
+			err = checkDefaultExpr(tmpl.Root)
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("parse defaultExpr of %q field: %w", field.Name, err)
+		}
+		exprs[n] = tmpl
+
+		refs := make(map[string]bool)
+		templateFields(tmpl.Root, refs, true)
+		for i := 0; i < t.NumField(); i++ {
+			if refs[t.Field(i).Name] {
+				deps[n] = append(deps[n], i)
+			}
+		}
+	}
+
+	if len(exprs) == 0 {
+		return nil, nil, nil
+	}
+
+	order, err := sortDefaultExprs(t, exprs, deps)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return exprs, order, nil
+}
+
+// applyDefaultExprs evaluates the `defaultExpr` tags of the fields of the
+// struct val that were not resolved from the environment. Expressions are
+// text/template templates that may reference the sibling fields of the
+// struct, e.g. `defaultExpr:"{{.Host}}:{{.Port}}"`, and are evaluated after
+// the fields they reference. Only the exported, non-func fields of the struct
+// are visible to an expression, as plain values without methods; see
+// exprValue. If the parser merges, only the expressions of fields that are
+// still zero are evaluated.
+func (p *parser) applyDefaultExprs(val reflect.Value, s *structSchema, resolved []bool) error {
+	if s.exprErr != nil {
+		return s.exprErr
+	}
+	if len(s.exprs) == 0 {
+		return nil
+	}
+
+	data := make(map[string]any, len(s.dataFields))
+	for _, n := range s.dataFields {
+		data[s.fields[n].name] = exprValue(val.Field(n), 0)
+	}
+
+	for _, n := range s.exprOrder {
+		if resolved[n] || p.merge && !val.Field(n).IsZero() {
+			continue
+		}
+
+		field := s.fields[n]
+
+		var buf strings.Builder
+		if err := s.exprs[n].Execute(&buf, data); err != nil {
+			return fmt.Errorf("evaluate defaultExpr of %q field: %w", field.name, err)
+		}
+
+		v, ok, err := p.parseFieldValue(buf.String(), &field)
+		if err != nil {
+			return fmt.Errorf("parse defaultExpr of %q field: %w", field.name, err)
+		}
+		if !ok {
+			continue
+		}
+
+		val.Field(n).Set(v)
+		resolved[n] = true
+		if _, ok := data[field.name]; ok {
+			data[field.name] = exprValue(val.Field(n), 0)
+		}
+	}
+
+	return nil
+}
+
+// exprValue returns the value of v as it is visible to `defaultExpr` tags.
+// Expressions see plain values without methods, so they cannot call methods
+// of fields such as Lazy.MustGet. Value fields are unwrapped, values that
+// implement fmt.Stringer are formatted, e.g. time.Duration and Lazy fields,
+// which are not resolved, structs become maps of their exported fields, and
+// slices and maps hold the values of their elements. Funcs, channels and
+// values nested deeper than maxExprDepth are nil.
+func exprValue(v reflect.Value, depth int) any {
+	if !v.IsValid() || depth > maxExprDepth {
+		return nil
+	}
+	if isWrapper(v.Type()) {
+		return exprValue(v.FieldByName("Value"), depth)
+	}
+	if s, ok := stringer(v); ok {
+		return s.String()
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return exprValue(v.Elem(), depth)
+	case reflect.Bool:
+		return v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint()
+	case reflect.Float32, reflect.Float64:
+		return v.Float()
+	case reflect.Complex64, reflect.Complex128:
+		return v.Complex()
+	case reflect.String:
+		return v.String()
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return nil
+		}
+		out := make([]any, v.Len())
+		for i := range out {
+			out[i] = exprValue(v.Index(i), depth+1)
+		}
+		return out
+	case reflect.Map:
+		if v.IsNil() {
+			return nil
+		}
+		out := make(map[string]any, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			out[fmt.Sprint(exprValue(iter.Key(), depth+1))] = exprValue(iter.Value(), depth+1)
+		}
+		return out
+	case reflect.Struct:
+		out := make(map[string]any, v.NumField())
+		for n := 0; n < v.NumField(); n++ {
+			if v.Type().Field(n).IsExported() {
+				out[v.Type().Field(n).Name] = exprValue(v.Field(n), depth+1)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+// stringer returns v as a fmt.Stringer if its type or, for addressable
+// values, its pointer type implements it.
+func stringer(v reflect.Value) (fmt.Stringer, bool) {
+	if v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		return nil, false
+	}
+	if v.Type().Implements(stringerType) && v.CanInterface() {
+		return v.Interface().(fmt.Stringer), true
+	}
+	if v.CanAddr() && reflect.PointerTo(v.Type()).Implements(stringerType) && v.Addr().CanInterface() {
+		return v.Addr().Interface().(fmt.Stringer), true
+	}
+	return nil, false
+}
+
+var stringerType = reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
+
+// checkDefaultExpr returns an error if the template node of a `defaultExpr`
+// tag contains a range action or invokes a template. Without them, the number
+// of steps that evaluating the expression takes is bounded by its length.
+func checkDefaultExpr(node parse.Node) error {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return nil
+		}
+		for _, child := range n.Nodes {
+			if err := checkDefaultExpr(child); err != nil {
+				return err
+			}
+		}
+	case *parse.IfNode:
+		return checkDefaultExprBranch(&n.BranchNode)
+	case *parse.WithNode:
+		return checkDefaultExprBranch(&n.BranchNode)
+	case *parse.RangeNode:
+		return errors.New("range is not allowed in defaultExpr")
+	case *parse.TemplateNode:
+		return errors.New("template is not allowed in defaultExpr")
+	}
+	return nil
+}
+
+func checkDefaultExprBranch(n *parse.BranchNode) error {
+	if err := checkDefaultExpr(n.List); err != nil {
+		return err
+	}
+	return checkDefaultExpr(n.ElseList)
+}
+
+// sortDefaultExprs returns the indices of the fields with a `defaultExpr` tag
+// in evaluation order, so that every expression is evaluated after the
+// expressions it depends on. It returns an error if the expressions contain a
+// cycle.
+func sortDefaultExprs(t reflect.Type, exprs map[int]*template.Template, deps map[int][]int) ([]int, error) {
+	const (
+		visiting = iota + 1
+		visited
+	)
+
+	state := make(map[int]int)
+	order := make([]int, 0, len(exprs))
+	var path []int
+
+	var visit func(n int) error
+	visit = func(n int) error {
+		switch state[n] {
+		case visited:
+			return nil
+		case visiting:
+			var cycle []string
+			for i := len(path) - 1; i >= 0 && path[i] != n; i-- {
+				cycle = append(cycle, t.Field(path[i]).Name)
+			}
+			cycle = append(cycle, t.Field(n).Name)
+			for i, j := 0, len(cycle)-1; i < j; i, j = i+1, j-1 {
+				cycle[i], cycle[j] = cycle[j], cycle[i]
+			}
+			cycle = append(cycle, t.Field(n).Name)
+			return fmt.Errorf("defaultExpr cycle: %s", strings.Join(cycle, " -> "))
+		}
+
+		state[n] = visiting
+		path = append(path, n)
+		for _, dep := range deps[n] {
+			if _, ok := exprs[dep]; !ok {
+				continue
+			}
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		state[n] = visited
+		order = append(order, n)
+
+		return nil
+	}
+
+	for n := 0; n < t.NumField(); n++ {
+		if _, ok := exprs[n]; !ok {
+			continue
+		}
+		if err := visit(n); err != nil {
+			return nil, err
+		}
+	}
+
+	return order, nil
+}
+
+// templateFields collects the names of the top-level fields referenced by the
+// template node into refs. root reports whether dot is the struct in node;
+// fields of dot are only collected if it is, as {{with}} and {{range}} set dot
+// to other values in their bodies. Fields of $ are always collected.
+func templateFields(node parse.Node, refs map[string]bool, root bool) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			templateFields(child, refs, root)
+		}
+	case *parse.ActionNode:
+		templateFields(n.Pipe, refs, root)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, cmd := range n.Cmds {
+			templateFields(cmd, refs, root)
+		}
+	case *parse.CommandNode:
+		for _, arg := range n.Args {
+			templateFields(arg, refs, root)
+		}
+	case *parse.ChainNode:
+		templateFields(n.Node, refs, root)
+	case *parse.FieldNode:
+		if root {
+			refs[n.Ident[0]] = true
+		}
+	case *parse.VariableNode:
+		if len(n.Ident) > 1 && n.Ident[0] == "$" {
+			refs[n.Ident[1]] = true
+		}
+	case *parse.IfNode:
+		templateBranchFields(&n.BranchNode, refs, root, root)
+	case *parse.RangeNode:
+		templateBranchFields(&n.BranchNode, refs, root, false)
+	case *parse.WithNode:
+		templateBranchFields(&n.BranchNode, refs, root, false)
+	case *parse.TemplateNode:
+		templateFields(n.Pipe, refs, root)
+	}
+}
+
+// templateBranchFields collects the fields referenced by the branch node n
+// into refs. body reports whether dot is the struct in the body of n.
+func templateBranchFields(n *parse.BranchNode, refs map[string]bool, root, body bool) {
+	templateFields(n.Pipe, refs, root)
+	templateFields(n.List, refs, body)
+	templateFields(n.ElseList, refs, root)
+}
@@ -0,0 +1,304 @@
+package envi_test
+
+import (
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bounoable/envi"
+	"github.com/google/go-cmp/cmp"
+)
+
+type defaultEnv struct {
+	Host    string        `env:"DEFAULT_HOST" default:"localhost"`
+	Port    int           `env:"DEFAULT_PORT" default:"8080"`
+	Debug   bool          `env:"DEFAULT_DEBUG" default:"true"`
+	Timeout time.Duration `env:"DEFAULT_TIMEOUT" default:"5s"`
+	Addr    string        `env:"DEFAULT_ADDR" defaultExpr:"{{.Host}}:{{.Port}}"`
+	URL     string        `env:"DEFAULT_URL" defaultExpr:"http://{{.Addr}}/{{.Path}}"`
+	Path    string        `env:"DEFAULT_PATH" defaultExpr:"{{if .Debug}}debug{{else}}api{{end}}"`
+}
+
+// TestParse_default verifies that the `default` and `defaultExpr` tags are
+// applied to unset variables, that expressions are evaluated after the
+// fields they reference, and that explicit values take precedence.
+func TestParse_default(t *testing.T) {
+	tests := []struct {
+		name        string
+		environment map[string]string
+		want        defaultEnv
+	}{
+		{
+			name: "defaults",
+			want: defaultEnv{
+				Host:    "localhost",
+				Port:    8080,
+				Debug:   true,
+				Timeout: 5 * time.Second,
+				Addr:    "localhost:8080",
+				URL:     "http://localhost:8080/debug",
+				Path:    "debug",
+			},
+		},
+		{
+			name: "overrides",
+			environment: map[string]string{
+				"DEFAULT_HOST":  "example.com",
+				"DEFAULT_DEBUG": "false",
+				"DEFAULT_ADDR":  "example.com:443",
+			},
+			want: defaultEnv{
+				Host:    "example.com",
+				Port:    8080,
+				Debug:   false,
+				Timeout: 5 * time.Second,
+				Addr:    "example.com:443",
+				URL:     "http://example.com:443/api",
+				Path:    "api",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Clearenv()
+			for k, v := range tt.environment {
+				os.Setenv(k, v)
+			}
+
+			e, err := envi.New[defaultEnv]()
+			if err != nil {
+				t.Fatalf("New() failed: %v", err)
+			}
+
+			if !cmp.Equal(tt.want, e) {
+				t.Fatalf("env = %v, want = %v\n\n%s", e, tt.want, cmp.Diff(tt.want, e))
+			}
+		})
+	}
+}
+
+// TestParse_defaultExprErrors verifies that cyclic, unknown and disallowed
+// references in `defaultExpr` tags are reported as errors.
+func TestParse_defaultExprErrors(t *testing.T) {
+	os.Clearenv()
+
+	type cyclic struct {
+		A string `env:"A" defaultExpr:"{{.B}}"`
+		B string `env:"B" defaultExpr:"{{.C}}"`
+		C string `env:"C" defaultExpr:"{{.A}}"`
+	}
+	if _, err := envi.New[cyclic](); err == nil || !strings.Contains(err.Error(), "A -> B -> C -> A") {
+		t.Fatalf("New() should fail with a cycle error; got %v", err)
+	}
+
+	type unknown struct {
+		A string `env:"A" defaultExpr:"{{.Missing}}"`
+	}
+	if _, err := envi.New[unknown](); err == nil {
+		t.Fatalf("New() should fail for an unknown field reference")
+	}
+
+	type call struct {
+		Fn func() string
+		A  string `env:"A" defaultExpr:"{{call .Fn}}"`
+	}
+	if _, err := envi.New[call](); err == nil {
+		t.Fatalf("New() should fail for a call expression")
+	}
+
+	type loop struct {
+		Hosts []string `env:"HOSTS" default:"a,b"`
+		A     string   `env:"A" defaultExpr:"{{range .Hosts}}{{range $.Hosts}}x{{end}}{{end}}"`
+	}
+	if _, err := envi.New[loop](); err == nil || !strings.Contains(err.Error(), "range is not allowed") {
+		t.Fatalf("New() should fail for a range action; got %v", err)
+	}
+
+	type recursive struct {
+		A string `env:"A" defaultExpr:"{{define \"x\"}}{{template \"x\"}}{{end}}{{template \"x\"}}"`
+	}
+	if _, err := envi.New[recursive](); err == nil || !strings.Contains(err.Error(), "template is not allowed") {
+		t.Fatalf("New() should fail for a template action; got %v", err)
+	}
+
+	type lazy struct {
+		DB envi.Lazy[string] `env:"DB"`
+		A  string            `env:"A" defaultExpr:"{{.DB.MustGet}}"`
+	}
+	src := &countingLookups{Map: envi.Map{"DB": "postgres://localhost"}}
+	if _, err := envi.New[lazy](envi.WithSource(src)); err == nil || src.lookups != 1 {
+		t.Fatalf("New() should fail without resolving the Lazy field; got %v after %d lookups", err, src.lookups)
+	}
+}
+
+// TestParse_defaultExprValues verifies that `defaultExpr` tags see plain
+// values of the fields of the struct.
+func TestParse_defaultExprValues(t *testing.T) {
+	type valuesEnv struct {
+		Timeout time.Duration     `env:"TIMEOUT" default:"5s"`
+		Port    envi.Value[int]   `env:"PORT" default:"8080"`
+		Debug   envi.Value[bool]  `env:"DEBUG"`
+		Labels  map[string]string `env:"LABEL"`
+		Hosts   []string          `env:"HOSTS" default:"a,b"`
+		DB      struct {
+			User string `env:"USER"`
+		} `envPrefix:"DB_"`
+		Lazy envi.Lazy[string] `env:"LAZY"`
+		A    string            `env:"A" defaultExpr:"{{.Timeout}} {{.Port}} {{if .Debug}}debug{{else}}release{{end}} {{.Labels.team}} {{index .Hosts 1}} {{.DB.User}} {{.Lazy}}"`
+	}
+
+	e, err := envi.New[valuesEnv](envi.WithSource(envi.Map{"LABEL_team": "core", "DB_USER": "admin"}))
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	if want := "5s 8080 release core b admin <lazy>"; e.A != want {
+		t.Fatalf("A = %q, want %q", e.A, want)
+	}
+}
+
+// TestParse_defaultExprWith verifies that fields referenced inside {{with}}
+// bodies are not mistaken for fields of the struct.
+func TestParse_defaultExprWith(t *testing.T) {
+	type withEnv struct {
+		DB struct {
+			Host string `env:"HOST"`
+			Port int    `env:"PORT"`
+		} `envPrefix:"DB_"`
+		Port int    `env:"PORT" defaultExpr:"{{with .DB}}{{.Port}}{{end}}"`
+		Host string `env:"HOST" defaultExpr:"{{with .DB}}{{.Host}}:{{$.Port}}{{else}}localhost{{end}}"`
+	}
+
+	e, err := envi.New[withEnv](envi.WithSource(envi.Map{"DB_HOST": "db", "DB_PORT": "5432"}))
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	if e.Port != 5432 || e.Host != "db:5432" {
+		t.Fatalf("Port, Host = %d, %q; want %d, %q", e.Port, e.Host, 5432, "db:5432")
+	}
+}
+
+// TestParse_defaultFrom verifies that fields fall back to the variables of
+// their `defaultFrom` tag, in order, before their `default` tag.
+func TestParse_defaultFrom(t *testing.T) {
+	type defaultFromEnv struct {
+		GlobalTimeout time.Duration `env:"GLOBAL_TIMEOUT"`
+		DBTimeout     time.Duration `env:"DB_TIMEOUT" defaultFrom:"GLOBAL_TIMEOUT"`
+		CacheTimeout  time.Duration `env:"CACHE_TIMEOUT" defaultFrom:"CACHE_DEFAULT_TIMEOUT, GLOBAL_TIMEOUT"`
+		HTTPTimeout   time.Duration `env:"HTTP_TIMEOUT" defaultFrom:"HTTP_DEFAULT_TIMEOUT" default:"10s"`
+	}
+
+	os.Clearenv()
+	os.Setenv("GLOBAL_TIMEOUT", "30s")
+	os.Setenv("CACHE_TIMEOUT", "1s")
+
+	var report envi.Report
+	e, err := envi.New[defaultFromEnv](envi.WithReport(&report))
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	want := defaultFromEnv{
+		GlobalTimeout: 30 * time.Second,
+		DBTimeout:     30 * time.Second,
+		CacheTimeout:  time.Second,
+		HTTPTimeout:   10 * time.Second,
+	}
+	if !cmp.Equal(want, e) {
+		t.Fatalf("env = %v, want = %v\n\n%s", e, want, cmp.Diff(want, e))
+	}
+
+	if keys := report.Fields[1].Keys; !cmp.Equal([]string{"DB_TIMEOUT", "GLOBAL_TIMEOUT"}, keys) {
+		t.Fatalf("consulted keys = %v, want %v", keys, []string{"DB_TIMEOUT", "GLOBAL_TIMEOUT"})
+	}
+
+	os.Unsetenv("CACHE_TIMEOUT")
+	os.Setenv("CACHE_DEFAULT_TIMEOUT", "5s")
+	if e, err = envi.New[defaultFromEnv](); err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	if e.CacheTimeout != 5*time.Second {
+		t.Fatalf("CacheTimeout = %v, want %v", e.CacheTimeout, 5*time.Second)
+	}
+}
+
+// TestWithProfile verifies that `default.<profile>` tags override the default
+// of a field for the selected profile.
+func TestWithProfile(t *testing.T) {
+	type profileEnv struct {
+		LogLevel string `env:"PROFILE_LOG_LEVEL" default:"debug" default.prod:"info"`
+		Replicas int    `env:"PROFILE_REPLICAS" default:"1" default.prod:"3" default.staging:"2"`
+		Region   string `env:"PROFILE_REGION" default:"eu"`
+	}
+
+	tests := []struct {
+		profile string
+		want    profileEnv
+	}{
+		{profile: "", want: profileEnv{LogLevel: "debug", Replicas: 1, Region: "eu"}},
+		{profile: "staging", want: profileEnv{LogLevel: "debug", Replicas: 2, Region: "eu"}},
+		{profile: "prod", want: profileEnv{LogLevel: "info", Replicas: 3, Region: "eu"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.profile, func(t *testing.T) {
+			e, err := envi.New[profileEnv](envi.WithSource(envi.Map{}), envi.WithProfile(tt.profile))
+			if err != nil {
+				t.Fatalf("New() failed: %v", err)
+			}
+			if e != tt.want {
+				t.Fatalf("env = %+v, want %+v", e, tt.want)
+			}
+		})
+	}
+
+	e, err := envi.New[profileEnv](envi.WithSource(envi.Map{"PROFILE_LOG_LEVEL": "warn"}), envi.WithProfile("prod"))
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	if e.LogLevel != "warn" {
+		t.Fatalf("LogLevel = %q, want %q", e.LogLevel, "warn")
+	}
+}
+
+// TestParse_goosDefault verifies that `default.<GOOS>` tags override the
+// default of a field on the operating system the program runs on, and are
+// overridden by `default.<profile>` tags.
+func TestParse_goosDefault(t *testing.T) {
+	type goosEnv struct {
+		Socket string `env:"GOOS_SOCKET" default:"/var/run/app.sock" default.linux:"/run/app.sock" default.darwin:"/tmp/app.sock" default.windows:"\\\\.\\pipe\\app"`
+		Shell  string `env:"GOOS_SHELL" default:"sh" default.windows:"cmd" default.prod:"bash"`
+	}
+
+	want := goosEnv{Socket: "/var/run/app.sock", Shell: "sh"}
+	switch runtime.GOOS {
+	case "linux":
+		want.Socket = "/run/app.sock"
+	case "darwin":
+		want.Socket = "/tmp/app.sock"
+	case "windows":
+		want = goosEnv{Socket: `\\.\pipe\app`, Shell: "cmd"}
+	}
+
+	e, err := envi.New[goosEnv](envi.WithSource(envi.Map{}))
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	if e != want {
+		t.Fatalf("env = %+v, want %+v", e, want)
+	}
+
+	if vars := envi.Variables[goosEnv](); vars[0].Default != want.Socket {
+		t.Fatalf("Variables()[0].Default = %q, want %q", vars[0].Default, want.Socket)
+	}
+
+	e, err = envi.New[goosEnv](envi.WithSource(envi.Map{}), envi.WithProfile("prod"))
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	if e.Shell != "bash" {
+		t.Fatalf("Shell = %q, want %q", e.Shell, "bash")
+	}
+}
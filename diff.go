@@ -0,0 +1,111 @@
+package envi
+
+import (
+	"reflect"
+	"strings"
+)
+
+// FieldChange describes a field whose value differs between two Configs.
+type FieldChange struct {
+	// Field is the path of the field, e.g. "Database.URL".
+	Field string
+
+	// Key is the environment variable of the field, or the prefix of the
+	// variables of a map field. It is empty for fields that are only
+	// computed by a `defaultExpr` tag.
+	Key string
+
+	// Old and New are the formatted values of the field. They are redacted
+	// for fields with a `secret` tag and the fields of structs with one.
+	Old, New string
+}
+
+// Diff compares two parsed Configs field by field and returns the changes in
+// the order of the fields, e.g. to log what changed on reload:
+//
+//	w.Subscribe(func(old, new Config) {
+//		for _, c := range envi.Diff(old, new) {
+//			log.Printf("%s changed from %q to %q", c.Field, c.Old, c.New)
+//		}
+//	})
+//
+// Only fields that are resolved by Parse are compared; Lazy fields are not,
+// as that would resolve them. Config must be a struct or a pointer to a
+// struct; Diff returns nil otherwise.
+func Diff[Config any](old, new Config) []FieldChange {
+	ov, nv := reflect.ValueOf(&old).Elem(), reflect.ValueOf(&new).Elem()
+	for ov.Kind() == reflect.Pointer {
+		ov, nv = derefStruct(ov), derefStruct(nv)
+	}
+	if ov.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var changes []FieldChange
+	diffStruct(&changes, nil, schemaOf(ov.Type()), ov, nv)
+	return changes
+}
+
+func diffStruct(changes *[]FieldChange, path []string, schema *structSchema, ov, nv reflect.Value) {
+	for n := range schema.fields {
+		field := &schema.fields[n]
+		if !field.exported || field.recursive {
+			continue
+		}
+
+		of, nf := ov.Field(n), nv.Field(n)
+
+		if field.isStruct {
+			if field.isPointer {
+				of, nf = derefStruct(of), derefStruct(nf)
+			}
+			nested := path
+			if !field.squash {
+				nested = append(path, field.name)
+			}
+			diffStruct(changes, nested, field.nested(), of, nf)
+			continue
+		}
+
+		if !field.hasKey && !field.hasDefaultExpr {
+			continue
+		}
+
+		if equalValues(of, nf) {
+			continue
+		}
+
+		change := FieldChange{
+			Field: strings.Join(append(path, field.name), "."),
+			Key:   field.key,
+			Old:   formatValue(of),
+			New:   formatValue(nf),
+		}
+		if field.secret {
+			change.Old, change.New = redacted, redacted
+		}
+		*changes = append(*changes, change)
+	}
+}
+
+func equalValues(a, b reflect.Value) bool {
+	switch {
+	case isLazy(a.Type()):
+		// Lazy values are not resolved to compare them, and their resolve
+		// functions never compare as equal.
+		return true
+	case a.Type() == locationType:
+		// Locations that were loaded separately differ in their caches.
+		return formatValue(a) == formatValue(b)
+	}
+	return reflect.DeepEqual(a.Interface(), b.Interface())
+}
+
+// derefStruct returns the struct that v points to, or the zero struct if v is
+// nil.
+func derefStruct(v reflect.Value) reflect.Value {
+	if v.IsNil() {
+		return reflect.Zero(v.Type().Elem())
+	}
+	return v.Elem()
+}
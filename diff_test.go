@@ -0,0 +1,117 @@
+package envi_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bounoable/envi"
+	"github.com/google/go-cmp/cmp"
+)
+
+type diffEnv struct {
+	Host     string            `env:"HOST"`
+	Port     int               `env:"PORT"`
+	Timeout  time.Duration     `env:"TIMEOUT"`
+	Password string            `env:"PASSWORD" secret:"true"`
+	Labels   map[string]string `env:"LABEL"`
+	Addr     string            `defaultExpr:"{{.Host}}:{{.Port}}"`
+	Database *struct {
+		URL string `env:"DATABASE_URL"`
+	}
+	Ignored string
+}
+
+// TestDiff verifies that Diff reports the changed fields in field order and
+// redacts secrets.
+func TestDiff(t *testing.T) {
+	old := diffEnv{
+		Host:     "localhost",
+		Port:     8080,
+		Timeout:  time.Second,
+		Password: "hunter2",
+		Labels:   map[string]string{"team": "core"},
+		Addr:     "localhost:8080",
+		Ignored:  "a",
+	}
+
+	new := old
+	new.Port = 9090
+	new.Password = "hunter3"
+	new.Labels = map[string]string{"team": "platform"}
+	new.Addr = "localhost:9090"
+	new.Ignored = "b"
+	new.Database = &struct {
+		URL string `env:"DATABASE_URL"`
+	}{URL: "postgres://localhost"}
+
+	want := []envi.FieldChange{
+		{Field: "Port", Key: "PORT", Old: "8080", New: "9090"},
+		{Field: "Password", Key: "PASSWORD", Old: "<redacted>", New: "<redacted>"},
+		{Field: "Labels", Key: "LABEL", Old: "map[team:core]", New: "map[team:platform]"},
+		{Field: "Addr", Old: "localhost:8080", New: "localhost:9090"},
+		{Field: "Database.URL", Key: "DATABASE_URL", Old: "", New: "postgres://localhost"},
+	}
+
+	changes := envi.Diff(old, new)
+	if !cmp.Equal(want, changes) {
+		t.Fatalf("Diff() = %v, want = %v\n\n%s", changes, want, cmp.Diff(want, changes))
+	}
+
+	if changes := envi.Diff(&old, &old); len(changes) != 0 {
+		t.Fatalf("Diff() of equal configs should be empty; got %v", changes)
+	}
+}
+
+type secretDiffEnv struct {
+	Host     string `env:"HOST"`
+	Database struct {
+		User    string `env:"USER"`
+		Replica *struct {
+			Password string `env:"REPLICA_PASSWORD"`
+		}
+	} `envPrefix:"DB_" secret:"true"`
+}
+
+// TestDiff_nestedSecret verifies that Diff redacts the fields of structs with
+// a `secret` tag, including those of their nested structs.
+func TestDiff_nestedSecret(t *testing.T) {
+	var old, new secretDiffEnv
+	old.Host, new.Host = "a", "b"
+	old.Database.User, new.Database.User = "admin", "root"
+	new.Database.Replica = &struct {
+		Password string `env:"REPLICA_PASSWORD"`
+	}{Password: "hunter2"}
+
+	want := []envi.FieldChange{
+		{Field: "Host", Key: "HOST", Old: "a", New: "b"},
+		{Field: "Database.User", Key: "DB_USER", Old: "<redacted>", New: "<redacted>"},
+		{Field: "Database.Replica.Password", Key: "DB_REPLICA_PASSWORD", Old: "<redacted>", New: "<redacted>"},
+	}
+
+	changes := envi.Diff(old, new)
+	if !cmp.Equal(want, changes) {
+		t.Fatalf("Diff() = %v, want = %v\n\n%s", changes, want, cmp.Diff(want, changes))
+	}
+}
+
+// TestDiff_lazy verifies that Diff doesn't report Lazy fields of configs that
+// were parsed separately as changed.
+func TestDiff_lazy(t *testing.T) {
+	type lazyDiffEnv struct {
+		Token envi.Lazy[string] `env:"TOKEN"`
+	}
+
+	src := envi.WithSource(envi.Map{"TOKEN": "foo"})
+	old, err := envi.New[lazyDiffEnv](src)
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	new, err := envi.New[lazyDiffEnv](src)
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	if changes := envi.Diff(old, new); len(changes) != 0 {
+		t.Fatalf("Diff() should be empty; got %v", changes)
+	}
+}
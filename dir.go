@@ -0,0 +1,78 @@
+package envi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Dir returns a Source that treats every file in the directory at path as a
+// variable, with the file name as key and the file contents as value. This is
+// the layout of ConfigMap and Secret volumes mounted into Kubernetes pods. A
+// single trailing newline is removed from the contents. Hidden files, such as
+// the "..data" entries of projected volumes, are ignored.
+func Dir(path string) Source {
+	return dirSource(path)
+}
+
+type dirSource string
+
+func (d dirSource) Lookup(ctx context.Context, key string) (string, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return "", false, err
+	}
+
+	if !validDirKey(key) {
+		return "", false, nil
+	}
+
+	b, err := os.ReadFile(filepath.Join(string(d), key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+
+	v := strings.TrimSuffix(string(b), "\n")
+	v = strings.TrimSuffix(v, "\r")
+
+	return v, true, nil
+}
+
+func (d dirSource) Keys(ctx context.Context) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(string(d))
+	if err != nil {
+		return nil, fmt.Errorf("read directory: %w", err)
+	}
+
+	keys := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if !validDirKey(e.Name()) {
+			continue
+		}
+
+		// Follow symlinks, which Kubernetes uses for the files of projected
+		// volumes.
+		info, err := os.Stat(filepath.Join(string(d), e.Name()))
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+
+		keys = append(keys, e.Name())
+	}
+
+	return keys, nil
+}
+
+func validDirKey(key string) bool {
+	return key != "" && !strings.HasPrefix(key, ".") && !strings.ContainsAny(key, `/\`)
+}
@@ -0,0 +1,64 @@
+package envi_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bounoable/envi"
+	"github.com/google/go-cmp/cmp"
+)
+
+// TestDir verifies that the files of a directory laid out like a Kubernetes
+// projected volume resolve as variables, and that hidden entries and paths
+// outside the directory are ignored.
+func TestDir(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "secrets")
+	data := filepath.Join(dir, "..2024_01_01_00_00_00.000000000")
+
+	if err := os.MkdirAll(data, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"SOURCE_HOST":    "example.com\n",
+		"SOURCE_PORT":    "8080",
+		"SOURCE_LABEL_a": "1",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(data, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink(filepath.Base(data), filepath.Join(dir, "..data")); err != nil {
+		t.Fatal(err)
+	}
+	for name := range files {
+		if err := os.Symlink(filepath.Join("..data", name), filepath.Join(dir, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(root, "SOURCE_OUTSIDE"), []byte("outside"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	os.Clearenv()
+
+	e, err := envi.New[sourceEnv](envi.WithSource(envi.Dir(dir)))
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	want := sourceEnv{
+		Host:   "example.com",
+		Port:   8080,
+		Labels: map[string]string{"a": "1"},
+	}
+	if !cmp.Equal(want, e) {
+		t.Fatalf("env = %v, want = %v\n\n%s", e, want, cmp.Diff(want, e))
+	}
+
+	if v := envi.Get[string]("../SOURCE_OUTSIDE", envi.WithSource(envi.Dir(dir))); v != "" {
+		t.Fatalf("Get() = %q, want %q", v, "")
+	}
+}
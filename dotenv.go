@@ -0,0 +1,473 @@
+package envi
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// DotEnvVar is a variable of a dotenv file.
+type DotEnvVar struct {
+	Key   string
+	Value string
+
+	// Line is the line number of the variable in the file, starting at 1.
+	Line int
+}
+
+// DotEnvDialect is the syntax of an env file.
+type DotEnvDialect int
+
+const (
+	// DotEnv is the syntax of dotenv files; see ParseDotEnv.
+	DotEnv DotEnvDialect = iota
+
+	// DockerEnvFile is the syntax of the files of `docker run --env-file`.
+	// Lines have the form KEY=VALUE, and lines starting with # are ignored.
+	// Values are taken literally, without quote processing, comments or
+	// expansion, and only leading whitespace is trimmed from lines. A line
+	// with only a key takes the value of the variable from the environment of
+	// the current process, and is left out if the variable is not set.
+	DockerEnvFile
+
+	// SystemdEnvFile is the syntax of the EnvironmentFile= files of systemd
+	// units. Lines starting with # or ; are ignored, and a backslash at the
+	// end of a line continues it. Values may be single- or double-quoted, and
+	// quoted values may span multiple lines. Outside of quotes, a backslash
+	// escapes the next character and trailing whitespace is trimmed. In
+	// double-quoted values, a backslash only escapes ", \, ` and $. A path
+	// passed to ReadDotEnvDialect may be prefixed with "-", in which case a
+	// missing file yields no variables instead of an error.
+	SystemdEnvFile
+
+	// ShellScript is the syntax of shell scripts that export variables, such
+	// as files used with `source env.sh`. Lines are assignments, optionally
+	// prefixed with "export", e.g. `export HOST=localhost PORT=8080`, and
+	// commands can also be separated by semicolons. Quoting and escaping
+	// follow POSIX shells, and $VAR and ${VAR} references are expanded with
+	// the variables assigned before or the environment of the current
+	// process. Other commands, command substitution and other parameter
+	// expansions are not supported.
+	ShellScript
+)
+
+// String returns the name of the dialect, e.g. "docker".
+func (d DotEnvDialect) String() string {
+	switch d {
+	case DotEnv:
+		return "dotenv"
+	case DockerEnvFile:
+		return "docker"
+	case SystemdEnvFile:
+		return "systemd"
+	case ShellScript:
+		return "shell"
+	default:
+		return fmt.Sprintf("DotEnvDialect(%d)", int(d))
+	}
+}
+
+// ReadDotEnv reads the dotenv file at path and returns its variables as a Map,
+// which can be used as a Source:
+//
+//	dotenv, err := envi.ReadDotEnv(".env")
+//	if err != nil {
+//		return err
+//	}
+//	err = envi.Parse(&env, envi.WithSource(envi.OS(), dotenv))
+//
+// If a key occurs multiple times, the last value wins. See ParseDotEnv for the
+// syntax of dotenv files.
+func ReadDotEnv(path string) (Map, error) {
+	return ReadDotEnvDialect(path, DotEnv)
+}
+
+// ReadDotEnvDialect is like ReadDotEnv, but reads a file with the syntax of
+// the given dialect.
+func ReadDotEnvDialect(path string, dialect DotEnvDialect) (Map, error) {
+	optional := dialect == SystemdEnvFile && strings.HasPrefix(path, "-")
+	if optional {
+		path = path[1:]
+	}
+
+	f, err := os.Open(path)
+	if optional && errors.Is(err, fs.ErrNotExist) {
+		return Map{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	vars, err := ParseDotEnvDialect(f, dialect)
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+
+	m := make(Map, len(vars))
+	for _, v := range vars {
+		m[v.Key] = v.Value
+	}
+
+	return m, nil
+}
+
+// ParseDotEnv parses the variables of a dotenv file in the order they occur,
+// including duplicates. Each line has the form KEY=VALUE, optionally prefixed
+// with "export ". Empty lines and lines starting with # are ignored.
+//
+// Values may be quoted. Single-quoted values are taken literally, while
+// double-quoted values support the escape sequences \n, \r, \t, \" and \\.
+// Both may span multiple lines. Unquoted values are trimmed, and a # preceded
+// by whitespace starts a comment.
+func ParseDotEnv(r io.Reader) ([]DotEnvVar, error) {
+	return ParseDotEnvDialect(r, DotEnv)
+}
+
+// ParseDotEnvDialect is like ParseDotEnv, but parses the syntax of the given
+// dialect, e.g. to validate a file exactly as `docker run --env-file` reads it.
+func ParseDotEnvDialect(r io.Reader, dialect DotEnvDialect) ([]DotEnvVar, error) {
+	switch dialect {
+	case DotEnv:
+		return parseDotEnv(r)
+	case DockerEnvFile:
+		return parseDockerEnvFile(r)
+	case SystemdEnvFile:
+		return parseSystemdEnvFile(r)
+	case ShellScript:
+		return parseShellScript(r)
+	default:
+		return nil, fmt.Errorf("unknown dialect %v", dialect)
+	}
+}
+
+func parseDotEnv(r io.Reader) ([]DotEnvVar, error) {
+	var vars []DotEnvVar
+
+	scanner := bufio.NewScanner(r)
+	var line int
+	for scanner.Scan() {
+		line++
+		start := line
+
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		text = strings.TrimPrefix(text, "export ")
+
+		key, value, ok := strings.Cut(text, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" || strings.IndexFunc(key, unicode.IsSpace) >= 0 {
+			return vars, fmt.Errorf("line %d: invalid variable %q", start, text)
+		}
+		value = strings.TrimLeftFunc(value, unicode.IsSpace)
+
+		if value == "" || (value[0] != '"' && value[0] != '\'') {
+			vars = append(vars, DotEnvVar{Key: key, Value: unquotedValue(value), Line: start})
+			continue
+		}
+
+		// Quoted values may span multiple lines.
+		quote := value[0]
+		raw := value[1:]
+		end := closingQuote(raw, quote)
+		for end < 0 {
+			if !scanner.Scan() {
+				return vars, fmt.Errorf("line %d: unterminated quoted value of %s", start, key)
+			}
+			line++
+			raw += "\n" + scanner.Text()
+			end = closingQuote(raw, quote)
+		}
+
+		rest := strings.TrimSpace(raw[end+1:])
+		if rest != "" && !strings.HasPrefix(rest, "#") {
+			return vars, fmt.Errorf("line %d: unexpected %q after quoted value of %s", line, rest, key)
+		}
+
+		value = raw[:end]
+		if quote == '"' {
+			value = unescapeDotEnv(value)
+		}
+
+		vars = append(vars, DotEnvVar{Key: key, Value: value, Line: start})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return vars, err
+	}
+
+	return vars, nil
+}
+
+func parseDockerEnvFile(r io.Reader) ([]DotEnvVar, error) {
+	var vars []DotEnvVar
+
+	scanner := bufio.NewScanner(r)
+	var line int
+	for scanner.Scan() {
+		line++
+
+		text := scanner.Text()
+		if line == 1 {
+			text = strings.TrimPrefix(text, "\uFEFF")
+		}
+		if !utf8.ValidString(text) {
+			return vars, fmt.Errorf("line %d: invalid UTF-8", line)
+		}
+
+		text = strings.TrimLeftFunc(text, unicode.IsSpace)
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		key, value, ok := strings.Cut(text, "=")
+		if key == "" {
+			return vars, fmt.Errorf("line %d: no variable name in %q", line, text)
+		}
+		if strings.ContainsAny(key, " \t") {
+			return vars, fmt.Errorf("line %d: variable %q contains whitespace", line, key)
+		}
+		if !ok {
+			if value, ok = os.LookupEnv(key); !ok {
+				continue
+			}
+		}
+
+		vars = append(vars, DotEnvVar{Key: key, Value: value, Line: line})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return vars, err
+	}
+
+	return vars, nil
+}
+
+func parseSystemdEnvFile(r io.Reader) ([]DotEnvVar, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	s := string(data)
+
+	var vars []DotEnvVar
+	line := 1
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\n':
+			line++
+			continue
+		case ' ', '\t', '\r':
+			continue
+		case '#', ';':
+			// Comments can be continued with a backslash, too.
+			for ; i < len(s) && s[i] != '\n'; i++ {
+				if s[i] == '\\' && i+1 < len(s) {
+					i++
+					if s[i] == '\n' {
+						line++
+					}
+				}
+			}
+			line++
+			continue
+		}
+
+		start := line
+		end := strings.IndexAny(s[i:], "=\n")
+		if end < 0 || s[i+end] == '\n' {
+			text, _, _ := strings.Cut(s[i:], "\n")
+			return vars, fmt.Errorf("line %d: missing '=' in %q", start, text)
+		}
+		key := strings.TrimRight(s[i:i+end], " \t")
+		if !validEnvName(key) {
+			return vars, fmt.Errorf("line %d: invalid variable name %q", start, key)
+		}
+
+		var value string
+		if value, i, line, err = systemdValue(s, i+end+1, line); err != nil {
+			return vars, fmt.Errorf("line %d: %s: %w", start, key, err)
+		}
+		vars = append(vars, DotEnvVar{Key: key, Value: value, Line: start})
+		line++
+	}
+
+	return vars, nil
+}
+
+// systemdValue parses the value of a systemd environment file that starts at
+// s[i]. It returns the value, the index of the newline that ends it or len(s),
+// and the number of the line it ends on.
+func systemdValue(s string, i, line int) (string, int, int, error) {
+	const (
+		preValue = iota
+		unquoted
+		unquotedEscape
+		singleQuoted
+		doubleQuoted
+		doubleQuotedEscape
+	)
+
+	var b strings.Builder
+	// keep is the length of the value without trailing unquoted whitespace.
+	var keep int
+	state := preValue
+	for ; i < len(s); i++ {
+		c := s[i]
+		switch state {
+		case preValue, unquoted:
+			switch {
+			case c == '\n':
+				return b.String()[:keep], i, line, nil
+			case c == '\\':
+				state = unquotedEscape
+			case state == preValue && c == '\'':
+				state = singleQuoted
+			case state == preValue && c == '"':
+				state = doubleQuoted
+			case c == ' ' || c == '\t' || c == '\r':
+				if state == unquoted {
+					b.WriteByte(c)
+				}
+			default:
+				state = unquoted
+				b.WriteByte(c)
+				keep = b.Len()
+			}
+		case unquotedEscape:
+			if c == '\n' {
+				line++
+			} else {
+				b.WriteByte(c)
+				keep = b.Len()
+			}
+			state = unquoted
+		case singleQuoted:
+			if c == '\'' {
+				state = preValue
+				continue
+			}
+			if c == '\n' {
+				line++
+			}
+			b.WriteByte(c)
+			keep = b.Len()
+		case doubleQuoted:
+			switch c {
+			case '"':
+				state = preValue
+				continue
+			case '\\':
+				state = doubleQuotedEscape
+				continue
+			case '\n':
+				line++
+			}
+			b.WriteByte(c)
+			keep = b.Len()
+		case doubleQuotedEscape:
+			switch {
+			case c == '\n':
+				line++
+			case strings.IndexByte("\"\\`$", c) >= 0:
+				b.WriteByte(c)
+			default:
+				b.WriteByte('\\')
+				b.WriteByte(c)
+			}
+			keep = b.Len()
+			state = doubleQuoted
+		}
+	}
+
+	if state == singleQuoted || state == doubleQuoted || state == doubleQuotedEscape {
+		return "", i, line, errors.New("unterminated quoted value")
+	}
+	return b.String()[:keep], i, line, nil
+}
+
+// validEnvName reports whether name consists of letters, digits and
+// underscores and doesn't start with a digit.
+func validEnvName(name string) bool {
+	if name == "" || isDigit(name[0]) {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		if name[i] != '_' && !isAlnum(name[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+func isAlnum(c byte) bool {
+	return isDigit(c) || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+// unquotedValue removes the comment from an unquoted value and trims it.
+func unquotedValue(v string) string {
+	for i := 1; i < len(v); i++ {
+		if v[i] == '#' && (v[i-1] == ' ' || v[i-1] == '\t') {
+			v = v[:i]
+			break
+		}
+	}
+	return strings.TrimSpace(v)
+}
+
+// closingQuote returns the index of the quote that closes s, or -1 if s is not
+// closed. In double-quoted values, quotes can be escaped with a backslash.
+func closingQuote(s string, quote byte) int {
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if quote == '"' {
+				i++
+			}
+		case quote:
+			return i
+		}
+	}
+	return -1
+}
+
+func unescapeDotEnv(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i == len(s)-1 {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 't':
+			b.WriteByte('\t')
+		case '"', '\\':
+			b.WriteByte(s[i])
+		default:
+			b.WriteByte('\\')
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,193 @@
+package envi_test
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bounoable/envi"
+	"github.com/google/go-cmp/cmp"
+)
+
+// TestParseDotEnv verifies the supported syntax of dotenv files.
+func TestParseDotEnv(t *testing.T) {
+	src := `# comment
+HOST=localhost
+export PORT = 8080
+EMPTY=
+NAME=envi # trailing comment
+HASH=a#b
+SINGLE='literal \n # not a comment'
+DOUBLE="line1\nline2 \"quoted\"" # comment
+MULTI="first
+second"
+HOST=example.com
+`
+
+	vars, err := envi.ParseDotEnv(strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("ParseDotEnv() failed: %v", err)
+	}
+
+	want := []envi.DotEnvVar{
+		{Key: "HOST", Value: "localhost", Line: 2},
+		{Key: "PORT", Value: "8080", Line: 3},
+		{Key: "EMPTY", Value: "", Line: 4},
+		{Key: "NAME", Value: "envi", Line: 5},
+		{Key: "HASH", Value: "a#b", Line: 6},
+		{Key: "SINGLE", Value: `literal \n # not a comment`, Line: 7},
+		{Key: "DOUBLE", Value: "line1\nline2 \"quoted\"", Line: 8},
+		{Key: "MULTI", Value: "first\nsecond", Line: 9},
+		{Key: "HOST", Value: "example.com", Line: 11},
+	}
+	if !cmp.Equal(want, vars) {
+		t.Fatalf("vars = %v, want = %v\n\n%s", vars, want, cmp.Diff(want, vars))
+	}
+}
+
+// TestParseDotEnv_invalid verifies that syntax errors are reported with their
+// line number.
+func TestParseDotEnv_invalid(t *testing.T) {
+	tests := map[string]string{
+		"missing separator":  "HOST=localhost\nPORT\n",
+		"invalid key":        "MY HOST=localhost\n",
+		"unterminated quote": "HOST=\"localhost\n",
+		"text after quote":   "HOST='localhost' extra\n",
+	}
+
+	for name, src := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := envi.ParseDotEnv(strings.NewReader(src)); err == nil || !strings.HasPrefix(err.Error(), "line ") {
+				t.Fatalf("ParseDotEnv() should fail with a line number; got %v", err)
+			}
+		})
+	}
+}
+
+// TestParseDotEnvDialect_docker verifies that the DockerEnvFile dialect
+// matches the rules of `docker run --env-file`.
+func TestParseDotEnvDialect_docker(t *testing.T) {
+	t.Setenv("DOCKER_INHERITED", "from host")
+
+	src := "\uFEFFHOST=localhost\n" +
+		"  # comment\n" +
+		"QUOTED=\"value\" # not a comment\n" +
+		"  INDENTED= spaced  \n" +
+		"\n" +
+		"EXPAND=${HOST}\n" +
+		"DOCKER_INHERITED\n" +
+		"DOCKER_UNSET\n" +
+		"EMPTY=\n"
+
+	vars, err := envi.ParseDotEnvDialect(strings.NewReader(src), envi.DockerEnvFile)
+	if err != nil {
+		t.Fatalf("ParseDotEnvDialect() failed: %v", err)
+	}
+
+	want := []envi.DotEnvVar{
+		{Key: "HOST", Value: "localhost", Line: 1},
+		{Key: "QUOTED", Value: `"value" # not a comment`, Line: 3},
+		{Key: "INDENTED", Value: " spaced  ", Line: 4},
+		{Key: "EXPAND", Value: "${HOST}", Line: 6},
+		{Key: "DOCKER_INHERITED", Value: "from host", Line: 7},
+		{Key: "EMPTY", Value: "", Line: 9},
+	}
+	if !cmp.Equal(want, vars) {
+		t.Fatalf("vars = %v, want = %v\n\n%s", vars, want, cmp.Diff(want, vars))
+	}
+
+	for _, src := range []string{"export HOST=localhost\n", "=value\n", "HOST=a\nPORT=\xff\n"} {
+		if _, err := envi.ParseDotEnvDialect(strings.NewReader(src), envi.DockerEnvFile); err == nil || !strings.HasPrefix(err.Error(), "line ") {
+			t.Fatalf("ParseDotEnvDialect(%q) should fail with a line number; got %v", src, err)
+		}
+	}
+}
+
+// TestParseDotEnvDialect_systemd verifies that the SystemdEnvFile dialect
+// matches the rules of systemd's EnvironmentFile=.
+func TestParseDotEnvDialect_systemd(t *testing.T) {
+	src := `# comment \
+continued comment
+; another comment
+HOST = localhost   
+PATH_LIST=/usr/bin\
+:/bin
+SINGLE='literal \n $HOME'
+DOUBLE="a \"quoted\" \$HOME \n
+second line"
+CONCAT='a' "b" c d
+ESCAPED=a\ \#b\\
+INLINE=a 'b'
+EMPTY=
+`
+
+	vars, err := envi.ParseDotEnvDialect(strings.NewReader(src), envi.SystemdEnvFile)
+	if err != nil {
+		t.Fatalf("ParseDotEnvDialect() failed: %v", err)
+	}
+
+	want := []envi.DotEnvVar{
+		{Key: "HOST", Value: "localhost", Line: 4},
+		{Key: "PATH_LIST", Value: "/usr/bin:/bin", Line: 5},
+		{Key: "SINGLE", Value: `literal \n $HOME`, Line: 7},
+		{Key: "DOUBLE", Value: "a \"quoted\" $HOME \\n\nsecond line", Line: 8},
+		{Key: "CONCAT", Value: "abc d", Line: 10},
+		{Key: "ESCAPED", Value: `a #b\`, Line: 11},
+		{Key: "INLINE", Value: "a 'b'", Line: 12},
+		{Key: "EMPTY", Value: "", Line: 13},
+	}
+	if !cmp.Equal(want, vars) {
+		t.Fatalf("vars = %v, want = %v\n\n%s", vars, want, cmp.Diff(want, vars))
+	}
+
+	for _, src := range []string{"HOST\n", "MY HOST=a\n", "1HOST=a\n", "HOST=\"a\n"} {
+		if _, err := envi.ParseDotEnvDialect(strings.NewReader(src), envi.SystemdEnvFile); err == nil || !strings.HasPrefix(err.Error(), "line ") {
+			t.Fatalf("ParseDotEnvDialect(%q) should fail with a line number; got %v", src, err)
+		}
+	}
+}
+
+// TestReadDotEnvDialect_optional verifies that a missing systemd environment
+// file prefixed with "-" yields no variables.
+func TestReadDotEnvDialect_optional(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing.env")
+
+	vars, err := envi.ReadDotEnvDialect("-"+path, envi.SystemdEnvFile)
+	if err != nil {
+		t.Fatalf("ReadDotEnvDialect() failed: %v", err)
+	}
+	if len(vars) != 0 {
+		t.Fatalf("vars = %v, want none", vars)
+	}
+
+	if _, err := envi.ReadDotEnvDialect(path, envi.SystemdEnvFile); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("ReadDotEnvDialect() should fail with %v; got %v", fs.ErrNotExist, err)
+	}
+}
+
+// TestReadDotEnv verifies that a dotenv file can be used as a Source, with
+// later duplicates taking precedence.
+func TestReadDotEnv(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".env")
+	if err := os.WriteFile(path, []byte("SOURCE_HOST=a\nSOURCE_PORT=8080\nSOURCE_HOST=b\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	dotenv, err := envi.ReadDotEnv(path)
+	if err != nil {
+		t.Fatalf("ReadDotEnv() failed: %v", err)
+	}
+
+	e, err := envi.New[sourceEnv](envi.WithSource(dotenv))
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	want := sourceEnv{Host: "b", Port: 8080}
+	if !cmp.Equal(want, e) {
+		t.Fatalf("env = %v, want = %v\n\n%s", e, want, cmp.Diff(want, e))
+	}
+}
@@ -0,0 +1,166 @@
+package envi
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// DSN is a database connection string that is parsed from a URL-style value
+// into its components, e.g. "postgres://app:secret@db:5432/app?sslmode=disable".
+//
+// If the variable of a DSN field is not set, a `dsn` tag assembles the DSN
+// from individual variables with the prefix of the tag instead, e.g. DB_SCHEME,
+// DB_HOST, DB_PORT, DB_USER, DB_PASSWORD, DB_NAME and DB_OPTIONS (in URL query
+// syntax) for `dsn:"DB"`:
+//
+//	type Env struct {
+//		Database envi.DSN `env:"DATABASE_URL" dsn:"DB" secret:"true"`
+//	}
+type DSN struct {
+	Scheme   string
+	User     string
+	Password string
+	Host     string
+	Port     int
+	Database string
+	Options  map[string]string
+}
+
+// dsnVariables are the suffixes of the variables a DSN is assembled from.
+var dsnVariables = []string{"SCHEME", "HOST", "PORT", "USER", "PASSWORD", "NAME", "OPTIONS"}
+
+// ParseDSN parses a URL-style DSN.
+func ParseDSN(s string) (DSN, error) {
+	u, err := url.Parse(s)
+	if err != nil {
+		return DSN{}, fmt.Errorf("invalid DSN: %w", err)
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return DSN{}, fmt.Errorf("invalid DSN %q: missing scheme or host", u.Redacted())
+	}
+
+	dsn := DSN{
+		Scheme:   u.Scheme,
+		Host:     u.Hostname(),
+		Database: strings.TrimPrefix(u.Path, "/"),
+	}
+	if u.User != nil {
+		dsn.User = u.User.Username()
+		dsn.Password, _ = u.User.Password()
+	}
+	if port := u.Port(); port != "" {
+		if dsn.Port, err = strconv.Atoi(port); err != nil {
+			return DSN{}, fmt.Errorf("invalid DSN port %q", port)
+		}
+	}
+	if query := u.Query(); len(query) > 0 {
+		dsn.Options = make(map[string]string, len(query))
+		for k := range query {
+			dsn.Options[k] = query.Get(k)
+		}
+	}
+
+	return dsn, nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (d *DSN) UnmarshalText(text []byte) error {
+	dsn, err := ParseDSN(string(text))
+	if err != nil {
+		return err
+	}
+	*d = dsn
+	return nil
+}
+
+// Addr returns the host and port of the DSN, e.g. "db:5432".
+func (d DSN) Addr() string {
+	if d.Port == 0 {
+		return d.Host
+	}
+	return net.JoinHostPort(d.Host, strconv.Itoa(d.Port))
+}
+
+// URL returns the DSN as a URL.
+func (d DSN) URL() *url.URL {
+	u := &url.URL{
+		Scheme: d.Scheme,
+		Host:   d.Addr(),
+	}
+	if d.Database != "" {
+		u.Path = "/" + d.Database
+	}
+	if d.User != "" || d.Password != "" {
+		if d.Password != "" {
+			u.User = url.UserPassword(d.User, d.Password)
+		} else {
+			u.User = url.User(d.User)
+		}
+	}
+	if len(d.Options) > 0 {
+		query := make(url.Values, len(d.Options))
+		for k, v := range d.Options {
+			query.Set(k, v)
+		}
+		u.RawQuery = query.Encode()
+	}
+	return u
+}
+
+// String returns the DSN as a URL, including the password.
+func (d DSN) String() string {
+	return d.URL().String()
+}
+
+// Redacted is like String, but replaces the password with "xxxxx".
+func (d DSN) Redacted() string {
+	return d.URL().Redacted()
+}
+
+// assembleDSN assembles a URL-style DSN from the variables with the given
+// prefix. It returns an empty string if none of the variables is set.
+func (p *parser) assembleDSN(prefix string) (string, error) {
+	vals := make(map[string]string, len(dsnVariables))
+	for _, suffix := range dsnVariables {
+		v, err := p.getenv(prefix + "_" + suffix)
+		if err != nil {
+			return "", err
+		}
+		if v != "" {
+			vals[suffix] = v
+		}
+	}
+	if len(vals) == 0 {
+		return "", nil
+	}
+
+	dsn := DSN{
+		Scheme:   vals["SCHEME"],
+		Host:     vals["HOST"],
+		User:     vals["USER"],
+		Password: vals["PASSWORD"],
+		Database: vals["NAME"],
+	}
+	if port, ok := vals["PORT"]; ok {
+		n, err := strconv.Atoi(port)
+		if err != nil {
+			return "", fmt.Errorf("%s_PORT: invalid port %q", prefix, port)
+		}
+		dsn.Port = n
+	}
+	if opts, ok := vals["OPTIONS"]; ok {
+		query, err := url.ParseQuery(opts)
+		if err != nil {
+			return "", fmt.Errorf("%s_OPTIONS: %w", prefix, err)
+		}
+		dsn.Options = make(map[string]string, len(query))
+		for k := range query {
+			dsn.Options[k] = query.Get(k)
+		}
+	}
+
+	return dsn.String(), nil
+}
@@ -0,0 +1,94 @@
+package envi
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// enums holds the registered enums, keyed by their reflect.Type.
+var enums sync.Map
+
+// enum is a registered enum type.
+type enum struct {
+	values map[string]reflect.Value
+
+	// names are the sorted names of the values, and valueNames are the names
+	// of the values, keyed by the values.
+	names      []string
+	valueNames map[any]string
+}
+
+// RegisterEnum registers the names of the values of the enum type T, so that
+// fields of type T are parsed from the names instead of the underlying
+// values, e.g. for integer-backed log levels:
+//
+//	type LogLevel int
+//
+//	const (
+//		Debug LogLevel = iota
+//		Info
+//	)
+//
+//	func init() {
+//		envi.RegisterEnum(map[string]LogLevel{"debug": Debug, "info": Info})
+//	}
+//
+// Names are matched case-insensitively if there is no exact match, and values
+// that match no name are rejected with an error that lists the valid names.
+// The names are also used to format values of T in traces, reports and
+// flags. Registering T again replaces its names.
+func RegisterEnum[T comparable](names map[string]T) {
+	e := enum{
+		values:     make(map[string]reflect.Value, len(names)),
+		names:      make([]string, 0, len(names)),
+		valueNames: make(map[any]string, len(names)),
+	}
+	for name, v := range names {
+		e.values[name] = reflect.ValueOf(v)
+		e.names = append(e.names, name)
+	}
+	sort.Strings(e.names)
+
+	// Values with multiple names are formatted with the first name.
+	for n := len(e.names) - 1; n >= 0; n-- {
+		e.valueNames[names[e.names[n]]] = e.names[n]
+	}
+
+	enums.Store(reflect.TypeOf((*T)(nil)).Elem(), &e)
+}
+
+// enumOf returns the registered enum of type t.
+func enumOf(t reflect.Type) (*enum, bool) {
+	e, ok := enums.Load(t)
+	if !ok {
+		return nil, false
+	}
+	return e.(*enum), true
+}
+
+// parse returns the value with the given name.
+func (e *enum) parse(name string) (reflect.Value, error) {
+	if v, ok := e.values[name]; ok {
+		return v, nil
+	}
+	for _, n := range e.names {
+		if strings.EqualFold(n, name) {
+			return e.values[n], nil
+		}
+	}
+	return reflect.Value{}, fmt.Errorf("invalid value %q, must be one of %s", name, strings.Join(e.names, ", "))
+}
+
+// enumName returns the name of v if its type is a registered enum and v has a
+// name.
+func enumName(v reflect.Value) (string, bool) {
+	e, ok := enumOf(v.Type())
+	if !ok {
+		return "", false
+	}
+	name, ok := e.valueNames[v.Interface()]
+	return name, ok
+}
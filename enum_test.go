@@ -0,0 +1,58 @@
+package envi_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bounoable/envi"
+	"github.com/google/go-cmp/cmp"
+)
+
+type logLevel int
+
+const (
+	levelDebug logLevel = iota
+	levelInfo
+	levelWarn
+)
+
+type logFormat string
+
+func init() {
+	envi.RegisterEnum(map[string]logLevel{"debug": levelDebug, "info": levelInfo, "warn": levelWarn, "warning": levelWarn})
+}
+
+// TestRegisterEnum verifies that registered enum types are parsed from their
+// names and that invalid names are rejected with the valid names.
+func TestRegisterEnum(t *testing.T) {
+	type enumEnv struct {
+		Level   logLevel   `env:"ENUM_LEVEL" default:"info"`
+		Levels  []logLevel `env:"ENUM_LEVELS"`
+		Pointer *logLevel  `env:"ENUM_POINTER"`
+		Format  logFormat  `env:"ENUM_FORMAT"`
+	}
+
+	var events []envi.TraceEvent
+	e, err := envi.New[enumEnv](envi.WithSource(envi.Map{
+		"ENUM_LEVELS":  "debug,WARNING",
+		"ENUM_POINTER": "Warn",
+		"ENUM_FORMAT":  "json",
+	}), envi.WithTrace(func(e envi.TraceEvent) { events = append(events, e) }))
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	warn := levelWarn
+	want := enumEnv{Level: levelInfo, Levels: []logLevel{levelDebug, levelWarn}, Pointer: &warn, Format: "json"}
+	if !cmp.Equal(want, e) {
+		t.Fatalf("env = %v, want = %v\n\n%s", e, want, cmp.Diff(want, e))
+	}
+	if events[0].Value != "info" || events[2].Value != "warn" {
+		t.Fatalf("trace values = %q, %q; want %q, %q", events[0].Value, events[2].Value, "info", "warn")
+	}
+
+	_, err = envi.New[enumEnv](envi.WithSource(envi.Map{"ENUM_LEVEL": "verbose"}))
+	if want := `invalid value "verbose", must be one of debug, info, warn, warning`; err == nil || !strings.Contains(err.Error(), want) {
+		t.Fatalf("New() should fail with %q; got %v", want, err)
+	}
+}
@@ -1,26 +1,31 @@
 package envi
 
 import (
+	"context"
+	"encoding"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"os"
+	"net/url"
 	"reflect"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // New creates an instance of the provided Env type by parsing environment
 // variables according to the struct tags. It returns the parsed environment and
 // an error if any occurred during parsing.
-func New[Env any]() (Env, error) {
+func New[Env any](opts ...Option) (Env, error) {
 	var env Env
-	err := Parse(&env)
+	err := Parse(&env, opts...)
 	return env, err
 }
 
 // Must creates a new environment of type Env and parses the environment
 // variables into it. If an error occurs during parsing, it panics.
-func Must[Env any]() Env {
-	env, err := New[Env]()
+func Must[Env any](opts ...Option) Env {
+	env, err := New[Env](opts...)
 	if err != nil {
 		panic(err)
 	}
@@ -30,8 +35,8 @@ func Must[Env any]() Env {
 // MustParse parses the given environment variables into the provided env
 // pointer, which must be a pointer to a struct. It panics if there is an error
 // during parsing.
-func MustParse[Env any](env *Env) {
-	if err := Parse(env); err != nil {
+func MustParse[Env any](env *Env, opts ...Option) {
+	if err := Parse(env, opts...); err != nil {
 		panic(err)
 	}
 }
@@ -39,17 +44,299 @@ func MustParse[Env any](env *Env) {
 // Parse populates the provided env pointer, which must be a pointer to a
 // struct, with the parsed values of environment variables specified in the
 // struct tags. It returns an error if the parsing fails.
-func Parse[Env any](env *Env) error {
+func Parse[Env any](env *Env, opts ...Option) error {
+	return ParseContext(context.Background(), env, opts...)
+}
+
+// ParseContext is like Parse, but passes ctx to the configured sources, so
+// lookups that perform I/O can be canceled. Parsing stops with the context's
+// error if ctx is canceled or its deadline is exceeded.
+func ParseContext[Env any](ctx context.Context, env *Env, opts ...Option) error {
+	return newParser(ctx, opts).parseInto(env)
+}
+
+// ParseEnviron is like Parse, but parses the variables of environ, which has
+// the KEY=VALUE form of os.Environ and exec.Cmd.Env, instead of the
+// environment of the current process; see Environ. Sources of opts are
+// consulted after environ.
+func ParseEnviron[Env any](env *Env, environ []string, opts ...Option) error {
+	return Parse(env, append([]Option{WithSource(Environ(environ))}, opts...)...)
+}
+
+// Merge is like Parse, but only writes the fields of env whose variables are
+// set, so values that were set before, e.g. programmatically, are preserved.
+// Defaults and `defaultExpr` tags only apply to fields that are still zero,
+// and required fields are satisfied by non-zero existing values.
+func Merge[Env any](env *Env, opts ...Option) error {
+	p := newParser(context.Background(), opts)
+	p.merge = true
+	return p.parseInto(env)
+}
+
+// ParseValue parses the environment variable with the given key into a value
+// of type T, using the same conversion rules as Parse. For map types, key is
+// used as the prefix of the variables to collect. If the variable is not set,
+// the zero value of T is returned. The options that configure sources,
+// lookups and conversions apply, e.g. WithSource, WithExpand, WithDecodeHook,
+// WithSeparator and WithEmptySlices; options that configure fields or
+// their reporting, such as WithFields and WithTrace, have no effect.
+func ParseValue[T any](key string, opts ...Option) (T, error) {
+	v, _, err := lookupValue[T](key, opts)
+	return v, err
+}
+
+// Get returns the value of the environment variable with the given key,
+// converted to type T using the same conversion rules as Parse. It returns the
+// zero value of T if the variable is not set or cannot be parsed; use
+// ParseValue to handle parse errors explicitly.
+func Get[T any](key string, opts ...Option) T {
+	v, _, _ := lookupValue[T](key, opts)
+	return v
+}
+
+// GetOr returns the value of the environment variable with the given key,
+// converted to type T using the same conversion rules as Parse. It returns
+// fallback if the variable is not set, is empty, or cannot be parsed. With
+// WithEmptySlices, an empty variable of a slice type T yields an empty slice
+// instead.
+func GetOr[T any](key string, fallback T, opts ...Option) T {
+	v, ok, err := lookupValue[T](key, opts)
+	if err != nil || !ok {
+		return fallback
+	}
+	return v
+}
+
+// Decode converts s into the value that out, which must be a non-nil pointer,
+// points to, using the same conversion rules as Parse, including the decode
+// hooks and list options of opts. This allows other inputs, such as
+// command-line arguments or query parameters, to be parsed exactly like
+// variables. out is left unchanged if s is empty.
+func Decode(s string, out any, opts ...Option) error {
+	rv := reflect.ValueOf(out)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return fmt.Errorf("out must be a non-nil pointer, got %T", out)
+	}
+
+	p := newParser(context.Background(), opts)
+	v, ok, err := p.parseValue(s, rv.Elem().Type())
+	if err != nil {
+		return err
+	}
+	if ok {
+		rv.Elem().Set(v)
+	}
+
+	return nil
+}
+
+// lookupValue parses the variable with the given key into a T. The returned
+// bool reports whether a non-empty value was found.
+func lookupValue[T any](key string, opts []Option) (T, bool, error) {
+	var out T
+	rv := reflect.ValueOf(&out).Elem()
+	p := newParser(context.Background(), opts)
+
+	if isPrefixMap(rv.Type()) {
+		v, err := p.parseMap(key, rv.Type(), true, false)
+		if err != nil {
+			return out, false, fmt.Errorf("parse %q: %w", key, err)
+		}
+		rv.Set(v)
+		return out, !rv.IsNil(), nil
+	}
+
+	value, set, err := p.lookup(key)
+	if err != nil {
+		return out, false, err
+	}
+	if set && value == "" && rv.Kind() == reflect.Slice && rv.Type() != rawMessageType && p.list.emptySlice {
+		rv.Set(reflect.MakeSlice(rv.Type(), 0, 0))
+		return out, true, nil
+	}
+	if value, err = p.expandValue(value, true); err != nil {
+		return out, false, err
+	}
+
+	v, ok, err := p.parseValue(value, rv.Type())
+	if err != nil {
+		return out, false, fmt.Errorf("parse %q: %w", key, err)
+	}
+	if !ok {
+		return out, false, nil
+	}
+	rv.Set(v)
+
+	return out, value != "", nil
+}
+
+// Option is an option for Parse.
+type Option func(*parser)
+
+type parser struct {
+	ctx     context.Context
+	sources []Source
+	hooks   []decodeHook
+	warn    func(Warning)
+	trace   func(TraceEvent)
+	report  *Report
+
+	// problems collects the errors of all fields instead of failing on the
+	// first one if it is non-nil; see Check.
+	problems []Problem
+
+	// expand reports whether variable references in values are expanded;
+	// see WithExpand.
+	expand bool
+
+	// deferRequired reports whether the checks of required fields are
+	// skipped; see WithDeferredRequired.
+	deferRequired bool
+
+	// strictEmpty reports whether empty values of non-string fields are
+	// errors; see WithStrictEmpty.
+	strictEmpty bool
+
+	// strictUnexported reports whether unexported fields with an `env` tag
+	// are errors; see WithStrictUnexported.
+	strictUnexported bool
+
+	// skipUnsupported reports whether fields of unsupported types are
+	// ignored; see WithSkipUnsupported.
+	skipUnsupported bool
+
+	// list is the format of list values; it is overridden by the tags of the
+	// field that is being parsed.
+	list listFormat
+
+	// root is the type of the struct that is parsed, and declared holds the
+	// keys and map prefixes it declares; see consumed.
+	root     reflect.Type
+	declared *declaredKeys
+
+	// unescape reports whether escape sequences in values are unescaped;
+	// see WithUnescape.
+	unescape bool
+
+	// indirection reports whether "@KEY" values are resolved; see
+	// WithIndirection.
+	indirection bool
+
+	// references are the Sources of WithReferences, or nil.
+	references *references
+
+	// aliases maps the deprecated keys of WithAliases to their new keys.
+	aliases map[string]string
+
+	// decryptors decrypt values by the prefix of their scheme, e.g. "age";
+	// see WithDecryptor.
+	decryptors map[string]Decryptor
+
+	// profile is the profile whose defaults apply; see WithProfile.
+	profile string
+
+	// namespace is the namespace of WithNamespace, or nil.
+	namespace *namespace
+
+	// fields are the paths of the fields of WithFields, or nil if all
+	// fields are parsed.
+	fields []string
+
+	// prefix is the prefix of the keys of the struct that is parsed; see the
+	// `envPrefix` tag.
+	prefix string
+
+	// secret reports whether the struct that is parsed is the struct of a
+	// field with a `secret` tag, which its fields inherit.
+	secret bool
+
+	// groups are the groups of WithGroups, or nil if all fields are parsed.
+	// group are the groups that the fields of the struct that is parsed
+	// inherit; see fieldGroups.
+	groups []string
+	group  []string
+
+	// decimalComma reports whether floats may have a decimal comma; see
+	// WithDecimalComma.
+	decimalComma bool
+
+	// raw are the raw values of WithRawValues, or nil. secrets are the keys
+	// whose raw values are redacted, once they have been collected.
+	raw     map[string]string
+	secrets *declaredKeys
+
+	// blob is the key of the variable of WithBlob, or empty.
+	blob string
+
+	// parallel is the maximum number of concurrent lookups of
+	// WithParallelLookups, and prefetched holds the results of the lookups,
+	// keyed by variable.
+	parallel   int
+	prefetched map[string]lookupResult
+
+	// merge reports whether only fields whose variables are set are written;
+	// see Merge.
+	merge bool
+
+	// found counts the fields for which a non-empty value was found, to
+	// determine whether any variable of a nested struct is set.
+	found int
+
+	// path holds the names of the struct fields that are being parsed
+	// recursively.
+	path []string
+
+	// keyIndex holds the sorted keys of the sources once they have been
+	// listed, which is recorded by keysListed.
+	keyIndex   []string
+	keysListed bool
+}
+
+func newParser(ctx context.Context, opts []Option) *parser {
+	p := parser{ctx: ctx, list: defaultListFormat}
+	for _, opt := range opts {
+		opt(&p)
+	}
+	if len(p.sources) == 0 {
+		p.sources = []Source{OS()}
+	}
+	if p.warn == nil {
+		p.warn = logWarning
+	}
+	return &p
+}
+
+// parseInto parses the environment into env, which must be a pointer to a
+// struct. env is only modified if parsing succeeds.
+func (p *parser) parseInto(env any) error {
+	if err := p.loadBlob(); err != nil {
+		return err
+	}
+
 	rv := reflect.ValueOf(env)
-	p, err := parseStruct(rv)
+	p.root = rv.Type()
+	if p.root.Kind() == reflect.Pointer && p.root.Elem().Kind() == reflect.Struct {
+		if err := checkCollisions(p.root.Elem()); err != nil {
+			return err
+		}
+		if err := p.checkFields(p.root.Elem()); err != nil {
+			return err
+		}
+		if p.parallel > 1 {
+			p.prefetch(schemaOf(p.root.Elem()))
+		}
+	}
+	parsed, err := p.parseStruct(rv)
 	if err != nil {
 		return err
 	}
-	rv.Elem().Set(p)
+	if p.problems == nil {
+		rv.Elem().Set(parsed)
+	}
 	return nil
 }
 
-func parseStruct(envValue reflect.Value) (reflect.Value, error) {
+func (p *parser) parseStruct(envValue reflect.Value) (reflect.Value, error) {
 	envType := envValue.Type()
 	staticType := envType.Elem()
 
@@ -63,149 +350,335 @@ func parseStruct(envValue reflect.Value) (reflect.Value, error) {
 
 	ptr := reflect.New(staticType)
 	val := ptr.Elem()
+	if p.partial() {
+		val.Set(envValue.Elem())
+	}
 
-	for n := 0; n < val.NumField(); n++ {
-		field := staticType.Field(n)
-		parsed, ok, err := parseField(field)
+	schema := prefixedSchemaOf(staticType, p.prefix, p.secret)
+	if schema.tagErr != nil {
+		if err := p.fail("", "", schema.tagErr); err != nil {
+			return reflect.Value{}, err
+		}
+	}
+
+	resolved := make([]bool, len(schema.fields))
+	results := make([]fieldResult, len(schema.fields))
+	for n := range schema.fields {
+		if err := p.ctx.Err(); err != nil {
+			return reflect.Value{}, err
+		}
+
+		field := &schema.fields[n]
+		if !field.settable() {
+			resolved[n] = true
+			if err := p.checkUnexported(field); err != nil {
+				if err := p.fail(field.name, field.key, err); err != nil {
+					return reflect.Value{}, err
+				}
+			}
+			continue
+		}
+		if p.skips(field) {
+			results[n].skipped = true
+			resolved[n] = true
+			continue
+		}
+
+		parsed, ok, err := p.parseField(field, &results[n], val.Field(n))
+		if results[n].found {
+			p.found++
+		}
+		if err != nil && p.keeps(field, val.Field(n), err) {
+			err = nil
+		}
 		if err != nil {
-			return reflect.Value{}, fmt.Errorf("parse %q field: %w", field.Name, err)
+			if err := p.fail(field.name, field.key, err); err != nil {
+				return reflect.Value{}, err
+			}
 		}
-		if !ok {
+		if ok && (!p.merge || field.isStruct || results[n].set || val.Field(n).IsZero()) {
+			setExported(val.Field(n), parsed)
+			resolved[n] = true
+		}
+
+		if !results[n].consulted {
 			continue
 		}
+		if !resolved[n] && field.hasDefaultExpr {
+			// The field is recorded once its defaultExpr was evaluated.
+			results[n].deferred = true
+			continue
+		}
+		p.recordField(field, results[n], val.Field(n))
+	}
+
+	if err := p.applyDefaultExprs(val, schema, resolved); err != nil {
+		if err := p.fail("", "", err); err != nil {
+			return reflect.Value{}, err
+		}
+	}
 
-		val.Field(n).Set(parsed)
+	for n := range schema.fields {
+		field := &schema.fields[n]
+		if !results[n].deferred {
+			continue
+		}
+		if !resolved[n] {
+			if err := p.checkRequired(field); err != nil && !p.keeps(field, val.Field(n), err) {
+				if err := p.fail(field.name, field.key, err); err != nil {
+					return reflect.Value{}, err
+				}
+			}
+		}
+		results[n].def = resolved[n]
+		p.recordField(field, results[n], val.Field(n))
+	}
+
+	if !p.deferRequired {
+		if err := checkGroups(schema, results); err != nil {
+			if err := p.fail("", "", err); err != nil {
+				return reflect.Value{}, err
+			}
+		}
 	}
 
 	return val, nil
 }
 
-func parseField(field reflect.StructField) (reflect.Value, bool, error) {
-	fieldKind := field.Type.Kind()
+// keeps reports whether err, which was returned for field, is ignored because
+// the parser merges and the required field already has the non-zero value
+// cur.
+func (p *parser) keeps(field *fieldSchema, cur reflect.Value, err error) bool {
+	return p.merge && !field.isStruct && errors.Is(err, ErrRequired) && !cur.IsZero()
+}
 
-	isStruct, isPointer := isStruct(field.Type)
+// parseField parses the value of field and records how it was resolved in res.
+// res is left empty for fields that are not resolved from variables. cur is
+// the current value of the field, which nested structs are merged into if
+// the parser merges; see Merge.
+func (p *parser) parseField(field *fieldSchema, res *fieldResult, cur reflect.Value) (reflect.Value, bool, error) {
+	if field.recursive {
+		return reflect.Value{}, false, fmt.Errorf("recursive struct type %s is not supported", field.typ)
+	}
 
-	if isStruct {
-		ft := field.Type
-		if isPointer {
+	if field.isStruct {
+		ft := field.typ
+		if field.isPointer {
 			ft = ft.Elem()
 		}
 
 		fv := reflect.New(ft)
+		if p.partial() {
+			if field.isPointer && !cur.IsNil() {
+				fv.Elem().Set(cur.Elem())
+			} else if !field.isPointer {
+				setExported(fv.Elem(), cur)
+			}
+		}
 
-		rv, err := parseStruct(fv)
+		found, path, group, prefix, secret := p.found, p.path, p.group, p.prefix, p.secret
+		if !field.squash {
+			p.path = append(p.path, field.name)
+		}
+		p.group, p.prefix, p.secret = p.fieldGroups(field), field.prefix, field.secret
+		rv, err := p.parseStruct(fv)
+		p.path, p.group, p.prefix, p.secret = path, group, prefix, secret
+		res.found = p.found > found
 		if err != nil {
 			return reflect.Value{}, false, err
 		}
 
-		if rv.IsZero() {
+		if rv.IsZero() && !(field.isPointer && field.init) {
 			return reflect.Value{}, false, nil
 		}
 
-		if isPointer {
+		if field.isPointer {
 			rv = rv.Addr()
 		}
 
 		return rv, true, nil
 	}
 
-	if fieldKind == reflect.Map {
-		v, err := parseMap(field)
+	if field.wrapper {
+		return p.parseWrapped(field, res)
+	}
+
+	if field.lazy {
+		if !field.hasKey {
+			return reflect.Value{}, false, nil
+		}
+		// Lazy fields are bound even if the parser merges.
+		res.set = true
+		return p.bindLazy(field), true, nil
+	}
+
+	if field.unsupported && p.skipUnsupported {
+		return reflect.Value{}, false, nil
+	}
+
+	if isPrefixMap(field.typ) {
+		// The values of maps of slices are split with the list format of the
+		// tags of the map field.
+		list := p.list
+		p.list = list.with(field.list)
+		v, err := p.parseMap(field.key, field.typ, field.expand, field.rest)
+		p.list = list
 		if err != nil {
-			return reflect.Value{}, false, fmt.Errorf("parse %q field: %w", field.Name, err)
+			return reflect.Value{}, false, fmt.Errorf("parse %q field: %w", field.name, err)
+		}
+		res.consulted = true
+		res.key = field.key
+		res.set = !v.IsNil()
+		if res.set {
+			res.found = v.Len() > 0
+			p.warnDeprecated(field, field.key)
+		} else if err := p.checkRequired(field); err != nil {
+			return reflect.Value{}, false, err
 		}
-		fv := reflect.New(field.Type).Elem()
-		fv.Set(v)
 		return v, true, nil
 	}
 
-	envKey, ok := field.Tag.Lookup("env")
-	if !ok {
+	if !field.hasKey {
 		return reflect.Value{}, false, nil
 	}
 
-	s := os.Getenv(envKey)
-	return parseValue(s, field.Type)
+	value, source, set, err := p.lookupSource(field.key)
+	if err != nil {
+		return reflect.Value{}, false, err
+	}
+	from := field.key
+	if !set {
+		if from, value, source, set, err = p.lookupAlias(field); err != nil {
+			return reflect.Value{}, false, err
+		}
+	}
+	res.consulted = true
+	res.key, res.set, res.source = field.key, set, source
+	if set {
+		res.from, res.raw = from, value
+	}
+
+	if value != "" {
+		p.warnDeprecated(field, field.key)
+	}
+
+	if set && value == "" && field.typ.Kind() == reflect.Slice && field.typ != rawMessageType &&
+		p.list.with(field.list).emptySlice {
+		return reflect.MakeSlice(field.typ, 0, 0), true, nil
+	}
+
+	if set && value == "" {
+		if err := p.checkEmpty(field); err != nil {
+			return reflect.Value{}, false, err
+		}
+	}
+
+	for _, key := range field.defaultFrom {
+		if value != "" {
+			break
+		}
+		res.fallbacks = append(res.fallbacks, key)
+		if value, source, set, err = p.lookupSource(key); err != nil {
+			return reflect.Value{}, false, err
+		}
+		if set {
+			res.set, res.source = true, source
+			res.from, res.raw = key, value
+		}
+	}
+
+	if value != "" {
+		res.found = true
+	}
+
+	if value == "" && field.dsn != "" {
+		if value, err = p.assembleDSN(field.dsn); err != nil {
+			return reflect.Value{}, false, err
+		}
+		res.found = res.found || value != ""
+	}
+
+	if def, ok := p.defaultOf(field); ok && value == "" {
+		value = def
+		res.def = true
+	}
+
+	if value, err = p.processValue(field, value); err != nil {
+		return reflect.Value{}, false, err
+	}
+
+	if value == "" && !field.hasDefaultExpr {
+		if err := p.checkRequired(field); err != nil {
+			return reflect.Value{}, false, err
+		}
+	}
+
+	v, ok, err := p.parseFieldValue(value, field)
+	if err == nil && !ok && field.init && field.typ.Kind() == reflect.Pointer && !field.hasDefaultExpr {
+		return reflect.New(field.typ.Elem()), true, nil
+	}
+
+	return v, ok, err
 }
 
-func parseValue(value string, t reflect.Type) (reflect.Value, bool, error) {
-	kind := t.Kind()
+// processValue unescapes, expands, transforms and validates the value of
+// field before it is converted, as configured by the tags of field and the
+// options of the parser.
+func (p *parser) processValue(field *fieldSchema, value string) (string, error) {
+	if p.unescapes(field) {
+		value = unescapeValue(value)
+	}
 
-	if value == "" && valueRequired(kind) {
-		return reflect.Value{}, false, nil
+	value, err := p.expandValue(value, field.expand)
+	if err != nil {
+		return "", err
 	}
 
-	switch kind {
-	case reflect.String:
-		return reflect.ValueOf(value), true, nil
-	case reflect.Int:
-		n, err := strconv.ParseInt(value, 10, strconv.IntSize)
-		return reflect.ValueOf(int(n)), err == nil, err
-	case reflect.Int8:
-		n, err := strconv.ParseInt(value, 10, 8)
-		return reflect.ValueOf(int8(n)), err == nil, err
-	case reflect.Int16:
-		n, err := strconv.ParseInt(value, 10, 16)
-		return reflect.ValueOf(int16(n)), err == nil, err
-	case reflect.Int32:
-		n, err := strconv.ParseInt(value, 10, 32)
-		return reflect.ValueOf(int32(n)), err == nil, err
-	case reflect.Int64:
-		n, err := strconv.ParseInt(value, 10, 64)
-		return reflect.ValueOf(n), err == nil, err
-	case reflect.Uint:
-		n, err := strconv.ParseUint(value, 10, strconv.IntSize)
-		return reflect.ValueOf(uint(n)), err == nil, err
-	case reflect.Uint8:
-		n, err := strconv.ParseUint(value, 10, 8)
-		return reflect.ValueOf(uint8(n)), err == nil, err
-	case reflect.Uint16:
-		n, err := strconv.ParseUint(value, 10, 16)
-		return reflect.ValueOf(uint16(n)), err == nil, err
-	case reflect.Uint32:
-		n, err := strconv.ParseUint(value, 10, 32)
-		return reflect.ValueOf(uint32(n)), err == nil, err
-	case reflect.Uint64:
-		n, err := strconv.ParseUint(value, 10, 64)
-		return reflect.ValueOf(uint64(n)), err == nil, err
-	case reflect.Complex64:
-		c, err := strconv.ParseComplex(value, 64)
-		return reflect.ValueOf(complex64(c)), err == nil, err
-	case reflect.Complex128:
-		c, err := strconv.ParseComplex(value, 128)
-		return reflect.ValueOf(c), err == nil, err
-	case reflect.Float64:
-		f, err := strconv.ParseFloat(value, 64)
-		return reflect.ValueOf(f), err == nil, err
-	case reflect.Float32:
-		f, err := strconv.ParseFloat(value, 32)
-		return reflect.ValueOf(float32(f)), err == nil, err
-	case reflect.Bool:
-		return reflect.ValueOf(parseBool(value)), true, nil
-	case reflect.Array:
-		vals := mapSlice(strings.Split(value, ","), strings.TrimSpace)
-		return parseArray(vals, t)
-	case reflect.Slice:
-		vals := mapSlice(strings.Split(value, ","), strings.TrimSpace)
-		return parseSlice(vals, t)
-	case reflect.Pointer:
-		v, ok, err := parseValue(value, t.Elem())
+	if field.expandPath {
+		if value, err = p.expandPath(value); err != nil {
+			return "", err
+		}
+	}
+
+	if value, err = transform(field, value); err != nil {
+		return "", err
+	}
+
+	if value != "" {
+		if err := p.validate(field, value); err != nil {
+			return "", err
+		}
+	}
+
+	return value, nil
+}
+
+// parseFieldValue parses value into the type of field, with the list format
+// overridden by the tags of field.
+func (p *parser) parseFieldValue(value string, field *fieldSchema) (reflect.Value, bool, error) {
+	list := p.list
+	p.list = list.with(field.list)
+	defer func() { p.list = list }()
+
+	return p.parseValue(value, field.typ)
+}
+
+func (p *parser) parseValue(value string, t reflect.Type) (reflect.Value, bool, error) {
+	if len(p.hooks) > 0 {
+		decoded, done, err := p.runDecodeHooks(value, t)
 		if err != nil {
 			return reflect.Value{}, false, err
 		}
-		if !ok {
-			return reflect.Value{}, false, nil
+		if done {
+			return decoded, decoded.IsValid(), nil
 		}
-		p := reflect.New(v.Type())
-		p.Elem().Set(v)
-		return p, true, nil
-
-	default:
-		return reflect.Value{}, false, fmt.Errorf("unsupported Kind: %q", t.Kind())
+		value = decoded.String()
 	}
+
+	return converterOf(t)(p, value)
 }
 
-func parseArray(vals []string, t reflect.Type) (reflect.Value, bool, error) {
+func (p *parser) parseArray(vals []string, t reflect.Type) (reflect.Value, bool, error) {
 	out := reflect.New(t).Elem()
 
 	len := out.Len()
@@ -216,7 +689,7 @@ func parseArray(vals []string, t reflect.Type) (reflect.Value, bool, error) {
 
 		el := out.Index(i)
 
-		v, ok, err := parseValue(val, el.Type())
+		v, ok, err := p.parseValue(val, el.Type())
 		if err != nil {
 			return reflect.Value{}, false, fmt.Errorf("parse array value %q of kind %q: %w", val, el.Kind(), err)
 		}
@@ -229,13 +702,13 @@ func parseArray(vals []string, t reflect.Type) (reflect.Value, bool, error) {
 	return out, true, nil
 }
 
-func parseSlice(vals []string, t reflect.Type) (reflect.Value, bool, error) {
+func (p *parser) parseSlice(vals []string, t reflect.Type) (reflect.Value, bool, error) {
 	out := reflect.MakeSlice(t, len(vals), cap(vals))
 
 	for i, val := range vals {
 		el := out.Index(i)
 
-		v, ok, err := parseValue(val, el.Type())
+		v, ok, err := p.parseValue(val, el.Type())
 		if err != nil {
 			return reflect.Value{}, false, fmt.Errorf("parse array value %q of kind %q: %w", val, el.Kind(), err)
 		}
@@ -248,37 +721,42 @@ func parseSlice(vals []string, t reflect.Type) (reflect.Value, bool, error) {
 	return out, true, nil
 }
 
-func parseMap(field reflect.StructField) (reflect.Value, error) {
-	ft := field.Type
+// parseMap collects the variables with the given prefix into a map of type
+// ft. If rest is set, variables that are consumed by other fields are skipped.
+func (p *parser) parseMap(prefix string, ft reflect.Type, expand, rest bool) (reflect.Value, error) {
 	ftk := ft.Key()
 	vt := ft.Elem()
 
 	mt := reflect.MapOf(ftk, vt)
 
-	prefix := field.Tag.Get("env")
 	if prefix != "" {
 		prefix = prefix + "_"
 	}
 
 	out := reflect.MakeMap(mt)
 
+	keys, err := p.keysWithPrefix(prefix)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+
 	var found int
-	for _, env := range os.Environ() {
-		split := strings.Split(env, "=")
-		if len(split) != 2 {
+	for _, key := range keys {
+		if rest && p.consumed(key) {
 			continue
 		}
 
-		key := split[0]
-		val := split[1]
-
-		if !strings.HasPrefix(key, prefix) {
-			continue
+		val, err := p.getenv(key)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		if val, err = p.expandValue(val, expand); err != nil {
+			return reflect.Value{}, err
 		}
 
 		stripped := strings.TrimPrefix(key, prefix)
 
-		kv, ok, err := parseValue(stripped, ftk)
+		kv, ok, err := p.parseValue(stripped, ftk)
 		if err != nil {
 			return reflect.Value{}, fmt.Errorf("parse map key %q of kind %q: %w", key, ftk.Kind(), err)
 		}
@@ -286,7 +764,7 @@ func parseMap(field reflect.StructField) (reflect.Value, error) {
 			continue
 		}
 
-		vv, ok, err := parseValue(val, vt)
+		vv, ok, err := p.parseValue(val, vt)
 		if err != nil {
 			return reflect.Value{}, fmt.Errorf("parse map value %q of kind %q [key=%s]: %w", val, vt.Kind(), key, err)
 		}
@@ -306,6 +784,9 @@ func parseMap(field reflect.StructField) (reflect.Value, error) {
 }
 
 func parseBool(s string) bool {
+	if s == "" {
+		return false
+	}
 	if b, err := strconv.ParseBool(s); err == nil {
 		return b
 	}
@@ -323,13 +804,62 @@ func mapSlice[In, Out any](s []In, fn func(In) Out) []Out {
 	return out
 }
 
+var (
+	durationType   = reflect.TypeOf(time.Duration(0))
+	rawMessageType = reflect.TypeOf(json.RawMessage(nil))
+	locationType   = reflect.TypeOf((*time.Location)(nil))
+	urlType        = reflect.TypeOf(url.URL{})
+
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	bytesType           = reflect.TypeOf(Bytes(0))
+)
+
 var optionalValues = map[reflect.Kind]bool{reflect.Bool: true}
 
 func valueRequired(kind reflect.Kind) bool {
 	return !optionalValues[kind]
 }
 
+// isJSONType reports whether t is a map[string]any or []any, whose values are
+// decoded from JSON.
+func isJSONType(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Map:
+		return t.Key().Kind() == reflect.String && isEmptyInterface(t.Elem())
+	case reflect.Slice:
+		return isEmptyInterface(t.Elem())
+	default:
+		return false
+	}
+}
+
+func isEmptyInterface(t reflect.Type) bool {
+	return t.Kind() == reflect.Interface && t.NumMethod() == 0
+}
+
+// isPrefixMap reports whether t is a map that is collected from all variables
+// with the key of the field as prefix.
+func isPrefixMap(t reflect.Type) bool {
+	return t.Kind() == reflect.Map && !isJSONType(t)
+}
+
+// isText reports whether t is a struct that is parsed from text by its
+// UnmarshalText method, e.g. DSN or time.Time, instead of being parsed as a
+// nested struct.
+func isText(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && reflect.PointerTo(t).Implements(textUnmarshalerType)
+}
+
 func isStruct(v reflect.Type) (isStruct bool, isPointer bool) {
+	if v == locationType || v == urlType || v == reflect.PointerTo(urlType) {
+		return false, v.Kind() == reflect.Pointer
+	}
+	if isLazy(v) || isWrapper(v) {
+		return false, false
+	}
+	if isText(v) || (v.Kind() == reflect.Pointer && isText(v.Elem())) {
+		return false, v.Kind() == reflect.Pointer
+	}
 	kind := v.Kind()
 	isPointer = kind == reflect.Pointer
 	isStruct = kind == reflect.Struct || (isPointer && v.Elem().Kind() == reflect.Struct)
@@ -1,10 +1,13 @@
 package envi_test
 
 import (
+	"encoding/json"
 	"errors"
 	"os"
 	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/bounoable/envi"
 	"github.com/google/go-cmp/cmp"
@@ -110,6 +113,33 @@ func TestParse(t *testing.T) {
 			environment: map[string]string{"MY_FLOAT32": "9.87654321"},
 			want:        env{Float32: 9.87654321},
 		},
+		{
+			name:        "duration",
+			environment: map[string]string{"MY_DURATION": "1m30s"},
+			want:        env{Duration: 90 * time.Second},
+		},
+		{
+			name:        "json.RawMessage",
+			environment: map[string]string{"MY_RAW_JSON": `{"enabled": true, "tags": ["a,b"]}`},
+			want:        env{RawJSON: json.RawMessage(`{"enabled": true, "tags": ["a,b"]}`)},
+		},
+		{
+			name: "map[string]any",
+			environment: map[string]string{
+				"MY_JSON_MAP":     `{"enabled": true, "rollout": 0.5, "groups": ["beta"]}`,
+				"MY_JSON_MAP_FOO": "not collected",
+			},
+			want: env{JSONMap: map[string]any{
+				"enabled": true,
+				"rollout": 0.5,
+				"groups":  []any{"beta"},
+			}},
+		},
+		{
+			name:        "[]any",
+			environment: map[string]string{"MY_JSON_SLICE": `[1, "two", {"three": 3}]`},
+			want:        env{JSONSlice: []any{1.0, "two", map[string]any{"three": 3.0}}},
+		},
 		{
 			name:        "bool (true)",
 			environment: map[string]string{"MY_BOOL": "true"},
@@ -356,6 +386,292 @@ func TestParse(t *testing.T) {
 	}
 }
 
+// TestParse_location verifies that *time.Location fields are loaded by name
+// and that unknown time zones fail with a descriptive error.
+func TestParse_location(t *testing.T) {
+	type locationEnv struct {
+		Location *time.Location `env:"MY_LOCATION"`
+		Unset    *time.Location `env:"MY_UNSET_LOCATION"`
+	}
+
+	os.Clearenv()
+	os.Setenv("MY_LOCATION", "Europe/Berlin")
+
+	e, err := envi.New[locationEnv]()
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	if e.Location == nil || e.Location.String() != "Europe/Berlin" {
+		t.Fatalf("Location = %v, want %v", e.Location, "Europe/Berlin")
+	}
+	if e.Unset != nil {
+		t.Fatalf("Unset = %v, want <nil>", e.Unset)
+	}
+
+	os.Setenv("MY_LOCATION", "Mars/Olympus_Mons")
+	if _, err := envi.New[locationEnv](); err == nil || !strings.Contains(err.Error(), `unknown time zone "Mars/Olympus_Mons"`) {
+		t.Fatalf("New() should fail with an unknown time zone error; got %v", err)
+	}
+}
+
+// TestParse_init verifies that pointer fields with an `init` tag are allocated
+// even if their variables are not set.
+func TestParse_init(t *testing.T) {
+	type initEnv struct {
+		Port  *int  `env:"INIT_PORT" init:"true"`
+		Debug *bool `env:"INIT_DEBUG"`
+		DB    *struct {
+			Host string `env:"INIT_DB_HOST"`
+		} `init:"true"`
+		Cache *struct {
+			Addr string `env:"INIT_CACHE_ADDR"`
+		}
+	}
+
+	os.Clearenv()
+
+	e, err := envi.New[initEnv]()
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	if e.Port == nil || *e.Port != 0 {
+		t.Fatalf("Port = %v, want pointer to 0", e.Port)
+	}
+	if e.DB == nil {
+		t.Fatalf("DB = <nil>, want allocated struct")
+	}
+	if e.Debug != nil || e.Cache != nil {
+		t.Fatalf("fields without init tag should be nil; got Debug = %v, Cache = %v", e.Debug, e.Cache)
+	}
+
+	os.Setenv("INIT_PORT", "8080")
+	os.Setenv("INIT_DB_HOST", "db")
+	if e, err = envi.New[initEnv](); err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	if *e.Port != 8080 || e.DB.Host != "db" {
+		t.Fatalf("Port = %d, DB.Host = %q; want 8080, %q", *e.Port, e.DB.Host, "db")
+	}
+}
+
+// TestMerge verifies that Merge only overwrites fields whose variables are set
+// and preserves all other values, including those of nested structs.
+func TestMerge(t *testing.T) {
+	type mergeEnv struct {
+		Host    string `env:"MERGE_HOST" required:"true"`
+		Port    int    `env:"MERGE_PORT" default:"8080"`
+		Debug   bool   `env:"MERGE_DEBUG"`
+		Timeout int    `env:"MERGE_TIMEOUT"`
+		DB      *struct {
+			URL   string `env:"MERGE_DB_URL"`
+			Conns int    `env:"MERGE_DB_CONNS"`
+		}
+	}
+
+	os.Clearenv()
+	os.Setenv("MERGE_PORT", "9090")
+	os.Setenv("MERGE_DB_CONNS", "10")
+
+	e := mergeEnv{Host: "localhost", Port: 80, Debug: true}
+	e.DB = &struct {
+		URL   string `env:"MERGE_DB_URL"`
+		Conns int    `env:"MERGE_DB_CONNS"`
+	}{URL: "postgres://localhost"}
+
+	if err := envi.Merge(&e); err != nil {
+		t.Fatalf("Merge() failed: %v", err)
+	}
+
+	if e.Host != "localhost" || e.Port != 9090 || !e.Debug || e.Timeout != 0 {
+		t.Fatalf("Merge() = %+v; want Host = localhost, Port = 9090, Debug = true, Timeout = 0", e)
+	}
+	if e.DB.URL != "postgres://localhost" || e.DB.Conns != 10 {
+		t.Fatalf("DB = %+v; want URL = postgres://localhost, Conns = 10", *e.DB)
+	}
+
+	var empty mergeEnv
+	if err := envi.Merge(&empty); !errors.Is(err, envi.ErrRequired) {
+		t.Fatalf("Merge() should fail with %q; got %v", envi.ErrRequired, err)
+	}
+}
+
+// TestMerge_defaults verifies that Merge applies `default` and `defaultExpr`
+// tags to fields that are still zero, and keeps the values of all others.
+func TestMerge_defaults(t *testing.T) {
+	type mergeEnv struct {
+		Host    string `env:"MERGE_HOST" default:"localhost"`
+		Port    int    `env:"MERGE_PORT" default:"8080"`
+		Addr    string `env:"MERGE_ADDR" defaultExpr:"{{.Host}}:{{.Port}}"`
+		Scheme  string `env:"MERGE_SCHEME" defaultExpr:"http"`
+		Timeout int    `env:"MERGE_TIMEOUT" default:"30"`
+	}
+
+	os.Clearenv()
+	os.Setenv("MERGE_TIMEOUT", "60")
+
+	e := mergeEnv{Port: 9090, Scheme: "https", Timeout: 10}
+	if err := envi.Merge(&e); err != nil {
+		t.Fatalf("Merge() failed: %v", err)
+	}
+
+	want := mergeEnv{Host: "localhost", Port: 9090, Addr: "localhost:9090", Scheme: "https", Timeout: 60}
+	if !cmp.Equal(want, e) {
+		t.Fatalf("env = %v, want = %v\n\n%s", e, want, cmp.Diff(want, e))
+	}
+}
+
+// TestParseValue verifies that ParseValue applies the same conversions as
+// Parse to a single environment variable, including slices and maps, and that
+// it returns the zero value for unset variables.
+func TestParseValue(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("MY_INT", "42")
+	os.Setenv("MY_SLICE", "1, 2, 3")
+	os.Setenv("MY_MAP_foo", "1")
+	os.Setenv("MY_MAP_bar", "2")
+	os.Setenv("MY_INVALID", "foo")
+
+	if v, err := envi.ParseValue[int]("MY_INT"); err != nil || v != 42 {
+		t.Fatalf("ParseValue[int]() = %v, %v; want %v, <nil>", v, err, 42)
+	}
+
+	if v, err := envi.ParseValue[*int]("MY_INT"); err != nil || v == nil || *v != 42 {
+		t.Fatalf("ParseValue[*int]() = %v, %v; want pointer to %v", v, err, 42)
+	}
+
+	v, err := envi.ParseValue[[]uint8]("MY_SLICE")
+	if err != nil {
+		t.Fatalf("ParseValue[[]uint8]() failed: %v", err)
+	}
+	if want := []uint8{1, 2, 3}; !cmp.Equal(want, v) {
+		t.Fatalf("ParseValue[[]uint8]() = %v, want %v", v, want)
+	}
+
+	m, err := envi.ParseValue[map[string]int]("MY_MAP")
+	if err != nil {
+		t.Fatalf("ParseValue[map[string]int]() failed: %v", err)
+	}
+	if want := map[string]int{"foo": 1, "bar": 2}; !cmp.Equal(want, m) {
+		t.Fatalf("ParseValue[map[string]int]() = %v, want %v", m, want)
+	}
+
+	if v, err := envi.ParseValue[string]("MY_UNSET"); err != nil || v != "" {
+		t.Fatalf("ParseValue[string]() = %q, %v; want %q, <nil>", v, err, "")
+	}
+
+	if _, err := envi.ParseValue[int]("MY_INVALID"); !errors.Is(err, strconv.ErrSyntax) {
+		t.Fatalf("ParseValue[int]() should fail with %q; got %q", strconv.ErrSyntax, err)
+	}
+
+	if _, err := envi.ParseValue[json.RawMessage]("MY_INVALID"); err == nil {
+		t.Fatalf("ParseValue[json.RawMessage]() should fail for invalid JSON")
+	}
+
+	var syntaxErr *json.SyntaxError
+	if _, err := envi.ParseValue[map[string]any]("MY_INVALID"); !errors.As(err, &syntaxErr) {
+		t.Fatalf("ParseValue[map[string]any]() should fail with a %T; got %v", syntaxErr, err)
+	}
+}
+
+// TestParseValue_emptySlices verifies that ParseValue and GetOr return an
+// empty slice for a variable that is set but empty with WithEmptySlices.
+func TestParseValue_emptySlices(t *testing.T) {
+	src := envi.WithSource(envi.Map{"MY_EMPTY": ""})
+
+	if v, err := envi.ParseValue[[]string]("MY_EMPTY", src); err != nil || v != nil {
+		t.Fatalf("ParseValue[[]string]() = %#v, %v; want nil, <nil>", v, err)
+	}
+	if v, err := envi.ParseValue[[]string]("MY_EMPTY", src, envi.WithEmptySlices()); err != nil || v == nil || len(v) != 0 {
+		t.Fatalf("ParseValue[[]string]() = %#v, %v; want []string{}, <nil>", v, err)
+	}
+	if v, err := envi.ParseValue[[]string]("MY_UNSET", src, envi.WithEmptySlices()); err != nil || v != nil {
+		t.Fatalf("ParseValue[[]string]() = %#v, %v; want nil, <nil>", v, err)
+	}
+	if v := envi.GetOr("MY_EMPTY", []string{"a"}, src, envi.WithEmptySlices()); v == nil || len(v) != 0 {
+		t.Fatalf("GetOr() = %#v; want []string{}", v)
+	}
+}
+
+// TestGet verifies that Get and GetOr return typed values for set variables,
+// and the zero value or fallback for unset, empty and invalid variables.
+func TestGet(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("MY_DURATION", "5s")
+	os.Setenv("MY_BOOL", "true")
+	os.Setenv("MY_SLICE", "foo,bar")
+	os.Setenv("MY_EMPTY", "")
+	os.Setenv("MY_INVALID", "foo")
+
+	if v := envi.Get[time.Duration]("MY_DURATION"); v != 5*time.Second {
+		t.Fatalf("Get[time.Duration]() = %v, want %v", v, 5*time.Second)
+	}
+
+	if v := envi.Get[bool]("MY_BOOL"); !v {
+		t.Fatalf("Get[bool]() = %v, want %v", v, true)
+	}
+
+	if v, want := envi.Get[[]string]("MY_SLICE"), []string{"foo", "bar"}; !cmp.Equal(want, v) {
+		t.Fatalf("Get[[]string]() = %v, want %v", v, want)
+	}
+
+	if v := envi.Get[int]("MY_INVALID"); v != 0 {
+		t.Fatalf("Get[int]() = %v, want %v", v, 0)
+	}
+
+	if v := envi.GetOr("MY_DURATION", time.Minute); v != 5*time.Second {
+		t.Fatalf("GetOr() = %v, want %v", v, 5*time.Second)
+	}
+
+	for _, key := range []string{"MY_UNSET", "MY_EMPTY", "MY_INVALID"} {
+		if v := envi.GetOr(key, 8080); v != 8080 {
+			t.Fatalf("GetOr(%q) = %v, want %v", key, v, 8080)
+		}
+	}
+
+	for _, key := range []string{"MY_UNSET", "MY_EMPTY"} {
+		if v := envi.GetOr(key, true); !v {
+			t.Fatalf("GetOr(%q) = %v, want %v", key, v, true)
+		}
+	}
+
+	if v, want := envi.GetOr("MY_MAP", map[string]int{"foo": 1}), map[string]int{"foo": 1}; !cmp.Equal(want, v) {
+		t.Fatalf("GetOr() = %v, want %v", v, want)
+	}
+}
+
+// TestDecode verifies that strings are converted with the conversion rules of
+// Parse.
+func TestDecode(t *testing.T) {
+	var d time.Duration
+	if err := envi.Decode("1m30s", &d); err != nil || d != 90*time.Second {
+		t.Fatalf("Decode() = %v, %v; want %v", d, err, 90*time.Second)
+	}
+
+	var ports []int
+	if err := envi.Decode("80;443", &ports, envi.WithSeparator(";")); err != nil {
+		t.Fatalf("Decode() failed: %v", err)
+	}
+	if want := []int{80, 443}; !cmp.Equal(want, ports) {
+		t.Fatalf("ports = %v, want %v", ports, want)
+	}
+
+	var size envi.Bytes
+	if err := envi.Decode("2KiB", &size); err != nil || size != 2048 {
+		t.Fatalf("Decode() = %v, %v; want %v", size, err, 2048)
+	}
+
+	n := 42
+	if err := envi.Decode("", &n); err != nil || n != 42 {
+		t.Fatalf("Decode() of an empty string = %v, %v; want %v", n, err, 42)
+	}
+	if err := envi.Decode("abc", &n); err == nil {
+		t.Fatalf("Decode() should fail for an invalid int")
+	}
+	if err := envi.Decode("1", n); err == nil {
+		t.Fatalf("Decode() should fail for a non-pointer")
+	}
+}
+
 type env struct {
 	Struct               myStruct
 	StructPtr            *myPtrStruct
@@ -374,6 +690,10 @@ type env struct {
 	Complex128           complex128             `env:"MY_COMPLEX128"`
 	Float64              float64                `env:"MY_FLOAT64"`
 	Float32              float32                `env:"MY_FLOAT32"`
+	Duration             time.Duration          `env:"MY_DURATION"`
+	RawJSON              json.RawMessage        `env:"MY_RAW_JSON"`
+	JSONMap              map[string]any         `env:"MY_JSON_MAP"`
+	JSONSlice            []any                  `env:"MY_JSON_SLICE"`
 	Bool                 bool                   `env:"MY_BOOL"`
 	StringArray          [3]string              `env:"MY_STRING_ARRAY"`
 	BoolArray            [7]bool                `env:"MY_BOOL_ARRAY"`
@@ -0,0 +1,390 @@
+// Package enviazure provides an envi.Source that resolves variables from
+// Azure App Configuration.
+//
+// Key-values are read using the App Configuration REST API, authenticated
+// either by an access key or by a Microsoft Entra ID token. Labels are layered
+// in order, so the values of later labels take precedence, e.g. for a shared
+// baseline with per-environment overrides:
+//
+//	store, err := enviazure.FromConnectionString(os.Getenv("APPCONFIG_CONNECTION_STRING"),
+//		enviazure.WithLabels("", "prod"),
+//		enviazure.WithFeatureFlags("FEATURE_"),
+//	)
+//
+//	err = envi.Parse(&env, envi.WithSource(envi.OS(), store))
+package enviazure
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/bounoable/envi"
+)
+
+// apiVersion is the version of the App Configuration REST API.
+const apiVersion = "1.0"
+
+// featureFlagPrefix is the key prefix of feature flags in App Configuration.
+const featureFlagPrefix = ".appconfig.featureflag/"
+
+// Source is an envi.Source that resolves variables from the key-values of an
+// App Configuration store.
+type Source struct {
+	endpoint string
+	client   *http.Client
+
+	credential string
+	secret     []byte
+	token      func(context.Context) (string, error)
+
+	labels     []string
+	prefix     string
+	flagPrefix string
+	flags      bool
+	toName     func(string) string
+	toKey      func(string) string
+}
+
+var (
+	_ envi.Source = (*Source)(nil)
+	_ envi.Lister = (*Source)(nil)
+)
+
+// Option is an option for a Source.
+type Option func(*Source)
+
+// WithAccessKey authenticates requests with the HMAC signature of the access
+// key with the given id and base64-encoded secret.
+func WithAccessKey(id, secret string) Option {
+	return func(s *Source) {
+		s.credential = id
+		s.secret, _ = base64.StdEncoding.DecodeString(secret)
+	}
+}
+
+// WithToken authenticates requests with Microsoft Entra ID tokens for the
+// scope "https://azconfig.io/.default", e.g. from a managed identity. token is
+// called for every request and is expected to cache tokens.
+func WithToken(token func(ctx context.Context) (string, error)) Option {
+	return func(s *Source) {
+		s.token = token
+	}
+}
+
+// WithLabels sets the labels of the key-values, in order of precedence: the
+// values of later labels override those of earlier ones. The empty string
+// selects key-values without a label. Defaults to key-values without a label.
+func WithLabels(labels ...string) Option {
+	return func(s *Source) {
+		s.labels = labels
+	}
+}
+
+// WithPrefix restricts the Source to the key-values whose keys start with
+// prefix, e.g. "myapp:". The prefix is not part of the variable keys.
+func WithPrefix(prefix string) Option {
+	return func(s *Source) {
+		s.prefix = prefix
+	}
+}
+
+// WithFeatureFlags provides the feature flags of the store as boolean
+// variables, whose keys are the mapped flag names with the given prefix,
+// e.g. FEATURE_Beta for the flag "Beta" and the prefix "FEATURE_".
+func WithFeatureFlags(prefix string) Option {
+	return func(s *Source) {
+		s.flags = true
+		s.flagPrefix = prefix
+	}
+}
+
+// WithMapping configures how variable keys map to the keys of key-values and
+// the names of feature flags. toName maps a variable key to a key or name,
+// and toKey maps a key or name back to a variable key. By default, they are
+// identical.
+//
+//	// DB_HOST <-> db:host
+//	enviazure.WithMapping(
+//		func(key string) string { return strings.ToLower(strings.ReplaceAll(key, "_", ":")) },
+//		func(name string) string { return strings.ToUpper(strings.ReplaceAll(name, ":", "_")) },
+//	)
+func WithMapping(toName, toKey func(string) string) Option {
+	return func(s *Source) {
+		s.toName = toName
+		s.toKey = toKey
+	}
+}
+
+// WithHTTPClient sets the HTTP client used to talk to App Configuration.
+// Defaults to http.DefaultClient.
+func WithHTTPClient(client *http.Client) Option {
+	return func(s *Source) {
+		s.client = client
+	}
+}
+
+// New returns a Source for the App Configuration store at endpoint, e.g.
+// "https://myapp.azconfig.io".
+func New(endpoint string, opts ...Option) *Source {
+	s := &Source{
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		client:   http.DefaultClient,
+		labels:   []string{""},
+		toName:   func(key string) string { return key },
+		toKey:    func(name string) string { return name },
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// FromConnectionString returns a Source for the store and access key of a
+// connection string of the form "Endpoint=...;Id=...;Secret=...".
+func FromConnectionString(connectionString string, opts ...Option) (*Source, error) {
+	fields := make(map[string]string)
+	for _, part := range strings.Split(connectionString, ";") {
+		if k, v, ok := strings.Cut(part, "="); ok {
+			fields[strings.TrimSpace(k)] = strings.TrimSpace(v)
+		}
+	}
+	if fields["Endpoint"] == "" || fields["Id"] == "" || fields["Secret"] == "" {
+		return nil, errors.New("invalid connection string: Endpoint, Id and Secret are required")
+	}
+	if _, err := base64.StdEncoding.DecodeString(fields["Secret"]); err != nil {
+		return nil, fmt.Errorf("invalid connection string: decode secret: %w", err)
+	}
+
+	opts = append([]Option{WithAccessKey(fields["Id"], fields["Secret"])}, opts...)
+	return New(fields["Endpoint"], opts...), nil
+}
+
+// keyValue is a key-value of App Configuration.
+type keyValue struct {
+	Key         string `json:"key"`
+	Label       string `json:"label"`
+	Value       string `json:"value"`
+	ContentType string `json:"content_type"`
+}
+
+// Lookup implements envi.Source. It returns the value of the key-value with
+// the last of the labels that has one, or false if there is none. Feature
+// flags are "true" if they are enabled and "false" otherwise.
+func (s *Source) Lookup(ctx context.Context, key string) (string, bool, error) {
+	name := s.prefix + s.toName(key)
+	flag := s.flags && strings.HasPrefix(key, s.flagPrefix)
+	if flag {
+		name = featureFlagPrefix + s.toName(strings.TrimPrefix(key, s.flagPrefix))
+	}
+
+	for i := len(s.labels) - 1; i >= 0; i-- {
+		query := url.Values{"api-version": {apiVersion}}
+		if s.labels[i] != "" {
+			query.Set("label", s.labels[i])
+		}
+
+		var kv keyValue
+		found, err := s.get(ctx, "/kv/"+url.PathEscape(name), query, &kv)
+		if err != nil {
+			return "", false, fmt.Errorf("read key-value %q: %w", name, err)
+		}
+		if !found {
+			continue
+		}
+
+		if flag {
+			enabled, err := flagEnabled(kv)
+			if err != nil {
+				return "", false, err
+			}
+			return fmt.Sprint(enabled), true, nil
+		}
+		return kv.Value, true, nil
+	}
+
+	return "", false, nil
+}
+
+// Keys implements envi.Lister. It returns the keys of the key-values with the
+// prefix and labels of the Source, and of the feature flags if enabled.
+func (s *Source) Keys(ctx context.Context) ([]string, error) {
+	seen := make(map[string]bool)
+	var keys []string
+	add := func(key string) {
+		if !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+
+	for _, label := range s.labels {
+		kvs, err := s.list(ctx, s.prefix, label)
+		if err != nil {
+			return nil, err
+		}
+		for _, kv := range kvs {
+			if strings.HasPrefix(kv.Key, featureFlagPrefix) {
+				continue
+			}
+			add(s.toKey(strings.TrimPrefix(kv.Key, s.prefix)))
+		}
+
+		if !s.flags {
+			continue
+		}
+		flags, err := s.list(ctx, featureFlagPrefix, label)
+		if err != nil {
+			return nil, err
+		}
+		for _, kv := range flags {
+			add(s.flagPrefix + s.toKey(strings.TrimPrefix(kv.Key, featureFlagPrefix)))
+		}
+	}
+
+	return keys, nil
+}
+
+// list returns the key-values whose keys start with prefix and that have the
+// given label, following the pages of the result.
+func (s *Source) list(ctx context.Context, prefix, label string) ([]keyValue, error) {
+	query := url.Values{
+		"api-version": {apiVersion},
+		"key":         {escapeFilter(prefix) + "*"},
+		"label":       {escapeFilter(label)},
+	}
+	if label == "" {
+		query.Set("label", "\x00")
+	}
+
+	var kvs []keyValue
+	path := "/kv"
+	for path != "" {
+		var page struct {
+			Items    []keyValue `json:"items"`
+			NextLink string     `json:"@nextLink"`
+		}
+		if _, err := s.get(ctx, path, query, &page); err != nil {
+			return nil, fmt.Errorf("list key-values: %w", err)
+		}
+		kvs = append(kvs, page.Items...)
+
+		// The next link contains the query of the next page.
+		path, query = "", nil
+		if page.NextLink != "" {
+			next, err := url.Parse(page.NextLink)
+			if err != nil {
+				return nil, fmt.Errorf("list key-values: invalid next link: %w", err)
+			}
+			path, query = next.Path, next.Query()
+		}
+	}
+
+	return kvs, nil
+}
+
+// get performs an authenticated GET request and decodes the JSON response
+// into out. It reports false if App Configuration responded with 404 Not
+// Found.
+func (s *Source) get(ctx context.Context, path string, query url.Values, out any) (bool, error) {
+	u := s.endpoint + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return false, err
+	}
+	if err := s.authorize(req); err != nil {
+		return false, err
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return false, nil
+	case resp.StatusCode >= 300:
+		return false, responseError(resp)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return false, fmt.Errorf("decode response: %w", err)
+	}
+
+	return true, nil
+}
+
+// authorize adds the authentication headers to req, either a bearer token or
+// the HMAC signature of the access key.
+func (s *Source) authorize(req *http.Request) error {
+	if s.token != nil {
+		token, err := s.token(req.Context())
+		if err != nil {
+			return fmt.Errorf("get token: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		return nil
+	}
+
+	if s.credential == "" {
+		return nil
+	}
+
+	date := time.Now().UTC().Format(http.TimeFormat)
+	hash := sha256.Sum256(nil)
+	contentHash := base64.StdEncoding.EncodeToString(hash[:])
+	req.Header.Set("x-ms-date", date)
+	req.Header.Set("x-ms-content-sha256", contentHash)
+
+	toSign := req.Method + "\n" + req.URL.RequestURI() + "\n" + date + ";" + req.URL.Host + ";" + contentHash
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(toSign))
+	signature := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"HMAC-SHA256 Credential=%s&SignedHeaders=x-ms-date;host;x-ms-content-sha256&Signature=%s",
+		s.credential, signature,
+	))
+	return nil
+}
+
+// flagEnabled reports whether the feature flag of kv is enabled.
+func flagEnabled(kv keyValue) (bool, error) {
+	var flag struct {
+		Enabled bool `json:"enabled"`
+	}
+	if err := json.Unmarshal([]byte(kv.Value), &flag); err != nil {
+		return false, fmt.Errorf("decode feature flag %q: %w", kv.Key, err)
+	}
+	return flag.Enabled, nil
+}
+
+// escapeFilter escapes the characters of key and label filters that have a
+// special meaning.
+func escapeFilter(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `*`, `\*`, `,`, `\,`)
+	return r.Replace(s)
+}
+
+func responseError(resp *http.Response) error {
+	var body struct {
+		Detail string `json:"detail"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil || body.Detail == "" {
+		return fmt.Errorf("app configuration responded with %s", resp.Status)
+	}
+	return fmt.Errorf("app configuration responded with %s: %s", resp.Status, body.Detail)
+}
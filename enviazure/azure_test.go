@@ -0,0 +1,236 @@
+package enviazure_test
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/bounoable/envi"
+	"github.com/bounoable/envi/enviazure"
+	"github.com/google/go-cmp/cmp"
+)
+
+var secret = base64.StdEncoding.EncodeToString([]byte("secret"))
+
+type env struct {
+	Host     string `env:"HOST"`
+	Password string `env:"DB_PASSWORD"`
+	Port     int    `env:"DB_PORT"`
+	Beta     bool   `env:"FEATURE_Beta"`
+	Legacy   bool   `env:"FEATURE_Legacy"`
+}
+
+type keyValue struct {
+	Key   string `json:"key"`
+	Label string `json:"label,omitempty"`
+	Value string `json:"value"`
+}
+
+// store is the content of the fake App Configuration store.
+var store = []keyValue{
+	{Key: "DB_PASSWORD", Value: "base"},
+	{Key: "DB_PORT", Value: "5432"},
+	{Key: "DB_PASSWORD", Label: "prod", Value: "s3cr3t"},
+	{Key: ".appconfig.featureflag/Beta", Value: `{"id":"Beta","enabled":false}`},
+	{Key: ".appconfig.featureflag/Beta", Label: "prod", Value: `{"id":"Beta","enabled":true}`},
+	{Key: ".appconfig.featureflag/Legacy", Value: `{"id":"Legacy","enabled":false}`},
+}
+
+func newServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	authorized := func(w http.ResponseWriter, r *http.Request) bool {
+		if r.Header.Get("Authorization") == "Bearer token" {
+			return true
+		}
+		date, hash := r.Header.Get("x-ms-date"), r.Header.Get("x-ms-content-sha256")
+		mac := hmac.New(sha256.New, []byte("secret"))
+		mac.Write([]byte(r.Method + "\n" + r.RequestURI + "\n" + date + ";" + r.Host + ";" + hash))
+		want := "HMAC-SHA256 Credential=id&SignedHeaders=x-ms-date;host;x-ms-content-sha256&Signature=" +
+			base64.StdEncoding.EncodeToString(mac.Sum(nil))
+		if date == "" || r.Header.Get("Authorization") != want {
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]any{"detail": "invalid signature"})
+			return false
+		}
+		return true
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/kv/", func(w http.ResponseWriter, r *http.Request) {
+		if !authorized(w, r) {
+			return
+		}
+		key := strings.TrimPrefix(r.URL.Path, "/kv/")
+		label := r.URL.Query().Get("label")
+		for _, kv := range store {
+			if kv.Key == key && kv.Label == label {
+				json.NewEncoder(w).Encode(kv)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusNotFound)
+	})
+	mux.HandleFunc("/kv", func(w http.ResponseWriter, r *http.Request) {
+		if !authorized(w, r) {
+			return
+		}
+		query := r.URL.Query()
+		prefix := strings.TrimSuffix(query.Get("key"), "*")
+		label := strings.TrimPrefix(query.Get("label"), "\x00")
+
+		var items []keyValue
+		for _, kv := range store {
+			if strings.HasPrefix(kv.Key, prefix) && kv.Label == label {
+				items = append(items, kv)
+			}
+		}
+
+		// Serve one item per page.
+		var page struct {
+			Items    []keyValue `json:"items"`
+			NextLink string     `json:"@nextLink,omitempty"`
+		}
+		var skip int
+		if s := query.Get("skip"); s != "" {
+			skip = int(s[0] - '0')
+		}
+		if skip < len(items) {
+			page.Items = items[skip : skip+1]
+		}
+		if skip+1 < len(items) {
+			query.Set("skip", string(rune('0'+skip+1)))
+			page.NextLink = "/kv?" + query.Encode()
+		}
+		json.NewEncoder(w).Encode(page)
+	})
+
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+// TestSource verifies that variables resolve from the key-values and feature
+// flags of the labels in order, using access key and token authentication.
+func TestSource(t *testing.T) {
+	srv := newServer(t)
+
+	os.Clearenv()
+	os.Setenv("HOST", "localhost")
+
+	want := env{
+		Host:     "localhost",
+		Password: "s3cr3t",
+		Port:     5432,
+		Beta:     true,
+	}
+
+	connStr := "Endpoint=" + srv.URL + ";Id=id;Secret=" + secret
+	fromConnStr, err := enviazure.FromConnectionString(connStr, enviazure.WithLabels("", "prod"), enviazure.WithFeatureFlags("FEATURE_"))
+	if err != nil {
+		t.Fatalf("FromConnectionString() failed: %v", err)
+	}
+
+	for name, source := range map[string]*enviazure.Source{
+		"access key": fromConnStr,
+		"token": enviazure.New(srv.URL,
+			enviazure.WithToken(func(context.Context) (string, error) { return "token", nil }),
+			enviazure.WithLabels("", "prod"),
+			enviazure.WithFeatureFlags("FEATURE_"),
+		),
+	} {
+		t.Run(name, func(t *testing.T) {
+			var e env
+			if err := envi.Parse(&e, envi.WithSource(envi.OS(), source)); err != nil {
+				t.Fatalf("Parse() failed: %v", err)
+			}
+			if !cmp.Equal(want, e) {
+				t.Fatalf("env = %v, want = %v\n\n%s", e, want, cmp.Diff(want, e))
+			}
+		})
+	}
+}
+
+// TestSource_labels verifies that the values of later labels take precedence.
+func TestSource_labels(t *testing.T) {
+	srv := newServer(t)
+
+	for _, tt := range []struct {
+		labels []string
+		want   string
+	}{
+		{nil, "base"},
+		{[]string{"prod"}, "s3cr3t"},
+		{[]string{"prod", ""}, "base"},
+		{[]string{"dev", ""}, "base"},
+	} {
+		var opts []enviazure.Option
+		if tt.labels != nil {
+			opts = append(opts, enviazure.WithLabels(tt.labels...))
+		}
+		s, err := enviazure.FromConnectionString("Endpoint="+srv.URL+";Id=id;Secret="+secret, opts...)
+		if err != nil {
+			t.Fatalf("FromConnectionString() failed: %v", err)
+		}
+
+		v, ok, err := s.Lookup(context.Background(), "DB_PASSWORD")
+		if err != nil {
+			t.Fatalf("Lookup() failed: %v", err)
+		}
+		if !ok || v != tt.want {
+			t.Fatalf("labels %q: Lookup() = %q, %v; want %q, true", tt.labels, v, ok, tt.want)
+		}
+	}
+}
+
+// TestSource_Keys verifies that the keys of all pages and labels are listed,
+// including feature flags.
+func TestSource_Keys(t *testing.T) {
+	srv := newServer(t)
+
+	s, err := enviazure.FromConnectionString("Endpoint="+srv.URL+";Id=id;Secret="+secret,
+		enviazure.WithLabels("", "prod"),
+		enviazure.WithFeatureFlags("FEATURE_"),
+	)
+	if err != nil {
+		t.Fatalf("FromConnectionString() failed: %v", err)
+	}
+
+	keys, err := s.Keys(context.Background())
+	if err != nil {
+		t.Fatalf("Keys() failed: %v", err)
+	}
+	sort.Strings(keys)
+
+	want := []string{"DB_PASSWORD", "DB_PORT", "FEATURE_Beta", "FEATURE_Legacy"}
+	if !cmp.Equal(want, keys) {
+		t.Fatalf("Keys() = %v, want = %v", keys, want)
+	}
+}
+
+// TestSource_errors verifies that authentication failures are reported with
+// the detail of the response.
+func TestSource_errors(t *testing.T) {
+	srv := newServer(t)
+
+	s := enviazure.New(srv.URL, enviazure.WithAccessKey("id", base64.StdEncoding.EncodeToString([]byte("wrong"))))
+	_, _, err := s.Lookup(context.Background(), "DB_PASSWORD")
+	if err == nil || !strings.Contains(err.Error(), "401 Unauthorized: invalid signature") {
+		t.Fatalf("Lookup() error = %v, want 401 with detail", err)
+	}
+
+	for _, cs := range []string{"", "Endpoint=" + srv.URL + ";Id=id", "Endpoint=" + srv.URL + ";Id=id;Secret=%%%"} {
+		if _, err := enviazure.FromConnectionString(cs); err == nil {
+			t.Fatalf("FromConnectionString(%q) should fail", cs)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package envicli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/bounoable/envi"
+)
+
+// check validates the environment, or the dotenv file given by the -env-file
+// flag, against Config and lists the missing and invalid variables.
+func check[Config any](args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("check", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	envFile := fs.String("env-file", "", "validate the dotenv `file` instead of the environment")
+	dialect := dialectFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	opts, err := sourceOptions(*envFile, *dialect)
+	if err != nil {
+		fmt.Fprintf(stderr, "check: %v\n", err)
+		return 1
+	}
+
+	problems := envi.Check[Config](opts...)
+	if len(problems) == 0 {
+		return 0
+	}
+
+	for _, p := range problems {
+		fmt.Fprintln(stdout, p)
+	}
+	fmt.Fprintf(stderr, "check: %d problem(s) found\n", len(problems))
+
+	return 1
+}
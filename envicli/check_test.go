@@ -0,0 +1,82 @@
+package envicli_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bounoable/envi/envicli"
+)
+
+type config struct {
+	Host string `env:"HOST" required:"true"`
+	Port int    `env:"PORT" default:"8080"`
+}
+
+// TestRun_check verifies that the check command lists the problems of a
+// dotenv file and exits with a non-zero code.
+func TestRun_check(t *testing.T) {
+	dir := t.TempDir()
+	invalid := filepath.Join(dir, "invalid.env")
+	if err := os.WriteFile(invalid, []byte("PORT=abc\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	valid := filepath.Join(dir, "valid.env")
+	if err := os.WriteFile(valid, []byte("HOST=localhost\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr strings.Builder
+	code := envicli.Run[config]([]string{"check", "-env-file", invalid}, &stdout, &stderr)
+	if code != 1 {
+		t.Fatalf("exit code = %d, want 1\n\n%s", code, stderr.String())
+	}
+
+	want := "Host (HOST): required variable is not set\n" +
+		"Port (PORT): strconv.ParseInt: parsing \"abc\": invalid syntax\n"
+	if stdout.String() != want {
+		t.Fatalf("output = %q, want %q", stdout.String(), want)
+	}
+
+	stdout.Reset()
+	if code := envicli.Run[config]([]string{"check", "-env-file", valid}, &stdout, &stderr); code != 0 {
+		t.Fatalf("exit code = %d, want 0\n\n%s", code, stdout.String())
+	}
+}
+
+// TestRun_usage verifies that invalid commands exit with code 2.
+func TestRun_usage(t *testing.T) {
+	var stdout, stderr strings.Builder
+	for _, args := range [][]string{nil, {"unknown"}, {"check", "-unknown"}} {
+		if code := envicli.Run[config](args, &stdout, &stderr); code != 2 {
+			t.Fatalf("Run(%q) exit code = %d, want 2", args, code)
+		}
+	}
+}
+
+// TestRun_check_dialect verifies that the -dialect flag selects the syntax of
+// the env file.
+func TestRun_check_dialect(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.env")
+	if err := os.WriteFile(path, []byte("HOST=localhost\nPORT=\"8080\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr strings.Builder
+	if code := envicli.Run[config]([]string{"check", "-env-file", path}, &stdout, &stderr); code != 0 {
+		t.Fatalf("exit code = %d, want 0\n\n%s", code, stdout.String())
+	}
+
+	code := envicli.Run[config]([]string{"check", "-dialect", "docker", "-env-file", path}, &stdout, &stderr)
+	if code != 1 {
+		t.Fatalf("exit code = %d, want 1\n\n%s", code, stderr.String())
+	}
+	if want := "Port (PORT): strconv.ParseInt: parsing \"\\\"8080\\\"\": invalid syntax\n"; stdout.String() != want {
+		t.Fatalf("output = %q, want %q", stdout.String(), want)
+	}
+
+	if code := envicli.Run[config]([]string{"check", "-dialect", "compose", "-env-file", path}, &stdout, &stderr); code != 2 {
+		t.Fatalf("exit code = %d, want 2", code)
+	}
+}
@@ -0,0 +1,207 @@
+package envicli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/bounoable/envi"
+)
+
+// ageScheme is the prefix of age-encrypted values; see envi.WithDecryptor.
+const ageScheme = "age:"
+
+// encrypt encrypts a value given by -value, or the values of a dotenv file,
+// for age recipients. In files, the variables of secret fields of Config are
+// encrypted, or the variables given by -key.
+func encrypt[Config any](args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("encrypt", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	var recipients, keys stringList
+	fs.Var(&recipients, "recipient", "encrypt for the age `recipient`; may be repeated")
+	fs.Var(&keys, "key", "encrypt the variable `KEY` instead of the variables of secret fields; may be repeated")
+	all := fs.Bool("all", false, "encrypt all variables")
+	value := fs.String("value", "", "encrypt the `value` instead of a file")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: encrypt -recipient age1... [flags] [file | -value value]\n\nFiles default to .env.\n\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if len(recipients) == 0 || fs.NArg() > 1 {
+		fs.Usage()
+		return 2
+	}
+
+	enc := envi.AgeRecipients(recipients...)
+	encryptValue := func(v string) (string, error) {
+		ciphertext, err := enc.Encrypt(context.Background(), []byte(v))
+		if err != nil {
+			return "", err
+		}
+		return ageScheme + string(ciphertext), nil
+	}
+
+	if flagSet(fs, "value") {
+		v, err := encryptValue(*value)
+		if err != nil {
+			fmt.Fprintf(stderr, "encrypt: %v\n", err)
+			return 1
+		}
+		fmt.Fprint(stdout, v)
+		return 0
+	}
+
+	selected := func(key string) bool {
+		if *all {
+			return true
+		}
+		for _, k := range keys {
+			if k == key {
+				return true
+			}
+		}
+		return false
+	}
+	if len(keys) == 0 && !*all {
+		selected = secretKeys[Config]()
+	}
+
+	return rewriteFile(fs.Arg(0), stdout, stderr, "encrypt", func(key, value string) (string, error) {
+		if !selected(key) || value == "" || strings.HasPrefix(value, ageScheme) {
+			return value, nil
+		}
+		return encryptValue(value)
+	})
+}
+
+// decrypt decrypts an age-encrypted value given by -value, or the encrypted
+// values of a dotenv file, with the identities of an age key file.
+func decrypt[Config any](args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("decrypt", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	identity := fs.String("identity", os.Getenv("AGE_KEY_FILE"), "decrypt with the age identity `file` (default $AGE_KEY_FILE)")
+	value := fs.String("value", "", "decrypt the `value` instead of a file")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: decrypt -identity file [file | -value value]\n\nFiles default to .env.\n\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *identity == "" || fs.NArg() > 1 {
+		fs.Usage()
+		return 2
+	}
+
+	dec := envi.AgeIdentityFile(*identity)
+	decryptValue := func(v string) (string, error) {
+		if !strings.HasPrefix(v, ageScheme) {
+			return v, nil
+		}
+		plaintext, err := dec.Decrypt(context.Background(), []byte(strings.TrimPrefix(v, ageScheme)))
+		if err != nil {
+			return "", err
+		}
+		return string(plaintext), nil
+	}
+
+	if flagSet(fs, "value") {
+		v, err := decryptValue(*value)
+		if err != nil {
+			fmt.Fprintf(stderr, "decrypt: %v\n", err)
+			return 1
+		}
+		fmt.Fprint(stdout, v)
+		return 0
+	}
+
+	return rewriteFile(fs.Arg(0), stdout, stderr, "decrypt", func(_, value string) (string, error) {
+		return decryptValue(value)
+	})
+}
+
+// rewriteFile prints the variables of the dotenv file at path, or .env if
+// path is empty, with their values replaced by fn.
+func rewriteFile(path string, stdout, stderr io.Writer, command string, fn func(key, value string) (string, error)) int {
+	if path == "" {
+		path = ".env"
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		fmt.Fprintf(stderr, "%s: %v\n", command, err)
+		return 1
+	}
+	defer f.Close()
+
+	vars, err := envi.ParseDotEnv(f)
+	if err != nil {
+		fmt.Fprintf(stderr, "%s: parse %s: %v\n", command, path, err)
+		return 1
+	}
+
+	var out strings.Builder
+	for _, v := range vars {
+		value, err := fn(v.Key, v.Value)
+		if err != nil {
+			fmt.Fprintf(stderr, "%s: %s: %v\n", command, v.Key, err)
+			return 1
+		}
+		fmt.Fprintf(&out, "%s=%s\n", v.Key, quoteDotEnv(value))
+	}
+	fmt.Fprint(stdout, out.String())
+
+	return 0
+}
+
+// secretKeys returns a function that reports whether a key is the variable
+// of a secret field of Config.
+func secretKeys[Config any]() func(key string) bool {
+	var keys, prefixes []string
+	for _, v := range envi.Variables[Config]() {
+		switch {
+		case v.Map && v.Secret:
+			prefixes = append(prefixes, v.Key)
+		case v.Secret:
+			keys = append(keys, v.Key)
+		}
+	}
+
+	return func(key string) bool {
+		for _, k := range keys {
+			if k == key {
+				return true
+			}
+		}
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(key, prefix) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// quoteDotEnv quotes value for a dotenv file if necessary, escaping newlines
+// so that armored ciphertext stays on one line.
+func quoteDotEnv(value string) string {
+	if !strings.ContainsAny(value, " \t\r\n#\"'\\$") {
+		return value
+	}
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "\t", `\t`)
+	return `"` + r.Replace(value) + `"`
+}
+
+// flagSet reports whether the flag with the given name was set.
+func flagSet(fs *flag.FlagSet, name string) bool {
+	var set bool
+	fs.Visit(func(f *flag.Flag) {
+		set = set || f.Name == name
+	})
+	return set
+}
@@ -0,0 +1,123 @@
+package envicli_test
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/bounoable/envi"
+	"github.com/bounoable/envi/envicli"
+)
+
+type cryptConfig struct {
+	Host     string `env:"HOST"`
+	Password string `env:"PASSWORD" secret:"true"`
+}
+
+// fakeAge installs an age command that "encrypts" by wrapping the plaintext
+// in armor lines and checks the recipient and identity arguments.
+func fakeAge(t *testing.T) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake age command is a shell script")
+	}
+
+	dir := t.TempDir()
+	script := `#!/bin/sh
+case "$*" in
+"--encrypt --armor --recipient age1test")
+	printf -- '-----BEGIN AGE ENCRYPTED FILE-----\n%s\n-----END AGE ENCRYPTED FILE-----\n' "$(cat)" ;;
+"--decrypt --identity key.txt")
+	printf '%s' "$(sed -e 1d -e '$d')" ;;
+*)
+	echo "bad arguments: $*" >&2; exit 1 ;;
+esac
+`
+	if err := os.WriteFile(filepath.Join(dir, "age"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+// TestRun_encrypt verifies that the encrypt command encrypts the values of
+// secrets in a dotenv file, and that the decrypt command restores them.
+func TestRun_encrypt(t *testing.T) {
+	fakeAge(t)
+
+	dir := t.TempDir()
+	plain := filepath.Join(dir, ".env")
+	if err := os.WriteFile(plain, []byte("HOST=localhost\nPASSWORD=s3cr3t\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr strings.Builder
+	if code := envicli.Run[cryptConfig]([]string{"encrypt", "-recipient", "age1test", plain}, &stdout, &stderr); code != 0 {
+		t.Fatalf("exit code = %d, want 0\n\n%s", code, stderr.String())
+	}
+	want := "HOST=localhost\n" +
+		`PASSWORD="age:-----BEGIN AGE ENCRYPTED FILE-----\ns3cr3t\n-----END AGE ENCRYPTED FILE-----\n"` + "\n"
+	if stdout.String() != want {
+		t.Fatalf("output = %q, want %q", stdout.String(), want)
+	}
+
+	encrypted := filepath.Join(dir, ".env.enc")
+	if err := os.WriteFile(encrypted, []byte(stdout.String()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The encrypted file can be used directly with envi.
+	vars, err := envi.ReadDotEnv(encrypted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cfg cryptConfig
+	if err := envi.Parse(&cfg, envi.WithSource(vars), envi.WithDecryptor("age", envi.AgeIdentityFile("key.txt"))); err != nil {
+		t.Fatalf("Parse() failed: %v", err)
+	}
+	if cfg.Password != "s3cr3t" {
+		t.Fatalf("Password = %q, want %q", cfg.Password, "s3cr3t")
+	}
+
+	stdout.Reset()
+	if code := envicli.Run[cryptConfig]([]string{"decrypt", "-identity", "key.txt", encrypted}, &stdout, &stderr); code != 0 {
+		t.Fatalf("exit code = %d, want 0\n\n%s", code, stderr.String())
+	}
+	if want := "HOST=localhost\nPASSWORD=s3cr3t\n"; stdout.String() != want {
+		t.Fatalf("output = %q, want %q", stdout.String(), want)
+	}
+
+	stdout.Reset()
+	if code := envicli.Run[cryptConfig]([]string{"encrypt", "-recipient", "age1test", "-key", "HOST", plain}, &stdout, &stderr); code != 0 {
+		t.Fatalf("exit code = %d, want 0\n\n%s", code, stderr.String())
+	}
+	if !strings.HasPrefix(stdout.String(), `HOST="age:`) || !strings.Contains(stdout.String(), "PASSWORD=s3cr3t\n") {
+		t.Fatalf("output = %q, want only HOST encrypted", stdout.String())
+	}
+
+	stdout.Reset()
+	if code := envicli.Run[cryptConfig]([]string{"encrypt", "-recipient", "age1test", "-value", "token"}, &stdout, &stderr); code != 0 {
+		t.Fatalf("exit code = %d, want 0\n\n%s", code, stderr.String())
+	}
+	value := stdout.String()
+	if want := "age:-----BEGIN AGE ENCRYPTED FILE-----\ntoken\n-----END AGE ENCRYPTED FILE-----\n"; value != want {
+		t.Fatalf("output = %q, want %q", value, want)
+	}
+
+	stdout.Reset()
+	if code := envicli.Run[cryptConfig]([]string{"decrypt", "-identity", "key.txt", "-value", value}, &stdout, &stderr); code != 0 {
+		t.Fatalf("exit code = %d, want 0\n\n%s", code, stderr.String())
+	}
+	if stdout.String() != "token" {
+		t.Fatalf("output = %q, want %q", stdout.String(), "token")
+	}
+
+	stderr.Reset()
+	if code := envicli.Run[cryptConfig]([]string{"decrypt", "-identity", "other.txt", encrypted}, &stdout, &stderr); code != 1 {
+		t.Fatalf("exit code = %d, want 1", code)
+	}
+	if !strings.Contains(stderr.String(), "bad arguments") {
+		t.Fatalf("stderr = %q, want error of age", stderr.String())
+	}
+}
@@ -0,0 +1,138 @@
+package envicli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/bounoable/envi"
+)
+
+// redacted replaces the values of secret variables in the output.
+const redacted = "<redacted>"
+
+// diff compares the variables of two env files, or of an env file and the
+// environment, and prints the added, removed and changed variables. Values
+// of variables of fields with a `secret` tag are redacted.
+func diff[Config any](args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("diff", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	dialect := dialectFlag(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: diff [-dialect syntax] old-file [new-file]\n\n"+
+			"Without new-file, old-file is compared to the environment.\n\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 || fs.NArg() > 2 {
+		fs.Usage()
+		return 2
+	}
+
+	old, err := envi.ReadDotEnvDialect(fs.Arg(0), *dialect)
+	if err != nil {
+		fmt.Fprintf(stderr, "diff: %v\n", err)
+		return 1
+	}
+
+	// The environment has many variables that are unrelated to Config, so
+	// only the variables of the files and of Config are compared.
+	new := envi.Environ(os.Environ())
+	if fs.NArg() == 2 {
+		if new, err = envi.ReadDotEnvDialect(fs.Arg(1), *dialect); err != nil {
+			fmt.Fprintf(stderr, "diff: %v\n", err)
+			return 1
+		}
+	}
+
+	changes := diffEnv[Config](old, new, fs.NArg() == 1)
+	for _, c := range changes {
+		fmt.Fprintln(stdout, c)
+	}
+
+	if len(changes) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// envChange is an added, removed or changed variable.
+type envChange struct {
+	key      string
+	old, new string
+	// kind is '+' for added, '-' for removed and '~' for changed variables.
+	kind byte
+}
+
+func (c envChange) String() string {
+	switch c.kind {
+	case '+':
+		return fmt.Sprintf("+ %s=%s", c.key, c.new)
+	case '-':
+		return fmt.Sprintf("- %s=%s", c.key, c.old)
+	default:
+		return fmt.Sprintf("~ %s: %s -> %s", c.key, c.old, c.new)
+	}
+}
+
+// diffEnv returns the changes from old to new, sorted by key. If environ is
+// true, new is the environment, and only its variables that are in old or
+// declared by Config are compared.
+func diffEnv[Config any](old, new envi.Map, environ bool) []envChange {
+	isSecret := secretKeys[Config]()
+	keys := make(map[string]bool)
+	for _, v := range envi.Variables[Config]() {
+		if !v.Map {
+			keys[v.Key] = true
+		}
+	}
+
+	for k := range old {
+		keys[k] = true
+	}
+	if !environ {
+		for k := range new {
+			keys[k] = true
+		}
+	}
+
+	sorted := make([]string, 0, len(keys))
+	for k := range keys {
+		sorted = append(sorted, k)
+	}
+	sort.Strings(sorted)
+
+	var changes []envChange
+	for _, key := range sorted {
+		o, inOld := old[key]
+		n, inNew := new[key]
+
+		c := envChange{key: key, old: o, new: n}
+		switch {
+		case inOld && !inNew:
+			c.kind = '-'
+		case !inOld && inNew:
+			c.kind = '+'
+		case inOld && inNew && o != n:
+			c.kind = '~'
+		default:
+			continue
+		}
+
+		if isSecret(key) {
+			if c.old != "" {
+				c.old = redacted
+			}
+			if c.new != "" {
+				c.new = redacted
+			}
+		}
+		changes = append(changes, c)
+	}
+
+	return changes
+}
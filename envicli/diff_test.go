@@ -0,0 +1,67 @@
+package envicli_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bounoable/envi/envicli"
+)
+
+type diffConfig struct {
+	Host     string            `env:"DIFF_HOST"`
+	Password string            `env:"DIFF_PASSWORD" secret:"true"`
+	Tokens   map[string]string `env:"DIFF_TOKEN" secret:"true"`
+	Region   string            `env:"DIFF_REGION"`
+}
+
+// TestRun_diff verifies that the diff command prints the added, removed and
+// changed variables of two env files, or of an env file and the environment,
+// with the values of secrets redacted.
+func TestRun_diff(t *testing.T) {
+	dir := t.TempDir()
+	staging := filepath.Join(dir, "staging.env")
+	if err := os.WriteFile(staging, []byte("DIFF_HOST=staging.internal\nDIFF_PASSWORD=a\nDIFF_TOKEN_ci=x\nDEBUG=true\nSAME=1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	prod := filepath.Join(dir, "prod.env")
+	if err := os.WriteFile(prod, []byte("DIFF_HOST=prod.internal\nDIFF_PASSWORD=b\nDIFF_TOKEN_ci=x\nDIFF_REGION=eu\nSAME=1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr strings.Builder
+	if code := envicli.Run[diffConfig]([]string{"diff", staging, prod}, &stdout, &stderr); code != 1 {
+		t.Fatalf("exit code = %d, want 1\n\n%s", code, stderr.String())
+	}
+	want := "- DEBUG=true\n" +
+		"~ DIFF_HOST: staging.internal -> prod.internal\n" +
+		"~ DIFF_PASSWORD: <redacted> -> <redacted>\n" +
+		"+ DIFF_REGION=eu\n"
+	if stdout.String() != want {
+		t.Fatalf("output = %q, want %q", stdout.String(), want)
+	}
+
+	t.Setenv("DIFF_HOST", "staging.internal")
+	t.Setenv("DIFF_PASSWORD", "a")
+	t.Setenv("DIFF_TOKEN_ci", "y")
+	t.Setenv("DIFF_REGION", "us")
+	t.Setenv("SAME", "1")
+	t.Setenv("UNRELATED", "1")
+
+	stdout.Reset()
+	if code := envicli.Run[diffConfig]([]string{"diff", staging}, &stdout, &stderr); code != 1 {
+		t.Fatalf("exit code = %d, want 1\n\n%s", code, stderr.String())
+	}
+	want = "- DEBUG=true\n" +
+		"+ DIFF_REGION=us\n" +
+		"~ DIFF_TOKEN_ci: <redacted> -> <redacted>\n"
+	if stdout.String() != want {
+		t.Fatalf("output = %q, want %q", stdout.String(), want)
+	}
+
+	stdout.Reset()
+	if code := envicli.Run[diffConfig]([]string{"diff", prod, prod}, &stdout, &stderr); code != 0 || stdout.Len() > 0 {
+		t.Fatalf("exit code = %d with output %q, want 0 without output", code, stdout.String())
+	}
+}
@@ -0,0 +1,152 @@
+package envicli
+
+import (
+	"flag"
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+
+	"github.com/bounoable/envi"
+)
+
+// docs writes the documentation of the variables of Config in the format
+// given by the -format flag.
+func docs[Config any](args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("docs", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	format := fs.String("format", "markdown", "output `format`: markdown or html")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	vars := envi.Variables[Config]()
+
+	switch *format {
+	case "markdown", "md":
+		writeMarkdown(stdout, vars)
+	case "html":
+		if err := htmlDocs.Execute(stdout, docRows(vars)); err != nil {
+			fmt.Fprintf(stderr, "docs: %v\n", err)
+			return 1
+		}
+	default:
+		fmt.Fprintf(stderr, "docs: unknown format %q\n", *format)
+		return 2
+	}
+
+	return 0
+}
+
+// docRow is a variable formatted for documentation.
+type docRow struct {
+	Key         string
+	Type        string
+	Default     string
+	Required    string
+	Description string
+	Example     string
+}
+
+func docRows(vars []envi.Variable) []docRow {
+	rows := make([]docRow, len(vars))
+	for i, v := range vars {
+		row := docRow{
+			Key:         v.Key,
+			Type:        v.Type,
+			Default:     v.Default,
+			Required:    "no",
+			Description: v.Description,
+			Example:     v.Example,
+		}
+		switch {
+		case v.Required:
+			row.Required = "yes"
+		case v.RequiredIf != "":
+			row.Required = "if " + v.RequiredIf
+		case v.RequiredUnless != "":
+			row.Required = "unless " + v.RequiredUnless
+		}
+		if v.Map {
+			row.Key = v.Key + "_*"
+			if v.Key == "" {
+				row.Key = "*"
+			}
+		}
+		if !v.HasDefault && v.DefaultExpr != "" {
+			row.Default = v.DefaultExpr
+		}
+		if len(v.DefaultFrom) > 0 {
+			from := "$" + strings.Join(v.DefaultFrom, ", $")
+			if row.Default != "" {
+				from += ", " + row.Default
+			}
+			row.Default = from
+		}
+		if v.IsDeprecated {
+			deprecated := "Deprecated"
+			if v.Deprecated != "" {
+				deprecated += ": " + v.Deprecated
+			}
+			row.Description = strings.TrimSpace(sentence(row.Description) + " " + deprecated + ".")
+		}
+		if row.Example != "" {
+			// The example follows the description as a sentence of its own.
+			row.Description = sentence(row.Description)
+		}
+		rows[i] = row
+	}
+	return rows
+}
+
+// sentence terminates s with a period unless it is empty or already ends with
+// a punctuation mark, so more sentences can follow it.
+func sentence(s string) string {
+	s = strings.TrimSpace(s)
+	if s == "" || strings.ContainsAny(s[len(s)-1:], ".!?") {
+		return s
+	}
+	return s + "."
+}
+
+func writeMarkdown(w io.Writer, vars []envi.Variable) {
+	fmt.Fprintln(w, "| Variable | Type | Default | Required | Description |")
+	fmt.Fprintln(w, "| --- | --- | --- | --- | --- |")
+	for _, row := range docRows(vars) {
+		desc := markdownEscape(row.Description)
+		if row.Example != "" {
+			desc = strings.TrimSpace(desc + " Example: " + markdownCode(row.Example))
+		}
+		fmt.Fprintf(w, "| %s | %s | %s | %s | %s |\n",
+			markdownCode(row.Key),
+			markdownCode(row.Type),
+			markdownCode(row.Default),
+			markdownEscape(row.Required),
+			desc,
+		)
+	}
+}
+
+func markdownCode(s string) string {
+	if s == "" {
+		return ""
+	}
+	return "`" + markdownEscape(s) + "`"
+}
+
+func markdownEscape(s string) string {
+	s = strings.ReplaceAll(s, "|", `\|`)
+	return strings.ReplaceAll(s, "\n", " ")
+}
+
+var htmlDocs = template.Must(template.New("docs").Parse(`<table>
+  <thead>
+    <tr><th>Variable</th><th>Type</th><th>Default</th><th>Required</th><th>Description</th></tr>
+  </thead>
+  <tbody>
+{{- range .}}
+    <tr><td><code>{{.Key}}</code></td><td><code>{{.Type}}</code></td><td>{{with .Default}}<code>{{.}}</code>{{end}}</td><td>{{.Required}}</td><td>{{.Description}}{{with .Example}} Example: <code>{{.}}</code>{{end}}</td></tr>
+{{- end}}
+  </tbody>
+</table>
+`))
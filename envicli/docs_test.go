@@ -0,0 +1,73 @@
+package envicli_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bounoable/envi/envicli"
+)
+
+type docsConfig struct {
+	Host   string            `env:"HOST" default:"localhost" desc:"Host to listen on."`
+	Port   int               `env:"PORT" required:"true" desc:"Port | protocol" example:"8080"`
+	Legacy string            `env:"LEGACY" deprecated:"use HOST instead" desc:"Old host"`
+	Labels map[string]string `env:"LABEL"`
+}
+
+// TestRun_docs verifies that the docs command documents the variables as a
+// Markdown or HTML table.
+func TestRun_docs(t *testing.T) {
+	var stdout, stderr strings.Builder
+	if code := envicli.Run[docsConfig]([]string{"docs"}, &stdout, &stderr); code != 0 {
+		t.Fatalf("exit code = %d, want 0\n\n%s", code, stderr.String())
+	}
+
+	want := "| Variable | Type | Default | Required | Description |\n" +
+		"| --- | --- | --- | --- | --- |\n" +
+		"| `HOST` | `string` | `localhost` | no | Host to listen on. |\n" +
+		"| `PORT` | `int` |  | yes | Port \\| protocol. Example: `8080` |\n" +
+		"| `LEGACY` | `string` |  | no | Old host. Deprecated: use HOST instead. |\n" +
+		"| `LABEL_*` | `map[string]string` |  | no |  |\n"
+	if stdout.String() != want {
+		t.Fatalf("output = \n%s\nwant\n%s", stdout.String(), want)
+	}
+
+	stdout.Reset()
+	if code := envicli.Run[docsConfig]([]string{"docs", "-format", "html"}, &stdout, &stderr); code != 0 {
+		t.Fatalf("exit code = %d, want 0\n\n%s", code, stderr.String())
+	}
+	for _, want := range []string{
+		"<tr><td><code>HOST</code></td><td><code>string</code></td><td><code>localhost</code></td><td>no</td><td>Host to listen on.</td></tr>",
+		"<td>Port | protocol. Example: <code>8080</code></td>",
+		"<td><code>LABEL_*</code></td>",
+	} {
+		if !strings.Contains(stdout.String(), want) {
+			t.Fatalf("output should contain %q\n\n%s", want, stdout.String())
+		}
+	}
+
+	if code := envicli.Run[docsConfig]([]string{"docs", "-format", "pdf"}, &stdout, &stderr); code != 2 {
+		t.Fatalf("exit code = %d, want 2", code)
+	}
+}
+
+// TestRun_docs_order verifies that the variables are documented in the order
+// of the fields, with the variables of nested structs kept together.
+func TestRun_docs_order(t *testing.T) {
+	var stdout, stderr strings.Builder
+	if code := envicli.Run[orderedConfig]([]string{"docs"}, &stdout, &stderr); code != 0 {
+		t.Fatalf("exit code = %d, want 0\n\n%s", code, stderr.String())
+	}
+
+	var keys []string
+	for _, line := range strings.Split(stdout.String(), "\n")[2:] {
+		if fields := strings.Fields(line); len(fields) > 1 {
+			keys = append(keys, strings.Trim(fields[1], "`"))
+		}
+	}
+
+	want := []string{"PORT", "DB_URL", "DB_REPLICA_URL", "DB_POOL", "REGION", "DEBUG", "ZONE"}
+	if strings.Join(keys, ",") != strings.Join(want, ",") {
+		t.Fatalf("keys = %v, want = %v", keys, want)
+	}
+}
@@ -0,0 +1,121 @@
+// Package envicli implements the commands of the envi CLI for a config
+// struct.
+//
+// The envi command (github.com/bounoable/envi/cmd/envi) runs these commands
+// against the struct of a package by generating a program that calls Main. A
+// service can also embed them in its own binary:
+//
+//	func main() {
+//		if len(os.Args) > 1 && os.Args[1] == "config" {
+//			envicli.Main[Config](os.Args[2:]...)
+//		}
+//		// ...
+//	}
+package envicli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/bounoable/envi"
+)
+
+type command struct {
+	usage string
+	run   func(args []string, stdout, stderr io.Writer) int
+}
+
+func commands[Config any]() map[string]command {
+	return map[string]command{
+		"check":    {"validate the environment or a dotenv file", check[Config]},
+		"decrypt":  {"decrypt age-encrypted values of a dotenv file", decrypt[Config]},
+		"diff":     {"compare the variables of two env files, or of an env file and the environment", diff[Config]},
+		"docs":     {"print the documentation of the variables", docs[Config]},
+		"encrypt":  {"encrypt the values of secrets in a dotenv file with age", encrypt[Config]},
+		"exec":     {"run a command with the variables of env files", execute[Config]},
+		"lint":     {"check dotenv files for unknown, duplicate and invalid variables", lint[Config]},
+		"template": {"print a dotenv template of the variables, with their descriptions", dotenvTemplate[Config]},
+	}
+}
+
+// Main runs the command given by args, e.g. "check -env-file .env", and exits
+// the process with its exit code.
+func Main[Config any](args ...string) {
+	os.Exit(Run[Config](args, os.Stdout, os.Stderr))
+}
+
+// Run runs the command given by args for the struct type Config and returns
+// its exit code: 0 on success, 1 if the command found problems or failed, and
+// 2 on invalid usage.
+func Run[Config any](args []string, stdout, stderr io.Writer) int {
+	cmds := commands[Config]()
+
+	if len(args) == 0 {
+		usage(stderr, cmds)
+		return 2
+	}
+
+	cmd, ok := cmds[args[0]]
+	if !ok {
+		fmt.Fprintf(stderr, "unknown command %q\n\n", args[0])
+		usage(stderr, cmds)
+		return 2
+	}
+
+	return cmd.run(args[1:], stdout, stderr)
+}
+
+func usage(w io.Writer, cmds map[string]command) {
+	names := make([]string, 0, len(cmds))
+	for name := range cmds {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintf(w, "Usage: <command> [flags]\n\nCommands:\n")
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-8s %s\n", name, cmds[name].usage)
+	}
+}
+
+// sourceOptions returns the options that read the variables from the env file
+// at path, or from the environment if path is empty.
+func sourceOptions(path string, dialect envi.DotEnvDialect) ([]envi.Option, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	dotenv, err := envi.ReadDotEnvDialect(path, dialect)
+	if err != nil {
+		return nil, err
+	}
+
+	return []envi.Option{envi.WithSource(dotenv)}, nil
+}
+
+// dialects are the names of the env file dialects of the -dialect flag.
+var dialects = []envi.DotEnvDialect{envi.DotEnv, envi.DockerEnvFile, envi.SystemdEnvFile, envi.ShellScript}
+
+// dialectFlag defines the -dialect flag, which selects the syntax of env
+// files, on fs.
+func dialectFlag(fs *flag.FlagSet) *envi.DotEnvDialect {
+	d := envi.DotEnv
+	names := make([]string, len(dialects))
+	for n, d := range dialects {
+		names[n] = d.String()
+	}
+	fs.Func("dialect", "the `syntax` of env files: "+strings.Join(names, ", ")+" (default dotenv)", func(s string) error {
+		for _, dialect := range dialects {
+			if dialect.String() == s {
+				d = dialect
+				return nil
+			}
+		}
+		return fmt.Errorf("unknown dialect %q", s)
+	})
+	return &d
+}
@@ -0,0 +1,163 @@
+package envicli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+
+	"github.com/bounoable/envi"
+)
+
+// execute runs a command with the variables of env files added to the
+// environment, after validating the result against Config.
+func execute[Config any](args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("exec", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	var files stringList
+	fs.Var(&files, "env-file", "add the variables of the dotenv `file`; may be repeated, later files take precedence")
+	dialect := dialectFlag(fs)
+	expand := fs.Bool("expand", false, "expand ${VAR} and $VAR references in the values of env files")
+	override := fs.Bool("override", false, "let env files take precedence over the environment")
+	noCheck := fs.Bool("no-check", false, "run the command without validating the environment")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: exec [flags] [--] command [arg ...]\n\nFiles default to .env.\n\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+	if len(files) == 0 {
+		files = stringList{".env"}
+	}
+
+	vars, err := execEnv(files, *dialect, *expand, *override)
+	if err != nil {
+		fmt.Fprintf(stderr, "exec: %v\n", err)
+		return 1
+	}
+
+	if !*noCheck {
+		// The variables are already expanded, so they are checked as they
+		// are passed to the command instead of being expanded again.
+		if problems := envi.Check[Config](envi.WithSource(vars)); len(problems) > 0 {
+			for _, p := range problems {
+				fmt.Fprintln(stderr, p)
+			}
+			fmt.Fprintf(stderr, "exec: %d problem(s) found\n", len(problems))
+			return 1
+		}
+	}
+
+	return run(fs.Args(), environ(vars), stdout, stderr)
+}
+
+// execEnv returns the environment of the command: the variables of the env
+// files merged with the environment of the process.
+func execEnv(files []string, dialect envi.DotEnvDialect, expand, override bool) (envi.Map, error) {
+	env := envi.Environ(os.Environ())
+
+	fileVars := make(envi.Map)
+	for _, file := range files {
+		vars, err := envi.ReadDotEnvDialect(file, dialect)
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range vars {
+			fileVars[k] = v
+		}
+	}
+
+	// References are resolved against the merged environment, so files can
+	// refer to variables of the environment and of each other.
+	lookup := func(key string) string {
+		if v, ok := env[key]; ok && !override {
+			return v
+		}
+		if v, ok := fileVars[key]; ok {
+			return v
+		}
+		return env[key]
+	}
+
+	out := make(envi.Map, len(env)+len(fileVars))
+	for k, v := range fileVars {
+		if expand {
+			v = os.Expand(v, lookup)
+		}
+		out[k] = v
+	}
+	for k, v := range env {
+		if _, ok := out[k]; !ok || !override {
+			out[k] = v
+		}
+	}
+
+	return out, nil
+}
+
+// run runs the command given by args with the environment env and returns
+// its exit code. Interrupts and termination signals are forwarded to the
+// command.
+func run(args, env []string, stdout, stderr io.Writer) int {
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Env = env
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, stdout, stderr
+
+	if err := cmd.Start(); err != nil {
+		fmt.Fprintf(stderr, "exec: %v\n", err)
+		return 1
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	go func() {
+		for sig := range signals {
+			cmd.Process.Signal(sig)
+		}
+	}()
+
+	if err := cmd.Wait(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() >= 0 {
+			return exitErr.ExitCode()
+		}
+		fmt.Fprintf(stderr, "exec: %v\n", err)
+		return 1
+	}
+
+	return 0
+}
+
+// environ returns the variables of m as sorted KEY=VALUE entries.
+func environ(m envi.Map) []string {
+	env := make([]string, 0, len(m))
+	for k, v := range m {
+		env = append(env, k+"="+v)
+	}
+	sort.Strings(env)
+	return env
+}
+
+// stringList is a flag.Value for a repeated flag, e.g. of file paths.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(s string) error {
+	*l = append(*l, s)
+	return nil
+}
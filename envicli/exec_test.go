@@ -0,0 +1,103 @@
+package envicli_test
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/bounoable/envi/envicli"
+)
+
+// TestRun_exec verifies that the exec command runs a command with the
+// variables of env files, validated against the config struct.
+func TestRun_exec(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("exec test uses sh")
+	}
+
+	dir := t.TempDir()
+	base := filepath.Join(dir, "base.env")
+	if err := os.WriteFile(base, []byte("HOST=base.internal\nPORT=80\nGREETING=hello\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	local := filepath.Join(dir, "local.env")
+	if err := os.WriteFile(local, []byte("HOST=local.internal\nURL=http://${HOST}:${PORT}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PORT", "8080")
+	t.Setenv("GREETING", "hi")
+
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{
+			name: "env precedence",
+			args: []string{"-env-file", base, "-env-file", local},
+			want: "local.internal 8080 hi http://${HOST}:${PORT}\n",
+		},
+		{
+			name: "expand",
+			args: []string{"-env-file", base, "-env-file", local, "-expand"},
+			want: "local.internal 8080 hi http://local.internal:8080\n",
+		},
+		{
+			name: "override",
+			args: []string{"-env-file", base, "-env-file", local, "-override", "-expand"},
+			want: "local.internal 80 hello http://local.internal:80\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := append([]string{"exec"}, tt.args...)
+			args = append(args, "--", "sh", "-c", `echo "$HOST $PORT $GREETING $URL"`)
+
+			var stdout, stderr strings.Builder
+			if code := envicli.Run[config](args, &stdout, &stderr); code != 0 {
+				t.Fatalf("exit code = %d, want 0\n\n%s", code, stderr.String())
+			}
+			if stdout.String() != tt.want {
+				t.Fatalf("output = %q, want %q", stdout.String(), tt.want)
+			}
+		})
+	}
+
+	t.Run("expand once", func(t *testing.T) {
+		ref := filepath.Join(dir, "ref.env")
+		if err := os.WriteFile(ref, []byte("HOST=${RAW}\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		t.Setenv("RAW", "$MISSING")
+
+		var stdout, stderr strings.Builder
+		args := []string{"exec", "-env-file", ref, "-expand", "--", "sh", "-c", `echo "$HOST"`}
+		if code := envicli.Run[config](args, &stdout, &stderr); code != 0 {
+			t.Fatalf("exit code = %d, want 0\n\n%s", code, stderr.String())
+		}
+		if want := "$MISSING\n"; stdout.String() != want {
+			t.Fatalf("output = %q, want %q", stdout.String(), want)
+		}
+	})
+
+	var stdout, stderr strings.Builder
+	if code := envicli.Run[config]([]string{"exec", "-env-file", local, "sh", "-c", "exit 3"}, &stdout, &stderr); code != 3 {
+		t.Fatalf("exit code = %d, want exit code 3 of the command\n\n%s", code, stderr.String())
+	}
+
+	invalid := filepath.Join(dir, "invalid.env")
+	if err := os.WriteFile(invalid, []byte("PORT=abc\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	stderr.Reset()
+	code := envicli.Run[config]([]string{"exec", "-env-file", invalid, "-override", "sh", "-c", "echo started"}, &stdout, &stderr)
+	if code != 1 || strings.Contains(stdout.String(), "started") {
+		t.Fatalf("exit code = %d, want 1 without running the command", code)
+	}
+	if !strings.Contains(stderr.String(), "Host (HOST): required variable is not set") {
+		t.Fatalf("stderr = %q, want problems", stderr.String())
+	}
+}
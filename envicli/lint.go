@@ -0,0 +1,141 @@
+package envicli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/bounoable/envi"
+)
+
+// lint checks dotenv files against Config for unknown, duplicate, invalid,
+// missing and deprecated variables.
+func lint[Config any](args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("lint", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	dialect := dialectFlag(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: lint [-dialect syntax] [file ...]\n\nFiles default to .env.\n\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	files := fs.Args()
+	if len(files) == 0 {
+		files = []string{".env"}
+	}
+
+	var failed bool
+	for _, file := range files {
+		findings, err := lintFile[Config](file, *dialect)
+		if err != nil {
+			fmt.Fprintf(stderr, "lint: %v\n", err)
+			return 1
+		}
+		for _, f := range findings {
+			fmt.Fprintln(stdout, f)
+		}
+		failed = failed || len(findings) > 0
+	}
+
+	if failed {
+		return 1
+	}
+	return 0
+}
+
+// finding is an issue of a dotenv file. Line is 0 for issues that don't refer
+// to a line, such as missing variables.
+type finding struct {
+	file    string
+	line    int
+	message string
+}
+
+func (f finding) String() string {
+	if f.line == 0 {
+		return fmt.Sprintf("%s: %s", f.file, f.message)
+	}
+	return fmt.Sprintf("%s:%d: %s", f.file, f.line, f.message)
+}
+
+func lintFile[Config any](file string, dialect envi.DotEnvDialect) ([]finding, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	vars, err := envi.ParseDotEnvDialect(f, dialect)
+	if err != nil {
+		return []finding{{file: file, message: err.Error()}}, nil
+	}
+
+	known := envi.Variables[Config]()
+
+	var findings []finding
+	lines := make(map[string]int)
+	values := make(envi.Map)
+	for _, v := range vars {
+		if first, ok := lines[v.Key]; ok {
+			findings = append(findings, finding{file, v.Line, fmt.Sprintf("duplicate variable %s, first defined on line %d", v.Key, first)})
+		} else {
+			lines[v.Key] = v.Line
+		}
+		values[v.Key] = v.Value
+
+		if !knownKey(known, v.Key) {
+			findings = append(findings, finding{file, v.Line, fmt.Sprintf("unknown variable %s", v.Key)})
+		}
+	}
+
+	problems := envi.Check[Config](
+		envi.WithSource(values),
+		envi.WithWarningHandler(func(w envi.Warning) {
+			findings = append(findings, finding{file, lines[w.Key], fmt.Sprintf("%s is %s", w.Key, w.Message)})
+		}),
+	)
+	for _, p := range problems {
+		if errors.Is(p.Err, envi.ErrRequired) {
+			findings = append(findings, finding{file, 0, fmt.Sprintf("missing required variable %s", p.Key)})
+			continue
+		}
+		findings = append(findings, finding{file, lines[p.Key], fmt.Sprintf("invalid value of %s: %v", p.Key, p.Err)})
+	}
+
+	// Findings that refer to a line come first, in the order of the lines.
+	sort.SliceStable(findings, func(i, j int) bool {
+		li, lj := findings[i].line, findings[j].line
+		if li == 0 || lj == 0 {
+			return li != 0 && lj == 0
+		}
+		return li < lj
+	})
+
+	return findings, nil
+}
+
+// knownKey reports whether key is the key or an alias of one of the
+// variables, or has the prefix of a map variable.
+func knownKey(vars []envi.Variable, key string) bool {
+	for _, v := range vars {
+		if !v.Map && v.Key == key {
+			return true
+		}
+		for _, alias := range v.Aliases {
+			if alias == key {
+				return true
+			}
+		}
+		if v.Map && (v.Key == "" || strings.HasPrefix(key, v.Key+"_")) {
+			return true
+		}
+	}
+	return false
+}
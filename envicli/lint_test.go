@@ -0,0 +1,58 @@
+package envicli_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bounoable/envi/envicli"
+)
+
+type lintConfig struct {
+	Host   string            `env:"HOST" required:"true"`
+	Port   int               `env:"PORT"`
+	Token  string            `env:"TOKEN" required:"true"`
+	Legacy string            `env:"LEGACY" deprecated:"use HOST instead"`
+	Region string            `env:"REGION" aliases:"AWS_REGION"`
+	Labels map[string]string `env:"LABEL"`
+}
+
+// TestRun_lint verifies that the lint command reports unknown, duplicate,
+// invalid, deprecated and missing variables with their line numbers.
+func TestRun_lint(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.env")
+	src := "HOST=localhost\n" +
+		"PORT=http\n" +
+		"LABEL_TEAM=core\n" +
+		"HOTS=typo\n" +
+		"LEGACY=old\n" +
+		"HOST=example.com\n" +
+		"AWS_REGION=eu-west-1\n"
+	if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr strings.Builder
+	if code := envicli.Run[lintConfig]([]string{"lint", path}, &stdout, &stderr); code != 1 {
+		t.Fatalf("exit code = %d, want 1\n\n%s", code, stderr.String())
+	}
+
+	want := path + ":2: invalid value of PORT: strconv.ParseInt: parsing \"http\": invalid syntax\n" +
+		path + ":4: unknown variable HOTS\n" +
+		path + ":5: LEGACY is deprecated: use HOST instead\n" +
+		path + ":6: duplicate variable HOST, first defined on line 1\n" +
+		path + ":7: AWS_REGION is deprecated; rename it to REGION\n" +
+		path + ": missing required variable TOKEN\n"
+	if stdout.String() != want {
+		t.Fatalf("output = \n%s\nwant\n%s", stdout.String(), want)
+	}
+
+	if err := os.WriteFile(path, []byte("HOST=localhost\nTOKEN=secret\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	stdout.Reset()
+	if code := envicli.Run[lintConfig]([]string{"lint", path}, &stdout, &stderr); code != 0 {
+		t.Fatalf("exit code = %d, want 0\n\n%s", code, stdout.String())
+	}
+}
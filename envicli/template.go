@@ -0,0 +1,104 @@
+package envicli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/bounoable/envi"
+)
+
+// dotenvTemplate writes a dotenv template for the variables of Config, e.g.
+// to commit as .env.example.
+func dotenvTemplate[Config any](args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("template", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: template\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	writeTemplate(stdout, envi.Explain[Config]())
+
+	return 0
+}
+
+// writeTemplate writes an entry for every variable, preceded by its `desc`
+// tag as a comment. Required variables are uncommented for the user to fill
+// in; all others are commented out. The value of an entry is the `example`
+// tag of the variable, or else its default.
+//
+// Entries are written in the order of the fields, so templates diff cleanly
+// when the struct changes. The variables of a nested struct follow a header
+// with the path of the struct; those of the top-level struct that follow a
+// nested struct are separated by an empty header.
+func writeTemplate(w io.Writer, fields []envi.FieldInfo) {
+	var group string
+	for i, f := range fields {
+		v := f.Variable
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+
+		if g := strings.Join(f.Path[:len(f.Path)-1], "."); g != group {
+			group = g
+			if group == "" {
+				fmt.Fprint(w, "# ---\n\n")
+			} else {
+				fmt.Fprintf(w, "# --- %s ---\n\n", group)
+			}
+		}
+
+		for _, line := range strings.Split(v.Description, "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				fmt.Fprintf(w, "# %s\n", line)
+			}
+		}
+
+		switch {
+		case v.Required:
+			fmt.Fprintln(w, "# Required.")
+		case v.RequiredIf != "":
+			fmt.Fprintf(w, "# Required if %s.\n", v.RequiredIf)
+		case v.RequiredUnless != "":
+			fmt.Fprintf(w, "# Required unless %s.\n", v.RequiredUnless)
+		}
+		if v.IsDeprecated {
+			deprecated := "Deprecated"
+			if v.Deprecated != "" {
+				deprecated += ": " + v.Deprecated
+			}
+			fmt.Fprintf(w, "# %s.\n", deprecated)
+		}
+
+		if v.Example != "" && v.HasDefault && !v.Secret {
+			fmt.Fprintf(w, "# Defaults to %s.\n", quoteDotEnv(v.Default))
+		}
+
+		key := v.Key
+		if v.Map {
+			key += "_<name>"
+			if v.Key == "" {
+				key = "<name>"
+			}
+		}
+
+		var value string
+		switch {
+		case v.Example != "":
+			value = quoteDotEnv(v.Example)
+		case v.HasDefault && !v.Secret:
+			value = quoteDotEnv(v.Default)
+		}
+
+		if v.Required {
+			fmt.Fprintf(w, "%s=%s\n", key, value)
+			continue
+		}
+		fmt.Fprintf(w, "# %s=%s\n", key, value)
+	}
+}
@@ -0,0 +1,108 @@
+package envicli_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bounoable/envi/envicli"
+)
+
+type templateConfig struct {
+	Host     string            `env:"HOST" default:"localhost" desc:"Host to listen on."`
+	Port     int               `env:"PORT" required:"true" desc:"Port to listen on."`
+	Greeting string            `env:"GREETING" default:"hello world"`
+	Password string            `env:"PASSWORD" required_if:"HOST" secret:"true" default:"changeme"`
+	Legacy   string            `env:"LEGACY" deprecated:"use HOST instead"`
+	Labels   map[string]string `env:"LABEL" desc:"Labels of the service."`
+	Redis    string            `env:"REDIS_URL" required:"true" example:"redis://localhost:6379/0"`
+	Timeout  string            `env:"TIMEOUT" default:"10s" example:"1m"`
+}
+
+// TestRun_template verifies that the template command writes an entry for
+// every variable, documented by its `desc` tag and with the value of its
+// `example` tag.
+func TestRun_template(t *testing.T) {
+	var stdout, stderr strings.Builder
+	if code := envicli.Run[templateConfig]([]string{"template"}, &stdout, &stderr); code != 0 {
+		t.Fatalf("exit code = %d, want 0\n\n%s", code, stderr.String())
+	}
+
+	want := "# Host to listen on.\n" +
+		"# HOST=localhost\n" +
+		"\n" +
+		"# Port to listen on.\n" +
+		"# Required.\n" +
+		"PORT=\n" +
+		"\n" +
+		"# GREETING=\"hello world\"\n" +
+		"\n" +
+		"# Required if HOST.\n" +
+		"# PASSWORD=\n" +
+		"\n" +
+		"# Deprecated: use HOST instead.\n" +
+		"# LEGACY=\n" +
+		"\n" +
+		"# Labels of the service.\n" +
+		"# LABEL_<name>=\n" +
+		"\n" +
+		"# Required.\n" +
+		"REDIS_URL=redis://localhost:6379/0\n" +
+		"\n" +
+		"# Defaults to 10s.\n" +
+		"# TIMEOUT=1m\n"
+	if stdout.String() != want {
+		t.Fatalf("output = \n%s\nwant\n%s", stdout.String(), want)
+	}
+}
+
+type orderedConfig struct {
+	Port     int `env:"PORT"`
+	Database struct {
+		URL     string `env:"URL"`
+		Replica struct {
+			URL string `env:"REPLICA_URL"`
+		}
+		Pool int `env:"POOL"`
+	} `envPrefix:"DB_"`
+	Shared `env:",squash"`
+	Debug  bool   `env:"DEBUG"`
+	Zone   string `env:"ZONE"`
+}
+
+type Shared struct {
+	Region string `env:"REGION"`
+}
+
+// TestRun_template_order verifies that entries are written in the order of
+// the fields, with the variables of nested structs kept together.
+func TestRun_template_order(t *testing.T) {
+	var stdout, stderr strings.Builder
+	if code := envicli.Run[orderedConfig]([]string{"template"}, &stdout, &stderr); code != 0 {
+		t.Fatalf("exit code = %d, want 0\n\n%s", code, stderr.String())
+	}
+
+	want := "# PORT=\n" +
+		"\n" +
+		"# --- Database ---\n" +
+		"\n" +
+		"# DB_URL=\n" +
+		"\n" +
+		"# --- Database.Replica ---\n" +
+		"\n" +
+		"# DB_REPLICA_URL=\n" +
+		"\n" +
+		"# --- Database ---\n" +
+		"\n" +
+		"# DB_POOL=\n" +
+		"\n" +
+		"# ---\n" +
+		"\n" +
+		"# REGION=\n" +
+		"\n" +
+		"# DEBUG=\n" +
+		"\n" +
+		"# ZONE=\n"
+	if stdout.String() != want {
+		t.Fatalf("output = \n%s\nwant\n%s", stdout.String(), want)
+	}
+}
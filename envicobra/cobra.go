@@ -0,0 +1,152 @@
+// Package envicobra integrates envi with pflag (github.com/spf13/pflag) and
+// cobra (github.com/spf13/cobra).
+//
+// Bind registers a flag for every tagged field of a config struct and wires
+// the command's PreRunE, so the environment and the command-line flags
+// populate the same struct before the command runs:
+//
+//	type Config struct {
+//		Port int `env:"PORT" short:"p" desc:"The port to listen on."`
+//	}
+//
+//	var cfg Config
+//	cmd := &cobra.Command{
+//		Use: "serve",
+//		RunE: func(cmd *cobra.Command, args []string) error {
+//			return serve(cfg)
+//		},
+//	}
+//	if err := envicobra.Bind(cmd, &cfg); err != nil {
+//		log.Fatal(err)
+//	}
+//
+// Flag names are derived from the variable keys (PORT becomes --port), the
+// `short` tag sets the shorthand and the `desc` tag sets the usage text.
+package envicobra
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/bounoable/envi"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// BindFlags parses the environment into cfg and registers a pflag flag for
+// every tagged field of cfg in fs. Flags write directly into cfg when fs is
+// parsed, so values are resolved with the precedence flags > environment >
+// defaults. Since flags may set required fields, they are not checked by
+// BindFlags; call envi.CheckRequired after fs is parsed.
+func BindFlags[Config any](fs *pflag.FlagSet, cfg *Config, opts ...envi.Option) error {
+	opts = deferRequired(opts)
+	if err := envi.Parse(cfg, opts...); err != nil {
+		return err
+	}
+	_, err := register(fs, envi.Flags(cfg, opts...))
+	return err
+}
+
+// Bind registers a flag for every tagged field of cfg in the flags of cmd and
+// wires the PreRunE of cmd to parse the environment into cfg before the
+// command-line flags are applied on top of it. The environment is parsed
+// using the context of the command. An existing PreRunE or PreRun of cmd is
+// called afterwards.
+//
+// Bind parses the environment once when it is called, so the help output
+// shows the values from the environment as flag defaults, except for those of
+// fields with a `secret` tag. Required fields are
+// checked in PreRunE, after the flags were applied, so they may be set by
+// flags alone.
+func Bind[Config any](cmd *cobra.Command, cfg *Config, opts ...envi.Option) error {
+	checkOpts := opts
+	opts = deferRequired(opts)
+	if err := envi.Parse(cfg, opts...); err != nil {
+		return err
+	}
+
+	values, err := register(cmd.Flags(), envi.Flags(cfg, opts...))
+	if err != nil {
+		return err
+	}
+
+	preRunE, preRun := cmd.PreRunE, cmd.PreRun
+	cmd.PreRun = nil
+	cmd.PreRunE = func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		if ctx == nil {
+			ctx = context.Background()
+		}
+
+		if err := envi.ParseContext(ctx, cfg, opts...); err != nil {
+			return err
+		}
+
+		for _, v := range values {
+			for _, raw := range v.raw {
+				if err := v.FlagValue.Set(raw); err != nil {
+					return err
+				}
+			}
+		}
+
+		if err := envi.CheckRequired(cfg, checkOpts...); err != nil {
+			return err
+		}
+
+		if preRunE != nil {
+			return preRunE(cmd, args)
+		}
+		if preRun != nil {
+			preRun(cmd, args)
+		}
+
+		return nil
+	}
+
+	return nil
+}
+
+// deferRequired returns opts with the checks of required fields deferred
+// until the flags were applied.
+func deferRequired(opts []envi.Option) []envi.Option {
+	return append(opts[:len(opts):len(opts)], envi.WithDeferredRequired())
+}
+
+// value records the raw values that a flag was set to, so they can be
+// re-applied after the environment was parsed.
+type value struct {
+	envi.FlagValue
+	raw []string
+}
+
+func (v *value) Set(s string) error {
+	if err := v.FlagValue.Set(s); err != nil {
+		return err
+	}
+	v.raw = append(v.raw, s)
+	return nil
+}
+
+func register(fs *pflag.FlagSet, flags []envi.Flag) ([]*value, error) {
+	values := make([]*value, 0, len(flags))
+	for _, f := range flags {
+		if fs.Lookup(f.Name) != nil {
+			return nil, fmt.Errorf("bind %q field: flag --%s already defined", f.Field, f.Name)
+		}
+		if len(f.Shorthand) > 1 {
+			return nil, fmt.Errorf("bind %q field: shorthand %q is more than one ASCII character", f.Field, f.Shorthand)
+		}
+		if f.Shorthand != "" && fs.ShorthandLookup(f.Shorthand) != nil {
+			return nil, fmt.Errorf("bind %q field: shorthand -%s already defined", f.Field, f.Shorthand)
+		}
+
+		v := &value{FlagValue: f.Value}
+		pf := fs.VarPF(v, f.Name, f.Shorthand, f.Usage)
+		if f.Value.Type() == "bool" {
+			pf.NoOptDefVal = "true"
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
@@ -0,0 +1,197 @@
+package envicobra_test
+
+import (
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bounoable/envi"
+	"github.com/bounoable/envi/envicobra"
+	"github.com/google/go-cmp/cmp"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+type config struct {
+	Host    string        `env:"HOST" default:"localhost" desc:"The host to listen on."`
+	Port    int           `env:"PORT" short:"p" default:"8080"`
+	Debug   bool          `env:"DEBUG" short:"d"`
+	Timeout time.Duration `env:"TIMEOUT"`
+	Tags    []string      `env:"TAGS"`
+}
+
+// TestBind verifies that Bind registers pflag flags with shorthands and
+// usage texts, and that the environment is parsed before the flags are
+// applied when the command runs.
+func TestBind(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("HOST", "bind-time.example.com")
+
+	var (
+		cfg        config
+		preRun     bool
+		runWithCfg config
+	)
+	cmd := &cobra.Command{
+		Use:    "serve",
+		PreRun: func(*cobra.Command, []string) { preRun = true },
+		Run:    func(*cobra.Command, []string) { runWithCfg = cfg },
+	}
+	cmd.SetOut(io.Discard)
+
+	if err := envicobra.Bind(cmd, &cfg); err != nil {
+		t.Fatalf("Bind() failed: %v", err)
+	}
+
+	host := cmd.Flags().Lookup("host")
+	if host == nil || host.Usage != "The host to listen on." || host.DefValue != "bind-time.example.com" {
+		t.Fatalf("--host should be registered with usage and default from the environment; got %+v", host)
+	}
+	if cmd.Flags().ShorthandLookup("p") == nil {
+		t.Fatalf("shorthand -p should be registered")
+	}
+
+	// The environment changes between construction and execution.
+	os.Setenv("HOST", "run-time.example.com")
+	os.Setenv("PORT", "9090")
+	os.Setenv("TIMEOUT", "1s")
+
+	cmd.SetArgs([]string{"-p", "1234", "-d", "--tags", "a,b"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("Execute() failed: %v", err)
+	}
+
+	want := config{
+		Host:    "run-time.example.com",
+		Port:    1234,
+		Debug:   true,
+		Timeout: time.Second,
+		Tags:    []string{"a", "b"},
+	}
+	if !cmp.Equal(want, runWithCfg) {
+		t.Fatalf("config = %v, want %v\n\n%s", runWithCfg, want, cmp.Diff(want, runWithCfg))
+	}
+
+	if !preRun {
+		t.Fatalf("the existing PreRun should be called")
+	}
+}
+
+// TestBindFlags verifies that BindFlags registers flags in a pflag.FlagSet
+// that override the environment.
+func TestBindFlags(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("PORT", "9090")
+
+	fs := pflag.NewFlagSet("test", pflag.ContinueOnError)
+
+	var cfg config
+	if err := envicobra.BindFlags(fs, &cfg); err != nil {
+		t.Fatalf("BindFlags() failed: %v", err)
+	}
+
+	if err := fs.Parse([]string{"--debug", "--timeout=5s"}); err != nil {
+		t.Fatalf("Parse() failed: %v", err)
+	}
+
+	want := config{Host: "localhost", Port: 9090, Debug: true, Timeout: 5 * time.Second}
+	if !cmp.Equal(want, cfg) {
+		t.Fatalf("config = %v, want %v\n\n%s", cfg, want, cmp.Diff(want, cfg))
+	}
+
+	if err := envicobra.BindFlags(fs, &cfg); err == nil {
+		t.Fatalf("BindFlags() should fail for already defined flags")
+	}
+}
+
+// TestBind_required verifies that required fields may be set by flags only,
+// and fail the command if they are set by neither flags nor the environment.
+func TestBind_required(t *testing.T) {
+	os.Clearenv()
+
+	type required struct {
+		Token string `env:"TOKEN" required:"true"`
+	}
+
+	for _, tt := range []struct {
+		args    []string
+		wantErr bool
+	}{
+		{args: []string{"--token", "secret"}},
+		{args: nil, wantErr: true},
+	} {
+		var cfg required
+		cmd := &cobra.Command{Use: "serve", Run: func(*cobra.Command, []string) {}}
+		cmd.SetOut(io.Discard)
+		cmd.SetErr(io.Discard)
+		if err := envicobra.Bind(cmd, &cfg); err != nil {
+			t.Fatalf("Bind() failed: %v", err)
+		}
+
+		cmd.SetArgs(tt.args)
+		err := cmd.Execute()
+		if tt.wantErr {
+			if !errors.Is(err, envi.ErrRequired) {
+				t.Fatalf("Execute() should fail with ErrRequired; got %v", err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Execute() failed: %v", err)
+		}
+		if cfg.Token != "secret" {
+			t.Fatalf("Token = %q, want %q", cfg.Token, "secret")
+		}
+	}
+}
+
+// TestBindFlags_required verifies that BindFlags leaves the checks of
+// required fields to envi.CheckRequired.
+func TestBindFlags_required(t *testing.T) {
+	os.Clearenv()
+
+	var cfg struct {
+		Token string `env:"TOKEN" required:"true"`
+	}
+	fs := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	if err := envicobra.BindFlags(fs, &cfg); err != nil {
+		t.Fatalf("BindFlags() failed: %v", err)
+	}
+	if err := fs.Parse([]string{"--token", "secret"}); err != nil {
+		t.Fatalf("Parse() failed: %v", err)
+	}
+	if err := envi.CheckRequired(&cfg); err != nil {
+		t.Fatalf("CheckRequired() failed: %v", err)
+	}
+}
+
+// TestBind_secret verifies that the help output doesn't show the values of
+// secret fields as flag defaults.
+func TestBind_secret(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("TOKEN", "hunter2")
+	os.Setenv("HOST", "example.com")
+
+	var cfg struct {
+		Host  string `env:"HOST"`
+		Token string `env:"TOKEN" secret:"true"`
+	}
+	cmd := &cobra.Command{Use: "serve", Run: func(*cobra.Command, []string) {}}
+	var out strings.Builder
+	cmd.SetOut(&out)
+	cmd.SetArgs([]string{"--help"})
+
+	if err := envicobra.Bind(cmd, &cfg); err != nil {
+		t.Fatalf("Bind() failed: %v", err)
+	}
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("Execute() failed: %v", err)
+	}
+
+	if help := out.String(); strings.Contains(help, "hunter2") || !strings.Contains(help, "example.com") {
+		t.Fatalf("help should show the default of --host but not of --token; got\n%s", help)
+	}
+}
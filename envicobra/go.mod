@@ -0,0 +1,14 @@
+module github.com/bounoable/envi/envicobra
+
+go 1.18
+
+require (
+	github.com/bounoable/envi v0.0.0
+	github.com/google/go-cmp v0.5.9
+	github.com/spf13/cobra v1.8.1
+	github.com/spf13/pflag v1.0.5
+)
+
+require github.com/inconshreveable/mousetrap v1.1.0 // indirect
+
+replace github.com/bounoable/envi => ../
@@ -0,0 +1,199 @@
+// Package envikoanf integrates envi with koanf (github.com/knadh/koanf).
+//
+// Provider exposes a Config parsed by envi as a koanf Provider, so envi can
+// be used purely for the environment layer of a koanf instance:
+//
+//	k := koanf.New(".")
+//	k.Load(file.Provider("config.yaml"), yaml.Parser())
+//	k.Load(envikoanf.Provider[Config](), nil)
+//
+//	var cfg Config
+//	k.Unmarshal("", &cfg)
+//
+// Conversely, Source exposes a koanf instance as an envi.Source, so
+// configuration loaded by koanf can be parsed using envi's struct tags:
+//
+//	err := envi.Parse(&cfg, envi.WithSource(envi.OS(), envikoanf.Source(k)))
+//
+// The package does not depend on koanf; it relies on koanf's interfaces being
+// satisfied structurally.
+package envikoanf
+
+import (
+	"context"
+	"encoding"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/bounoable/envi"
+)
+
+// EnvProvider is a koanf Provider that reads a Config from the environment
+// using envi.
+type EnvProvider[Config any] struct {
+	opts []envi.Option
+}
+
+// Provider returns a koanf Provider that parses a Config using envi and the
+// given options. The returned map is nested according to the `koanf` struct
+// tags of the Config, falling back to the field names, so it can be
+// unmarshaled into the same struct. Fields that hold their zero value after
+// parsing are omitted, so they don't override lower layers of the koanf
+// instance. Structs that implement encoding.TextMarshaler or fmt.Stringer,
+// such as time.Time, url.URL, envi.DSN and envi.Value, are kept as values
+// instead of being nested.
+func Provider[Config any](opts ...envi.Option) *EnvProvider[Config] {
+	return &EnvProvider[Config]{opts: opts}
+}
+
+// ReadBytes is not supported and returns an error.
+func (p *EnvProvider[Config]) ReadBytes() ([]byte, error) {
+	return nil, errors.New("envikoanf provider does not support ReadBytes")
+}
+
+// Read parses the Config and returns it as a nested map.
+func (p *EnvProvider[Config]) Read() (map[string]interface{}, error) {
+	cfg, err := envi.New[Config](p.opts...)
+	if err != nil {
+		return nil, err
+	}
+	return structMap(reflect.ValueOf(cfg)), nil
+}
+
+func structMap(v reflect.Value) map[string]interface{} {
+	out := make(map[string]interface{})
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		key := field.Name
+		if tag, _, _ := strings.Cut(field.Tag.Get("koanf"), ","); tag == "-" {
+			continue
+		} else if tag != "" {
+			key = tag
+		}
+
+		fv := v.Field(i)
+		if isLeaf(fv.Type()) {
+			if !fv.IsZero() {
+				out[key] = fv.Interface()
+			}
+			continue
+		}
+
+		if fv.Kind() == reflect.Pointer && fv.Type().Elem().Kind() == reflect.Struct {
+			if fv.IsNil() {
+				continue
+			}
+			fv = fv.Elem()
+		}
+
+		if fv.Kind() == reflect.Struct && fv.NumField() > 0 {
+			if m := structMap(fv); len(m) > 0 {
+				out[key] = m
+			}
+			continue
+		}
+
+		if !fv.IsZero() {
+			out[key] = fv.Interface()
+		}
+	}
+	return out
+}
+
+var (
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	stringerType      = reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
+)
+
+// isLeaf reports whether values of t are formatted as a whole and must not be
+// flattened into a nested map, even if t is a struct with exported fields.
+func isLeaf(t reflect.Type) bool {
+	if t.Kind() != reflect.Pointer {
+		t = reflect.PointerTo(t)
+	}
+	return t.Implements(textMarshalerType) || t.Implements(stringerType)
+}
+
+// Koanf is the subset of the *koanf.Koanf API that is used by Source.
+type Koanf interface {
+	Keys() []string
+	Get(path string) interface{}
+	Delim() string
+}
+
+type source struct {
+	k      Koanf
+	toPath func(string) string
+	toKey  func(string) string
+}
+
+// Option is an option for Source.
+type Option func(*source)
+
+// WithMapping configures how variable keys map to koanf paths. toPath maps a
+// variable key to a path, and toKey maps a path back to a variable key. By
+// default, keys are lowercased and underscores are replaced by the delimiter
+// of the koanf instance, e.g. DB_HOST maps to "db.host".
+func WithMapping(toPath, toKey func(string) string) Option {
+	return func(s *source) {
+		s.toPath = toPath
+		s.toKey = toKey
+	}
+}
+
+// Source returns an envi.Source that resolves variables from the koanf
+// instance k. Slice values are joined with commas; other values are formatted
+// using fmt.Sprint.
+func Source(k Koanf, opts ...Option) envi.Source {
+	delim := k.Delim()
+	s := &source{
+		k: k,
+		toPath: func(key string) string {
+			return strings.ToLower(strings.ReplaceAll(key, "_", delim))
+		},
+		toKey: func(path string) string {
+			return strings.ToUpper(strings.ReplaceAll(path, delim, "_"))
+		},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+func (s *source) Lookup(_ context.Context, key string) (string, bool, error) {
+	v := s.k.Get(s.toPath(key))
+	if v == nil {
+		return "", false, nil
+	}
+	return format(v), true, nil
+}
+
+func (s *source) Keys(context.Context) ([]string, error) {
+	paths := s.k.Keys()
+	keys := make([]string, len(paths))
+	for i, p := range paths {
+		keys[i] = s.toKey(p)
+	}
+	return keys, nil
+}
+
+func format(v interface{}) string {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice || rv.Type().Elem().Kind() == reflect.Uint8 {
+		return fmt.Sprint(v)
+	}
+
+	vals := make([]string, rv.Len())
+	for i := range vals {
+		vals[i] = fmt.Sprint(rv.Index(i).Interface())
+	}
+	return strings.Join(vals, ",")
+}
@@ -0,0 +1,147 @@
+package envikoanf_test
+
+import (
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bounoable/envi"
+	"github.com/bounoable/envi/envikoanf"
+	"github.com/google/go-cmp/cmp"
+)
+
+type config struct {
+	Host    string        `env:"HOST" koanf:"host"`
+	Port    int           `env:"PORT" koanf:"port"`
+	Timeout time.Duration `env:"TIMEOUT"`
+	Tags    []string      `env:"TAGS" koanf:"tags"`
+	DB      dbConfig      `koanf:"db"`
+	Ignored string        `env:"IGNORED" koanf:"-"`
+}
+
+type dbConfig struct {
+	User     string `env:"DB_USER" koanf:"user"`
+	Password string `env:"DB_PASSWORD" koanf:"password"`
+}
+
+// TestProvider verifies that the provider returns the parsed config as a
+// nested map keyed by the koanf tags and omits zero values.
+func TestProvider(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("HOST", "example.com")
+	os.Setenv("TIMEOUT", "5s")
+	os.Setenv("DB_USER", "admin")
+	os.Setenv("IGNORED", "foo")
+
+	p := envikoanf.Provider[config]()
+
+	got, err := p.Read()
+	if err != nil {
+		t.Fatalf("Read() failed: %v", err)
+	}
+
+	want := map[string]interface{}{
+		"host":    "example.com",
+		"Timeout": 5 * time.Second,
+		"db":      map[string]interface{}{"user": "admin"},
+	}
+	if !cmp.Equal(want, got) {
+		t.Fatalf("Read() = %v, want %v\n\n%s", got, want, cmp.Diff(want, got))
+	}
+
+	if _, err := p.ReadBytes(); err == nil {
+		t.Fatalf("ReadBytes() should fail")
+	}
+}
+
+// TestProvider_leaves verifies that structs that format as a whole are
+// returned as values instead of nested maps.
+func TestProvider_leaves(t *testing.T) {
+	type leafConfig struct {
+		Since    time.Time       `env:"SINCE" koanf:"since"`
+		Endpoint *url.URL        `env:"ENDPOINT" koanf:"endpoint"`
+		Database envi.DSN        `env:"DATABASE_URL" koanf:"database"`
+		Port     envi.Value[int] `env:"PORT" koanf:"port"`
+		Until    time.Time       `env:"UNTIL" koanf:"until"`
+	}
+
+	os.Clearenv()
+	os.Setenv("SINCE", "2024-01-02T03:04:05Z")
+	os.Setenv("ENDPOINT", "https://example.com")
+	os.Setenv("DATABASE_URL", "postgres://db:5432/app")
+	os.Setenv("PORT", "8080")
+
+	got, err := envikoanf.Provider[leafConfig]().Read()
+	if err != nil {
+		t.Fatalf("Read() failed: %v", err)
+	}
+
+	cfg, err := envi.New[leafConfig]()
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	want := map[string]interface{}{
+		"since":    cfg.Since,
+		"endpoint": cfg.Endpoint,
+		"database": cfg.Database,
+		"port":     cfg.Port,
+	}
+	if !cmp.Equal(want, got) {
+		t.Fatalf("Read() = %v, want %v\n\n%s", got, want, cmp.Diff(want, got))
+	}
+}
+
+type fakeKoanf map[string]interface{}
+
+func (k fakeKoanf) Keys() []string {
+	var keys []string
+	for k := range k {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+func (k fakeKoanf) Get(path string) interface{} { return k[path] }
+
+func (k fakeKoanf) Delim() string { return "." }
+
+// TestSource verifies that variables resolve from koanf paths using the
+// default and custom mappings.
+func TestSource(t *testing.T) {
+	os.Clearenv()
+
+	k := fakeKoanf{
+		"host":        "example.com",
+		"port":        8080,
+		"tags":        []interface{}{"a", "b"},
+		"db.user":     "admin",
+		"db.password": "secret",
+	}
+
+	cfg, err := envi.New[config](envi.WithSource(envikoanf.Source(k)))
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	want := config{
+		Host: "example.com",
+		Port: 8080,
+		Tags: []string{"a", "b"},
+		DB:   dbConfig{User: "admin", Password: "secret"},
+	}
+	if !cmp.Equal(want, cfg) {
+		t.Fatalf("config = %v, want %v\n\n%s", cfg, want, cmp.Diff(want, cfg))
+	}
+
+	s := envikoanf.Source(fakeKoanf{"app/host": "custom"}, envikoanf.WithMapping(
+		func(key string) string { return "app/" + strings.ToLower(key) },
+		func(path string) string { return strings.ToUpper(strings.TrimPrefix(path, "app/")) },
+	))
+
+	if v := envi.Get[string]("HOST", envi.WithSource(s)); v != "custom" {
+		t.Fatalf("Get() = %q, want %q", v, "custom")
+	}
+}
@@ -0,0 +1,142 @@
+// Package envissm provides an envi.Source that resolves variables from the
+// AWS Systems Manager Parameter Store.
+//
+// Parameters are expected below a path prefix, e.g. the variable DB_PASSWORD
+// is resolved from the parameter "/myapp/prod/DB_PASSWORD" for the path
+// "/myapp/prod/". SecureString parameters are decrypted. To layer the Parameter
+// Store under the real environment, configure it after envi.OS():
+//
+//	cfg, err := config.LoadDefaultConfig(ctx)
+//	client := ssm.NewFromConfig(cfg)
+//
+//	err = envi.ParseContext(ctx, &env, envi.WithSource(
+//		envi.OS(),
+//		envissm.New(client, "/myapp/prod/"),
+//	))
+package envissm
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"github.com/bounoable/envi"
+)
+
+// Client is the subset of the *ssm.Client API that is used by Source.
+type Client interface {
+	GetParameter(context.Context, *ssm.GetParameterInput, ...func(*ssm.Options)) (*ssm.GetParameterOutput, error)
+	GetParametersByPath(context.Context, *ssm.GetParametersByPathInput, ...func(*ssm.Options)) (*ssm.GetParametersByPathOutput, error)
+}
+
+// Source is an envi.Source that resolves variables from the Parameter Store.
+type Source struct {
+	client  Client
+	path    string
+	decrypt bool
+	toName  func(string) string
+	toKey   func(string) string
+}
+
+var (
+	_ envi.Source = (*Source)(nil)
+	_ envi.Lister = (*Source)(nil)
+)
+
+// Option is an option for a Source.
+type Option func(*Source)
+
+// WithDecryption sets whether SecureString parameters are decrypted. Defaults
+// to true.
+func WithDecryption(decrypt bool) Option {
+	return func(s *Source) {
+		s.decrypt = decrypt
+	}
+}
+
+// WithMapping configures how variable keys map to parameter names below the
+// path prefix. toName maps a variable key to a parameter name, and toKey maps
+// a parameter name back to a variable key. By default, keys and names are
+// identical.
+//
+//	// DB_PASSWORD <-> /myapp/prod/db/password
+//	envissm.WithMapping(
+//		func(key string) string { return strings.ToLower(strings.ReplaceAll(key, "_", "/")) },
+//		func(name string) string { return strings.ToUpper(strings.ReplaceAll(name, "/", "_")) },
+//	)
+func WithMapping(toName, toKey func(string) string) Option {
+	return func(s *Source) {
+		s.toName = toName
+		s.toKey = toKey
+	}
+}
+
+// New returns a Source that resolves variables from the parameters below the
+// given path prefix.
+func New(client Client, path string, opts ...Option) *Source {
+	s := &Source{
+		client:  client,
+		path:    path,
+		decrypt: true,
+		toName:  func(key string) string { return key },
+		toKey:   func(name string) string { return name },
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Lookup implements envi.Source. It returns false if the parameter does not
+// exist.
+func (s *Source) Lookup(ctx context.Context, key string) (string, bool, error) {
+	out, err := s.client.GetParameter(ctx, &ssm.GetParameterInput{
+		Name:           aws.String(s.path + s.toName(key)),
+		WithDecryption: aws.Bool(s.decrypt),
+	})
+	if err != nil {
+		var notFound *types.ParameterNotFound
+		if errors.As(err, &notFound) {
+			return "", false, nil
+		}
+		return "", false, err
+	}
+
+	if out.Parameter == nil || out.Parameter.Value == nil {
+		return "", false, nil
+	}
+
+	return *out.Parameter.Value, true, nil
+}
+
+// Keys implements envi.Lister. It returns the keys of all parameters below the
+// path prefix, including nested paths.
+func (s *Source) Keys(ctx context.Context) ([]string, error) {
+	var keys []string
+
+	input := &ssm.GetParametersByPathInput{
+		Path:      aws.String(s.path),
+		Recursive: aws.Bool(true),
+	}
+	for {
+		out, err := s.client.GetParametersByPath(ctx, input)
+		if err != nil {
+			return keys, err
+		}
+
+		for _, p := range out.Parameters {
+			if p.Name == nil {
+				continue
+			}
+			keys = append(keys, s.toKey(strings.TrimPrefix(*p.Name, s.path)))
+		}
+
+		if out.NextToken == nil || *out.NextToken == "" {
+			return keys, nil
+		}
+		input.NextToken = out.NextToken
+	}
+}
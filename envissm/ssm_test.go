@@ -0,0 +1,135 @@
+package envissm_test
+
+import (
+	"context"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"github.com/bounoable/envi"
+	"github.com/bounoable/envi/envissm"
+)
+
+type fakeClient struct {
+	params  map[string]string
+	decrypt []bool
+}
+
+func (c *fakeClient) GetParameter(_ context.Context, in *ssm.GetParameterInput, _ ...func(*ssm.Options)) (*ssm.GetParameterOutput, error) {
+	c.decrypt = append(c.decrypt, aws.ToBool(in.WithDecryption))
+	v, ok := c.params[aws.ToString(in.Name)]
+	if !ok {
+		return nil, &types.ParameterNotFound{}
+	}
+	return &ssm.GetParameterOutput{Parameter: &types.Parameter{Name: in.Name, Value: aws.String(v)}}, nil
+}
+
+func (c *fakeClient) GetParametersByPath(_ context.Context, in *ssm.GetParametersByPathInput, _ ...func(*ssm.Options)) (*ssm.GetParametersByPathOutput, error) {
+	var out ssm.GetParametersByPathOutput
+	for name, v := range c.params {
+		if strings.HasPrefix(name, aws.ToString(in.Path)) {
+			out.Parameters = append(out.Parameters, types.Parameter{Name: aws.String(name), Value: aws.String(v)})
+		}
+	}
+
+	sort.Slice(out.Parameters, func(i, j int) bool {
+		return aws.ToString(out.Parameters[i].Name) < aws.ToString(out.Parameters[j].Name)
+	})
+
+	// Return one parameter per page to exercise pagination.
+	if len(out.Parameters) == 0 {
+		return &out, nil
+	}
+	page := 0
+	if in.NextToken != nil {
+		for page < len(out.Parameters) && aws.ToString(out.Parameters[page].Name) != *in.NextToken {
+			page++
+		}
+	}
+	params := out.Parameters
+	out.Parameters = params[page : page+1]
+	if page+1 < len(params) {
+		out.NextToken = params[page+1].Name
+	}
+
+	return &out, nil
+}
+
+type env struct {
+	Host     string            `env:"HOST"`
+	Password string            `env:"DB_PASSWORD"`
+	Labels   map[string]string `env:"LABEL"`
+}
+
+// TestSource verifies that variables resolve from the parameters below the
+// path prefix, that the real environment takes precedence when layered first,
+// and that map fields are populated from all parameters.
+func TestSource(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("HOST", "localhost")
+
+	client := &fakeClient{params: map[string]string{
+		"/app/prod/HOST":        "example.com",
+		"/app/prod/DB_PASSWORD": "secret",
+		"/app/prod/LABEL_a":     "1",
+		"/app/prod/LABEL_b":     "2",
+		"/app/dev/DB_PASSWORD":  "dev",
+	}}
+
+	e, err := envi.New[env](envi.WithSource(envi.OS(), envissm.New(client, "/app/prod/")))
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	if e.Host != "localhost" {
+		t.Fatalf("Host = %q, want %q", e.Host, "localhost")
+	}
+	if e.Password != "secret" {
+		t.Fatalf("Password = %q, want %q", e.Password, "secret")
+	}
+	if len(e.Labels) != 2 || e.Labels["a"] != "1" || e.Labels["b"] != "2" {
+		t.Fatalf("Labels = %v, want map[a:1 b:2]", e.Labels)
+	}
+	for _, d := range client.decrypt {
+		if !d {
+			t.Fatalf("parameters should be decrypted by default")
+		}
+	}
+}
+
+// TestWithMapping verifies that custom mappings translate between variable
+// keys and parameter names.
+func TestWithMapping(t *testing.T) {
+	client := &fakeClient{params: map[string]string{
+		"/app/db/password": "secret",
+	}}
+
+	s := envissm.New(client, "/app/",
+		envissm.WithDecryption(false),
+		envissm.WithMapping(
+			func(key string) string { return strings.ToLower(strings.ReplaceAll(key, "_", "/")) },
+			func(name string) string { return strings.ToUpper(strings.ReplaceAll(name, "/", "_")) },
+		),
+	)
+
+	v, ok, err := s.Lookup(context.Background(), "DB_PASSWORD")
+	if err != nil || !ok || v != "secret" {
+		t.Fatalf("Lookup() = %q, %v, %v; want %q, true, <nil>", v, ok, err, "secret")
+	}
+	if client.decrypt[0] {
+		t.Fatalf("parameters should not be decrypted")
+	}
+
+	keys, err := s.Keys(context.Background())
+	if err != nil || len(keys) != 1 || keys[0] != "DB_PASSWORD" {
+		t.Fatalf("Keys() = %v, %v; want [DB_PASSWORD], <nil>", keys, err)
+	}
+
+	if _, ok, err := s.Lookup(context.Background(), "MISSING"); ok || err != nil {
+		t.Fatalf("Lookup() = _, %v, %v; want false, <nil>", ok, err)
+	}
+}
@@ -0,0 +1,146 @@
+// Package envitest provides helpers for tests of code that is configured by
+// envi. Set sets environment variables for the duration of a test, Source is
+// a fake Source that records lookups and can simulate errors, and
+// AssertRequired asserts that a set of variables satisfies all required
+// fields of a config:
+//
+//	func TestServer(t *testing.T) {
+//		envitest.Set(t, map[string]string{"PORT": "0"})
+//		// ...
+//	}
+//
+// Tests that use Set can't run in parallel, because the environment is shared
+// by the whole process. Parallel tests can pass a Source to envi.WithSource
+// instead.
+package envitest
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/bounoable/envi"
+)
+
+// Set sets the environment variables vars for the duration of the test t.
+// The previous values are restored when the test and its subtests finish.
+// Set panics if it is called from a parallel test.
+func Set(t testing.TB, vars map[string]string) {
+	t.Helper()
+
+	keys := make([]string, 0, len(vars))
+	for key := range vars {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		t.Setenv(key, vars[key])
+	}
+}
+
+// Source is a fake envi.Source that provides the variables of a map. It
+// records the keys that are looked up and returns the errors that were set
+// with Fail. Source is safe for concurrent use.
+type Source struct {
+	mux     sync.Mutex
+	vars    map[string]string
+	errs    map[string]error
+	lookups []string
+}
+
+var (
+	_ envi.Source = (*Source)(nil)
+	_ envi.Lister = (*Source)(nil)
+)
+
+// NewSource returns a Source that provides a copy of vars.
+func NewSource(vars map[string]string) *Source {
+	s := &Source{vars: make(map[string]string, len(vars)), errs: make(map[string]error)}
+	for k, v := range vars {
+		s.vars[k] = v
+	}
+	return s
+}
+
+// Set sets the variable with the given key.
+func (s *Source) Set(key, value string) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.vars[key] = value
+}
+
+// Unset removes the variable with the given key.
+func (s *Source) Unset(key string) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	delete(s.vars, key)
+}
+
+// Fail makes lookups of the variable with the given key fail with err, e.g.
+// to simulate an unavailable remote store. A nil err removes the failure.
+func (s *Source) Fail(key string, err error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if err == nil {
+		delete(s.errs, key)
+		return
+	}
+	s.errs[key] = err
+}
+
+// Lookup implements envi.Source.
+func (s *Source) Lookup(_ context.Context, key string) (string, bool, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	s.lookups = append(s.lookups, key)
+	if err, ok := s.errs[key]; ok {
+		return "", false, err
+	}
+	v, ok := s.vars[key]
+	return v, ok, nil
+}
+
+// Keys implements envi.Lister.
+func (s *Source) Keys(context.Context) ([]string, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	keys := make([]string, 0, len(s.vars))
+	for k := range s.vars {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// Lookups returns the keys that were looked up, in order and including
+// repeated lookups.
+func (s *Source) Lookups() []string {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return append([]string(nil), s.lookups...)
+}
+
+// AssertRequired reports an error to t for every required field of Config
+// whose variable is not covered by vars, including fields that are required
+// by the `required_if` and `required_unless` conditions that hold for vars.
+// opts are passed to envi.Check after the Source of vars, e.g. to select a
+// profile. AssertRequired reports whether all required fields are covered.
+func AssertRequired[Config any](t testing.TB, vars map[string]string, opts ...envi.Option) bool {
+	t.Helper()
+
+	opts = append([]envi.Option{envi.WithSource(envi.Map(vars))}, opts...)
+
+	ok := true
+	for _, p := range envi.Check[Config](opts...) {
+		if errors.Is(p.Err, envi.ErrRequired) {
+			t.Errorf("required variable %s of field %s is not covered", p.Key, p.Field)
+			ok = false
+		}
+	}
+	return ok
+}
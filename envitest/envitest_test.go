@@ -0,0 +1,113 @@
+package envitest_test
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/bounoable/envi"
+	"github.com/bounoable/envi/envitest"
+	"github.com/google/go-cmp/cmp"
+)
+
+type config struct {
+	Host  string `env:"ENVITEST_HOST" required:"true"`
+	Port  int    `env:"ENVITEST_PORT" default:"8080"`
+	TLS   bool   `env:"ENVITEST_TLS"`
+	Cert  string `env:"ENVITEST_CERT" required_if:"ENVITEST_TLS=true"`
+	Token string `env:"ENVITEST_TOKEN"`
+}
+
+// TestSet tests that Set sets variables for the duration of a test and
+// restores their previous values afterwards.
+func TestSet(t *testing.T) {
+	os.Setenv("ENVITEST_HOST", "before")
+	defer os.Unsetenv("ENVITEST_HOST")
+
+	t.Run("set", func(t *testing.T) {
+		envitest.Set(t, map[string]string{"ENVITEST_HOST": "example.com", "ENVITEST_PORT": "9090"})
+
+		cfg, err := envi.New[config]()
+		if err != nil {
+			t.Fatalf("New() failed: %v", err)
+		}
+		if cfg.Host != "example.com" || cfg.Port != 9090 {
+			t.Fatalf("cfg = %+v, want Host and Port from Set", cfg)
+		}
+	})
+
+	if v := os.Getenv("ENVITEST_HOST"); v != "before" {
+		t.Fatalf("ENVITEST_HOST = %q after the test, want %q", v, "before")
+	}
+	if _, ok := os.LookupEnv("ENVITEST_PORT"); ok {
+		t.Fatalf("ENVITEST_PORT should be unset after the test")
+	}
+}
+
+// TestSource tests that Source provides its variables, records lookups and
+// returns the errors set with Fail.
+func TestSource(t *testing.T) {
+	t.Parallel()
+
+	src := envitest.NewSource(map[string]string{"ENVITEST_HOST": "localhost"})
+	src.Set("ENVITEST_TOKEN", "secret")
+
+	cfg, err := envi.New[config](envi.WithSource(src))
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	if cfg.Host != "localhost" || cfg.Token != "secret" {
+		t.Fatalf("cfg = %+v, want Host and Token from the Source", cfg)
+	}
+
+	want := []string{"ENVITEST_HOST", "ENVITEST_PORT", "ENVITEST_TLS", "ENVITEST_CERT", "ENVITEST_TLS", "ENVITEST_TOKEN"}
+	if got := src.Lookups(); !cmp.Equal(want, got) {
+		t.Fatalf("Lookups() = %v, want = %v\n\n%s", got, want, cmp.Diff(want, got))
+	}
+
+	unavailable := errors.New("unavailable")
+	src.Fail("ENVITEST_TOKEN", unavailable)
+	if _, err := envi.New[config](envi.WithSource(src)); !errors.Is(err, unavailable) {
+		t.Fatalf("New() should fail with %v; got %v", unavailable, err)
+	}
+
+	src.Fail("ENVITEST_TOKEN", nil)
+	src.Unset("ENVITEST_HOST")
+	if _, err := envi.New[config](envi.WithSource(src)); !errors.Is(err, envi.ErrRequired) {
+		t.Fatalf("New() should fail with %v; got %v", envi.ErrRequired, err)
+	}
+}
+
+// recorder is a testing.TB that records the errors that are reported to it.
+type recorder struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recorder) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+// TestAssertRequired tests that AssertRequired reports the required variables
+// that are not covered, including conditionally required ones.
+func TestAssertRequired(t *testing.T) {
+	t.Parallel()
+
+	r := &recorder{TB: t}
+	if !envitest.AssertRequired[config](r, map[string]string{"ENVITEST_HOST": "localhost"}) || len(r.errors) > 0 {
+		t.Fatalf("AssertRequired() should pass; got %v", r.errors)
+	}
+
+	r = &recorder{TB: t}
+	if envitest.AssertRequired[config](r, map[string]string{"ENVITEST_TLS": "true"}) {
+		t.Fatalf("AssertRequired() should fail")
+	}
+	want := []string{
+		"required variable ENVITEST_HOST of field Host is not covered",
+		"required variable ENVITEST_CERT of field Cert is not covered",
+	}
+	if !cmp.Equal(want, r.errors) {
+		t.Fatalf("errors = %v, want = %v\n\n%s", r.errors, want, cmp.Diff(want, r.errors))
+	}
+}
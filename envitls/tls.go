@@ -0,0 +1,114 @@
+// Package envitls provides a Config for the TLS settings of a service that is
+// parsed from the environment and builds a *tls.Config:
+//
+//	type Env struct {
+//		Port int `env:"PORT"`
+//		TLS  envitls.Config
+//	}
+//
+//	env, err := envi.New[Env]()
+//	tlsConfig, err := env.TLS.TLSConfig()
+//
+// The variables of a Config are TLS_CERT_FILE, TLS_KEY_FILE, TLS_CA_FILE,
+// TLS_MIN_VERSION and TLS_CLIENT_AUTH.
+package envitls
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// Config holds the TLS settings of a server or client.
+type Config struct {
+	// CertFile and KeyFile are the paths of the PEM encoded certificate and
+	// private key. Both or neither must be set.
+	CertFile string `env:"TLS_CERT_FILE" path:"expand" validate:"file" desc:"Path of the PEM encoded TLS certificate."`
+	KeyFile  string `env:"TLS_KEY_FILE" path:"expand" validate:"file" desc:"Path of the PEM encoded TLS private key."`
+
+	// CAFile is the path of PEM encoded CA certificates, which verify the
+	// certificates of clients and servers.
+	CAFile string `env:"TLS_CA_FILE" path:"expand" validate:"file" desc:"Path of PEM encoded CA certificates."`
+
+	// MinVersion is the minimum TLS version: 1.0, 1.1, 1.2 or 1.3.
+	MinVersion string `env:"TLS_MIN_VERSION" default:"1.2" desc:"Minimum TLS version (1.0, 1.1, 1.2 or 1.3)."`
+
+	// ClientAuth is the policy for client certificates of servers: none,
+	// request, require, verify-if-given or require-and-verify.
+	ClientAuth string `env:"TLS_CLIENT_AUTH" default:"none" desc:"Client certificate policy (none, request, require, verify-if-given or require-and-verify)."`
+}
+
+var versions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+var clientAuths = map[string]tls.ClientAuthType{
+	"":                   tls.NoClientCert,
+	"none":               tls.NoClientCert,
+	"request":            tls.RequestClientCert,
+	"require":            tls.RequireAnyClientCert,
+	"verify-if-given":    tls.VerifyClientCertIfGiven,
+	"require-and-verify": tls.RequireAndVerifyClientCert,
+}
+
+// Enabled reports whether a certificate is configured.
+func (c Config) Enabled() bool {
+	return c.CertFile != "" || c.KeyFile != ""
+}
+
+// TLSConfig returns a *tls.Config with the certificate, CA certificates,
+// minimum version and client certificate policy of c. The CA certificates are
+// used both as root CAs and as client CAs.
+func (c Config) TLSConfig() (*tls.Config, error) {
+	version := uint16(tls.VersionTLS12)
+	if c.MinVersion != "" {
+		v, ok := versions[c.MinVersion]
+		if !ok {
+			return nil, fmt.Errorf("unknown TLS version %q", c.MinVersion)
+		}
+		version = v
+	}
+
+	clientAuth, ok := clientAuths[c.ClientAuth]
+	if !ok {
+		return nil, fmt.Errorf("unknown client auth policy %q", c.ClientAuth)
+	}
+
+	cfg := &tls.Config{
+		MinVersion: version,
+		ClientAuth: clientAuth,
+	}
+
+	if (c.CertFile == "") != (c.KeyFile == "") {
+		return nil, errors.New("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+	if c.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load key pair: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	if c.CAFile != "" {
+		pem, err := os.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("read CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", c.CAFile)
+		}
+		cfg.RootCAs = pool
+		cfg.ClientCAs = pool
+	} else if clientAuth >= tls.VerifyClientCertIfGiven {
+		return nil, fmt.Errorf("client auth policy %q requires TLS_CA_FILE", c.ClientAuth)
+	}
+
+	return cfg, nil
+}
@@ -0,0 +1,116 @@
+package envitls_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bounoable/envi"
+	"github.com/bounoable/envi/envitls"
+)
+
+// writeCert writes a self-signed certificate and its key to dir and returns
+// their paths.
+func writeCert(t *testing.T, dir string) (string, string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "localhost"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certFile := filepath.Join(dir, "tls.crt")
+	keyFile := filepath.Join(dir, "tls.key")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+// TestConfig_TLSConfig verifies that a Config parsed from the environment
+// builds a *tls.Config with its certificate, CA, version and client auth.
+func TestConfig_TLSConfig(t *testing.T) {
+	certFile, keyFile := writeCert(t, t.TempDir())
+
+	type env struct {
+		TLS envitls.Config
+	}
+	e, err := envi.New[env](envi.WithSource(envi.Map{
+		"TLS_CERT_FILE":   certFile,
+		"TLS_KEY_FILE":    keyFile,
+		"TLS_CA_FILE":     certFile,
+		"TLS_MIN_VERSION": "1.3",
+		"TLS_CLIENT_AUTH": "require-and-verify",
+	}))
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	if !e.TLS.Enabled() {
+		t.Fatalf("Enabled() should return true")
+	}
+
+	cfg, err := e.TLS.TLSConfig()
+	if err != nil {
+		t.Fatalf("TLSConfig() failed: %v", err)
+	}
+	if len(cfg.Certificates) != 1 || cfg.ClientCAs == nil || cfg.RootCAs == nil {
+		t.Fatalf("TLSConfig() should load the certificate and CA; got %+v", cfg)
+	}
+	if cfg.MinVersion != tls.VersionTLS13 || cfg.ClientAuth != tls.RequireAndVerifyClientCert {
+		t.Fatalf("MinVersion, ClientAuth = %v, %v; want TLS 1.3, RequireAndVerifyClientCert", cfg.MinVersion, cfg.ClientAuth)
+	}
+}
+
+// TestConfig_TLSConfig_invalid verifies that TLSConfig rejects incomplete or
+// unknown settings.
+func TestConfig_TLSConfig_invalid(t *testing.T) {
+	certFile, _ := writeCert(t, t.TempDir())
+
+	tests := []struct {
+		name    string
+		cfg     envitls.Config
+		wantErr string
+	}{
+		{name: "cert without key", cfg: envitls.Config{CertFile: certFile}, wantErr: "must be set together"},
+		{name: "unknown version", cfg: envitls.Config{MinVersion: "2.0"}, wantErr: `unknown TLS version "2.0"`},
+		{name: "unknown client auth", cfg: envitls.Config{ClientAuth: "always"}, wantErr: `unknown client auth policy "always"`},
+		{name: "verify without CA", cfg: envitls.Config{ClientAuth: "verify-if-given"}, wantErr: "requires TLS_CA_FILE"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.cfg.TLSConfig(); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("TLSConfig() should fail with %q; got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
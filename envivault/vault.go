@@ -0,0 +1,359 @@
+// Package envivault provides an envi.Source that resolves variables from a
+// HashiCorp Vault KV secret.
+//
+// Every key of the secret is a variable. Secrets are read using the Vault
+// HTTP API, authenticated either by a token or by an AppRole login:
+//
+//	vault := envivault.New("https://vault:8200", "secret", "myapp/prod",
+//		envivault.WithAppRole(roleID, secretID),
+//	)
+//
+//	err := envi.Parse(&env, envi.WithSource(envi.OS(), vault))
+//
+// The secret is read once and cached by the Source, so parsing a struct makes
+// a single request regardless of its number of fields. Use WithTTL or
+// Invalidate to pick up changes of the secret, e.g. for an envi.Watcher.
+package envivault
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bounoable/envi"
+)
+
+// Source is an envi.Source that resolves variables from a Vault KV secret.
+type Source struct {
+	addr      string
+	mount     string
+	path      string
+	version   int
+	namespace string
+	client    *http.Client
+
+	appRoleMount string
+	roleID       string
+	secretID     string
+
+	mux   sync.Mutex
+	token string
+
+	ttl     time.Duration
+	dataMux sync.Mutex
+	data    map[string]string
+	read    bool
+	expires time.Time
+}
+
+var (
+	_ envi.Source = (*Source)(nil)
+	_ envi.Lister = (*Source)(nil)
+)
+
+// Option is an option for a Source.
+type Option func(*Source)
+
+// WithToken authenticates requests using the given Vault token.
+func WithToken(token string) Option {
+	return func(s *Source) {
+		s.token = token
+	}
+}
+
+// WithAppRole authenticates using the AppRole auth method mounted at
+// "approle". The login is performed on first use, and repeated once if Vault
+// rejects the token.
+func WithAppRole(roleID, secretID string) Option {
+	return func(s *Source) {
+		s.roleID = roleID
+		s.secretID = secretID
+	}
+}
+
+// WithAppRoleMount sets the mount path of the AppRole auth method. Defaults to
+// "approle".
+func WithAppRoleMount(mount string) Option {
+	return func(s *Source) {
+		s.appRoleMount = mount
+	}
+}
+
+// WithKVVersion sets the version of the KV secrets engine (1 or 2). Defaults
+// to 2.
+func WithKVVersion(version int) Option {
+	return func(s *Source) {
+		s.version = version
+	}
+}
+
+// WithNamespace sets the Vault Enterprise namespace of the requests.
+func WithNamespace(namespace string) Option {
+	return func(s *Source) {
+		s.namespace = namespace
+	}
+}
+
+// WithTTL sets how long the secret is cached before it is read again. By
+// default, it is cached until Invalidate is called.
+func WithTTL(ttl time.Duration) Option {
+	return func(s *Source) {
+		s.ttl = ttl
+	}
+}
+
+// WithHTTPClient sets the HTTP client used to talk to Vault. Defaults to
+// http.DefaultClient.
+func WithHTTPClient(client *http.Client) Option {
+	return func(s *Source) {
+		s.client = client
+	}
+}
+
+// New returns a Source that reads the secret at path from the KV secrets
+// engine mounted at mount of the Vault server at addr.
+func New(addr, mount, path string, opts ...Option) *Source {
+	s := &Source{
+		addr:         strings.TrimSuffix(addr, "/"),
+		mount:        strings.Trim(mount, "/"),
+		path:         strings.Trim(path, "/"),
+		version:      2,
+		client:       http.DefaultClient,
+		appRoleMount: "approle",
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Lookup implements envi.Source. It returns false if the secret or the key
+// does not exist.
+func (s *Source) Lookup(ctx context.Context, key string) (string, bool, error) {
+	data, err := s.secret(ctx)
+	if err != nil {
+		return "", false, err
+	}
+	v, ok := data[key]
+	return v, ok, nil
+}
+
+// Keys implements envi.Lister. It returns the keys of the secret.
+func (s *Source) Keys(ctx context.Context) ([]string, error) {
+	data, err := s.secret(ctx)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	return keys, nil
+}
+
+// Invalidate removes the cached secret, e.g. after it was rotated, so that it
+// is read again by the next lookup.
+func (s *Source) Invalidate() {
+	s.dataMux.Lock()
+	defer s.dataMux.Unlock()
+	s.data, s.read = nil, false
+}
+
+// secret returns the cached data of the secret, reading it if it is not
+// cached or expired. Concurrent lookups wait for a single read.
+func (s *Source) secret(ctx context.Context) (map[string]string, error) {
+	s.dataMux.Lock()
+	defer s.dataMux.Unlock()
+
+	if s.read && (s.expires.IsZero() || time.Now().Before(s.expires)) {
+		return s.data, nil
+	}
+
+	data, err := s.fetch(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.data, s.read = data, true
+	s.expires = time.Time{}
+	if s.ttl > 0 {
+		s.expires = time.Now().Add(s.ttl)
+	}
+
+	return data, nil
+}
+
+// fetch reads the secret and returns its data with all values formatted as
+// strings.
+func (s *Source) fetch(ctx context.Context) (map[string]string, error) {
+	url := fmt.Sprintf("%s/v1/%s/%s", s.addr, s.mount, s.path)
+	if s.version == 2 {
+		url = fmt.Sprintf("%s/v1/%s/data/%s", s.addr, s.mount, s.path)
+	}
+
+	var body struct {
+		Data json.RawMessage `json:"data"`
+	}
+	found, err := s.do(ctx, http.MethodGet, url, nil, &body, true)
+	if err != nil {
+		return nil, fmt.Errorf("read secret %q: %w", s.mount+"/"+s.path, err)
+	}
+	if !found {
+		return nil, nil
+	}
+
+	raw := body.Data
+	if s.version == 2 {
+		var kv2 struct {
+			Data json.RawMessage `json:"data"`
+		}
+		if err := json.Unmarshal(raw, &kv2); err != nil {
+			return nil, fmt.Errorf("decode secret %q: %w", s.mount+"/"+s.path, err)
+		}
+		raw = kv2.Data
+	}
+
+	var values map[string]any
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	if err := dec.Decode(&values); err != nil {
+		return nil, fmt.Errorf("decode secret %q: %w", s.mount+"/"+s.path, err)
+	}
+
+	data := make(map[string]string, len(values))
+	for k, v := range values {
+		switch v := v.(type) {
+		case string:
+			data[k] = v
+		case json.Number:
+			data[k] = v.String()
+		default:
+			b, err := json.Marshal(v)
+			if err != nil {
+				return nil, fmt.Errorf("encode value of %q: %w", k, err)
+			}
+			data[k] = string(b)
+		}
+	}
+
+	return data, nil
+}
+
+// do performs an authenticated request and decodes the JSON response into
+// out. It reports false if Vault responded with 404 Not Found.
+func (s *Source) do(ctx context.Context, method, url string, in, out any, retry bool) (bool, error) {
+	token, err := s.authenticate(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	var reqBody io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return false, err
+		}
+		reqBody = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, reqBody)
+	if err != nil {
+		return false, err
+	}
+	if token != "" {
+		req.Header.Set("X-Vault-Token", token)
+	}
+	if s.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", s.namespace)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return false, nil
+	case resp.StatusCode == http.StatusForbidden && retry && s.roleID != "":
+		s.mux.Lock()
+		s.token = ""
+		s.mux.Unlock()
+		return s.do(ctx, method, url, in, out, false)
+	case resp.StatusCode >= 300:
+		return false, responseError(resp)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return false, fmt.Errorf("decode response: %w", err)
+	}
+
+	return true, nil
+}
+
+// authenticate returns the token to use for requests, logging in using the
+// AppRole auth method if necessary.
+func (s *Source) authenticate(ctx context.Context) (string, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	if s.token != "" || s.roleID == "" {
+		return s.token, nil
+	}
+
+	b, err := json.Marshal(map[string]string{
+		"role_id":   s.roleID,
+		"secret_id": s.secretID,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	url := fmt.Sprintf("%s/v1/auth/%s/login", s.addr, strings.Trim(s.appRoleMount, "/"))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(b))
+	if err != nil {
+		return "", err
+	}
+	if s.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", s.namespace)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("approle login: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return "", fmt.Errorf("approle login: %w", responseError(resp))
+	}
+
+	var body struct {
+		Auth struct {
+			ClientToken string `json:"client_token"`
+		} `json:"auth"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("approle login: decode response: %w", err)
+	}
+	s.token = body.Auth.ClientToken
+
+	return s.token, nil
+}
+
+func responseError(resp *http.Response) error {
+	var body struct {
+		Errors []string `json:"errors"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil || len(body.Errors) == 0 {
+		return fmt.Errorf("vault responded with %s", resp.Status)
+	}
+	return fmt.Errorf("vault responded with %s: %s", resp.Status, strings.Join(body.Errors, "; "))
+}
@@ -0,0 +1,183 @@
+package envivault_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bounoable/envi"
+	"github.com/bounoable/envi/envivault"
+	"github.com/google/go-cmp/cmp"
+)
+
+type env struct {
+	Host     string            `env:"HOST"`
+	Password string            `env:"DB_PASSWORD"`
+	Port     int               `env:"DB_PORT"`
+	Labels   map[string]string `env:"LABEL"`
+}
+
+func newServer(t *testing.T, logins *int32) *httptest.Server {
+	var reads int32
+	return newCountingServer(t, logins, &reads)
+}
+
+func newCountingServer(t *testing.T, logins, reads *int32) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/auth/approle/login", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(logins, 1)
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+		if body["role_id"] != "role" || body["secret_id"] != "secret" {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]any{"errors": []string{"invalid role"}})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"auth": map[string]any{"client_token": "approle-token"}})
+	})
+	mux.HandleFunc("/v1/secret/data/app", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(reads, 1)
+		if tok := r.Header.Get("X-Vault-Token"); tok != "token" && tok != "approle-token" {
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(map[string]any{"errors": []string{"permission denied"}})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{
+			"data": map[string]any{
+				"DB_PASSWORD": "s3cr3t",
+				"DB_PORT":     5432,
+				"LABEL_a":     "1",
+			},
+			"metadata": map[string]any{"version": 1},
+		}})
+	})
+	mux.HandleFunc("/v1/kv/app", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{"DB_PASSWORD": "v1"}})
+	})
+
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+// TestSource verifies that variables resolve from a KV v2 secret using token
+// and AppRole authentication, layered under the real environment.
+func TestSource(t *testing.T) {
+	var logins int32
+	srv := newServer(t, &logins)
+
+	os.Clearenv()
+	os.Setenv("HOST", "localhost")
+
+	want := env{
+		Host:     "localhost",
+		Password: "s3cr3t",
+		Port:     5432,
+		Labels:   map[string]string{"a": "1"},
+	}
+
+	for _, opt := range []envivault.Option{
+		envivault.WithToken("token"),
+		envivault.WithAppRole("role", "secret"),
+	} {
+		vault := envivault.New(srv.URL, "secret", "app", opt)
+
+		e, err := envi.New[env](envi.WithSource(envi.OS(), vault))
+		if err != nil {
+			t.Fatalf("New() failed: %v", err)
+		}
+
+		if !cmp.Equal(want, e) {
+			t.Fatalf("env = %v, want = %v\n\n%s", e, want, cmp.Diff(want, e))
+		}
+	}
+
+	if logins != 1 {
+		t.Fatalf("AppRole login should be performed once; got %d", logins)
+	}
+}
+
+// TestSource_cache verifies that the secret is read once for all variables
+// until it is invalidated.
+func TestSource_cache(t *testing.T) {
+	var logins, reads int32
+	srv := newCountingServer(t, &logins, &reads)
+
+	os.Clearenv()
+
+	vault := envivault.New(srv.URL, "secret", "app", envivault.WithToken("token"))
+	for i := 0; i < 2; i++ {
+		if _, err := envi.New[env](envi.WithSource(vault)); err != nil {
+			t.Fatalf("New() failed: %v", err)
+		}
+	}
+	if reads != 1 {
+		t.Fatalf("secret should be read once; got %d reads", reads)
+	}
+
+	vault.Invalidate()
+	if _, err := envi.New[env](envi.WithSource(vault)); err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	if reads != 2 {
+		t.Fatalf("secret should be read again after Invalidate(); got %d reads", reads)
+	}
+
+	vault = envivault.New(srv.URL, "secret", "app", envivault.WithToken("token"), envivault.WithTTL(time.Nanosecond))
+	for i := 0; i < 2; i++ {
+		if _, err := envi.New[env](envi.WithSource(vault)); err != nil {
+			t.Fatalf("New() failed: %v", err)
+		}
+	}
+	if reads < 4 {
+		t.Fatalf("expired secret should be read again; got %d reads", reads)
+	}
+}
+
+// TestSource_errors verifies that authentication failures are returned and
+// that a missing secret resolves no variables.
+func TestSource_errors(t *testing.T) {
+	var logins int32
+	srv := newServer(t, &logins)
+
+	os.Clearenv()
+
+	if _, err := envi.New[env](envi.WithSource(envivault.New(srv.URL, "secret", "app", envivault.WithToken("invalid")))); err == nil {
+		t.Fatalf("New() should fail with an invalid token")
+	}
+
+	if _, err := envi.New[env](envi.WithSource(envivault.New(srv.URL, "secret", "app", envivault.WithAppRole("role", "invalid")))); err == nil {
+		t.Fatalf("New() should fail with invalid AppRole credentials")
+	}
+
+	e, err := envi.New[env](envi.WithSource(envivault.New(srv.URL, "secret", "missing", envivault.WithToken("token"))))
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	if !cmp.Equal(env{}, e) {
+		t.Fatalf("env = %v, want zero value", e)
+	}
+}
+
+// TestWithKVVersion verifies that secrets are read from a KV v1 engine.
+func TestWithKVVersion(t *testing.T) {
+	var logins int32
+	srv := newServer(t, &logins)
+
+	os.Clearenv()
+
+	e, err := envi.New[env](envi.WithSource(envivault.New(srv.URL, "kv", "app", envivault.WithKVVersion(1))))
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	if e.Password != "v1" {
+		t.Fatalf("Password = %q, want %q", e.Password, "v1")
+	}
+}
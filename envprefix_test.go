@@ -0,0 +1,67 @@
+package envi_test
+
+import (
+	"testing"
+
+	"github.com/bounoable/envi"
+	"github.com/google/go-cmp/cmp"
+)
+
+type prefixHTTP struct {
+	Addr    string            `env:"ADDR" default:":8080"`
+	TLS     bool              `env:"TLS"`
+	Cert    string            `env:"CERT" required_if:"TLS=true"`
+	Headers map[string]string `env:"HEADER"`
+}
+
+type prefixEnv struct {
+	Public prefixHTTP  `envPrefix:"PUBLIC_"`
+	Admin  *prefixHTTP `envPrefix:"ADMIN_"`
+	App    struct {
+		Name string     `env:"NAME"`
+		HTTP prefixHTTP `envPrefix:"HTTP_"`
+	} `envPrefix:"APP_"`
+}
+
+// TestParse_envPrefix tests that an `envPrefix` tag on a struct field
+// prefixes the keys of its fields, so the same struct type can be nested
+// multiple times, and that prefixes of nested structs accumulate.
+func TestParse_envPrefix(t *testing.T) {
+	e, err := envi.New[prefixEnv](envi.WithSource(envi.Map{
+		"PUBLIC_ADDR":          ":80",
+		"PUBLIC_HEADER_Server": "envi",
+		"ADMIN_TLS":            "true",
+		"ADMIN_CERT":           "admin.pem",
+		"APP_NAME":             "app",
+		"APP_HTTP_ADDR":        ":9090",
+	}))
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	var want prefixEnv
+	want.Public = prefixHTTP{Addr: ":80", Headers: map[string]string{"Server": "envi"}}
+	want.Admin = &prefixHTTP{Addr: ":8080", TLS: true, Cert: "admin.pem"}
+	want.App.Name = "app"
+	want.App.HTTP = prefixHTTP{Addr: ":9090"}
+	if !cmp.Equal(want, e) {
+		t.Fatalf("env = %v, want = %v\n\n%s", e, want, cmp.Diff(want, e))
+	}
+
+	if _, err := envi.New[prefixEnv](envi.WithSource(envi.Map{"PUBLIC_TLS": "true"})); err == nil {
+		t.Fatalf("New() should fail if PUBLIC_CERT is not set")
+	}
+
+	var keys []string
+	for _, v := range envi.Variables[prefixEnv]() {
+		keys = append(keys, v.Key)
+	}
+	wantKeys := []string{
+		"PUBLIC_ADDR", "PUBLIC_TLS", "PUBLIC_CERT", "PUBLIC_HEADER",
+		"ADMIN_ADDR", "ADMIN_TLS", "ADMIN_CERT", "ADMIN_HEADER",
+		"APP_NAME", "APP_HTTP_ADDR", "APP_HTTP_TLS", "APP_HTTP_CERT", "APP_HTTP_HEADER",
+	}
+	if !cmp.Equal(wantKeys, keys) {
+		t.Fatalf("Variables() = %v, want = %v", keys, wantKeys)
+	}
+}
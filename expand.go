@@ -0,0 +1,54 @@
+package envi
+
+import (
+	"os"
+	"strconv"
+)
+
+// WithExpand enables the expansion of ${VAR} and $VAR references in values,
+// including defaults. References are resolved from the configured Sources,
+// and unset variables expand to an empty string. Fields with an
+// `expand:"false"` tag, e.g. regular expressions or passwords that contain a
+// '$', keep their raw value.
+func WithExpand() Option {
+	return func(p *parser) {
+		p.expand = true
+	}
+}
+
+// expandTag reports whether the `expand` tag of a field allows expansion.
+// Fields without the tag are expanded.
+func expandTag(v string, ok bool) bool {
+	if !ok {
+		return true
+	}
+	b, err := strconv.ParseBool(v)
+	return err != nil || b
+}
+
+// expandValue expands the variable references in s if expansion is enabled.
+func (p *parser) expandValue(s string, expand bool) (string, error) {
+	if !p.expand || !expand {
+		return s, nil
+	}
+	return p.expandVars(s)
+}
+
+// expandVars replaces the variable references in s with the values of the
+// variables.
+func (p *parser) expandVars(s string) (string, error) {
+	var err error
+	out := os.Expand(s, func(key string) string {
+		if err != nil {
+			return ""
+		}
+		var v string
+		v, err = p.getenv(key)
+		return v
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return out, nil
+}
@@ -0,0 +1,52 @@
+package envi_test
+
+import (
+	"testing"
+
+	"github.com/bounoable/envi"
+	"github.com/google/go-cmp/cmp"
+)
+
+// TestWithExpand verifies that variable references in values and defaults are
+// expanded with WithExpand, except for fields with an `expand:"false"` tag.
+func TestWithExpand(t *testing.T) {
+	type expandEnv struct {
+		Host     string            `env:"EXPAND_HOST"`
+		URL      string            `env:"EXPAND_URL"`
+		CacheDir string            `env:"EXPAND_CACHE_DIR" default:"${EXPAND_HOME}/.cache"`
+		Password string            `env:"EXPAND_PASSWORD" expand:"false"`
+		Labels   map[string]string `env:"EXPAND_LABEL"`
+	}
+
+	src := envi.Map{
+		"EXPAND_HOME":       "/home/bob",
+		"EXPAND_HOST":       "db",
+		"EXPAND_URL":        "postgres://${EXPAND_HOST}:5432/$EXPAND_MISSING",
+		"EXPAND_PASSWORD":   "pa$word",
+		"EXPAND_LABEL_HOST": "$EXPAND_HOST",
+	}
+
+	e, err := envi.New[expandEnv](envi.WithSource(src), envi.WithExpand())
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	want := expandEnv{
+		Host:     "db",
+		URL:      "postgres://db:5432/",
+		CacheDir: "/home/bob/.cache",
+		Password: "pa$word",
+		Labels:   map[string]string{"HOST": "db"},
+	}
+	if !cmp.Equal(want, e) {
+		t.Fatalf("env = %v, want = %v\n\n%s", e, want, cmp.Diff(want, e))
+	}
+
+	e, err = envi.New[expandEnv](envi.WithSource(src))
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	if want := "postgres://${EXPAND_HOST}:5432/$EXPAND_MISSING"; e.URL != want {
+		t.Fatalf("URL = %q, want %q", e.URL, want)
+	}
+}
@@ -0,0 +1,137 @@
+package envi
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// WithFields restricts parsing to the fields with the given paths and the
+// fields nested in them, e.g. WithFields("DB", "HTTP.Port"). Paths consist of
+// the names of the fields, separated by dots. Other fields keep their current
+// values and are neither looked up nor checked, so expensive or secret parts
+// of a config can be parsed later or by a different component. Parse fails
+// if a path does not name a field.
+func WithFields(paths ...string) Option {
+	return func(p *parser) {
+		p.fields = append(p.fields, paths...)
+	}
+}
+
+// BaseGroup is the group of the fields without a `group` tag; see WithGroups.
+const BaseGroup = "base"
+
+// WithGroups restricts parsing to the fields in the given groups, so a config
+// can be parsed in stages, e.g. the base config to set up logging first and
+// secrets later, once remote sources are available:
+//
+//	type Config struct {
+//		LogLevel string `env:"LOG_LEVEL"`
+//		APIKey   string `env:"API_KEY" group:"secrets"`
+//	}
+//
+//	err := envi.Parse(&cfg, envi.WithGroups(envi.BaseGroup))
+//	// ...
+//	err = envi.Parse(&cfg, envi.WithGroups("secrets"), envi.WithSource(vault))
+//
+// The `group` tag names one or more comma-separated groups of a field. The
+// fields of a nested struct inherit the groups of the struct field, and
+// fields without a `group` tag are in BaseGroup. Fields that are not parsed
+// keep their current values, as with WithFields.
+func WithGroups(groups ...string) Option {
+	return func(p *parser) {
+		p.groups = append(p.groups, groups...)
+	}
+}
+
+// partial reports whether fields that are not parsed keep their current
+// values, because the parser merges or only parses some of the fields.
+func (p *parser) partial() bool {
+	return p.merge || p.fields != nil || p.groups != nil
+}
+
+// skips reports whether field is not parsed because it is not in the groups of
+// WithGroups, or neither selected by WithFields nor contains selected fields.
+func (p *parser) skips(field *fieldSchema) bool {
+	if p.groups != nil && !field.isStruct && !p.inGroups(field) {
+		return true
+	}
+	if p.fields == nil || field.squash {
+		return false
+	}
+
+	path := field.name
+	if len(p.path) > 0 {
+		path = strings.Join(p.path, ".") + "." + path
+	}
+	for _, f := range p.fields {
+		if f == path || strings.HasPrefix(path, f+".") || (field.isStruct && strings.HasPrefix(f, path+".")) {
+			return false
+		}
+	}
+	return true
+}
+
+// checkFields returns an error if a path of WithFields does not name a field
+// of the struct type t.
+func (p *parser) checkFields(t reflect.Type) error {
+	for _, path := range p.fields {
+		if !hasField(t, strings.Split(path, ".")) {
+			return fmt.Errorf("WithFields: unknown field %q", path)
+		}
+	}
+	return nil
+}
+
+func hasField(t reflect.Type, names []string) bool {
+	schema := schemaOf(t)
+	for n := range schema.fields {
+		field := &schema.fields[n]
+		if field.recursive {
+			continue
+		}
+		if field.squash && field.exported && hasField(structType(field.typ), names) {
+			return true
+		}
+		if field.name != names[0] || !field.exported {
+			continue
+		}
+		if len(names) == 1 {
+			return true
+		}
+		if !field.isStruct {
+			return false
+		}
+		ft := field.typ
+		if field.isPointer {
+			ft = ft.Elem()
+		}
+		return hasField(ft, names[1:])
+	}
+	return false
+}
+
+// inGroups reports whether field is in one of the groups of WithGroups.
+func (p *parser) inGroups(field *fieldSchema) bool {
+	groups := p.fieldGroups(field)
+	for _, g := range p.groups {
+		for _, fg := range groups {
+			if g == fg {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// fieldGroups returns the groups of field, which are inherited from the
+// enclosing struct fields if the field has no `group` tag.
+func (p *parser) fieldGroups(field *fieldSchema) []string {
+	if field.groups != nil {
+		return field.groups
+	}
+	if p.group != nil {
+		return p.group
+	}
+	return []string{BaseGroup}
+}
@@ -0,0 +1,123 @@
+package envi_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bounoable/envi"
+	"github.com/google/go-cmp/cmp"
+)
+
+type fieldsEnv struct {
+	Name string `env:"FIELDS_NAME" default:"app"`
+	DB   struct {
+		URL      string `env:"FIELDS_DB_URL"`
+		Password string `env:"FIELDS_DB_PASSWORD" required:"true"`
+	}
+	HTTP *struct {
+		Host string `env:"FIELDS_HTTP_HOST" default:"localhost"`
+		Port int    `env:"FIELDS_HTTP_PORT"`
+	}
+	Token string `env:"FIELDS_TOKEN" required:"true"`
+}
+
+// TestWithFields verifies that only the selected fields are parsed and that
+// the other fields keep their values.
+func TestWithFields(t *testing.T) {
+	src := envi.Map{
+		"FIELDS_NAME":      "svc",
+		"FIELDS_DB_URL":    "postgres://db",
+		"FIELDS_HTTP_HOST": "example.com",
+		"FIELDS_HTTP_PORT": "8080",
+	}
+
+	e := fieldsEnv{Name: "existing"}
+	e.DB.Password = "secret"
+	if err := envi.Parse(&e, envi.WithSource(src), envi.WithFields("DB.URL", "HTTP.Port")); err != nil {
+		t.Fatalf("Parse() failed: %v", err)
+	}
+
+	want := fieldsEnv{Name: "existing"}
+	want.DB.URL = "postgres://db"
+	want.DB.Password = "secret"
+	want.HTTP = &struct {
+		Host string `env:"FIELDS_HTTP_HOST" default:"localhost"`
+		Port int    `env:"FIELDS_HTTP_PORT"`
+	}{Port: 8080}
+	if !cmp.Equal(want, e) {
+		t.Fatalf("env = %v, want = %v\n\n%s", e, want, cmp.Diff(want, e))
+	}
+
+	if err := envi.Parse(&e, envi.WithSource(src), envi.WithFields("Token")); err == nil {
+		t.Fatalf("Parse() should fail for the required Token field")
+	}
+	if err := envi.Parse(&e, envi.WithSource(src), envi.WithFields("DB.Host")); err == nil {
+		t.Fatalf("Parse() should fail for an unknown field")
+	}
+}
+
+type fieldsNode struct {
+	Next *fieldsNode `env:",squash"`
+	Name string      `env:"FIELDS_NODE_NAME"`
+}
+
+// TestWithFields_recursive verifies that the paths of WithFields are checked
+// without descending into recursive fields.
+func TestWithFields_recursive(t *testing.T) {
+	_, err := envi.New[fieldsNode](envi.WithSource(envi.Map{}), envi.WithFields("Port"))
+	if err == nil || !strings.Contains(err.Error(), `unknown field "Port"`) {
+		t.Fatalf("New() should fail for an unknown field; got %v", err)
+	}
+}
+
+// TestWithGroups verifies that only the fields of the selected groups are
+// parsed, and that nested fields inherit the groups of their struct field.
+func TestWithGroups(t *testing.T) {
+	type groupsEnv struct {
+		LogLevel string `env:"GROUPS_LOG_LEVEL" default:"info"`
+		APIKey   string `env:"GROUPS_API_KEY" group:"secrets" required:"true"`
+		DB       struct {
+			URL      string `env:"GROUPS_DB_URL"`
+			Password string `env:"GROUPS_DB_PASSWORD" group:"secrets"`
+		}
+		Vault struct {
+			Token string `env:"GROUPS_VAULT_TOKEN"`
+			Addr  string `env:"GROUPS_VAULT_ADDR" group:"base"`
+		} `group:"secrets"`
+		Both string `env:"GROUPS_BOTH" group:"base, secrets"`
+	}
+
+	src := envi.Map{
+		"GROUPS_LOG_LEVEL":   "debug",
+		"GROUPS_DB_URL":      "postgres://db",
+		"GROUPS_VAULT_ADDR":  "https://vault",
+		"GROUPS_BOTH":        "both",
+		"GROUPS_API_KEY":     "key",
+		"GROUPS_DB_PASSWORD": "password",
+		"GROUPS_VAULT_TOKEN": "token",
+	}
+
+	var e groupsEnv
+	if err := envi.Parse(&e, envi.WithSource(src), envi.WithGroups(envi.BaseGroup)); err != nil {
+		t.Fatalf("Parse() failed: %v", err)
+	}
+
+	want := groupsEnv{LogLevel: "debug", Both: "both"}
+	want.DB.URL = "postgres://db"
+	want.Vault.Addr = "https://vault"
+	if !cmp.Equal(want, e) {
+		t.Fatalf("env = %v, want = %v\n\n%s", e, want, cmp.Diff(want, e))
+	}
+
+	e.Both = ""
+	if err := envi.Parse(&e, envi.WithSource(src), envi.WithGroups("secrets")); err != nil {
+		t.Fatalf("Parse() failed: %v", err)
+	}
+
+	want.APIKey = "key"
+	want.DB.Password = "password"
+	want.Vault.Token = "token"
+	if !cmp.Equal(want, e) {
+		t.Fatalf("env = %v, want = %v\n\n%s", e, want, cmp.Diff(want, e))
+	}
+}
@@ -0,0 +1,272 @@
+package envi
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// BindFlags parses the environment into env and registers the Flags of env
+// in fs. Flags write directly into env when fs is parsed, so values are
+// resolved with the precedence flags > environment > defaults. Flag values
+// are decrypted, transformed and validated like variables. Since flags
+// may set required fields, they are not checked by BindFlags; call
+// CheckRequired after fs is parsed:
+//
+//	var cfg Config
+//	if err := envi.BindFlags(flag.CommandLine, &cfg); err != nil {
+//		log.Fatal(err)
+//	}
+//	flag.Parse()
+//	if err := envi.CheckRequired(&cfg); err != nil {
+//		log.Fatal(err)
+//	}
+func BindFlags[Env any](fs *flag.FlagSet, env *Env, opts ...Option) error {
+	opts = append(opts[:len(opts):len(opts)], WithDeferredRequired())
+	if err := Parse(env, opts...); err != nil {
+		return err
+	}
+
+	for _, f := range Flags(env, opts...) {
+		if fs.Lookup(f.Name) != nil {
+			return fmt.Errorf("bind %q field: flag -%s already defined", f.Field, f.Name)
+		}
+		fs.Var(f.Value, f.Name, f.Usage)
+	}
+
+	return nil
+}
+
+// Flag describes a command-line flag for a field with an `env` tag.
+type Flag struct {
+	// Name is the flag name, derived from the variable key by FlagName.
+	Name string
+
+	// Shorthand is the one-letter abbreviation from the `short` tag.
+	Shorthand string
+
+	// Key is the key of the environment variable.
+	Key string
+
+	// Field is the dot-separated path of the field, e.g. "DB.Host".
+	Field string
+
+	// Usage is the text of the `desc` tag, or a generic text if the field
+	// has no description.
+	Usage string
+
+	// Value sets the field when the flag is parsed.
+	Value FlagValue
+}
+
+// FlagValue is a flag.Value that is also compatible with pflag.Value.
+type FlagValue interface {
+	flag.Value
+
+	// Type returns the name of the value's type.
+	Type() string
+}
+
+// Flags returns a Flag for every field of env with an `env` tag, including the
+// fields of nested structs. The values of the flags write directly into env.
+// Map, Lazy and Value fields are not represented by flags. Flags does not parse the
+// environment; use BindFlags for flag.FlagSets.
+func Flags[Env any](env *Env, opts ...Option) []Flag {
+	p := newParser(context.Background(), opts)
+	root := reflect.ValueOf(env).Elem()
+
+	var flags []Flag
+	collectFlags(&flags, p, root, schemaOf(root.Type()), nil, "")
+
+	return flags
+}
+
+// FlagName returns the name of the flag for the variable with the given key.
+// Keys are lowercased and underscores are replaced with dashes, e.g. DB_HOST
+// becomes db-host.
+func FlagName(key string) string {
+	return strings.ToLower(strings.ReplaceAll(key, "_", "-"))
+}
+
+func collectFlags(flags *[]Flag, p *parser, root reflect.Value, schema *structSchema, path []int, prefix string) {
+	for n := range schema.fields {
+		field := &schema.fields[n]
+		fieldPath := append(append([]int(nil), path...), n)
+
+		if field.recursive || !field.settable() {
+			continue
+		}
+
+		if field.isStruct {
+			collectFlags(flags, p, root, field.nested(), fieldPath, field.nestedPath(prefix))
+			continue
+		}
+
+		if !field.hasKey || isPrefixMap(field.typ) || field.lazy || field.wrapper {
+			continue
+		}
+
+		usage := field.tag.Get("desc")
+		if usage == "" {
+			usage = fmt.Sprintf("Overrides the %s environment variable.", field.key)
+		}
+
+		*flags = append(*flags, Flag{
+			Name:      FlagName(field.key),
+			Shorthand: field.tag.Get("short"),
+			Key:       field.key,
+			Field:     prefix + field.name,
+			Usage:     usage,
+			Value: &fieldFlag{
+				parser: p,
+				root:   root,
+				path:   fieldPath,
+				field:  field,
+			},
+		})
+	}
+}
+
+// fieldFlag is a flag.Value that sets a (possibly nested) field of a struct.
+type fieldFlag struct {
+	parser *parser
+	root   reflect.Value
+	path   []int
+	field  *fieldSchema
+}
+
+// String formats the value of the field, which flag packages show as the
+// default of the flag. It is empty for fields with a `secret` tag, so usage
+// output doesn't reveal their values.
+func (f *fieldFlag) String() string {
+	if f == nil || !f.root.IsValid() || f.field.secret {
+		return ""
+	}
+
+	v := f.root
+	for _, i := range f.path {
+		if v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				return ""
+			}
+			v = v.Elem()
+		}
+		v = v.Field(i)
+	}
+
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return ""
+		}
+		if loc, ok := v.Interface().(*time.Location); ok {
+			return loc.String()
+		}
+		v = v.Elem()
+	}
+
+	if name, ok := enumName(v); ok {
+		return name
+	}
+
+	switch {
+	case v.Type() == urlType:
+		u := v.Interface().(url.URL)
+		return u.String()
+	case v.Type() == rawMessageType:
+		return string(v.Bytes())
+	case isJSONType(v.Type()):
+		if v.IsNil() {
+			return ""
+		}
+		b, _ := json.Marshal(v.Interface())
+		return string(b)
+	case v.Kind() == reflect.Slice || v.Kind() == reflect.Array:
+		vals := make([]string, v.Len())
+		for i := range vals {
+			if name, ok := enumName(v.Index(i)); ok {
+				vals[i] = name
+				continue
+			}
+			vals[i] = fmt.Sprint(v.Index(i).Interface())
+		}
+		return joinList(vals, f.parser.list.with(f.field.list).sep)
+	default:
+		return fmt.Sprint(v.Interface())
+	}
+}
+
+// Set sets the field to the value s, which is decrypted, unescaped,
+// expanded, transformed and validated like the value of its variable.
+func (f *fieldFlag) Set(s string) error {
+	s, err := f.parser.decrypt(f.field.key, s)
+	if err != nil {
+		return err
+	}
+	if s, err = f.parser.processValue(f.field, s); err != nil {
+		return err
+	}
+
+	parsed, ok, err := f.parser.parseFieldValue(s, f.field)
+	if err != nil {
+		return err
+	}
+
+	v := f.root
+	for _, i := range f.path {
+		if v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(i)
+	}
+
+	if !ok {
+		v.Set(reflect.Zero(f.field.typ))
+		return nil
+	}
+	v.Set(parsed)
+
+	return nil
+}
+
+// IsBoolFlag allows boolean flags to be set without a value.
+func (f *fieldFlag) IsBoolFlag() bool {
+	return f.field.typ.Kind() == reflect.Bool
+}
+
+// Type returns the name of the field's type, following the naming of pflag
+// for common types.
+func (f *fieldFlag) Type() string {
+	if f == nil || f.field == nil {
+		return ""
+	}
+	return flagTypeName(f.field.typ)
+}
+
+func flagTypeName(t reflect.Type) string {
+	switch {
+	case t == durationType:
+		return "duration"
+	case t == rawMessageType || isJSONType(t):
+		return "json"
+	case t == locationType:
+		return "location"
+	case t == bytesType:
+		return "bytes"
+	case t == urlType:
+		return "url"
+	case t.Kind() == reflect.Pointer:
+		return flagTypeName(t.Elem())
+	case t.Kind() == reflect.Slice || t.Kind() == reflect.Array:
+		return flagTypeName(t.Elem()) + "Slice"
+	default:
+		return t.Kind().String()
+	}
+}
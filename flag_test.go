@@ -0,0 +1,237 @@
+package envi_test
+
+import (
+	"errors"
+	"flag"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bounoable/envi"
+	"github.com/google/go-cmp/cmp"
+)
+
+type flagEnv struct {
+	Host    string        `env:"FLAG_HOST" default:"localhost" desc:"The host to listen on."`
+	Port    int           `env:"FLAG_PORT" default:"8080"`
+	Debug   bool          `env:"FLAG_DEBUG"`
+	Timeout time.Duration `env:"FLAG_TIMEOUT"`
+	Tags    []string      `env:"FLAG_TAGS"`
+	DB      *flagDB
+}
+
+type flagDB struct {
+	User string `env:"FLAG_DB_USER"`
+}
+
+// TestBindFlags verifies that BindFlags registers a flag for every tagged
+// field and resolves values with the precedence flags > environment >
+// defaults.
+func TestBindFlags(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("FLAG_HOST", "env.example.com")
+	os.Setenv("FLAG_PORT", "9090")
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	var e flagEnv
+	if err := envi.BindFlags(fs, &e); err != nil {
+		t.Fatalf("BindFlags() failed: %v", err)
+	}
+
+	for _, name := range []string{"flag-host", "flag-port", "flag-debug", "flag-timeout", "flag-tags", "flag-db-user"} {
+		if fs.Lookup(name) == nil {
+			t.Fatalf("flag -%s should be registered", name)
+		}
+	}
+
+	if got, want := fs.Lookup("flag-host").Usage, "The host to listen on."; got != want {
+		t.Fatalf("usage of -flag-host = %q, want %q", got, want)
+	}
+
+	if got := fs.Lookup("flag-port").DefValue; got != "9090" {
+		t.Fatalf("default value of -flag-port = %q, want %q", got, "9090")
+	}
+
+	if err := fs.Parse([]string{"-flag-port", "1234", "-flag-debug", "-flag-timeout=1m", "-flag-tags=a,b", "-flag-db-user=admin"}); err != nil {
+		t.Fatalf("Parse() failed: %v", err)
+	}
+
+	want := flagEnv{
+		Host:    "env.example.com",
+		Port:    1234,
+		Debug:   true,
+		Timeout: time.Minute,
+		Tags:    []string{"a", "b"},
+		DB:      &flagDB{User: "admin"},
+	}
+	if !cmp.Equal(want, e) {
+		t.Fatalf("env = %v, want = %v\n\n%s", e, want, cmp.Diff(want, e))
+	}
+
+	if err := fs.Parse([]string{"-flag-port", "invalid"}); err == nil {
+		t.Fatalf("Parse() should fail for an invalid value")
+	}
+}
+
+// TestBindFlags_listTags verifies that flags of list fields honor the `sep`
+// and `trim` tags of the fields.
+func TestBindFlags_listTags(t *testing.T) {
+	os.Clearenv()
+
+	var e struct {
+		Paths []string `env:"FLAG_PATHS" sep:";"`
+		Names []string `env:"FLAG_NAMES" trim:"false"`
+	}
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	if err := envi.BindFlags(fs, &e); err != nil {
+		t.Fatalf("BindFlags() failed: %v", err)
+	}
+
+	if err := fs.Parse([]string{"-flag-paths", "c;d", "-flag-names", " a, b"}); err != nil {
+		t.Fatalf("Parse() failed: %v", err)
+	}
+
+	if want := []string{"c", "d"}; !cmp.Equal(want, e.Paths) {
+		t.Fatalf("Paths = %q, want = %q", e.Paths, want)
+	}
+	if want := []string{" a", " b"}; !cmp.Equal(want, e.Names) {
+		t.Fatalf("Names = %q, want = %q", e.Names, want)
+	}
+	if got := fs.Lookup("flag-paths").Value.String(); got != "c;d" {
+		t.Fatalf("-flag-paths = %q, want %q", got, "c;d")
+	}
+}
+
+// TestBindFlags_secret verifies that the usage output of flags doesn't show
+// the values of secret fields as defaults.
+func TestBindFlags_secret(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("FLAG_TOKEN", "hunter2")
+	os.Setenv("FLAG_DB_PASSWORD", "s3cr3t")
+
+	var e struct {
+		Token string `env:"FLAG_TOKEN" secret:"true"`
+		DB    struct {
+			Password string `env:"FLAG_DB_PASSWORD"`
+		} `secret:"true"`
+	}
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	var usage strings.Builder
+	fs.SetOutput(&usage)
+	if err := envi.BindFlags(fs, &e); err != nil {
+		t.Fatalf("BindFlags() failed: %v", err)
+	}
+	fs.PrintDefaults()
+
+	if out := usage.String(); strings.Contains(out, "hunter2") || strings.Contains(out, "s3cr3t") {
+		t.Fatalf("PrintDefaults() should not show secret values; got\n%s", out)
+	}
+	if e.Token != "hunter2" || e.DB.Password != "s3cr3t" {
+		t.Fatalf("env = %+v; secret fields should still be parsed", e)
+	}
+}
+
+// TestBindFlags_pipeline verifies that flag values are transformed and
+// validated like the values of variables.
+func TestBindFlags_pipeline(t *testing.T) {
+	os.Clearenv()
+
+	var e struct {
+		Port  int    `env:"FLAG_PORT" validate:"port"`
+		Level string `env:"FLAG_LEVEL" transform:"lower"`
+	}
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	if err := envi.BindFlags(fs, &e); err != nil {
+		t.Fatalf("BindFlags() failed: %v", err)
+	}
+
+	if err := fs.Parse([]string{"-flag-level", "DEBUG", "-flag-port", "8080"}); err != nil {
+		t.Fatalf("Parse() failed: %v", err)
+	}
+	if e.Level != "debug" || e.Port != 8080 {
+		t.Fatalf("env = %+v; want Level = debug, Port = 8080", e)
+	}
+
+	if err := fs.Parse([]string{"-flag-port", "0"}); err == nil {
+		t.Fatalf("Parse() should fail for an invalid port")
+	}
+}
+
+// TestBindFlags_xor verifies that `xor` groups may be satisfied by flags, and
+// are checked by CheckRequired once the flags are parsed.
+func TestBindFlags_xor(t *testing.T) {
+	os.Clearenv()
+
+	type env struct {
+		Token    string `env:"FLAG_TOKEN" xor:"auth"`
+		Password string `env:"FLAG_PASSWORD" xor:"auth"`
+	}
+
+	var e env
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	if err := envi.BindFlags(fs, &e); err != nil {
+		t.Fatalf("BindFlags() failed: %v", err)
+	}
+	if err := envi.CheckRequired(&e); !errors.Is(err, envi.ErrXor) {
+		t.Fatalf("CheckRequired() should fail with %q before flags are parsed; got %v", envi.ErrXor, err)
+	}
+
+	if err := fs.Parse([]string{"-flag-token", "abc"}); err != nil {
+		t.Fatalf("Parse() failed: %v", err)
+	}
+	if err := envi.CheckRequired(&e); err != nil {
+		t.Fatalf("CheckRequired() failed: %v", err)
+	}
+
+	if err := fs.Parse([]string{"-flag-password", "def"}); err != nil {
+		t.Fatalf("Parse() failed: %v", err)
+	}
+	if err := envi.CheckRequired(&e); !errors.Is(err, envi.ErrXor) {
+		t.Fatalf("CheckRequired() should fail with %q for two fields; got %v", envi.ErrXor, err)
+	}
+}
+
+// TestBindFlags_required verifies that required fields may be set by flags
+// only, and are checked by CheckRequired once the flags are parsed.
+func TestBindFlags_required(t *testing.T) {
+	os.Clearenv()
+
+	type env struct {
+		Token string `env:"FLAG_TOKEN" required:"true"`
+		DB    *struct {
+			URL string `env:"FLAG_DB_URL" required:"true"`
+		}
+	}
+
+	var e env
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	if err := envi.BindFlags(fs, &e); err != nil {
+		t.Fatalf("BindFlags() failed: %v", err)
+	}
+
+	if err := fs.Parse([]string{"-flag-token", "secret"}); err != nil {
+		t.Fatalf("Parse() failed: %v", err)
+	}
+	err := envi.CheckRequired(&e)
+	if !errors.Is(err, envi.ErrRequired) || !strings.Contains(err.Error(), "FLAG_DB_URL") {
+		t.Fatalf("CheckRequired() should fail with ErrRequired for FLAG_DB_URL; got %v", err)
+	}
+
+	if err := fs.Parse([]string{"-flag-db-url", "postgres://"}); err != nil {
+		t.Fatalf("Parse() failed: %v", err)
+	}
+	if err := envi.CheckRequired(&e); err != nil {
+		t.Fatalf("CheckRequired() failed: %v", err)
+	}
+	if e.Token != "secret" || e.DB == nil || e.DB.URL != "postgres://" {
+		t.Fatalf("env = %+v, want Token and DB.URL set", e)
+	}
+}
@@ -0,0 +1,108 @@
+package envi
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// decodeHook is the normalized form of a mapstructure-compatible decode hook.
+type decodeHook func(from, to reflect.Value) (any, error)
+
+// The mapstructure decode hook signatures. Hooks of named types with the same
+// underlying type, such as mapstructure.DecodeHookFuncType, are converted to
+// these types before they are called.
+type (
+	decodeHookFuncType  = func(reflect.Type, reflect.Type, any) (any, error)
+	decodeHookFuncKind  = func(reflect.Kind, reflect.Kind, any) (any, error)
+	decodeHookFuncValue = func(from, to reflect.Value) (any, error)
+)
+
+// WithDecodeHook adds mapstructure-compatible decode hooks to the conversion
+// pipeline. Each hook must have the signature of a
+// mapstructure.DecodeHookFuncType, DecodeHookFuncKind or DecodeHookFuncValue,
+// so existing hooks (including the result of ComposeDecodeHookFunc) can be
+// passed as-is. WithDecodeHook panics if a hook has any other signature.
+//
+// Hooks are called in order before a raw variable value is converted, with the
+// string value as input and the target type as output type; each hook
+// receives the result of the previous one. If the final result is assignable
+// to the target type it is used as the parsed value. If it is a string, envi
+// converts it as usual.
+func WithDecodeHook(hooks ...any) Option {
+	normalized := make([]decodeHook, len(hooks))
+	for i, hook := range hooks {
+		h, err := newDecodeHook(hook)
+		if err != nil {
+			panic(err)
+		}
+		normalized[i] = h
+	}
+
+	return func(p *parser) {
+		p.hooks = append(p.hooks, normalized...)
+	}
+}
+
+func newDecodeHook(hook any) (decodeHook, error) {
+	rv := reflect.ValueOf(hook)
+	if rv.Kind() != reflect.Func || rv.IsNil() {
+		return nil, fmt.Errorf("invalid decode hook %T", hook)
+	}
+
+	for _, target := range []any{decodeHookFuncType(nil), decodeHookFuncKind(nil), decodeHookFuncValue(nil)} {
+		tt := reflect.TypeOf(target)
+		if !rv.Type().ConvertibleTo(tt) {
+			continue
+		}
+
+		switch fn := rv.Convert(tt).Interface().(type) {
+		case decodeHookFuncType:
+			return func(from, to reflect.Value) (any, error) {
+				return fn(from.Type(), to.Type(), from.Interface())
+			}, nil
+		case decodeHookFuncKind:
+			return func(from, to reflect.Value) (any, error) {
+				return fn(from.Kind(), to.Kind(), from.Interface())
+			}, nil
+		case decodeHookFuncValue:
+			return fn, nil
+		}
+	}
+
+	return nil, fmt.Errorf("invalid decode hook signature %T", hook)
+}
+
+// runDecodeHooks runs the configured decode hooks on value for the target
+// type t. If the hooks produced a value of the target type, it is returned
+// with done set to true. Otherwise, the returned value holds the (possibly
+// rewritten) string to convert.
+func (p *parser) runDecodeHooks(value string, t reflect.Type) (_ reflect.Value, done bool, _ error) {
+	var data any = value
+	for _, hook := range p.hooks {
+		from := reflect.ValueOf(data)
+		if !from.IsValid() {
+			break
+		}
+
+		out, err := hook(from, reflect.New(t).Elem())
+		if err != nil {
+			return reflect.Value{}, false, fmt.Errorf("decode hook: %w", err)
+		}
+		data = out
+	}
+
+	out := reflect.ValueOf(data)
+	switch {
+	case !out.IsValid():
+		return reflect.Value{}, true, nil
+	case out.Kind() == reflect.String:
+		return reflect.ValueOf(out.String()), false, nil
+	case out.Type().AssignableTo(t):
+		if out.Type() != t {
+			out = out.Convert(t)
+		}
+		return out, true, nil
+	default:
+		return reflect.Value{}, false, fmt.Errorf("decode hook returned %s, which is not assignable to %s", out.Type(), t)
+	}
+}
@@ -0,0 +1,99 @@
+package envi_test
+
+import (
+	"errors"
+	"net"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bounoable/envi"
+	"github.com/google/go-cmp/cmp"
+)
+
+// Hook types with the same underlying types as the mapstructure hook types.
+type (
+	decodeHookFuncType  func(reflect.Type, reflect.Type, interface{}) (interface{}, error)
+	decodeHookFuncKind  func(reflect.Kind, reflect.Kind, interface{}) (interface{}, error)
+	decodeHookFuncValue func(from reflect.Value, to reflect.Value) (interface{}, error)
+)
+
+type hookEnv struct {
+	IP    net.IP   `env:"HOOK_IP"`
+	Name  string   `env:"HOOK_NAME"`
+	Names []string `env:"HOOK_NAMES"`
+	Port  int      `env:"HOOK_PORT"`
+}
+
+// TestWithDecodeHook verifies that decode hooks of all mapstructure
+// signatures run before conversion, that they can produce the final value or
+// rewrite the raw string, and that hook errors are returned.
+func TestWithDecodeHook(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("HOOK_IP", "127.0.0.1")
+	os.Setenv("HOOK_NAME", "foo")
+	os.Setenv("HOOK_NAMES", "a;b;c")
+	os.Setenv("HOOK_PORT", "  8080 ")
+
+	ipHook := decodeHookFuncType(func(from, to reflect.Type, data interface{}) (interface{}, error) {
+		if from.Kind() != reflect.String || to != reflect.TypeOf(net.IP{}) {
+			return data, nil
+		}
+		return net.ParseIP(data.(string)), nil
+	})
+
+	upperHook := decodeHookFuncKind(func(from, to reflect.Kind, data interface{}) (interface{}, error) {
+		if from != reflect.String || to != reflect.String {
+			return data, nil
+		}
+		return strings.ToUpper(data.(string)), nil
+	})
+
+	trimHook := decodeHookFuncValue(func(from, to reflect.Value) (interface{}, error) {
+		if s, ok := from.Interface().(string); ok && to.Kind() == reflect.Int {
+			return strings.TrimSpace(s), nil
+		}
+		return from.Interface(), nil
+	})
+
+	splitHook := func(from, to reflect.Type, data interface{}) (interface{}, error) {
+		if to != reflect.TypeOf([]string{}) {
+			return data, nil
+		}
+		return strings.Split(data.(string), ";"), nil
+	}
+
+	e, err := envi.New[hookEnv](envi.WithDecodeHook(ipHook, upperHook, trimHook, splitHook))
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	want := hookEnv{
+		IP:    net.ParseIP("127.0.0.1"),
+		Name:  "FOO",
+		Names: []string{"a", "b", "c"},
+		Port:  8080,
+	}
+	if !cmp.Equal(want, e) {
+		t.Fatalf("env = %v, want = %v\n\n%s", e, want, cmp.Diff(want, e))
+	}
+
+	errHook := func(from, to reflect.Type, data interface{}) (interface{}, error) {
+		return nil, errors.New("hook failed")
+	}
+	if _, err := envi.New[hookEnv](envi.WithDecodeHook(errHook)); err == nil || !strings.Contains(err.Error(), "hook failed") {
+		t.Fatalf("New() should fail with the hook error; got %v", err)
+	}
+}
+
+// TestWithDecodeHook_invalidSignature verifies that WithDecodeHook panics for
+// functions that do not have a mapstructure decode hook signature.
+func TestWithDecodeHook_invalidSignature(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("WithDecodeHook() should panic for an invalid hook")
+		}
+	}()
+	envi.WithDecodeHook(func(s string) string { return s })
+}
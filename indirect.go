@@ -0,0 +1,46 @@
+package envi
+
+import (
+	"fmt"
+	"strings"
+)
+
+// WithIndirection resolves values of the form "@KEY" to the value of the
+// variable KEY, so variables can alias each other on platforms that can't
+// template values, e.g. DATABASE_URL=@POSTGRES_URL. References are followed
+// until a value is not a reference; circular references are an error. If the
+// referenced variable is not set, neither is the referencing one, so defaults
+// apply. A value that starts with "@@" is the literal value with the first
+// "@" removed, e.g. "@@home" for "@home".
+func WithIndirection() Option {
+	return func(p *parser) {
+		p.indirection = true
+	}
+}
+
+// dereference resolves value, the value of the variable with the given key
+// that was provided by source, if it is a reference.
+func (p *parser) dereference(key, value string, source Source) (string, Source, bool, error) {
+	seen := map[string]bool{key: true}
+	for {
+		if strings.HasPrefix(value, "@@") {
+			return value[1:], source, true, nil
+		}
+
+		ref := strings.TrimPrefix(value, "@")
+		if ref == value || !validEnvName(ref) {
+			return value, source, true, nil
+		}
+		if seen[ref] {
+			return "", nil, false, fmt.Errorf("resolve %q: circular reference to %s", key, ref)
+		}
+		seen[ref] = true
+		p.referRaw(key, ref)
+
+		var ok bool
+		var err error
+		if value, source, ok, err = p.lookupKey(ref); err != nil || !ok {
+			return "", nil, false, err
+		}
+	}
+}
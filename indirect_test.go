@@ -0,0 +1,58 @@
+package envi_test
+
+import (
+	"testing"
+
+	"github.com/bounoable/envi"
+	"github.com/google/go-cmp/cmp"
+)
+
+// TestWithIndirection tests that "@KEY" values are resolved to the value of
+// the referenced variable with WithIndirection.
+func TestWithIndirection(t *testing.T) {
+	type indirectEnv struct {
+		URL     string `env:"INDIRECT_URL"`
+		Replica string `env:"INDIRECT_REPLICA"`
+		Port    int    `env:"INDIRECT_PORT" default:"8080"`
+		Handle  string `env:"INDIRECT_HANDLE"`
+		Mail    string `env:"INDIRECT_MAIL"`
+	}
+
+	vars := envi.Map{
+		"INDIRECT_URL":     "@POSTGRES_URL",
+		"INDIRECT_REPLICA": "@INDIRECT_URL",
+		"INDIRECT_PORT":    "@UNSET_PORT",
+		"INDIRECT_HANDLE":  "@@envi",
+		"INDIRECT_MAIL":    "@ example.com",
+		"POSTGRES_URL":     "postgres://localhost",
+	}
+
+	e, err := envi.New[indirectEnv](envi.WithSource(vars), envi.WithIndirection())
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	want := indirectEnv{
+		URL:     "postgres://localhost",
+		Replica: "postgres://localhost",
+		Port:    8080,
+		Handle:  "@envi",
+		Mail:    "@ example.com",
+	}
+	if !cmp.Equal(want, e) {
+		t.Fatalf("env = %v, want = %v\n\n%s", e, want, cmp.Diff(want, e))
+	}
+
+	vars["INDIRECT_PORT"] = "9090"
+	e, err = envi.New[indirectEnv](envi.WithSource(vars))
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	if e.URL != "@POSTGRES_URL" {
+		t.Fatalf("URL = %q, want the reference without WithIndirection", e.URL)
+	}
+
+	vars["POSTGRES_URL"] = "@INDIRECT_REPLICA"
+	if _, err := envi.New[indirectEnv](envi.WithSource(vars), envi.WithIndirection()); err == nil {
+		t.Fatalf("New() should fail for circular references")
+	}
+}
@@ -0,0 +1,139 @@
+package envi
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+)
+
+// Lazy is a field type whose value is resolved on first access instead of
+// when the struct is parsed, e.g. for seldom-used credentials that are looked
+// up from slow remote sources:
+//
+//	type Config struct {
+//		APIKey envi.Lazy[string] `env:"API_KEY" required:"true"`
+//	}
+//
+//	key, err := cfg.APIKey.Get()
+//
+// The tags of the field apply when the value is resolved, so errors such as
+// missing required variables are returned by Get. The value is resolved with
+// the options of the Parse call and cached once it was resolved successfully.
+// Copies of a Lazy share the cached value. Lazy is safe for concurrent use.
+type Lazy[T any] struct {
+	state *lazyState[T]
+}
+
+type lazyState[T any] struct {
+	mux     sync.Mutex
+	resolve func(context.Context) (reflect.Value, error)
+	value   T
+	done    bool
+}
+
+// errLazyNotParsed is returned by Lazy values that were not parsed.
+var errLazyNotParsed = errors.New("lazy value was not parsed")
+
+// Get resolves the value on first access and returns it.
+func (l Lazy[T]) Get() (T, error) {
+	return l.GetContext(context.Background())
+}
+
+// GetContext is like Get, but passes ctx to the Sources if the value is not
+// resolved yet.
+func (l Lazy[T]) GetContext(ctx context.Context) (T, error) {
+	var zero T
+	if l.state == nil {
+		return zero, errLazyNotParsed
+	}
+
+	l.state.mux.Lock()
+	defer l.state.mux.Unlock()
+
+	if !l.state.done {
+		v, err := l.state.resolve(ctx)
+		if err != nil {
+			return zero, err
+		}
+		if v.IsValid() {
+			l.state.value = v.Interface().(T)
+		}
+		l.state.done = true
+	}
+
+	return l.state.value, nil
+}
+
+// MustGet is like Get, but panics if the value cannot be resolved.
+func (l Lazy[T]) MustGet() T {
+	v, err := l.Get()
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// String returns a placeholder, so formatting a config doesn't resolve its
+// Lazy fields.
+func (l Lazy[T]) String() string {
+	return "<lazy>"
+}
+
+func (Lazy[T]) lazyType() reflect.Type {
+	return reflect.TypeOf((*T)(nil)).Elem()
+}
+
+func (l *Lazy[T]) bindLazy(resolve func(context.Context) (reflect.Value, error)) {
+	l.state = &lazyState[T]{resolve: resolve}
+}
+
+// lazyValue is implemented by Lazy.
+type lazyValue interface {
+	lazyType() reflect.Type
+}
+
+var lazyValueType = reflect.TypeOf((*lazyValue)(nil)).Elem()
+
+// isLazy reports whether t is a Lazy type.
+func isLazy(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && t.Implements(lazyValueType)
+}
+
+// bindLazy returns a Lazy value of the type of field that parses the field
+// with the options of the parser on first access.
+func (p *parser) bindLazy(field *fieldSchema) reflect.Value {
+	leaf := *field
+	leaf.typ = reflect.Zero(field.typ).Interface().(lazyValue).lazyType()
+	leaf.lazy = false
+
+	snapshot := *p
+	snapshot.path = append([]string(nil), p.path...)
+	snapshot.trace, snapshot.report, snapshot.problems, snapshot.raw = nil, nil, nil, nil
+	snapshot.prefetched = nil
+
+	v := reflect.New(field.typ)
+	v.Interface().(interface {
+		bindLazy(func(context.Context) (reflect.Value, error))
+	}).bindLazy(func(ctx context.Context) (reflect.Value, error) {
+		lp := snapshot
+		lp.ctx = ctx
+		if lp.namespace != nil {
+			ns := *lp.namespace
+			lp.namespace = &ns
+		}
+		lp.keyIndex, lp.keysListed, lp.declared = nil, false, nil
+
+		var res fieldResult
+		v, ok, err := lp.parseField(&leaf, &res, reflect.New(leaf.typ).Elem())
+		if err != nil {
+			return reflect.Value{}, lp.fail(leaf.name, leaf.key, err)
+		}
+		if !ok {
+			return reflect.Value{}, nil
+		}
+		return v, nil
+	})
+
+	return v.Elem()
+}
@@ -0,0 +1,75 @@
+package envi_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"github.com/bounoable/envi"
+)
+
+// countingLookups is a Source that counts the lookups of its variables.
+type countingLookups struct {
+	envi.Map
+	lookups int32
+}
+
+func (s *countingLookups) Lookup(ctx context.Context, key string) (string, bool, error) {
+	atomic.AddInt32(&s.lookups, 1)
+	return s.Map.Lookup(ctx, key)
+}
+
+// TestLazy verifies that Lazy fields are resolved and cached on first access.
+func TestLazy(t *testing.T) {
+	type lazyEnv struct {
+		Host    string              `env:"LAZY_HOST"`
+		APIKey  envi.Lazy[string]   `env:"LAZY_API_KEY"`
+		Port    envi.Lazy[int]      `env:"LAZY_PORT" default:"8080"`
+		Token   envi.Lazy[string]   `env:"LAZY_TOKEN" required:"true"`
+		Servers envi.Lazy[[]string] `env:"LAZY_SERVERS"`
+	}
+
+	src := &countingLookups{Map: envi.Map{
+		"LAZY_HOST":    "localhost",
+		"LAZY_API_KEY": "key",
+		"LAZY_SERVERS": "a,b",
+	}}
+
+	e, err := envi.New[lazyEnv](envi.WithSource(src))
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	if src.lookups != 1 {
+		t.Fatalf("%d lookups before access, want 1", src.lookups)
+	}
+
+	for i := 0; i < 2; i++ {
+		if key, err := e.APIKey.Get(); err != nil || key != "key" {
+			t.Fatalf("APIKey.Get() = %q, %v; want %q", key, err, "key")
+		}
+	}
+	if src.lookups != 2 {
+		t.Fatalf("%d lookups after access, want 2", src.lookups)
+	}
+
+	if port := e.Port.MustGet(); port != 8080 {
+		t.Fatalf("Port = %d, want 8080", port)
+	}
+	if servers := e.Servers.MustGet(); len(servers) != 2 || servers[1] != "b" {
+		t.Fatalf("Servers = %v, want [a b]", servers)
+	}
+
+	if _, err := e.Token.Get(); !errors.Is(err, envi.ErrRequired) {
+		t.Fatalf("Token.Get() should fail with %v; got %v", envi.ErrRequired, err)
+	}
+	src.Map["LAZY_TOKEN"] = "token"
+	if token, err := e.Token.Get(); err != nil || token != "token" {
+		t.Fatalf("Token.Get() = %q, %v; want %q", token, err, "token")
+	}
+
+	var unparsed envi.Lazy[string]
+	if _, err := unparsed.Get(); err == nil {
+		t.Fatalf("Get() of an unparsed Lazy should fail")
+	}
+}
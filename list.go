@@ -0,0 +1,237 @@
+package envi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// listFormat controls how the values of slice and array fields are split into
+// their elements.
+type listFormat struct {
+	// sep separates the elements.
+	sep string
+
+	// trim reports whether unquoted elements are trimmed of surrounding
+	// whitespace.
+	trim bool
+
+	// emptySlice reports whether a slice field whose variable is set to an
+	// empty value is set to an empty slice instead of being left nil.
+	emptySlice bool
+}
+
+// defaultListFormat is the listFormat of a parser without options.
+var defaultListFormat = listFormat{sep: ",", trim: true}
+
+// WithSeparator sets the separator of the elements of slices and arrays, which
+// is a comma by default, e.g. ";" for values like Windows paths. A `sep` tag
+// overrides the separator for a field, e.g. `sep:":"`.
+func WithSeparator(sep string) Option {
+	return func(p *parser) {
+		if sep != "" {
+			p.list.sep = sep
+		}
+	}
+}
+
+// WithTrimSpace sets whether the elements of slices and arrays are trimmed of
+// surrounding whitespace, which they are by default. A `trim` tag overrides
+// the setting for a field, e.g. `trim:"false"`.
+func WithTrimSpace(trim bool) Option {
+	return func(p *parser) {
+		p.list.trim = trim
+	}
+}
+
+// WithEmptySlices sets slice fields whose variable is set but empty, e.g.
+// `HOSTS=`, to an empty, non-nil slice instead of leaving them nil, so an
+// explicitly cleared list can be told apart from an unset variable. Defaults
+// do not apply to such fields. An `emptySlice` tag enables or disables this
+// for a single field, e.g. `emptySlice:"true"`.
+func WithEmptySlices() Option {
+	return func(p *parser) {
+		p.list.emptySlice = true
+	}
+}
+
+// listTags are the overrides of the list format of the `sep`, `trim` and
+// `emptySlice` tags of a field. The tags are parsed once per field, instead
+// of on every Parse call.
+type listTags struct {
+	sep string
+
+	trim    bool
+	hasTrim bool
+
+	emptySlice    bool
+	hasEmptySlice bool
+}
+
+// parseListTags returns the listTags of the struct tag of a field.
+func parseListTags(tag reflect.StructTag) listTags {
+	var lt listTags
+	lt.sep = tag.Get("sep")
+	if v, ok := tag.Lookup("trim"); ok {
+		if b, err := strconv.ParseBool(v); err == nil {
+			lt.trim, lt.hasTrim = b, true
+		}
+	}
+	if v, ok := tag.Lookup("emptySlice"); ok {
+		if b, err := strconv.ParseBool(v); err == nil {
+			lt.emptySlice, lt.hasEmptySlice = b, true
+		}
+	}
+	return lt
+}
+
+// with returns f with the overrides of the tags of a field applied.
+func (f listFormat) with(lt listTags) listFormat {
+	if lt.sep != "" {
+		f.sep = lt.sep
+	}
+	if lt.hasTrim {
+		f.trim = lt.trim
+	}
+	if lt.hasEmptySlice {
+		f.emptySlice = lt.emptySlice
+	}
+	return f
+}
+
+// splitList splits the value of a slice or array field into its elements.
+// Elements are separated by f.sep and trimmed if f.trim is set. As in CSV, an
+// element that is enclosed in double quotes may contain the separator and
+// surrounding whitespace, and a double quote within it is escaped by doubling
+// it:
+//
+//	NAMES="Doe, John","Roe, Jane"
+//
+// Double quotes within unquoted elements are kept as they are. Alternatively,
+// a separator within an unquoted element is escaped by a backslash, e.g.
+// `a\,b,c` for ["a,b", "c"]; other backslashes are kept as they are.
+//
+// Values that are JSON arrays are decoded instead, e.g. `["a,b", "c"]`.
+func splitList(value string, f listFormat) ([]string, error) {
+	trim := func(s string) string { return s }
+	if f.trim {
+		trim = strings.TrimSpace
+	}
+
+	if elems, ok := jsonList(value); ok {
+		return elems, nil
+	}
+
+	escaped := `\` + f.sep
+	if !strings.Contains(value, `"`) && !strings.Contains(value, escaped) {
+		return mapSlice(strings.Split(value, f.sep), trim), nil
+	}
+
+	var elems []string
+	for {
+		if !strings.HasPrefix(strings.TrimLeft(value, " \t"), `"`) {
+			elem, rest, more := cutUnescaped(value, f.sep)
+			elems = append(elems, trim(elem))
+			if !more {
+				return elems, nil
+			}
+			value = rest
+			continue
+		}
+
+		value = strings.TrimLeft(value, " \t")
+		elem, rest, err := unquoteElement(value[1:])
+		if err != nil {
+			return nil, err
+		}
+		elems = append(elems, elem)
+
+		rest = strings.TrimLeft(rest, " \t")
+		if rest == "" {
+			return elems, nil
+		}
+		if !strings.HasPrefix(rest, f.sep) {
+			return nil, fmt.Errorf("unexpected %q after closing quote", rest[0])
+		}
+		value = rest[len(f.sep):]
+	}
+}
+
+// jsonList returns the elements of value if it is a JSON array, e.g.
+// `["a", "b"]` or `[1, 2]`. String elements are unquoted, and other elements
+// are returned as JSON text. It returns false if value is not a JSON array.
+func jsonList(value string) ([]string, bool) {
+	value = strings.TrimSpace(value)
+	if !strings.HasPrefix(value, "[") || !json.Valid([]byte(value)) {
+		return nil, false
+	}
+
+	var raw []json.RawMessage
+	if err := json.Unmarshal([]byte(value), &raw); err != nil {
+		return nil, false
+	}
+
+	elems := make([]string, len(raw))
+	for i, r := range raw {
+		if err := json.Unmarshal(r, &elems[i]); err != nil {
+			elems[i] = string(r)
+		}
+	}
+	return elems, true
+}
+
+// cutUnescaped is like strings.Cut, but skips separators that are escaped by
+// a backslash and removes their backslashes.
+func cutUnescaped(s, sep string) (before, after string, found bool) {
+	var b strings.Builder
+	for {
+		i := strings.Index(s, sep)
+		if i < 0 {
+			b.WriteString(s)
+			return b.String(), "", false
+		}
+		if i == 0 || s[i-1] != '\\' {
+			b.WriteString(s[:i])
+			return b.String(), s[i+len(sep):], true
+		}
+		b.WriteString(s[:i-1])
+		b.WriteString(sep)
+		s = s[i+len(sep):]
+	}
+}
+
+// unquoteElement returns the quoted element at the start of s, which follows
+// the opening quote, and the remainder of s after the closing quote.
+func unquoteElement(s string) (string, string, error) {
+	var b strings.Builder
+	for {
+		i := strings.IndexByte(s, '"')
+		if i < 0 {
+			return "", "", errors.New("missing closing quote")
+		}
+		b.WriteString(s[:i])
+		s = s[i+1:]
+
+		if !strings.HasPrefix(s, `"`) {
+			return b.String(), s, nil
+		}
+		b.WriteByte('"')
+		s = s[1:]
+	}
+}
+
+// joinList is the inverse of splitList. Elements that contain the separator,
+// double quotes or surrounding whitespace are quoted.
+func joinList(elems []string, sep string) string {
+	quoted := make([]string, len(elems))
+	for i, elem := range elems {
+		if strings.Contains(elem, sep) || strings.Contains(elem, `"`) || strings.TrimSpace(elem) != elem {
+			elem = `"` + strings.ReplaceAll(elem, `"`, `""`) + `"`
+		}
+		quoted[i] = elem
+	}
+	return strings.Join(quoted, sep)
+}
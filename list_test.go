@@ -0,0 +1,235 @@
+package envi_test
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/bounoable/envi"
+	"github.com/google/go-cmp/cmp"
+)
+
+// TestParse_quotedList verifies that elements of slices and arrays can be
+// quoted to contain commas and whitespace, as in CSV.
+func TestParse_quotedList(t *testing.T) {
+	type listEnv struct {
+		Names []string  `env:"LIST_NAMES"`
+		Pair  [2]string `env:"LIST_PAIR"`
+	}
+
+	tests := []struct {
+		name  string
+		names string
+		want  []string
+	}{
+		{name: "unquoted", names: " a, b ,c", want: []string{"a", "b", "c"}},
+		{name: "quoted", names: `"Doe, John","Roe, Jane"`, want: []string{"Doe, John", "Roe, Jane"}},
+		{name: "mixed", names: `a, "b, c" , d`, want: []string{"a", "b, c", "d"}},
+		{name: "whitespace", names: `" padded "`, want: []string{" padded "}},
+		{name: "escaped quote", names: `"say ""hi""",x`, want: []string{`say "hi"`, "x"}},
+		{name: "literal quote", names: `5'11",6'0"`, want: []string{`5'11"`, `6'0"`}},
+		{name: "empty quoted", names: `"",a`, want: []string{"", "a"}},
+		{name: "escaped separator", names: `a\,b,c`, want: []string{"a,b", "c"}},
+		{name: "escaped separators", names: `a\,b\,c, d`, want: []string{"a,b,c", "d"}},
+		{name: "backslash", names: `C:\bin,D:\`, want: []string{`C:\bin`, `D:\`}},
+		{name: "escaped and quoted", names: `a\,b,"c, d"`, want: []string{"a,b", "c, d"}},
+		{name: "json", names: ` ["a, b", "c"]`, want: []string{"a, b", "c"}},
+		{name: "ipv6", names: `[::1]:80,[::2]:80`, want: []string{"[::1]:80", "[::2]:80"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Clearenv()
+			os.Setenv("LIST_NAMES", tt.names)
+
+			e, err := envi.New[listEnv]()
+			if err != nil {
+				t.Fatalf("New() failed: %v", err)
+			}
+			if !cmp.Equal(tt.want, e.Names) {
+				t.Fatalf("Names = %v, want = %v\n\n%s", e.Names, tt.want, cmp.Diff(tt.want, e.Names))
+			}
+		})
+	}
+
+	os.Clearenv()
+	os.Setenv("LIST_PAIR", `"a,b", c`)
+	e, err := envi.New[listEnv]()
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	if want := [2]string{"a,b", "c"}; e.Pair != want {
+		t.Fatalf("Pair = %v, want %v", e.Pair, want)
+	}
+
+	for _, value := range []string{`"unterminated`, `"a" b,c`} {
+		os.Setenv("LIST_NAMES", value)
+		if _, err := envi.New[listEnv](); err == nil {
+			t.Fatalf("New() should fail for %q", value)
+		}
+	}
+}
+
+// TestParse_trim verifies that trimming of list elements can be disabled
+// globally with WithTrimSpace and per field with a `trim` tag.
+func TestParse_trim(t *testing.T) {
+	type trimEnv struct {
+		Names  []string `env:"TRIM_NAMES"`
+		Tokens []string `env:"TRIM_TOKENS" trim:"false"`
+		Keys   []string `env:"TRIM_KEYS" trim:"true"`
+	}
+
+	os.Clearenv()
+	os.Setenv("TRIM_NAMES", " a, b ")
+	os.Setenv("TRIM_TOKENS", " a, b ")
+	os.Setenv("TRIM_KEYS", " a, b ")
+
+	e, err := envi.New[trimEnv]()
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	want := trimEnv{
+		Names:  []string{"a", "b"},
+		Tokens: []string{" a", " b "},
+		Keys:   []string{"a", "b"},
+	}
+	if !cmp.Equal(want, e) {
+		t.Fatalf("env = %v, want = %v\n\n%s", e, want, cmp.Diff(want, e))
+	}
+
+	e, err = envi.New[trimEnv](envi.WithTrimSpace(false))
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	want.Names = []string{" a", " b "}
+	if !cmp.Equal(want, e) {
+		t.Fatalf("env = %v, want = %v\n\n%s", e, want, cmp.Diff(want, e))
+	}
+}
+
+// TestParse_emptySlice verifies that slices whose variable is set but empty
+// are empty instead of nil with WithEmptySlices or an `emptySlice` tag.
+func TestParse_emptySlice(t *testing.T) {
+	type emptyEnv struct {
+		Hosts []string `env:"EMPTY_HOSTS" default:"localhost"`
+		Ports []int    `env:"EMPTY_PORTS" emptySlice:"true"`
+		Names []string `env:"EMPTY_NAMES" emptySlice:"false"`
+		Tags  []string `env:"EMPTY_TAGS"`
+	}
+
+	os.Clearenv()
+	os.Setenv("EMPTY_HOSTS", "")
+	os.Setenv("EMPTY_PORTS", "")
+	os.Setenv("EMPTY_NAMES", "")
+
+	e, err := envi.New[emptyEnv]()
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	want := emptyEnv{Hosts: []string{"localhost"}, Ports: []int{}}
+	if !cmp.Equal(want, e) {
+		t.Fatalf("env = %v, want = %v\n\n%s", e, want, cmp.Diff(want, e))
+	}
+
+	e, err = envi.New[emptyEnv](envi.WithEmptySlices())
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	want = emptyEnv{Hosts: []string{}, Ports: []int{}}
+	if !cmp.Equal(want, e) {
+		t.Fatalf("env = %v, want = %v\n\n%s", e, want, cmp.Diff(want, e))
+	}
+	if e.Tags != nil {
+		t.Fatalf("Tags = %#v, want nil for unset variable", e.Tags)
+	}
+}
+
+// TestWithSeparator verifies that the separator of list elements can be set
+// globally with WithSeparator and per field with a `sep` tag.
+func TestWithSeparator(t *testing.T) {
+	type sepEnv struct {
+		Paths []string `env:"SEP_PATHS"`
+		Hosts []string `env:"SEP_HOSTS" sep:"|"`
+	}
+
+	os.Clearenv()
+	os.Setenv("SEP_PATHS", `C:\bin; "D:\a;b"`)
+	os.Setenv("SEP_HOSTS", "a,b|c")
+
+	e, err := envi.New[sepEnv](envi.WithSeparator(";"))
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	want := sepEnv{
+		Paths: []string{`C:\bin`, `D:\a;b`},
+		Hosts: []string{"a,b", "c"},
+	}
+	if !cmp.Equal(want, e) {
+		t.Fatalf("env = %v, want = %v\n\n%s", e, want, cmp.Diff(want, e))
+	}
+}
+
+// TestParse_jsonList verifies that JSON arrays are decoded for slices of any
+// element type.
+func TestParse_jsonList(t *testing.T) {
+	type jsonListEnv struct {
+		Ports     []int           `env:"JSON_PORTS"`
+		Timeouts  []time.Duration `env:"JSON_TIMEOUTS"`
+		Flags     [2]bool         `env:"JSON_FLAGS"`
+		Endpoints []string        `env:"JSON_ENDPOINTS"`
+	}
+
+	os.Clearenv()
+	os.Setenv("JSON_PORTS", "[80, 443]")
+	os.Setenv("JSON_TIMEOUTS", `["1s", "2m"]`)
+	os.Setenv("JSON_FLAGS", "[true, false]")
+	os.Setenv("JSON_ENDPOINTS", `["https://a.example.com/x,y"]`)
+
+	e, err := envi.New[jsonListEnv]()
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	want := jsonListEnv{
+		Ports:     []int{80, 443},
+		Timeouts:  []time.Duration{time.Second, 2 * time.Minute},
+		Flags:     [2]bool{true, false},
+		Endpoints: []string{"https://a.example.com/x,y"},
+	}
+	if !cmp.Equal(want, e) {
+		t.Fatalf("env = %v, want = %v\n\n%s", e, want, cmp.Diff(want, e))
+	}
+}
+
+// TestParse_mapSlices verifies that the values of maps of slices are split
+// into lists, using the list format of the tags of the map field.
+func TestParse_mapSlices(t *testing.T) {
+	type mapSlicesEnv struct {
+		Headers map[string][]string `env:"MAPSLICE_HEADER"`
+		Ports   map[string][]int    `env:"MAPSLICE_PORTS" sep:";"`
+	}
+
+	e, err := envi.New[mapSlicesEnv](envi.WithSource(envi.Map{
+		"MAPSLICE_HEADER_Accept":    `application/json, "text/plain; q=0.5, x"`,
+		"MAPSLICE_HEADER_X_Trace":   "1",
+		"MAPSLICE_PORTS_http":       "80;8080",
+		"MAPSLICE_PORTS_monitoring": "[9090, 9091]",
+	}))
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	want := mapSlicesEnv{
+		Headers: map[string][]string{
+			"Accept":  {"application/json", "text/plain; q=0.5, x"},
+			"X_Trace": {"1"},
+		},
+		Ports: map[string][]int{
+			"http":       {80, 8080},
+			"monitoring": {9090, 9091},
+		},
+	}
+	if !cmp.Equal(want, e) {
+		t.Fatalf("env = %v, want = %v\n\n%s", e, want, cmp.Diff(want, e))
+	}
+}
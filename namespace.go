@@ -0,0 +1,57 @@
+package envi
+
+import "strings"
+
+// WithNamespace looks up variables with the given prefix in the namespace of
+// the environment that the selector variable names first. With
+// WithNamespace("MYAPP", "APP_ENV") and APP_ENV=staging, the variable
+// MYAPP_DB_URL is looked up as MYAPP_STAGING_DB_URL, falling back to
+// MYAPP_DB_URL if that is not set. This allows the variables of multiple
+// environments to be kept in the same store. Variables are not namespaced if
+// the selector is empty. A trailing "_" of prefix is ignored, so "MYAPP_" is
+// the same prefix as "MYAPP".
+func WithNamespace(prefix, selector string) Option {
+	return func(p *parser) {
+		p.namespace = &namespace{prefix: strings.TrimRight(prefix, "_"), selector: selector}
+	}
+}
+
+// namespace is the configuration of WithNamespace.
+type namespace struct {
+	prefix   string
+	selector string
+
+	// env is the upper-cased value of the selector, once it was looked up.
+	env      string
+	resolved bool
+}
+
+// namespacedKey returns the key that key is looked up as first, or false if
+// the key is not namespaced.
+func (p *parser) namespacedKey(key string) (string, bool, error) {
+	ns := p.namespace
+	if ns == nil || key == ns.selector {
+		return "", false, nil
+	}
+
+	if !ns.resolved {
+		env, _, _, err := p.lookupRaw(ns.selector)
+		if err != nil {
+			return "", false, err
+		}
+		ns.env = strings.ToUpper(strings.TrimSpace(env))
+		ns.resolved = true
+	}
+	if ns.env == "" {
+		return "", false, nil
+	}
+
+	if ns.prefix == "" {
+		return ns.env + "_" + key, true, nil
+	}
+	rest := strings.TrimPrefix(key, ns.prefix+"_")
+	if rest == key {
+		return "", false, nil
+	}
+	return ns.prefix + "_" + ns.env + "_" + rest, true, nil
+}
@@ -0,0 +1,50 @@
+package envi_test
+
+import (
+	"testing"
+
+	"github.com/bounoable/envi"
+)
+
+// TestWithNamespace verifies that variables are looked up in the namespace of
+// the selected environment first, falling back to the plain key.
+func TestWithNamespace(t *testing.T) {
+	type nsEnv struct {
+		DatabaseURL string `env:"MYAPP_DB_URL"`
+		LogLevel    string `env:"MYAPP_LOG_LEVEL"`
+		Region      string `env:"REGION"`
+	}
+
+	src := envi.Map{
+		"APP_ENV":              "staging",
+		"MYAPP_DB_URL":         "postgres://prod",
+		"MYAPP_STAGING_DB_URL": "postgres://staging",
+		"MYAPP_LOG_LEVEL":      "info",
+		"REGION":               "eu",
+		"STAGING_REGION":       "ignored",
+	}
+
+	e, err := envi.New[nsEnv](envi.WithSource(src), envi.WithNamespace("MYAPP", "APP_ENV"))
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	want := nsEnv{DatabaseURL: "postgres://staging", LogLevel: "info", Region: "eu"}
+	if e != want {
+		t.Fatalf("env = %+v, want %+v", e, want)
+	}
+
+	if e, err = envi.New[nsEnv](envi.WithSource(src), envi.WithNamespace("MYAPP_", "APP_ENV")); err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	if e != want {
+		t.Fatalf("env = %+v, want %+v with a trailing separator", e, want)
+	}
+
+	src["APP_ENV"] = ""
+	if e, err = envi.New[nsEnv](envi.WithSource(src), envi.WithNamespace("MYAPP", "APP_ENV")); err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	if e.DatabaseURL != "postgres://prod" {
+		t.Fatalf("DatabaseURL = %q, want %q without selector", e.DatabaseURL, "postgres://prod")
+	}
+}
@@ -0,0 +1,91 @@
+package envi
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// WithDecimalComma accepts a comma as the decimal separator of floats, e.g.
+// "3,14", as it is commonly entered in many locales. Values with more than one
+// comma or with a decimal point are parsed as usual. Elements of float lists
+// must be quoted or use another separator, e.g. `"3,14","2,72"`.
+func WithDecimalComma() Option {
+	return func(p *parser) {
+		p.decimalComma = true
+	}
+}
+
+// floatText returns the text of the float s with a decimal comma replaced by
+// a decimal point if WithDecimalComma is used.
+func (p *parser) floatText(s string) string {
+	if p.decimalComma && strings.Count(s, ",") == 1 && !strings.Contains(s, ".") {
+		return strings.Replace(s, ",", ".", 1)
+	}
+	return s
+}
+
+// parseInt parses the integer s, which may have a 0x, 0o or 0b base prefix,
+// e.g. "0o755", and contain underscores between digits, e.g. "10_000_000".
+// Integers without a prefix are decimal, even with leading zeros.
+func parseInt(s string, bitSize int) (int64, error) {
+	text, base := intText(s)
+	n, err := strconv.ParseInt(text, base, bitSize)
+	return n, numError(err, s)
+}
+
+// parseUint is like parseInt, but for unsigned integers.
+func parseUint(s string, bitSize int) (uint64, error) {
+	text, base := intText(s)
+	n, err := strconv.ParseUint(text, base, bitSize)
+	return n, numError(err, s)
+}
+
+// intText returns the text and base that the integer s is parsed with.
+// Integers with a base prefix are parsed with base 0, which handles
+// underscores as in Go literals.
+func intText(s string) (string, int) {
+	unsigned := s
+	if len(s) > 0 && (s[0] == '+' || s[0] == '-') {
+		unsigned = s[1:]
+	}
+	if len(unsigned) > 2 && unsigned[0] == '0' && strings.IndexByte("xXoObB", unsigned[1]) >= 0 {
+		return s, 0
+	}
+	return digitText(s), 10
+}
+
+// parseFloat parses the float s, which may contain underscores between
+// digits and, if WithDecimalComma is used, a decimal comma.
+func (p *parser) parseFloat(s string, bitSize int) (float64, error) {
+	f, err := strconv.ParseFloat(digitText(p.floatText(s)), bitSize)
+	return f, numError(err, s)
+}
+
+// digitText returns s without the underscores that separate its digits, e.g.
+// "10_000" becomes "10000". s is returned as it is if any underscore does not
+// separate two digits, so that strconv reports it as invalid.
+func digitText(s string) string {
+	if !strings.Contains(s, "_") {
+		return s
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] == '_' && (i == 0 || i == len(s)-1 || !isDigit(s[i-1]) || !isDigit(s[i+1])) {
+			return s
+		}
+	}
+	return strings.ReplaceAll(s, "_", "")
+}
+
+// numError replaces the number of a *strconv.NumError with the original text
+// s, so errors show the value as it was set.
+func numError(err error, s string) error {
+	if err == nil {
+		return nil
+	}
+	var ne *strconv.NumError
+	if errors.As(err, &ne) {
+		ne.Num = s
+	}
+	return err
+}
@@ -0,0 +1,109 @@
+package envi_test
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/bounoable/envi"
+	"github.com/google/go-cmp/cmp"
+)
+
+// TestWithDecimalComma verifies that floats may have a decimal comma with
+// WithDecimalComma.
+func TestWithDecimalComma(t *testing.T) {
+	type decimalEnv struct {
+		Ratio   float64   `env:"DECIMAL_RATIO"`
+		Scale   float32   `env:"DECIMAL_SCALE"`
+		Weights []float64 `env:"DECIMAL_WEIGHTS"`
+	}
+
+	src := envi.Map{
+		"DECIMAL_RATIO":   "3,14",
+		"DECIMAL_SCALE":   "-0,5",
+		"DECIMAL_WEIGHTS": `"1,5",2.5`,
+	}
+
+	if _, err := envi.New[decimalEnv](envi.WithSource(src)); err == nil {
+		t.Fatalf("New() should fail without WithDecimalComma")
+	}
+
+	e, err := envi.New[decimalEnv](envi.WithSource(src), envi.WithDecimalComma())
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	want := decimalEnv{Ratio: 3.14, Scale: -0.5, Weights: []float64{1.5, 2.5}}
+	if !cmp.Equal(want, e) {
+		t.Fatalf("env = %v, want = %v\n\n%s", e, want, cmp.Diff(want, e))
+	}
+
+	src["DECIMAL_RATIO"] = "1.000,5"
+	if _, err := envi.New[decimalEnv](envi.WithSource(src), envi.WithDecimalComma()); err == nil {
+		t.Fatalf("New() should fail for a value with a decimal point and a comma")
+	}
+}
+
+// TestParse_underscores verifies that numbers may contain underscores between
+// digits, as in Go literals.
+func TestParse_underscores(t *testing.T) {
+	type underscoreEnv struct {
+		Limit int64   `env:"UNDERSCORE_LIMIT"`
+		Size  uint    `env:"UNDERSCORE_SIZE"`
+		Rate  float64 `env:"UNDERSCORE_RATE"`
+	}
+
+	e, err := envi.New[underscoreEnv](envi.WithSource(envi.Map{
+		"UNDERSCORE_LIMIT": "-10_000_000",
+		"UNDERSCORE_SIZE":  "1_024",
+		"UNDERSCORE_RATE":  "1_000.000_5",
+	}))
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	want := underscoreEnv{Limit: -10000000, Size: 1024, Rate: 1000.0005}
+	if !cmp.Equal(want, e) {
+		t.Fatalf("env = %v, want = %v\n\n%s", e, want, cmp.Diff(want, e))
+	}
+
+	for _, value := range []string{"_1000", "1000_", "1__000", "1_.5"} {
+		_, err := envi.New[underscoreEnv](envi.WithSource(envi.Map{"UNDERSCORE_RATE": value}))
+		if err == nil || !strings.Contains(err.Error(), strconv.Quote(value)) {
+			t.Fatalf("New() with %q should fail with the original value; got %v", value, err)
+		}
+	}
+}
+
+// TestParse_basePrefixes verifies that integers may have a 0x, 0o or 0b base
+// prefix, and that integers without a prefix are decimal.
+func TestParse_basePrefixes(t *testing.T) {
+	type baseEnv struct {
+		Perms   uint32  `env:"BASE_PERMS"`
+		Mask    uint8   `env:"BASE_MASK"`
+		Flags   int     `env:"BASE_FLAGS"`
+		Offset  int64   `env:"BASE_OFFSET"`
+		Padded  int     `env:"BASE_PADDED"`
+		Weights []int16 `env:"BASE_WEIGHTS"`
+	}
+
+	e, err := envi.New[baseEnv](envi.WithSource(envi.Map{
+		"BASE_PERMS":   "0o755",
+		"BASE_MASK":    "0xFF",
+		"BASE_FLAGS":   "0b1010_0101",
+		"BASE_OFFSET":  "-0x10",
+		"BASE_PADDED":  "0755",
+		"BASE_WEIGHTS": "0x_10,0O7,0B1",
+	}))
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	want := baseEnv{Perms: 0o755, Mask: 0xFF, Flags: 0b1010_0101, Offset: -0x10, Padded: 755, Weights: []int16{16, 7, 1}}
+	if !cmp.Equal(want, e) {
+		t.Fatalf("env = %v, want = %v\n\n%s", e, want, cmp.Diff(want, e))
+	}
+
+	for _, value := range []string{"0x", "0xFG", "0b102", "0x100"} {
+		if _, err := envi.New[baseEnv](envi.WithSource(envi.Map{"BASE_MASK": value})); err == nil {
+			t.Fatalf("New() with %q should fail", value)
+		}
+	}
+}
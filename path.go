@@ -0,0 +1,37 @@
+package envi
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// expandPath expands a leading "~" to the home directory of the current user
+// and references to variables in path, and returns the cleaned absolute path.
+// It is applied to the values of fields with a `path:"expand"` tag.
+func (p *parser) expandPath(path string) (string, error) {
+	if path == "" {
+		return "", nil
+	}
+
+	path, err := p.expandVars(path)
+	if err != nil {
+		return "", err
+	}
+
+	if path == "~" || strings.HasPrefix(path, "~/") || strings.HasPrefix(path, "~"+string(filepath.Separator)) {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("expand %q: %w", path, err)
+		}
+		path = filepath.Join(home, path[1:])
+	}
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", fmt.Errorf("expand %q: %w", path, err)
+	}
+
+	return abs, nil
+}
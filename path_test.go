@@ -0,0 +1,49 @@
+package envi_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bounoable/envi"
+)
+
+// TestParse_expandPath verifies that fields with a `path:"expand"` tag expand
+// "~" and variables, and are cleaned and made absolute.
+func TestParse_expandPath(t *testing.T) {
+	type pathEnv struct {
+		CertFile string `env:"PATH_CERT_FILE" path:"expand"`
+		DataDir  string `env:"PATH_DATA_DIR" path:"expand" default:"./data/../var"`
+		CacheDir string `env:"PATH_CACHE_DIR" path:"expand"`
+		Raw      string `env:"PATH_RAW"`
+		Unset    string `env:"PATH_UNSET" path:"expand"`
+	}
+
+	home := t.TempDir()
+	os.Clearenv()
+	os.Setenv("HOME", home)
+	os.Setenv("PATH_CERT_FILE", "~/certs//tls.pem")
+	os.Setenv("PATH_CACHE_DIR", "/srv/${PATH_APP}/cache/")
+	os.Setenv("PATH_APP", "api")
+	os.Setenv("PATH_RAW", "~/raw")
+
+	e, err := envi.New[pathEnv]()
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := pathEnv{
+		CertFile: filepath.Join(home, "certs", "tls.pem"),
+		DataDir:  filepath.Join(wd, "var"),
+		CacheDir: "/srv/api/cache",
+		Raw:      "~/raw",
+	}
+	if e != want {
+		t.Fatalf("env = %+v, want %+v", e, want)
+	}
+}
@@ -0,0 +1,122 @@
+package envi
+
+import (
+	"fmt"
+	"sync"
+)
+
+// WithParallelLookups looks up the variables of all fields concurrently,
+// with at most n lookups at a time, before the fields are parsed, so startup
+// time with remote Sources such as Vault or SSM doesn't grow linearly with
+// the number of variables. This includes the variables of `defaultFrom`,
+// `dsn`, `required_if` and `required_unless` tags. Lazy fields, maps and
+// fields that are not selected by WithFields or WithGroups are not looked up
+// in advance. The Sources must be safe for concurrent use. Errors of lookups
+// are only returned if the variable is used. n < 2 disables parallel
+// lookups.
+func WithParallelLookups(n int) Option {
+	return func(p *parser) {
+		p.parallel = n
+	}
+}
+
+// lookupResult is the result of looking up a variable in the Sources.
+type lookupResult struct {
+	value  string
+	source Source
+	ok     bool
+	err    error
+}
+
+// prefetch looks up the variables of the fields of the struct with the given
+// schema concurrently and stores the results for lookupRaw.
+func (p *parser) prefetch(schema *structSchema) {
+	var keys []string
+	p.prefetchKeys(schema, &keys, make(map[string]bool))
+
+	results := make([]lookupResult, len(keys))
+	sem := make(chan struct{}, p.parallel)
+	var wg sync.WaitGroup
+	for i, key := range keys {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, key string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			results[i] = p.lookupSources(key)
+		}(i, key)
+	}
+	wg.Wait()
+
+	p.prefetched = make(map[string]lookupResult, len(keys))
+	for i, key := range keys {
+		p.prefetched[key] = results[i]
+	}
+}
+
+// prefetchKeys collects the keys of the variables that the fields of the
+// struct with the given schema look up into keys, in order and without
+// duplicates.
+func (p *parser) prefetchKeys(schema *structSchema, keys *[]string, seen map[string]bool) {
+	add := func(key string) {
+		if nsKey, ok, err := p.namespacedKey(key); err == nil && ok && !seen[nsKey] {
+			seen[nsKey] = true
+			*keys = append(*keys, nsKey)
+		}
+		if !seen[key] {
+			seen[key] = true
+			*keys = append(*keys, key)
+		}
+	}
+
+	for n := range schema.fields {
+		field := &schema.fields[n]
+		switch {
+		case field.recursive || !field.settable() || p.skips(field):
+		case field.isStruct:
+			path, group := p.path, p.group
+			if !field.squash {
+				p.path = append(p.path, field.name)
+			}
+			p.group = p.fieldGroups(field)
+			p.prefetchKeys(field.nested(), keys, seen)
+			p.path, p.group = path, group
+		case field.lazy || field.rest || !field.hasKey || isPrefixMap(field.typ):
+		case field.unsupported && p.skipUnsupported:
+		default:
+			add(field.key)
+			for _, key := range p.aliasesOf(field) {
+				add(key)
+			}
+			for _, key := range field.defaultFrom {
+				add(key)
+			}
+			if field.dsn != "" {
+				for _, suffix := range dsnVariables {
+					add(field.dsn + "_" + suffix)
+				}
+			}
+			for _, c := range field.requiredIf {
+				add(c.key)
+			}
+			for _, c := range field.requiredUnless {
+				add(c.key)
+			}
+		}
+	}
+}
+
+// lookupSources looks up the variable with the given key in the configured
+// Sources. It doesn't modify the parser, so it may be called concurrently.
+func (p *parser) lookupSources(key string) lookupResult {
+	for _, s := range p.sources {
+		v, ok, err := s.Lookup(p.ctx, key)
+		if err != nil {
+			return lookupResult{err: fmt.Errorf("lookup %q: %w", key, err)}
+		}
+		if ok {
+			return lookupResult{value: v, source: origin(s, key), ok: true}
+		}
+	}
+	return lookupResult{}
+}
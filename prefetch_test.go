@@ -0,0 +1,97 @@
+package envi_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bounoable/envi"
+	"github.com/google/go-cmp/cmp"
+)
+
+// slowSource is a Source that takes a while to look up variables and records
+// the maximum number of concurrent lookups.
+type slowSource struct {
+	vars envi.Map
+	errs map[string]error
+
+	mux     sync.Mutex
+	active  int
+	max     int
+	lookups int
+}
+
+func (s *slowSource) Lookup(ctx context.Context, key string) (string, bool, error) {
+	s.mux.Lock()
+	s.active++
+	s.lookups++
+	if s.active > s.max {
+		s.max = s.active
+	}
+	s.mux.Unlock()
+
+	time.Sleep(10 * time.Millisecond)
+
+	s.mux.Lock()
+	s.active--
+	s.mux.Unlock()
+
+	if err, ok := s.errs[key]; ok {
+		return "", false, err
+	}
+	return s.vars.Lookup(ctx, key)
+}
+
+// TestWithParallelLookups tests that the variables of fields are looked up
+// concurrently with WithParallelLookups, with a bounded number of concurrent
+// lookups, and that the result matches sequential parsing.
+func TestWithParallelLookups(t *testing.T) {
+	type parallelEnv struct {
+		A string `env:"PARALLEL_A"`
+		B string `env:"PARALLEL_B"`
+		C int    `env:"PARALLEL_C" defaultFrom:"PARALLEL_FALLBACK"`
+		D struct {
+			E string `env:"PARALLEL_E" required_if:"PARALLEL_MODE=strict"`
+			F string `env:"PARALLEL_F"`
+		}
+		Lazy envi.Lazy[string] `env:"PARALLEL_LAZY"`
+	}
+
+	vars := envi.Map{
+		"PARALLEL_A":        "a",
+		"PARALLEL_B":        "b",
+		"PARALLEL_FALLBACK": "3",
+		"PARALLEL_E":        "e",
+		"PARALLEL_LAZY":     "lazy",
+	}
+
+	sequential, err := envi.New[parallelEnv](envi.WithSource(vars))
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	src := &slowSource{vars: vars}
+	e, err := envi.New[parallelEnv](envi.WithSource(src), envi.WithParallelLookups(3))
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	if !cmp.Equal(sequential, e, cmp.Comparer(func(a, b envi.Lazy[string]) bool { return a.MustGet() == b.MustGet() })) {
+		t.Fatalf("env = %v, want = %v", e, sequential)
+	}
+	if src.max < 2 || src.max > 3 {
+		t.Fatalf("%d concurrent lookups, want 2 or 3", src.max)
+	}
+	// A, B, C, FALLBACK, E, MODE and F are looked up in advance; the lazy
+	// field is looked up once it is accessed.
+	if src.lookups != 8 {
+		t.Fatalf("%d lookups, want 8", src.lookups)
+	}
+
+	unavailable := errors.New("unavailable")
+	src = &slowSource{vars: vars, errs: map[string]error{"PARALLEL_F": unavailable}}
+	if _, err := envi.New[parallelEnv](envi.WithSource(src), envi.WithParallelLookups(3)); !errors.Is(err, unavailable) {
+		t.Fatalf("New() should fail with %v; got %v", unavailable, err)
+	}
+}
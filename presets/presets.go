@@ -0,0 +1,79 @@
+// Package presets provides config structs for common groups of 12-factor
+// variables. The structs can be embedded into the config of a service:
+//
+//	type Config struct {
+//		presets.HTTPServer
+//		presets.Logging
+//		presets.OTel
+//
+//		DatabaseURL string `env:"DATABASE_URL" required:"true"`
+//	}
+//
+//	cfg, err := envi.New[Config]()
+package presets
+
+import (
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HTTPServer holds the settings of an HTTP server.
+type HTTPServer struct {
+	Host            string        `env:"HOST" desc:"Host or IP address to listen on; empty for all interfaces."`
+	Port            int           `env:"PORT" default:"8080" validate:"port" desc:"Port to listen on."`
+	ReadTimeout     time.Duration `env:"HTTP_READ_TIMEOUT" default:"10s" desc:"Maximum duration for reading a request."`
+	WriteTimeout    time.Duration `env:"HTTP_WRITE_TIMEOUT" default:"10s" desc:"Maximum duration for writing a response."`
+	IdleTimeout     time.Duration `env:"HTTP_IDLE_TIMEOUT" default:"2m" desc:"Maximum duration of idle keep-alive connections."`
+	ShutdownTimeout time.Duration `env:"HTTP_SHUTDOWN_TIMEOUT" default:"30s" desc:"Maximum duration of a graceful shutdown."`
+}
+
+// Addr returns the address to listen on, e.g. ":8080".
+func (s HTTPServer) Addr() string {
+	return net.JoinHostPort(s.Host, strconv.Itoa(s.Port))
+}
+
+// Logging holds the settings of a logger.
+type Logging struct {
+	Level  string `env:"LOG_LEVEL" default:"info" desc:"Minimum log level (debug, info, warn or error)."`
+	Format string `env:"LOG_FORMAT" default:"json" desc:"Log format (json or text)."`
+}
+
+// OTel holds the common settings of an OpenTelemetry SDK, using the variable
+// names of the OpenTelemetry specification.
+type OTel struct {
+	Disabled           bool   `env:"OTEL_SDK_DISABLED" desc:"Disables the OpenTelemetry SDK."`
+	ServiceName        string `env:"OTEL_SERVICE_NAME" desc:"Name of the service."`
+	ResourceAttributes string `env:"OTEL_RESOURCE_ATTRIBUTES" desc:"Resource attributes as key=value pairs separated by commas."`
+	Endpoint           string `env:"OTEL_EXPORTER_OTLP_ENDPOINT" validate:"url" desc:"Endpoint of the OTLP exporter."`
+	Protocol           string `env:"OTEL_EXPORTER_OTLP_PROTOCOL" default:"grpc" desc:"Protocol of the OTLP exporter (grpc, http/protobuf or http/json)."`
+	Headers            string `env:"OTEL_EXPORTER_OTLP_HEADERS" secret:"true" desc:"Headers of the OTLP exporter as key=value pairs separated by commas."`
+	TracesSampler      string `env:"OTEL_TRACES_SAMPLER" default:"parentbased_always_on" desc:"Sampler of traces."`
+	TracesSamplerArg   string `env:"OTEL_TRACES_SAMPLER_ARG" desc:"Argument of the sampler of traces."`
+}
+
+// HeaderMap returns the exporter headers as a map.
+func (o OTel) HeaderMap() map[string]string {
+	return parsePairs(o.Headers)
+}
+
+// AttributeMap returns the resource attributes as a map.
+func (o OTel) AttributeMap() map[string]string {
+	return parsePairs(o.ResourceAttributes)
+}
+
+// parsePairs parses key=value pairs separated by commas.
+func parsePairs(s string) map[string]string {
+	if s == "" {
+		return nil
+	}
+	out := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		k, v, _ := strings.Cut(pair, "=")
+		if k = strings.TrimSpace(k); k != "" {
+			out[k] = strings.TrimSpace(v)
+		}
+	}
+	return out
+}
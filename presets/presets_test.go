@@ -0,0 +1,53 @@
+package presets_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bounoable/envi"
+	"github.com/bounoable/envi/presets"
+	"github.com/google/go-cmp/cmp"
+)
+
+type config struct {
+	presets.HTTPServer
+	presets.Logging
+	presets.OTel
+}
+
+// TestPresets verifies that the presets can be embedded together and are
+// parsed with their defaults.
+func TestPresets(t *testing.T) {
+	cfg, err := envi.New[config](envi.WithSource(envi.Map{
+		"PORT":                        "9000",
+		"LOG_LEVEL":                   "debug",
+		"OTEL_SERVICE_NAME":           "orders",
+		"OTEL_EXPORTER_OTLP_ENDPOINT": "http://collector:4317",
+		"OTEL_EXPORTER_OTLP_HEADERS":  "api-key=secret, tenant=acme",
+	}))
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	if got, want := cfg.Addr(), ":9000"; got != want {
+		t.Fatalf("Addr() = %q, want %q", got, want)
+	}
+	if cfg.ReadTimeout != 10*time.Second || cfg.ShutdownTimeout != 30*time.Second {
+		t.Fatalf("ReadTimeout, ShutdownTimeout = %v, %v; want 10s, 30s", cfg.ReadTimeout, cfg.ShutdownTimeout)
+	}
+	if cfg.Level != "debug" || cfg.Format != "json" {
+		t.Fatalf("Level, Format = %q, %q; want debug, json", cfg.Level, cfg.Format)
+	}
+	if cfg.ServiceName != "orders" || cfg.Protocol != "grpc" {
+		t.Fatalf("ServiceName, Protocol = %q, %q; want orders, grpc", cfg.ServiceName, cfg.Protocol)
+	}
+
+	want := map[string]string{"api-key": "secret", "tenant": "acme"}
+	if got := cfg.HeaderMap(); !cmp.Equal(want, got) {
+		t.Fatalf("HeaderMap() = %v, want = %v\n\n%s", got, want, cmp.Diff(want, got))
+	}
+
+	if _, err := envi.New[config](envi.WithSource(envi.Map{"PORT": "0"})); err == nil {
+		t.Fatalf("New() should fail for port 0")
+	}
+}
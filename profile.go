@@ -0,0 +1,26 @@
+package envi
+
+// WithProfile selects the profile whose defaults apply. Fields fall back to
+// the value of their `default.<profile>` tag if they have one, and to their
+// `default.<GOOS>` or `default` tag otherwise:
+//
+//	type Env struct {
+//		LogLevel string `env:"LOG_LEVEL" default:"debug" default.prod:"info"`
+//	}
+//
+//	err := envi.Parse(&env, envi.WithProfile("prod"))
+func WithProfile(profile string) Option {
+	return func(p *parser) {
+		p.profile = profile
+	}
+}
+
+// defaultOf returns the default value of field for the selected profile.
+func (p *parser) defaultOf(field *fieldSchema) (string, bool) {
+	if p.profile != "" {
+		if def, ok := field.tag.Lookup("default." + p.profile); ok {
+			return def, true
+		}
+	}
+	return field.def, field.hasDefault
+}
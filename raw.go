@@ -0,0 +1,150 @@
+package envi
+
+import (
+	"net/url"
+	"reflect"
+	"strings"
+)
+
+// WithRawValues records the raw value of every variable that is read while
+// parsing in values, keyed by the variable, e.g. for debugging and "effective
+// config" endpoints. This includes the variables of `defaultFrom` tags,
+// aliases, conditions and expanded references. The values of variables that
+// are bound to fields with a `secret` tag, the variables they refer to with
+// WithIndirection, the password variables of `dsn` tags and the variable of
+// WithBlob are redacted, as are the passwords in the URLs of URL, DSN and
+// RedisURL fields. values must not be nil.
+func WithRawValues(values map[string]string) Option {
+	return func(p *parser) {
+		p.raw = values
+	}
+}
+
+// recordRaw records the raw value of the variable with the given key if
+// WithRawValues is used.
+func (p *parser) recordRaw(key, value string) {
+	if p.raw == nil {
+		return
+	}
+
+	secrets := p.secretKeys()
+	switch {
+	case secrets.secret(key) || key == p.blob:
+		value = redacted
+	case secrets.urls[key]:
+		value = redactURL(value)
+	}
+
+	p.raw[key] = value
+}
+
+// secretKeys returns the keys whose raw values are redacted, collecting them
+// on first use.
+func (p *parser) secretKeys() *declaredKeys {
+	if p.secrets == nil {
+		p.secrets = &declaredKeys{keys: make(map[string]bool), urls: make(map[string]bool)}
+		if p.root != nil && p.root.Kind() == reflect.Pointer && p.root.Elem().Kind() == reflect.Struct {
+			p.secrets.collectSecrets(schemaOf(p.root.Elem()))
+		}
+		p.secrets.addAliases(p.aliases)
+	}
+	return p.secrets
+}
+
+// referRaw marks the variable ref, which the variable key refers to, as
+// redacted like key, so the value of a secret is not recorded under the key
+// of its target.
+func (p *parser) referRaw(key, ref string) {
+	if p.raw == nil {
+		return
+	}
+
+	secrets := p.secretKeys()
+	if secrets.secret(key) || key == p.blob {
+		secrets.keys[ref] = true
+	}
+	if secrets.urls[key] {
+		secrets.urls[ref] = true
+	}
+}
+
+// secret reports whether key is one of the keys of d, or has one of its
+// prefixes.
+func (d *declaredKeys) secret(key string) bool {
+	if d.keys[key] {
+		return true
+	}
+	for _, prefix := range d.prefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactURL replaces the password of value with "xxxxx" if value is a URL.
+func redactURL(value string) string {
+	u, err := url.Parse(value)
+	if err != nil || u.User == nil {
+		return value
+	}
+	return u.Redacted()
+}
+
+// redacterType is the interface of types that can format themselves without
+// secrets, such as DSN and RedisURL.
+var redacterType = reflect.TypeOf((*interface{ Redacted() string })(nil)).Elem()
+
+// isURLType reports whether values of type t are URLs that may contain a
+// password.
+func isURLType(t reflect.Type) bool {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t == urlType || t.Implements(redacterType) || reflect.PointerTo(t).Implements(redacterType)
+}
+
+// collectSecrets collects the keys and map prefixes of the variables of the
+// fields of the struct with the given schema that are redacted.
+func (d *declaredKeys) collectSecrets(schema *structSchema) {
+	for n := range schema.fields {
+		field := &schema.fields[n]
+		secret := field.secret
+		switch {
+		case field.recursive || !field.settable():
+		case field.isStruct:
+			d.collectSecrets(field.nested())
+		case !field.hasKey:
+		case isPrefixMap(field.typ):
+			if !secret {
+				continue
+			}
+			prefix := field.key
+			if prefix != "" {
+				prefix += "_"
+			}
+			d.prefixes = append(d.prefixes, prefix)
+		default:
+			if field.dsn != "" {
+				d.keys[field.dsn+"_PASSWORD"] = true
+			}
+			keys := append(append([]string{field.key}, field.aliases...), field.defaultFrom...)
+			if !secret {
+				if isURLType(field.typ) {
+					for _, key := range keys {
+						d.urls[key] = true
+					}
+				}
+				continue
+			}
+			for _, key := range keys {
+				d.keys[key] = true
+			}
+			if field.dsn != "" {
+				for _, suffix := range dsnVariables {
+					d.keys[field.dsn+"_"+suffix] = true
+				}
+			}
+		}
+	}
+}
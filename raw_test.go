@@ -0,0 +1,82 @@
+package envi_test
+
+import (
+	"testing"
+
+	"github.com/bounoable/envi"
+	"github.com/google/go-cmp/cmp"
+)
+
+// TestWithRawValues verifies that the raw values of all variables that were
+// read are recorded, with secrets redacted.
+func TestWithRawValues(t *testing.T) {
+	type rawEnv struct {
+		Host     string            `env:"RAW_HOST"`
+		Port     int               `env:"RAW_PORT" default:"8080"`
+		URL      string            `env:"RAW_URL"`
+		Token    string            `env:"RAW_TOKEN" defaultFrom:"RAW_LEGACY_TOKEN" secret:"true"`
+		Database envi.DSN          `env:"RAW_DATABASE_URL" dsn:"RAW_DB"`
+		Keys     map[string]string `env:"RAW_KEY" secret:"true"`
+	}
+
+	raw := make(map[string]string)
+	_, err := envi.New[rawEnv](envi.WithSource(envi.Map{
+		"RAW_HOST":         "localhost",
+		"RAW_URL":          "http://${RAW_HOST}",
+		"RAW_LEGACY_TOKEN": "token",
+		"RAW_DB_SCHEME":    "postgres",
+		"RAW_DB_HOST":      "db",
+		"RAW_DB_PASSWORD":  "password",
+		"RAW_KEY_A":        "a",
+		"RAW_UNUSED":       "unused",
+	}), envi.WithExpand(), envi.WithRawValues(raw))
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	want := map[string]string{
+		"RAW_HOST":         "localhost",
+		"RAW_URL":          "http://${RAW_HOST}",
+		"RAW_LEGACY_TOKEN": "<redacted>",
+		"RAW_DB_SCHEME":    "postgres",
+		"RAW_DB_HOST":      "db",
+		"RAW_DB_PASSWORD":  "<redacted>",
+		"RAW_KEY_A":        "<redacted>",
+	}
+	if !cmp.Equal(want, raw) {
+		t.Fatalf("raw values = %v, want = %v\n\n%s", raw, want, cmp.Diff(want, raw))
+	}
+}
+
+// TestWithRawValues_redaction verifies that the targets of references from
+// secret variables and the passwords in URLs are redacted.
+func TestWithRawValues_redaction(t *testing.T) {
+	type rawEnv struct {
+		Password string         `env:"RAW_PASSWORD" secret:"true"`
+		Database envi.DSN       `env:"RAW_DATABASE_URL"`
+		Redis    *envi.RedisURL `env:"RAW_REDIS_URL"`
+	}
+
+	raw := make(map[string]string)
+	_, err := envi.New[rawEnv](envi.WithSource(envi.Map{
+		"RAW_PASSWORD":     "@RAW_SHARED",
+		"RAW_SHARED":       "topsecret",
+		"RAW_DATABASE_URL": "@RAW_POSTGRES_URL",
+		"RAW_POSTGRES_URL": "postgres://app:hunter2@db:5432/app",
+		"RAW_REDIS_URL":    "redis://:hunter2@cache:6379/0",
+	}), envi.WithIndirection(), envi.WithRawValues(raw))
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	want := map[string]string{
+		"RAW_PASSWORD":     "<redacted>",
+		"RAW_SHARED":       "<redacted>",
+		"RAW_DATABASE_URL": "@RAW_POSTGRES_URL",
+		"RAW_POSTGRES_URL": "postgres://app:xxxxx@db:5432/app",
+		"RAW_REDIS_URL":    "redis://:xxxxx@cache:6379/0",
+	}
+	if !cmp.Equal(want, raw) {
+		t.Fatalf("raw values = %v, want = %v\n\n%s", raw, want, cmp.Diff(want, raw))
+	}
+}
@@ -0,0 +1,120 @@
+package envi
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"sync"
+)
+
+// sourceFactories holds the registered SourceFactories, keyed by scheme.
+var sourceFactories sync.Map
+
+// SourceFactory creates a Source from a URL with the scheme that the factory
+// was registered for.
+type SourceFactory func(ctx context.Context, u *url.URL) (Source, error)
+
+// RegisterSource registers the factory of the Sources for the given URL
+// scheme, so that packages can provide Sources, such as for secret managers,
+// without envi depending on them:
+//
+//	func init() {
+//		envi.RegisterSource("doppler", func(ctx context.Context, u *url.URL) (envi.Source, error) {
+//			return doppler.New(u.Host, u.Path)
+//		})
+//	}
+//
+// Sources of registered schemes are opened by OpenSource, and resolve
+// references in values with WithReferences. Registering a scheme again
+// replaces its factory.
+func RegisterSource(scheme string, factory SourceFactory) {
+	sourceFactories.Store(scheme, factory)
+}
+
+// OpenSource opens the Source for the given URL with the SourceFactory that
+// is registered for its scheme, e.g. to configure sources by URL:
+//
+//	vault, err := envi.OpenSource(ctx, os.Getenv("SECRETS_URL"))
+func OpenSource(ctx context.Context, rawURL string) (Source, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("open source: %w", err)
+	}
+	factory, ok := sourceFactory(u.Scheme)
+	if !ok {
+		return nil, fmt.Errorf("open source: no source registered for scheme %q (registered: %v)", u.Scheme, registeredSchemes())
+	}
+
+	s, err := factory(ctx, u)
+	if err != nil {
+		return nil, fmt.Errorf("open %s source: %w", u.Scheme, err)
+	}
+	return s, nil
+}
+
+// WithReferences resolves values that are URLs with the scheme of a
+// registered SourceFactory, e.g. "op://vault/item/field", with a Source of
+// the scheme. The Source is opened once per scheme with a URL that has only
+// the scheme, and the value is looked up as its key. References whose Source
+// doesn't have the key are unset, so defaults apply.
+func WithReferences() Option {
+	return func(p *parser) {
+		p.references = &references{sources: make(map[string]Source)}
+	}
+}
+
+// references holds the Sources that resolve references in values, keyed by
+// scheme, once they have been opened.
+type references struct {
+	mux     sync.Mutex
+	sources map[string]Source
+}
+
+// resolveReference resolves value, the value of the variable with the given
+// key, if it is a reference; see WithReferences.
+func (p *parser) resolveReference(key, value string) (string, bool, error) {
+	u, err := url.Parse(value)
+	if err != nil || u.Scheme == "" || u.Opaque != "" {
+		return value, true, nil
+	}
+	factory, ok := sourceFactory(u.Scheme)
+	if !ok {
+		return value, true, nil
+	}
+
+	p.references.mux.Lock()
+	s, ok := p.references.sources[u.Scheme]
+	if !ok {
+		if s, err = factory(p.ctx, &url.URL{Scheme: u.Scheme}); err != nil {
+			p.references.mux.Unlock()
+			return "", false, fmt.Errorf("resolve %q: open %s source: %w", key, u.Scheme, err)
+		}
+		p.references.sources[u.Scheme] = s
+	}
+	p.references.mux.Unlock()
+
+	v, ok, err := s.Lookup(p.ctx, value)
+	if err != nil {
+		return "", false, fmt.Errorf("resolve %q: %w", key, err)
+	}
+	return v, ok, nil
+}
+
+func sourceFactory(scheme string) (SourceFactory, bool) {
+	f, ok := sourceFactories.Load(scheme)
+	if !ok {
+		return nil, false
+	}
+	return f.(SourceFactory), true
+}
+
+func registeredSchemes() []string {
+	var schemes []string
+	sourceFactories.Range(func(k, _ any) bool {
+		schemes = append(schemes, k.(string))
+		return true
+	})
+	sort.Strings(schemes)
+	return schemes
+}
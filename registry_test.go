@@ -0,0 +1,91 @@
+package envi_test
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+
+	"github.com/bounoable/envi"
+	"github.com/google/go-cmp/cmp"
+)
+
+func init() {
+	envi.RegisterSource("fakestore", func(_ context.Context, u *url.URL) (envi.Source, error) {
+		if u.Query().Get("fail") != "" {
+			return nil, errors.New("unauthorized")
+		}
+		// Opened by URL, the store provides the variables of its project;
+		// opened for references, it resolves them by path.
+		if u.Host != "" {
+			return envi.Map{"REGISTRY_HOST": u.Host + ".internal"}, nil
+		}
+		return envi.Map{
+			"fakestore://prod/db/password": "s3cr3t",
+			"fakestore://prod/db/port":     "5432",
+		}, nil
+	})
+}
+
+// TestOpenSource verifies that Sources are opened by URL with the factory
+// that is registered for the scheme.
+func TestOpenSource(t *testing.T) {
+	ctx := context.Background()
+
+	s, err := envi.OpenSource(ctx, "fakestore://billing")
+	if err != nil {
+		t.Fatalf("OpenSource() failed: %v", err)
+	}
+	var e struct {
+		Host string `env:"REGISTRY_HOST"`
+	}
+	if err := envi.Parse(&e, envi.WithSource(s)); err != nil {
+		t.Fatalf("Parse() failed: %v", err)
+	}
+	if e.Host != "billing.internal" {
+		t.Fatalf("Host = %q, want %q", e.Host, "billing.internal")
+	}
+
+	for _, u := range []string{"unknown://billing", "fakestore://billing?fail=1", "://"} {
+		if _, err := envi.OpenSource(ctx, u); err == nil {
+			t.Fatalf("OpenSource(%q) should fail", u)
+		}
+	}
+}
+
+// TestWithReferences verifies that values that are URLs with a registered
+// scheme are resolved by the Source of the scheme.
+func TestWithReferences(t *testing.T) {
+	type referencesEnv struct {
+		Password string `env:"REGISTRY_PASSWORD"`
+		Port     int    `env:"REGISTRY_PORT"`
+		User     string `env:"REGISTRY_USER" default:"admin"`
+		URL      string `env:"REGISTRY_URL"`
+	}
+
+	vars := envi.Map{
+		"REGISTRY_PASSWORD": "fakestore://prod/db/password",
+		"REGISTRY_PORT":     "fakestore://prod/db/port",
+		"REGISTRY_USER":     "fakestore://prod/db/user",
+		"REGISTRY_URL":      "https://example.com",
+	}
+
+	e, err := envi.New[referencesEnv](envi.WithSource(vars), envi.WithReferences())
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	want := referencesEnv{Password: "s3cr3t", Port: 5432, User: "admin", URL: "https://example.com"}
+	if !cmp.Equal(want, e) {
+		t.Fatalf("env = %v, want = %v\n\n%s", e, want, cmp.Diff(want, e))
+	}
+
+	var unresolved struct {
+		Password string `env:"REGISTRY_PASSWORD"`
+	}
+	if err := envi.Parse(&unresolved, envi.WithSource(vars)); err != nil {
+		t.Fatalf("Parse() failed: %v", err)
+	}
+	if unresolved.Password != vars["REGISTRY_PASSWORD"] {
+		t.Fatalf("Password = %q, want unresolved reference without WithReferences", unresolved.Password)
+	}
+}
@@ -0,0 +1,99 @@
+package envi
+
+import (
+	"fmt"
+	"strings"
+	"text/tabwriter"
+)
+
+// Report describes how the fields of a struct were resolved by Parse.
+type Report struct {
+	Fields []FieldReport
+}
+
+// FieldReport describes how the value of a single field was resolved.
+type FieldReport struct {
+	// Field is the path of the field, e.g. "Database.URL".
+	Field string
+
+	// Keys are the environment variables that were consulted, or the prefix
+	// of the variables of a map field.
+	Keys []string
+
+	// From is the variable that provided the value if it is not the first of
+	// Keys, i.e. a deprecated alias or a variable of the `defaultFrom` tag.
+	From string
+
+	// Found reports whether a variable was set in any Source.
+	Found bool
+
+	// Source is the Source that provided the variable, or nil if the
+	// variable was not set or the field is a map.
+	Source Source
+
+	// Default reports whether the value of the field was taken from its
+	// `default` or `defaultExpr` tag.
+	Default bool
+
+	// Value is the formatted final value of the field. It is redacted for
+	// fields with a `secret` tag and the fields of structs with one.
+	Value string
+}
+
+// WithReport fills r with a FieldReport for every field that is resolved from
+// a variable. Any previous content of r is discarded.
+func WithReport(r *Report) Option {
+	return func(p *parser) {
+		r.Fields = nil
+		p.report = r
+	}
+}
+
+// DryRun performs all lookups, conversions and validations of Parse for a
+// Config without populating one, e.g. to verify an environment before the
+// real process starts. It returns the Report of the fields that were resolved
+// and the first error, if any.
+func DryRun[Config any](opts ...Option) (Report, error) {
+	var (
+		env    Config
+		report Report
+	)
+	err := Parse(&env, append(opts[:len(opts):len(opts)], WithReport(&report))...)
+	return report, err
+}
+
+// String formats the report as a table, e.g. to print it at startup.
+func (r *Report) String() string {
+	var b strings.Builder
+	w := tabwriter.NewWriter(&b, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "FIELD\tKEY\tFOUND\tSOURCE\tDEFAULT\tVALUE")
+	for _, f := range r.Fields {
+		source := "-"
+		if f.Source != nil {
+			source = sourceName(f.Source)
+		}
+		key := strings.Join(f.Keys, ",")
+		if f.From != "" {
+			key += " (from " + f.From + ")"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%t\t%s\t%t\t%s\n", f.Field, key, f.Found, source, f.Default, f.Value)
+	}
+	w.Flush()
+	return b.String()
+}
+
+// sourceName returns a human-readable name of s: the result of its String
+// method if it has one, or its type otherwise.
+func sourceName(s Source) string {
+	if s, ok := s.(fmt.Stringer); ok {
+		return s.String()
+	}
+	switch s.(type) {
+	case osSource:
+		return "os"
+	case Map:
+		return "map"
+	default:
+		return fmt.Sprintf("%T", s)
+	}
+}
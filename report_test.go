@@ -0,0 +1,103 @@
+package envi_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bounoable/envi"
+	"github.com/google/go-cmp/cmp"
+)
+
+type reportEnv struct {
+	Host     string `env:"REPORT_HOST" default:"localhost"`
+	Port     int    `env:"REPORT_PORT"`
+	Password string `env:"REPORT_PASSWORD" secret:"true"`
+	Debug    bool   `env:"REPORT_DEBUG"`
+}
+
+// TestWithReport verifies that WithReport describes how every field was
+// resolved and that the report can be formatted as a table.
+func TestWithReport(t *testing.T) {
+	src := envi.Map{"REPORT_PORT": "8080", "REPORT_PASSWORD": "hunter2"}
+
+	var r envi.Report
+	if _, err := envi.New[reportEnv](envi.WithSource(src), envi.WithReport(&r)); err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	want := []envi.FieldReport{
+		{Field: "Host", Keys: []string{"REPORT_HOST"}, Default: true, Value: "localhost"},
+		{Field: "Port", Keys: []string{"REPORT_PORT"}, Found: true, Source: src, Value: "8080"},
+		{Field: "Password", Keys: []string{"REPORT_PASSWORD"}, Found: true, Source: src, Value: "<redacted>"},
+		{Field: "Debug", Keys: []string{"REPORT_DEBUG"}, Value: "false"},
+	}
+	if !cmp.Equal(want, r.Fields, sameSource) {
+		t.Fatalf("report = %v, want = %v\n\n%s", r.Fields, want, cmp.Diff(want, r.Fields, sameSource))
+	}
+
+	wantTable := "" +
+		"FIELD     KEY              FOUND  SOURCE  DEFAULT  VALUE\n" +
+		"Host      REPORT_HOST      false  -       true     localhost\n" +
+		"Port      REPORT_PORT      true   map     false    8080\n" +
+		"Password  REPORT_PASSWORD  true   map     false    <redacted>\n" +
+		"Debug     REPORT_DEBUG     false  -       false    false\n"
+	if table := r.String(); table != wantTable {
+		t.Fatalf("String() = \n%s\nwant\n%s", table, wantTable)
+	}
+
+	if _, err := envi.New[reportEnv](envi.WithSource(src), envi.WithReport(&r)); err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	if len(r.Fields) != len(want) {
+		t.Fatalf("report should be reset; got %d fields, want %d", len(r.Fields), len(want))
+	}
+}
+
+type secretReportEnv struct {
+	Host     string `env:"REPORT_HOST"`
+	Database struct {
+		User     string `env:"USER"`
+		Password string `env:"PASSWORD"`
+	} `envPrefix:"REPORT_DB_" secret:"true"`
+}
+
+// TestWithReport_nestedSecret verifies that the fields of structs with a
+// `secret` tag are redacted in reports.
+func TestWithReport_nestedSecret(t *testing.T) {
+	src := envi.Map{"REPORT_HOST": "localhost", "REPORT_DB_USER": "admin", "REPORT_DB_PASSWORD": "hunter2"}
+
+	var r envi.Report
+	if _, err := envi.New[secretReportEnv](envi.WithSource(src), envi.WithReport(&r)); err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	want := []envi.FieldReport{
+		{Field: "Host", Keys: []string{"REPORT_HOST"}, Found: true, Source: src, Value: "localhost"},
+		{Field: "Database.User", Keys: []string{"REPORT_DB_USER"}, Found: true, Source: src, Value: "<redacted>"},
+		{Field: "Database.Password", Keys: []string{"REPORT_DB_PASSWORD"}, Found: true, Source: src, Value: "<redacted>"},
+	}
+	if !cmp.Equal(want, r.Fields, sameSource) {
+		t.Fatalf("report = %v, want = %v\n\n%s", r.Fields, want, cmp.Diff(want, r.Fields, sameSource))
+	}
+	if table := r.String(); strings.Contains(table, "admin") || strings.Contains(table, "hunter2") {
+		t.Fatalf("String() should redact nested secrets; got\n%s", table)
+	}
+}
+
+// TestDryRun verifies that DryRun reports how the fields would be resolved and
+// fails like Parse for invalid values.
+func TestDryRun(t *testing.T) {
+	src := envi.Map{"REPORT_PORT": "8080"}
+
+	r, err := envi.DryRun[reportEnv](envi.WithSource(src))
+	if err != nil {
+		t.Fatalf("DryRun() failed: %v", err)
+	}
+	if len(r.Fields) != 4 || r.Fields[1].Value != "8080" || !r.Fields[1].Found {
+		t.Fatalf("DryRun() report = %+v; want 4 fields with Port found as 8080", r.Fields)
+	}
+
+	if _, err := envi.DryRun[reportEnv](envi.WithSource(envi.Map{"REPORT_PORT": "http"})); err == nil {
+		t.Fatalf("DryRun() should fail for an invalid port")
+	}
+}
@@ -0,0 +1,71 @@
+package envi
+
+import (
+	"reflect"
+	"strings"
+)
+
+// declaredKeys are the keys and map prefixes of the variables that the fields
+// of a struct, including nested structs, consume.
+type declaredKeys struct {
+	keys     map[string]bool
+	prefixes []string
+
+	// urls are the keys of URL-typed fields whose passwords are redacted;
+	// see WithRawValues.
+	urls map[string]bool
+}
+
+// consumed reports whether the variable with the given key is consumed by a
+// field of the parsed struct other than an `env:",rest"` map, or is the
+// variable of WithBlob.
+func (p *parser) consumed(key string) bool {
+	if p.declared == nil {
+		p.declared = &declaredKeys{keys: make(map[string]bool)}
+		if p.root != nil && p.root.Kind() == reflect.Pointer && p.root.Elem().Kind() == reflect.Struct {
+			p.declared.collect(schemaOf(p.root.Elem()))
+		}
+		p.declared.addAliases(p.aliases)
+	}
+
+	if p.declared.keys[key] || key == p.blob {
+		return true
+	}
+	for _, prefix := range p.declared.prefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *declaredKeys) collect(schema *structSchema) {
+	for n := range schema.fields {
+		field := &schema.fields[n]
+		switch {
+		case field.recursive || !field.settable():
+		case field.isStruct:
+			d.collect(field.nested())
+		case field.rest || !field.hasKey:
+		case isPrefixMap(field.typ):
+			prefix := field.key
+			if prefix != "" {
+				prefix += "_"
+			}
+			d.prefixes = append(d.prefixes, prefix)
+		default:
+			d.keys[field.key] = true
+			for _, key := range field.aliases {
+				d.keys[key] = true
+			}
+			for _, key := range field.defaultFrom {
+				d.keys[key] = true
+			}
+			if field.dsn != "" {
+				for _, suffix := range dsnVariables {
+					d.keys[field.dsn+"_"+suffix] = true
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package envi_test
+
+import (
+	"testing"
+
+	"github.com/bounoable/envi"
+	"github.com/google/go-cmp/cmp"
+)
+
+// TestParse_rest verifies that a map with an `env:",rest"` tag collects the
+// variables with its prefix that no other field consumes.
+func TestParse_rest(t *testing.T) {
+	type restEnv struct {
+		Host   string            `env:"APP_HOST"`
+		Port   int               `env:"APP_PORT" defaultFrom:"APP_LISTEN_PORT"`
+		Labels map[string]string `env:"APP_LABEL"`
+		DB     struct {
+			URL string `env:"APP_DB_URL"`
+		}
+		Extra map[string]string `env:"APP,rest"`
+	}
+
+	src := envi.Map{
+		"APP_HOST":         "localhost",
+		"APP_LISTEN_PORT":  "8080",
+		"APP_LABEL_TEAM":   "core",
+		"APP_DB_URL":       "postgres://localhost",
+		"APP_PLUGIN_TOKEN": "secret",
+		"APP_FEATURE":      "on",
+		"OTHER":            "ignored",
+	}
+
+	e, err := envi.New[restEnv](envi.WithSource(src))
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	want := map[string]string{"PLUGIN_TOKEN": "secret", "FEATURE": "on"}
+	if !cmp.Equal(want, e.Extra) {
+		t.Fatalf("Extra = %v, want = %v\n\n%s", e.Extra, want, cmp.Diff(want, e.Extra))
+	}
+}
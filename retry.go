@@ -0,0 +1,138 @@
+package envi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// RetryPolicy configures how a Source returned by WithRetry retries failed
+// lookups. The zero value retries up to 3 attempts with delays of 100ms and
+// 200ms.
+type RetryPolicy struct {
+	// Attempts is the maximum number of attempts, including the first one.
+	// Defaults to 3.
+	Attempts int
+
+	// Delay is the delay before the first retry. Defaults to 100ms.
+	Delay time.Duration
+
+	// MaxDelay caps the delay between attempts. Zero means no cap.
+	MaxDelay time.Duration
+
+	// Multiplier is the factor the delay grows by after each retry. Defaults
+	// to 2.
+	Multiplier float64
+
+	// Jitter randomizes each delay by up to the given fraction, e.g. 0.2 for
+	// ±20%, so that nodes which start at the same time don't retry in lockstep.
+	Jitter float64
+
+	// Retryable reports whether a lookup that failed with err should be
+	// retried, e.g. only for throttling and 5xx errors. By default, all
+	// errors are retried except cancellation of the context.
+	Retryable func(err error) bool
+}
+
+// WithRetry returns a Source that retries failed lookups in source with
+// exponential backoff, so transient errors of remote stores don't fail
+// startup:
+//
+//	envi.WithSource(envi.OS(), envi.WithRetry(vault, envi.RetryPolicy{Attempts: 5}))
+//
+// Retries stop when the context is done. To limit the time of each attempt,
+// wrap source with WithSourceTimeout first. The returned Source implements
+// Lister if source does.
+func WithRetry(source Source, policy RetryPolicy) Source {
+	if policy.Attempts <= 0 {
+		policy.Attempts = 3
+	}
+	if policy.Delay <= 0 {
+		policy.Delay = 100 * time.Millisecond
+	}
+	if policy.Multiplier < 1 {
+		policy.Multiplier = 2
+	}
+	if policy.Retryable == nil {
+		policy.Retryable = func(error) bool { return true }
+	}
+	return &retrySource{source: source, policy: policy}
+}
+
+type retrySource struct {
+	source Source
+	policy RetryPolicy
+}
+
+// Lookup implements Source.
+func (s *retrySource) Lookup(ctx context.Context, key string) (string, bool, error) {
+	var v string
+	var ok bool
+	err := s.retry(ctx, func() (err error) {
+		v, ok, err = s.source.Lookup(ctx, key)
+		return err
+	})
+	if err != nil {
+		return "", false, err
+	}
+	return v, ok, nil
+}
+
+// Keys implements Lister. It returns no keys if the wrapped Source does not
+// implement Lister.
+func (s *retrySource) Keys(ctx context.Context) ([]string, error) {
+	l, ok := s.source.(Lister)
+	if !ok {
+		return nil, nil
+	}
+
+	var keys []string
+	err := s.retry(ctx, func() (err error) {
+		keys, err = l.Keys(ctx)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// retry calls fn until it succeeds, fails with an error that is not
+// retryable, or the attempts of the policy are exhausted.
+func (s *retrySource) retry(ctx context.Context, fn func() error) error {
+	delay := s.policy.Delay
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil || errors.Is(err, context.Canceled) || !s.policy.Retryable(err) {
+			return err
+		}
+		if attempt >= s.policy.Attempts {
+			return fmt.Errorf("giving up after %d attempts: %w", attempt, err)
+		}
+
+		timer := time.NewTimer(s.policy.jitter(delay))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+
+		delay = time.Duration(float64(delay) * s.policy.Multiplier)
+		if s.policy.MaxDelay > 0 && delay > s.policy.MaxDelay {
+			delay = s.policy.MaxDelay
+		}
+	}
+}
+
+func (p RetryPolicy) jitter(d time.Duration) time.Duration {
+	if p.Jitter <= 0 {
+		return d
+	}
+	return time.Duration(float64(d) * (1 + p.Jitter*(2*rand.Float64()-1)))
+}
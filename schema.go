@@ -0,0 +1,392 @@
+package envi
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+// schemas caches the structSchema of every struct type parsed so far, keyed by
+// reflect.Type, so repeated Parse calls don't analyze the same struct again.
+var schemas sync.Map
+
+// structSchema is the analyzed field metadata of a struct type.
+type structSchema struct {
+	fields []fieldSchema
+
+	// exprs are the parsed `defaultExpr` tags, keyed by field index, and
+	// exprOrder is their evaluation order. exprErr is the error that occurred
+	// while parsing or ordering the expressions.
+	exprs     map[int]*template.Template
+	exprOrder []int
+	exprErr   error
+
+	// tagErr is the error of the first field with an unknown or misapplied
+	// option in its `env` tag.
+	tagErr error
+
+	// groups are the `xor` groups of the struct, in the order of their first
+	// field.
+	groups []xorGroup
+
+	// dataFields are the indices of the fields that are visible to
+	// `defaultExpr` tags.
+	dataFields []int
+}
+
+// fieldSchema is the analyzed metadata of a struct field.
+type fieldSchema struct {
+	index    int
+	name     string
+	typ      reflect.Type
+	tag      reflect.StructTag
+	exported bool
+
+	// embedded reports whether the field is an embedded field.
+	embedded bool
+
+	// isStruct reports whether the field is a struct or a pointer to a
+	// struct, which is parsed recursively; isPointer reports the latter.
+	isStruct  bool
+	isPointer bool
+
+	// recursive reports whether the struct of the field contains, directly or
+	// through nested structs, the struct that declares the field. Recursive
+	// fields are not parsed.
+	recursive bool
+
+	// prefix is the prefix of the keys of the struct of the field, which is
+	// the value of its `envPrefix` tag, preceded by the prefixes of the
+	// structs it is nested in.
+	prefix string
+
+	key    string
+	hasKey bool
+
+	// skip reports whether the field has an `env:"-"` tag, which excludes it
+	// and, for structs, its fields from parsing.
+	skip bool
+
+	// rest reports whether the field is a map with an `env:",rest"` tag,
+	// which collects the variables that no other field consumes.
+	rest bool
+
+	// squash reports whether the field is a struct with an `env:",squash"`
+	// tag, whose fields are treated as fields of the enclosing struct.
+	squash bool
+
+	// def is the value of the `default.<GOOS>` tag for the operating system
+	// that the program runs on, or of the `default` tag.
+	def        string
+	hasDefault bool
+
+	// defaultFrom are the keys of the `defaultFrom` tag, which are looked up
+	// in order if the variable of the field is not set.
+	defaultFrom []string
+
+	// aliases are the deprecated keys of the `aliases` tag, which are looked
+	// up in order if the variable of the field is not set.
+	aliases []string
+
+	deprecated   string
+	isDeprecated bool
+
+	// required reports whether the field has a `required` tag.
+	// requiredIf and requiredUnless are the conditions of the `required_if`
+	// and `required_unless` tags.
+	required       bool
+	requiredIf     []condition
+	requiredUnless []condition
+
+	// secret reports whether the field has a `secret` tag, which redacts
+	// its value in traces, reports and diffs. The fields of a struct field
+	// with a `secret` tag inherit it; see nested.
+	secret bool
+
+	// hasDefaultExpr reports whether the field has a `defaultExpr` tag.
+	hasDefaultExpr bool
+
+	// expandPath reports whether the field has a `path:"expand"` tag, which
+	// expands "~" and variables in its value and makes it absolute.
+	expandPath bool
+
+	// dsn is the prefix of the variables that a DSN field is assembled from
+	// if its variable is not set.
+	dsn string
+
+	// rules are the rules of the `validate` tag.
+	rules []rule
+
+	// transforms are the transformers of the `transform` tag, which are
+	// applied to the value before it is validated and converted.
+	transforms []rule
+
+	// init reports whether the field has an `init` tag, which allocates
+	// pointer fields that would otherwise be left nil.
+	init bool
+
+	// expand reports whether variable references in the value are expanded
+	// if WithExpand is used; it is false for fields with `expand:"false"`.
+	expand bool
+
+	// unescape is the value of the `unescape` tag, which overrides
+	// WithUnescape for the field if hasUnescape is set.
+	unescape    bool
+	hasUnescape bool
+
+	// lazy reports whether the field is a Lazy value, which is resolved on
+	// first access.
+	lazy bool
+
+	// wrapper reports whether the field is a Value, which records where its
+	// value came from.
+	wrapper bool
+
+	// unsupported reports whether the type of the field cannot be parsed
+	// from variables; see WithSkipUnsupported.
+	unsupported bool
+
+	// groups are the groups of the `group` tag, or nil if the field has no
+	// `group` tag.
+	groups []string
+
+	// list are the overrides of the list format of the tags of the field.
+	list listTags
+}
+
+// schemaOf returns the structSchema of the struct type t.
+func schemaOf(t reflect.Type) *structSchema {
+	if s, ok := schemas.Load(t); ok {
+		return s.(*structSchema)
+	}
+	s, _ := schemas.LoadOrStore(t, newStructSchema(t))
+	return s.(*structSchema)
+}
+
+// prefixedSchemas caches the structSchemas of struct types whose keys have a
+// prefix or whose fields are secret, keyed by prefixedType.
+var prefixedSchemas sync.Map
+
+type prefixedType struct {
+	typ    reflect.Type
+	prefix string
+	secret bool
+}
+
+// prefixedSchemaOf returns the structSchema of the struct type t, with prefix
+// prepended to the keys of its fields. If secret is set, all fields of the
+// struct are secret, as are the fields of its nested structs.
+func prefixedSchemaOf(t reflect.Type, prefix string, secret bool) *structSchema {
+	if prefix == "" && !secret {
+		return schemaOf(t)
+	}
+
+	key := prefixedType{typ: t, prefix: prefix, secret: secret}
+	if s, ok := prefixedSchemas.Load(key); ok {
+		return s.(*structSchema)
+	}
+	s, _ := prefixedSchemas.LoadOrStore(key, withPrefix(schemaOf(t), prefix, secret))
+	return s.(*structSchema)
+}
+
+// withPrefix returns a copy of s with prefix prepended to the keys of its
+// fields, including the keys that fields default to, the prefixes of DSN
+// fields and the keys of conditions. If secret is set, the fields of the copy
+// are secret.
+func withPrefix(s *structSchema, prefix string, secret bool) *structSchema {
+	out := *s
+	out.fields = make([]fieldSchema, len(s.fields))
+	copy(out.fields, s.fields)
+
+	prefixed := func(keys []string) []string {
+		return mapSlice(keys, func(key string) string { return prefix + key })
+	}
+	conditions := func(conds []condition) []condition {
+		return mapSlice(conds, func(c condition) condition {
+			c.key = prefix + c.key
+			return c
+		})
+	}
+
+	for n := range out.fields {
+		field := &out.fields[n]
+		field.secret = field.secret || secret
+		switch {
+		case field.isStruct:
+			field.prefix = prefix + field.prefix
+			continue
+		case field.rest:
+		case field.key == "" && isPrefixMap(field.typ):
+			field.key = strings.TrimSuffix(prefix, "_")
+		case field.hasKey:
+			field.key = prefix + field.key
+		}
+		if field.dsn != "" {
+			field.dsn = prefix + field.dsn
+		}
+		field.defaultFrom = prefixed(field.defaultFrom)
+		field.aliases = prefixed(field.aliases)
+		field.requiredIf = conditions(field.requiredIf)
+		field.requiredUnless = conditions(field.requiredUnless)
+	}
+
+	return &out
+}
+
+// nested returns the schema of the struct of the struct field f, whose keys
+// have the prefix of f. The fields of the struct of a secret field are
+// secret, so traces, reports, diffs and raw values redact them alike.
+func (f *fieldSchema) nested() *structSchema {
+	return prefixedSchemaOf(structType(f.typ), f.prefix, f.secret)
+}
+
+// nestedPath returns the dot-separated path of the fields of the struct of
+// the struct field f, given the path of f's struct. The fields of squashed
+// structs have the path of the enclosing struct.
+func (f *fieldSchema) nestedPath(path string) string {
+	if f.squash {
+		return path
+	}
+	return path + f.name + "."
+}
+
+func newStructSchema(t reflect.Type) *structSchema {
+	s := structSchema{fields: make([]fieldSchema, t.NumField())}
+
+	for n := 0; n < t.NumField(); n++ {
+		field := t.Field(n)
+		fs := fieldSchema{
+			index:    n,
+			name:     field.Name,
+			typ:      field.Type,
+			tag:      field.Tag,
+			exported: field.IsExported(),
+			embedded: field.Anonymous,
+		}
+		fs.isStruct, fs.isPointer = isStruct(field.Type)
+		fs.recursive = fs.isStruct && refersTo(structType(field.Type), t, map[reflect.Type]bool{})
+		if fs.isStruct {
+			fs.prefix = field.Tag.Get("envPrefix")
+		}
+		fs.lazy = isLazy(field.Type)
+		fs.wrapper = isWrapper(field.Type)
+		fs.unsupported = isUnsupported(field.Type)
+		fs.key, fs.hasKey = field.Tag.Lookup("env")
+		if key, opts, ok := strings.Cut(fs.key, ","); ok {
+			fs.key = key
+			fs.rest = opts == "rest" && isPrefixMap(field.Type)
+			fs.squash = opts == "squash" && fs.isStruct
+			if err := checkTagOption(opts, fs.rest, fs.squash); err != nil && s.tagErr == nil {
+				s.tagErr = fmt.Errorf("env tag of %q field: %w", field.Name, err)
+			}
+		}
+		if fs.key == "-" {
+			fs.key, fs.hasKey, fs.skip = "", false, true
+		}
+		fs.def, fs.hasDefault = field.Tag.Lookup("default")
+		if def, ok := field.Tag.Lookup("default." + runtime.GOOS); ok {
+			fs.def, fs.hasDefault = def, true
+		}
+		if from, ok := field.Tag.Lookup("defaultFrom"); ok {
+			fs.defaultFrom = mapSlice(strings.Split(from, ","), strings.TrimSpace)
+		}
+		if aliases, ok := field.Tag.Lookup("aliases"); ok {
+			fs.aliases = mapSlice(strings.Split(aliases, ","), strings.TrimSpace)
+		}
+		fs.deprecated, fs.isDeprecated = field.Tag.Lookup("deprecated")
+		fs.secret = boolTag(field.Tag, "secret")
+		fs.required = boolTag(field.Tag, "required")
+		fs.requiredIf = parseConditions(field.Tag.Get("required_if"))
+		fs.requiredUnless = parseConditions(field.Tag.Get("required_unless"))
+		_, fs.hasDefaultExpr = field.Tag.Lookup("defaultExpr")
+		fs.expand = expandTag(field.Tag.Lookup("expand"))
+		fs.init = boolTag(field.Tag, "init")
+		_, fs.hasUnescape = field.Tag.Lookup("unescape")
+		fs.unescape = boolTag(field.Tag, "unescape")
+		fs.expandPath = field.Tag.Get("path") == "expand"
+		fs.rules = parseRules(field.Tag.Get("validate"))
+		fs.transforms = parseRules(field.Tag.Get("transform"))
+		fs.dsn = field.Tag.Get("dsn")
+		fs.list = parseListTags(field.Tag)
+		if groups, ok := field.Tag.Lookup("group"); ok {
+			fs.groups = mapSlice(strings.Split(groups, ","), strings.TrimSpace)
+		}
+		s.fields[n] = fs
+
+		if !field.IsExported() {
+			continue
+		}
+		if k := field.Type.Kind(); k == reflect.Func || k == reflect.Chan || k == reflect.UnsafePointer {
+			continue
+		}
+		s.dataFields = append(s.dataFields, n)
+	}
+
+	s.groups = xorGroups(s.fields, t)
+	s.exprs, s.exprOrder, s.exprErr = parseDefaultExprs(t)
+
+	return &s
+}
+
+// checkTagOption returns an error if opts, the options of an `env` tag, are
+// unknown or don't apply to the field, given whether the field is a rest map
+// or squashed struct.
+func checkTagOption(opts string, rest, squash bool) error {
+	switch {
+	case opts != "rest" && opts != "squash":
+		return fmt.Errorf("unknown option %q", opts)
+	case opts == "rest" && !rest:
+		return errors.New(`option "rest" requires a map field`)
+	case opts == "squash" && !squash:
+		return errors.New(`option "squash" requires a struct field`)
+	}
+	return nil
+}
+
+// boolTag reports whether the struct tag has the given key and its value is
+// empty or parses as true, e.g. `secret:""` or `secret:"true"`.
+func boolTag(tag reflect.StructTag, key string) bool {
+	v, ok := tag.Lookup(key)
+	if !ok {
+		return false
+	}
+	if v == "" {
+		return true
+	}
+	b, _ := strconv.ParseBool(v)
+	return b
+}
+
+// structType returns the struct type of a struct or pointer-to-struct type t.
+func structType(t reflect.Type) reflect.Type {
+	if t.Kind() == reflect.Pointer {
+		return t.Elem()
+	}
+	return t
+}
+
+// refersTo reports whether the struct type t is target or has a field that is
+// parsed as a nested struct which refers to target. seen holds the types that
+// were already visited.
+func refersTo(t, target reflect.Type, seen map[reflect.Type]bool) bool {
+	if t == target {
+		return true
+	}
+	if seen[t] {
+		return false
+	}
+	seen[t] = true
+
+	for n := 0; n < t.NumField(); n++ {
+		ft := t.Field(n).Type
+		if ok, _ := isStruct(ft); ok && refersTo(structType(ft), target, seen) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,172 @@
+package envi_test
+
+import (
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bounoable/envi"
+	"github.com/google/go-cmp/cmp"
+)
+
+type schemaEnv struct {
+	Host    string            `env:"SCHEMA_HOST" default:"localhost"`
+	Port    int               `env:"SCHEMA_PORT"`
+	Addr    string            `defaultExpr:"{{.Host}}:{{.Port}}"`
+	Timeout time.Duration     `env:"SCHEMA_TIMEOUT"`
+	Labels  map[string]string `env:"SCHEMA_LABEL"`
+	Nested  struct {
+		Debug bool `env:"SCHEMA_DEBUG"`
+	}
+}
+
+// TestParse_repeated tests that parsing the same struct type repeatedly and
+// concurrently yields the same result every time.
+func TestParse_repeated(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("SCHEMA_PORT", "8080")
+	os.Setenv("SCHEMA_TIMEOUT", "3s")
+	os.Setenv("SCHEMA_LABEL_TEAM", "core")
+	os.Setenv("SCHEMA_DEBUG", "true")
+
+	var want schemaEnv
+	want.Host = "localhost"
+	want.Port = 8080
+	want.Addr = "localhost:8080"
+	want.Timeout = 3 * time.Second
+	want.Labels = map[string]string{"TEAM": "core"}
+	want.Nested.Debug = true
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 16)
+	results := make(chan schemaEnv, 16)
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			env, err := envi.New[schemaEnv]()
+			if err != nil {
+				errs <- err
+				return
+			}
+			results <- env
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	close(results)
+
+	for err := range errs {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	for env := range results {
+		if !cmp.Equal(want, env) {
+			t.Fatalf("env = %v, want = %v\n\n%s", env, want, cmp.Diff(want, env))
+		}
+	}
+}
+
+func BenchmarkParse(b *testing.B) {
+	os.Clearenv()
+	os.Setenv("SCHEMA_PORT", "8080")
+	os.Setenv("SCHEMA_LABEL_TEAM", "core")
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var env schemaEnv
+		if err := envi.Parse(&env); err != nil {
+			b.Fatalf("Parse() failed: %v", err)
+		}
+	}
+}
+
+type benchmarkEnv struct {
+	Host     string        `env:"BENCH_HOST"`
+	Port     int           `env:"BENCH_PORT"`
+	Debug    bool          `env:"BENCH_DEBUG"`
+	Ratio    float64       `env:"BENCH_RATIO"`
+	Timeout  time.Duration `env:"BENCH_TIMEOUT"`
+	Hosts    []string      `env:"BENCH_HOSTS"`
+	Ports    []uint16      `env:"BENCH_PORTS" sep:";"`
+	Retries  *int          `env:"BENCH_RETRIES"`
+	Region   string        `env:"BENCH_REGION" default:"eu"`
+	Database struct {
+		URL   string `env:"BENCH_DB_URL"`
+		Conns int    `env:"BENCH_DB_CONNS"`
+	}
+}
+
+// BenchmarkParse_values measures the conversion of values of common types
+// from a Map source, without map fields or expressions.
+func BenchmarkParse_values(b *testing.B) {
+	source := envi.WithSource(envi.Map{
+		"BENCH_HOST":     "example.com",
+		"BENCH_PORT":     "8080",
+		"BENCH_DEBUG":    "true",
+		"BENCH_RATIO":    "0.5",
+		"BENCH_TIMEOUT":  "5s",
+		"BENCH_HOSTS":    "a,b,c",
+		"BENCH_PORTS":    "80;443",
+		"BENCH_RETRIES":  "3",
+		"BENCH_DB_URL":   "postgres://localhost",
+		"BENCH_DB_CONNS": "10",
+	})
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var env benchmarkEnv
+		if err := envi.Parse(&env, source); err != nil {
+			b.Fatalf("Parse() failed: %v", err)
+		}
+	}
+}
+
+type recursiveNode struct {
+	Name string `env:"RECURSIVE_NAME"`
+	Next *recursiveNode
+}
+
+type recursiveParent struct {
+	Host  string `env:"RECURSIVE_HOST"`
+	Child recursiveChild
+}
+
+type recursiveChild struct {
+	Port   int `env:"RECURSIVE_PORT"`
+	Parent *recursiveParent
+}
+
+// TestParse_recursive tests that struct types which contain themselves,
+// directly or through other structs, fail to parse instead of recursing
+// indefinitely, and that the other fields of such types can still be listed.
+func TestParse_recursive(t *testing.T) {
+	os.Clearenv()
+
+	if _, err := envi.New[recursiveNode](); err == nil || !strings.Contains(err.Error(), "recursive struct type") {
+		t.Fatalf("New() should fail for a self-referencing struct; got %v", err)
+	}
+	if _, err := envi.New[recursiveParent](); err == nil || !strings.Contains(err.Error(), `"Child" field`) {
+		t.Fatalf("New() should fail for mutually referencing structs; got %v", err)
+	}
+
+	problems := envi.Check[recursiveParent]()
+	if len(problems) != 1 || problems[0].Field != "Child" {
+		t.Fatalf("Check() = %v, want a problem for Child", problems)
+	}
+
+	var keys []string
+	for _, v := range envi.Variables[recursiveParent]() {
+		keys = append(keys, v.Key)
+	}
+	if want := []string{"RECURSIVE_HOST"}; !cmp.Equal(want, keys) {
+		t.Fatalf("Variables() = %v, want = %v", keys, want)
+	}
+
+	var env recursiveNode
+	if flags := envi.Flags(&env); len(flags) != 1 {
+		t.Fatalf("Flags() returned %d flags, want 1", len(flags))
+	}
+}
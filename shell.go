@@ -0,0 +1,264 @@
+package envi
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// shellScript parses the ShellScript dialect.
+type shellScript struct {
+	s    string
+	i    int
+	line int
+
+	// vars are the variables assigned so far, which are expanded in later
+	// values.
+	vars map[string]string
+	out  []DotEnvVar
+}
+
+// shellWord is a word of a shell command. name is the name of the variable
+// that is assigned if the word is an assignment.
+type shellWord struct {
+	line   int
+	name   string
+	value  string
+	assign bool
+}
+
+func parseShellScript(r io.Reader) ([]DotEnvVar, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	sh := shellScript{s: string(data), line: 1, vars: make(map[string]string)}
+	for sh.i < len(sh.s) {
+		start := sh.line
+		words, err := sh.command()
+		if err == nil {
+			err = sh.run(words)
+		}
+		if err != nil {
+			return sh.out, fmt.Errorf("line %d: %w", start, err)
+		}
+	}
+
+	return sh.out, nil
+}
+
+// run evaluates the words of a command, which is either a list of
+// assignments or an export command.
+func (sh *shellScript) run(words []shellWord) error {
+	if len(words) == 0 {
+		return nil
+	}
+
+	export := !words[0].assign
+	if export {
+		if words[0].value != "export" {
+			return fmt.Errorf("unsupported command %q", words[0].value)
+		}
+		words = words[1:]
+	}
+
+	for _, w := range words {
+		switch {
+		case w.assign:
+			sh.out = append(sh.out, DotEnvVar{Key: w.name, Value: w.value, Line: w.line})
+		case !export:
+			return fmt.Errorf("unsupported command %q", w.value)
+		case !validEnvName(w.value):
+			// "export NAME" exports a variable that is already set.
+			return fmt.Errorf("unsupported argument %q", w.value)
+		}
+	}
+
+	return nil
+}
+
+// command parses the words of the command at the current position, up to the
+// end of the line or a semicolon.
+func (sh *shellScript) command() ([]shellWord, error) {
+	var words []shellWord
+	for sh.i < len(sh.s) {
+		switch c := sh.s[sh.i]; {
+		case c == '\n':
+			sh.i++
+			sh.line++
+			return words, nil
+		case c == ';':
+			sh.i++
+			return words, nil
+		case c == ' ' || c == '\t' || c == '\r':
+			sh.i++
+		case c == '\\' && sh.i+1 < len(sh.s) && sh.s[sh.i+1] == '\n':
+			sh.i += 2
+			sh.line++
+		case c == '#':
+			for sh.i < len(sh.s) && sh.s[sh.i] != '\n' {
+				sh.i++
+			}
+		default:
+			w := shellWord{line: sh.line}
+			if name, ok := sh.assignment(len(words) == 0 || words[0].assign || words[0].value == "export"); ok {
+				w.name, w.assign = name, true
+			}
+			var err error
+			if w.value, err = sh.word(); err != nil {
+				return nil, err
+			}
+			if w.assign {
+				sh.vars[w.name] = w.value
+			}
+			words = append(words, w)
+		}
+	}
+	return words, nil
+}
+
+// assignment consumes the NAME= prefix of an assignment word at the current
+// position if allowed is set, and returns the name.
+func (sh *shellScript) assignment(allowed bool) (string, bool) {
+	if !allowed {
+		return "", false
+	}
+	end := strings.IndexByte(sh.s[sh.i:], '=')
+	if end <= 0 || !validEnvName(sh.s[sh.i:sh.i+end]) {
+		return "", false
+	}
+	name := sh.s[sh.i : sh.i+end]
+	sh.i += end + 1
+	return name, true
+}
+
+// word parses the word at the current position, removing quotes and expanding
+// variables.
+func (sh *shellScript) word() (string, error) {
+	var b strings.Builder
+	for sh.i < len(sh.s) {
+		c := sh.s[sh.i]
+		switch c {
+		case ' ', '\t', '\r', '\n', ';':
+			return b.String(), nil
+		case '\\':
+			sh.i++
+			if sh.i < len(sh.s) {
+				if sh.s[sh.i] == '\n' {
+					sh.line++
+				} else {
+					b.WriteByte(sh.s[sh.i])
+				}
+				sh.i++
+			}
+		case '\'':
+			end := strings.IndexByte(sh.s[sh.i+1:], '\'')
+			if end < 0 {
+				return "", errors.New("unterminated quoted value")
+			}
+			quoted := sh.s[sh.i+1 : sh.i+1+end]
+			sh.line += strings.Count(quoted, "\n")
+			b.WriteString(quoted)
+			sh.i += end + 2
+		case '"':
+			if err := sh.doubleQuoted(&b); err != nil {
+				return "", err
+			}
+		case '$':
+			if err := sh.expand(&b); err != nil {
+				return "", err
+			}
+		case '`':
+			return "", errors.New("command substitution is not supported")
+		case '|', '&', '<', '>', '(', ')':
+			return "", fmt.Errorf("unsupported operator %q", c)
+		default:
+			b.WriteByte(c)
+			sh.i++
+		}
+	}
+	return b.String(), nil
+}
+
+// doubleQuoted parses the double-quoted string at the current position into b.
+func (sh *shellScript) doubleQuoted(b *strings.Builder) error {
+	sh.i++
+	for sh.i < len(sh.s) {
+		c := sh.s[sh.i]
+		switch c {
+		case '"':
+			sh.i++
+			return nil
+		case '\\':
+			sh.i++
+			if sh.i >= len(sh.s) {
+				return errors.New("unterminated quoted value")
+			}
+			switch e := sh.s[sh.i]; e {
+			case '\n':
+				sh.line++
+			case '$', '`', '"', '\\':
+				b.WriteByte(e)
+			default:
+				b.WriteByte('\\')
+				b.WriteByte(e)
+			}
+			sh.i++
+		case '$':
+			if err := sh.expand(b); err != nil {
+				return err
+			}
+		case '`':
+			return errors.New("command substitution is not supported")
+		default:
+			if c == '\n' {
+				sh.line++
+			}
+			b.WriteByte(c)
+			sh.i++
+		}
+	}
+	return errors.New("unterminated quoted value")
+}
+
+// expand expands the variable reference at the current position into b.
+// Variables that were not assigned by the script are looked up in the
+// environment of the current process.
+func (sh *shellScript) expand(b *strings.Builder) error {
+	rest := sh.s[sh.i+1:]
+
+	var name string
+	switch {
+	case strings.HasPrefix(rest, "("):
+		return errors.New("command substitution is not supported")
+	case strings.HasPrefix(rest, "{"):
+		end := strings.IndexByte(rest, '}')
+		if end < 0 || !validEnvName(rest[1:end]) {
+			return errors.New("unsupported parameter expansion")
+		}
+		name = rest[1:end]
+		sh.i += end + 2
+	default:
+		n := 0
+		for n < len(rest) && (rest[n] == '_' || isAlnum(rest[n]) && (n > 0 || !isDigit(rest[n]))) {
+			n++
+		}
+		if n == 0 {
+			b.WriteByte('$')
+			sh.i++
+			return nil
+		}
+		name = rest[:n]
+		sh.i += n + 1
+	}
+
+	if v, ok := sh.vars[name]; ok {
+		b.WriteString(v)
+	} else {
+		b.WriteString(os.Getenv(name))
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package envi_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bounoable/envi"
+	"github.com/google/go-cmp/cmp"
+)
+
+// TestParseDotEnvDialect_shell verifies the supported subset of shell scripts
+// that export variables.
+func TestParseDotEnvDialect_shell(t *testing.T) {
+	t.Setenv("SHELL_TEST_HOME", "/home/envi")
+
+	src := `#!/bin/sh
+# comment
+export HOST=localhost
+PORT=8080; export PORT
+export DATA_DIR="$SHELL_TEST_HOME/data" CACHE_DIR=${SHELL_TEST_HOME}/cache
+URL="http://${HOST}:$PORT" # comment
+SINGLE='literal $HOST \n'
+ESCAPED=a\ b\$c"d\"e\\f\g"
+MULTI="first
+second"
+CONTINUED=one\
+two
+EMPTY=
+PRICE='$5'
+`
+
+	vars, err := envi.ParseDotEnvDialect(strings.NewReader(src), envi.ShellScript)
+	if err != nil {
+		t.Fatalf("ParseDotEnvDialect() failed: %v", err)
+	}
+
+	want := []envi.DotEnvVar{
+		{Key: "HOST", Value: "localhost", Line: 3},
+		{Key: "PORT", Value: "8080", Line: 4},
+		{Key: "DATA_DIR", Value: "/home/envi/data", Line: 5},
+		{Key: "CACHE_DIR", Value: "/home/envi/cache", Line: 5},
+		{Key: "URL", Value: "http://localhost:8080", Line: 6},
+		{Key: "SINGLE", Value: `literal $HOST \n`, Line: 7},
+		{Key: "ESCAPED", Value: `a b$cd"e\f\g`, Line: 8},
+		{Key: "MULTI", Value: "first\nsecond", Line: 9},
+		{Key: "CONTINUED", Value: "onetwo", Line: 11},
+		{Key: "EMPTY", Value: "", Line: 13},
+		{Key: "PRICE", Value: "$5", Line: 14},
+	}
+	if !cmp.Equal(want, vars) {
+		t.Fatalf("vars = %v, want = %v\n\n%s", vars, want, cmp.Diff(want, vars))
+	}
+}
+
+// TestParseDotEnvDialect_shellUnsupported verifies that unsupported shell
+// syntax is reported with its line number.
+func TestParseDotEnvDialect_shellUnsupported(t *testing.T) {
+	tests := map[string]string{
+		"command":              "HOST=a\necho hello\n",
+		"prefix assignment":    "HOST=a make\n",
+		"command substitution": "HOST=$(hostname)\n",
+		"backticks":            "HOST=`hostname`\n",
+		"parameter expansion":  "HOST=${HOST:-localhost}\n",
+		"pipe":                 "HOST=a | cat\n",
+		"unterminated quote":   "HOST='a\n",
+	}
+
+	for name, src := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := envi.ParseDotEnvDialect(strings.NewReader(src), envi.ShellScript); err == nil || !strings.HasPrefix(err.Error(), "line ") {
+				t.Fatalf("ParseDotEnvDialect() should fail with a line number; got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,267 @@
+package envi
+
+import (
+	"bufio"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ReadSOPS reads the SOPS-encrypted dotenv file at path and returns its
+// decrypted variables as a Map, so encrypted configuration can be committed
+// and used as a Source without a separate decryption step:
+//
+//	vars, err := envi.ReadSOPS(ctx, ".env.enc", map[string]envi.Decryptor{
+//		"kms": kmsDecryptor,
+//	})
+//
+// The data key of the file is decrypted by the Decryptor for the type of one
+// of its master keys, i.e. "age", "kms", "gcp_kms", "azure_kv", "hc_vault" or
+// "pgp". The Decryptors of "kms" and "gcp_kms" keys receive the decoded
+// ciphertext; all others receive the stored text, e.g. an armored age file.
+// The message authentication code of the file is verified. Shamir key groups
+// are not supported, and neither are the YAML, JSON and INI formats of SOPS,
+// whose files are rejected with an error.
+func ReadSOPS(ctx context.Context, path string, decryptors map[string]Decryptor) (Map, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	file, err := parseSOPS(f)
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+
+	vars, err := file.decrypt(ctx, decryptors)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt %s: %w", path, err)
+	}
+
+	return vars, nil
+}
+
+// sopsFile is a SOPS-encrypted dotenv file.
+type sopsFile struct {
+	entries  []sopsEntry
+	metadata map[string]string
+}
+
+// sopsEntry is a variable or comment of a SOPS-encrypted dotenv file.
+type sopsEntry struct {
+	key     string
+	value   string
+	comment bool
+}
+
+// parseSOPS parses a dotenv file as written by SOPS: every line is either a
+// comment or has the form KEY=VALUE, with newlines in values escaped as \n.
+// Variables with the prefix "sops_" are the metadata of the file.
+func parseSOPS(r io.Reader) (sopsFile, error) {
+	file := sopsFile{metadata: make(map[string]string)}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<20)
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		switch {
+		case strings.TrimSpace(text) == "":
+			continue
+		case strings.HasPrefix(text, "#"):
+			file.entries = append(file.entries, sopsEntry{value: text[1:], comment: true})
+			continue
+		}
+
+		key, value, ok := strings.Cut(text, "=")
+		if format := sopsTreeFormat(text, key, ok); format != "" {
+			return sopsFile{}, fmt.Errorf("line %d: %s files are not supported; encrypt the file with sops --output-type dotenv", line, format)
+		}
+		if !ok {
+			return sopsFile{}, fmt.Errorf("line %d: missing '='", line)
+		}
+		value = strings.ReplaceAll(value, `\n`, "\n")
+
+		if strings.HasPrefix(key, "sops_") {
+			file.metadata[strings.TrimPrefix(key, "sops_")] = value
+			continue
+		}
+		file.entries = append(file.entries, sopsEntry{key: key, value: value})
+	}
+	if err := scanner.Err(); err != nil {
+		return sopsFile{}, err
+	}
+
+	if file.metadata["mac"] == "" || file.metadata["lastmodified"] == "" {
+		return sopsFile{}, errors.New("missing sops metadata; file is not encrypted with SOPS")
+	}
+
+	return file, nil
+}
+
+// sopsTreeFormat returns the format of a SOPS file whose line text, which
+// was cut at its first '=' into key, is not a dotenv line, i.e. "JSON",
+// "INI" or "YAML". It returns "" for dotenv lines and lines that are invalid
+// in all formats.
+func sopsTreeFormat(text, key string, ok bool) string {
+	text = strings.TrimSpace(text)
+	switch {
+	case strings.HasPrefix(text, "{"):
+		return "JSON"
+	case strings.HasPrefix(text, "[") && strings.HasSuffix(text, "]"):
+		return "INI"
+	case ok && !strings.ContainsAny(key, ": \t"):
+		return ""
+	case strings.HasPrefix(text, "- ") || strings.Contains(text, ":"):
+		return "YAML"
+	}
+	return ""
+}
+
+// decrypt decrypts the data key and the values of the file and verifies its
+// message authentication code.
+func (f sopsFile) decrypt(ctx context.Context, decryptors map[string]Decryptor) (Map, error) {
+	key, err := f.dataKey(ctx, decryptors)
+	if err != nil {
+		return nil, err
+	}
+
+	macOnlyEncrypted := f.metadata["mac_only_encrypted"] == "true"
+	hash := sha512.New()
+	vars := make(Map)
+	for _, e := range f.entries {
+		value := e.value
+		encrypted := strings.HasPrefix(value, "ENC[")
+		if encrypted {
+			// SOPS authenticates each value with its path in the tree, which
+			// is "KEY:" for variables and ":" for comments of dotenv files.
+			path := e.key + ":"
+			if value, err = decryptSOPSValue(key, value, path); err != nil {
+				if e.comment {
+					return nil, fmt.Errorf("comment: %w", err)
+				}
+				return nil, fmt.Errorf("%s: %w", e.key, err)
+			}
+		}
+		if encrypted || !macOnlyEncrypted {
+			hash.Write([]byte(value))
+		}
+		if !e.comment {
+			vars[e.key] = value
+		}
+	}
+
+	mac, err := decryptSOPSValue(key, f.metadata["mac"], f.metadata["lastmodified"])
+	if err != nil {
+		return nil, fmt.Errorf("mac: %w", err)
+	}
+	if want := fmt.Sprintf("%X", hash.Sum(nil)); mac != want {
+		return nil, errors.New("mac mismatch; file has been modified")
+	}
+
+	return vars, nil
+}
+
+// sopsMasterKey matches the flattened metadata key of the encrypted data key
+// of a master key, e.g. "age__list_0__map_enc" or
+// "key_groups__list_0__map_kms__list_1__map_enc".
+var sopsMasterKey = regexp.MustCompile(`(?:^|__map_)([a-z_]+?)__list_\d+__map_enc$`)
+
+// dataKey decrypts the data key of the file with the first master key that
+// has a Decryptor.
+func (f sopsFile) dataKey(ctx context.Context, decryptors map[string]Decryptor) ([]byte, error) {
+	if f.metadata["shamir_threshold"] != "" {
+		return nil, errors.New("shamir key groups are not supported")
+	}
+
+	names := make([]string, 0, len(f.metadata))
+	for name := range f.metadata {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var types []string
+	var errs []string
+	for _, name := range names {
+		m := sopsMasterKey.FindStringSubmatch(name)
+		if m == nil {
+			continue
+		}
+		typ := m[1]
+		types = append(types, typ)
+
+		d, ok := decryptors[typ]
+		if !ok {
+			continue
+		}
+
+		ciphertext := []byte(f.metadata[name])
+		if typ == "kms" || typ == "gcp_kms" {
+			var err error
+			if ciphertext, err = base64.StdEncoding.DecodeString(f.metadata[name]); err != nil {
+				errs = append(errs, fmt.Sprintf("%s: %v", typ, err))
+				continue
+			}
+		}
+
+		key, err := d.Decrypt(ctx, ciphertext)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", typ, err))
+			continue
+		}
+		return key, nil
+	}
+
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("decrypt data key: %s", strings.Join(errs, "; "))
+	}
+	return nil, fmt.Errorf("decrypt data key: no Decryptor for master keys of type %s", strings.Join(types, ", "))
+}
+
+// sopsValue matches a value encrypted by SOPS, e.g.
+// ENC[AES256_GCM,data:...,iv:...,tag:...,type:str].
+var sopsValue = regexp.MustCompile(`^ENC\[AES256_GCM,data:([^,]*),iv:([^,]+),tag:([^,]+),type:([a-z]+)\]$`)
+
+// decryptSOPSValue decrypts a value encrypted by SOPS with AES-GCM, using
+// additionalData as the additional authenticated data.
+func decryptSOPSValue(key []byte, value, additionalData string) (string, error) {
+	m := sopsValue.FindStringSubmatch(value)
+	if m == nil {
+		return "", errors.New("invalid encrypted value")
+	}
+
+	var parts [3][]byte
+	for i, s := range m[1:4] {
+		b, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return "", fmt.Errorf("invalid encrypted value: %w", err)
+		}
+		parts[i] = b
+	}
+	data, iv, tag := parts[0], parts[1], parts[2]
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := cipher.NewGCMWithNonceSize(block, len(iv))
+	if err != nil {
+		return "", err
+	}
+
+	plaintext, err := gcm.Open(nil, iv, append(data, tag...), []byte(additionalData))
+	if err != nil {
+		return "", errors.New("could not decrypt value with data key")
+	}
+
+	return string(plaintext), nil
+}
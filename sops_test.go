@@ -0,0 +1,149 @@
+package envi_test
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bounoable/envi"
+	"github.com/google/go-cmp/cmp"
+)
+
+// TestReadSOPS verifies that SOPS-encrypted dotenv files are decrypted with
+// the Decryptor of one of their master keys and that their MAC is verified.
+func TestReadSOPS(t *testing.T) {
+	dataKey := make([]byte, 32)
+	rand.Read(dataKey)
+
+	// The fake KMS "encrypts" the data key by reversing it.
+	kms := envi.DecryptorFunc(func(_ context.Context, ciphertext []byte) ([]byte, error) {
+		key := make([]byte, len(ciphertext))
+		for i, b := range ciphertext {
+			key[len(key)-1-i] = b
+		}
+		return key, nil
+	})
+	encryptedKey := make([]byte, len(dataKey))
+	for i, b := range dataKey {
+		encryptedKey[len(dataKey)-1-i] = b
+	}
+
+	lastModified := "2024-05-01T12:00:00Z"
+	mac := sha512.Sum512([]byte("a comment" + "db.internal" + "s3cr3t\nline" + "plain"))
+	file := strings.Join([]string{
+		"#" + sopsEncrypt(t, dataKey, "a comment", ":"),
+		"DB_HOST=" + sopsEncrypt(t, dataKey, "db.internal", "DB_HOST:"),
+		"DB_PASSWORD=" + sopsEncrypt(t, dataKey, "s3cr3t\nline", "DB_PASSWORD:"),
+		"DB_NAME_unencrypted=plain",
+		"sops_age__list_0__map_recipient=age1xyz",
+		`sops_age__list_0__map_enc=-----BEGIN AGE ENCRYPTED FILE-----\nYWdl\n-----END AGE ENCRYPTED FILE-----\n`,
+		"sops_kms__list_0__map_arn=arn:aws:kms:eu-central-1:123456789012:key/abc",
+		"sops_kms__list_0__map_enc=" + base64.StdEncoding.EncodeToString(encryptedKey),
+		"sops_lastmodified=" + lastModified,
+		"sops_mac=" + sopsEncrypt(t, dataKey, fmt.Sprintf("%X", mac), lastModified),
+		"sops_unencrypted_suffix=_unencrypted",
+		"sops_version=3.8.1",
+	}, "\n") + "\n"
+
+	path := filepath.Join(t.TempDir(), ".env.enc")
+	if err := os.WriteFile(path, []byte(file), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	vars, err := envi.ReadSOPS(ctx, path, map[string]envi.Decryptor{"kms": kms})
+	if err != nil {
+		t.Fatalf("ReadSOPS() failed: %v", err)
+	}
+	want := envi.Map{
+		"DB_HOST":             "db.internal",
+		"DB_PASSWORD":         "s3cr3t\nline",
+		"DB_NAME_unencrypted": "plain",
+	}
+	if !cmp.Equal(want, vars) {
+		t.Fatalf("vars = %v, want = %v\n\n%s", vars, want, cmp.Diff(want, vars))
+	}
+
+	if _, err := envi.ReadSOPS(ctx, path, nil); err == nil || !strings.Contains(err.Error(), "no Decryptor") {
+		t.Fatalf("ReadSOPS() should fail without Decryptor; got %v", err)
+	}
+
+	denied := errors.New("access denied")
+	_, err = envi.ReadSOPS(ctx, path, map[string]envi.Decryptor{
+		"kms": envi.DecryptorFunc(func(context.Context, []byte) ([]byte, error) { return nil, denied }),
+	})
+	if err == nil || !strings.Contains(err.Error(), denied.Error()) {
+		t.Fatalf("ReadSOPS() should fail with %q; got %v", denied, err)
+	}
+
+	tampered := strings.Replace(file, "DB_NAME_unencrypted=plain", "DB_NAME_unencrypted=changed", 1)
+	if err := os.WriteFile(path, []byte(tampered), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := envi.ReadSOPS(ctx, path, map[string]envi.Decryptor{"kms": kms}); err == nil || !strings.Contains(err.Error(), "mac mismatch") {
+		t.Fatalf("ReadSOPS() should fail for tampered file; got %v", err)
+	}
+
+	if err := os.WriteFile(path, []byte("DB_HOST=db.internal\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := envi.ReadSOPS(ctx, path, map[string]envi.Decryptor{"kms": kms}); err == nil {
+		t.Fatal("ReadSOPS() should fail for unencrypted file")
+	}
+}
+
+// TestReadSOPS_treeFormats verifies that SOPS files in the YAML, JSON and INI
+// formats are rejected with an error that names the format.
+func TestReadSOPS_treeFormats(t *testing.T) {
+	files := map[string]string{
+		"YAML": "db:\n    password: ENC[AES256_GCM,data:abc=,iv:def=,tag:ghi=,type:str]\nsops:\n    mac: ENC[...]\n",
+		"JSON": "{\n\t\"password\": \"ENC[AES256_GCM,data:abc=,iv:def=,tag:ghi=,type:str]\"\n}\n",
+		"INI":  "[db]\npassword = ENC[AES256_GCM,data:abc=,iv:def=,tag:ghi=,type:str]\n",
+	}
+
+	for format, file := range files {
+		t.Run(format, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "secrets.enc")
+			if err := os.WriteFile(path, []byte(file), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err := envi.ReadSOPS(context.Background(), path, nil)
+			if want := format + " files are not supported"; err == nil || !strings.Contains(err.Error(), want) {
+				t.Fatalf("ReadSOPS() should fail with %q; got %v", want, err)
+			}
+		})
+	}
+}
+
+// sopsEncrypt encrypts value like SOPS does, using additionalData as the
+// additional authenticated data.
+func sopsEncrypt(t *testing.T, key []byte, value, additionalData string) string {
+	t.Helper()
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	iv := make([]byte, 32)
+	rand.Read(iv)
+	gcm, err := cipher.NewGCMWithNonceSize(block, len(iv))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sealed := gcm.Seal(nil, iv, []byte(value), []byte(additionalData))
+	data, tag := sealed[:len(sealed)-gcm.Overhead()], sealed[len(sealed)-gcm.Overhead():]
+
+	enc := base64.StdEncoding.EncodeToString
+	return fmt.Sprintf("ENC[AES256_GCM,data:%s,iv:%s,tag:%s,type:str]", enc(data), enc(iv), enc(tag))
+}
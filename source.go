@@ -0,0 +1,278 @@
+package envi
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Source provides the values of environment variables. Implementations that
+// perform I/O should respect the cancellation and deadline of ctx.
+type Source interface {
+	// Lookup returns the value of the variable with the given key and
+	// whether the variable is set.
+	Lookup(ctx context.Context, key string) (string, bool, error)
+}
+
+// Lister is implemented by Sources that can enumerate the keys of their
+// variables. Map fields are only populated from Sources that implement Lister.
+type Lister interface {
+	// Keys returns the keys of all variables of the Source.
+	Keys(ctx context.Context) ([]string, error)
+}
+
+// WithSource sets the Sources that variables are looked up from, replacing
+// the default OS Source. Sources are consulted in order, and the first Source
+// that has a variable set provides its value, as with Sources.
+func WithSource(sources ...Source) Option {
+	return func(p *parser) {
+		p.sources = append(p.sources, sources...)
+	}
+}
+
+// OS returns the Source that reads from the environment of the current
+// process. It is used if no other Source is configured.
+func OS() Source {
+	return osSource{}
+}
+
+type osSource struct{}
+
+func (osSource) Lookup(_ context.Context, key string) (string, bool, error) {
+	v, ok := os.LookupEnv(key)
+	return v, ok, nil
+}
+
+func (osSource) Keys(context.Context) ([]string, error) {
+	environ := os.Environ()
+	keys := make([]string, 0, len(environ))
+	for _, env := range environ {
+		if key, _, _ := strings.Cut(env, "="); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+// Map is a Source that provides the variables of a map, e.g. as the lowest
+// precedence layer of defaults.
+type Map map[string]string
+
+// Lookup implements Source.
+func (m Map) Lookup(_ context.Context, key string) (string, bool, error) {
+	v, ok := m[key]
+	return v, ok, nil
+}
+
+// Keys implements Lister.
+func (m Map) Keys(context.Context) ([]string, error) {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// Environ returns a Map of the variables of environ, which has the KEY=VALUE
+// form of os.Environ and exec.Cmd.Env. Entries are split at the first "=", so
+// values may contain "=", and if a key occurs multiple times, the last value
+// wins. Entries without "=" are ignored.
+func Environ(environ []string) Map {
+	m := make(Map, len(environ))
+	for _, env := range environ {
+		// On Windows, the keys of some variables start with "=", e.g. "=C:".
+		if env == "" {
+			continue
+		}
+		i := strings.IndexByte(env[1:], '=')
+		if i < 0 {
+			continue
+		}
+		m[env[:i+1]] = env[i+2:]
+	}
+	return m
+}
+
+// Chain is a Source that looks up variables from multiple Sources with
+// explicit precedence: Sources are consulted in the order they were passed to
+// Sources, and the first Source that has a variable set provides its value.
+// Errors of a Source are returned immediately without consulting the
+// remaining Sources. Chain records which Source provided each variable.
+type Chain struct {
+	sources []Source
+
+	mux     sync.RWMutex
+	origins map[string]Source
+}
+
+// Sources returns a Chain of the given Sources, in order of precedence:
+//
+//	envi.Sources(envi.OS(), vault, envi.Map{"PORT": "8080"})
+func Sources(sources ...Source) *Chain {
+	return &Chain{
+		sources: sources,
+		origins: make(map[string]Source),
+	}
+}
+
+// Lookup implements Source.
+func (c *Chain) Lookup(ctx context.Context, key string) (string, bool, error) {
+	for _, s := range c.sources {
+		v, ok, err := s.Lookup(ctx, key)
+		if err != nil {
+			return "", false, err
+		}
+		if ok {
+			c.mux.Lock()
+			c.origins[key] = s
+			c.mux.Unlock()
+			return v, true, nil
+		}
+	}
+
+	c.mux.Lock()
+	delete(c.origins, key)
+	c.mux.Unlock()
+
+	return "", false, nil
+}
+
+// Keys implements Lister. It returns the deduplicated keys of all Sources that
+// implement Lister.
+func (c *Chain) Keys(ctx context.Context) ([]string, error) {
+	return listKeys(ctx, c.sources)
+}
+
+// Origin returns the Source that provided the variable with the given key in
+// the most recent lookup, or false if the variable was not found.
+func (c *Chain) Origin(key string) (Source, bool) {
+	c.mux.RLock()
+	defer c.mux.RUnlock()
+	s, ok := c.origins[key]
+	return s, ok
+}
+
+// lookup looks up the variable with the given key in the configured Sources.
+func (p *parser) lookup(key string) (string, bool, error) {
+	v, _, ok, err := p.lookupSource(key)
+	return v, ok, err
+}
+
+// lookupSource is like lookup, but also returns the Source that provided the
+// variable. References are resolved if WithIndirection or WithReferences is
+// used, and encrypted values are decrypted; see WithDecryptor.
+func (p *parser) lookupSource(key string) (string, Source, bool, error) {
+	v, s, ok, err := p.lookupKey(key)
+	if err == nil && ok && p.indirection {
+		v, s, ok, err = p.dereference(key, v, s)
+	}
+	if err == nil && ok && p.references != nil {
+		v, ok, err = p.resolveReference(key, v)
+	}
+	if err != nil || !ok {
+		return "", nil, false, err
+	}
+	if len(p.decryptors) > 0 {
+		if v, err = p.decrypt(key, v); err != nil {
+			return "", nil, false, err
+		}
+	}
+	return v, s, true, nil
+}
+
+// lookupKey looks up the variable with the given key in the configured
+// Sources. Namespaced keys are looked up first; see WithNamespace.
+func (p *parser) lookupKey(key string) (string, Source, bool, error) {
+	nsKey, namespaced, err := p.namespacedKey(key)
+	if err != nil {
+		return "", nil, false, err
+	}
+	if namespaced {
+		v, s, ok, err := p.lookupRaw(nsKey)
+		if err != nil || ok {
+			return v, s, ok, err
+		}
+	}
+	return p.lookupRaw(key)
+}
+
+// lookupRaw looks up the variable with the given key in the configured
+// Sources without namespacing.
+func (p *parser) lookupRaw(key string) (string, Source, bool, error) {
+	r, ok := p.prefetched[key]
+	if !ok {
+		r = p.lookupSources(key)
+	}
+	if r.err != nil {
+		return "", nil, false, r.err
+	}
+	if r.ok {
+		p.recordRaw(key, r.value)
+	}
+	return r.value, r.source, r.ok, nil
+}
+
+// getenv returns the value of the variable with the given key, or an empty
+// string if it is not set.
+func (p *parser) getenv(key string) (string, error) {
+	v, _, err := p.lookup(key)
+	return v, err
+}
+
+// keys returns the deduplicated keys of all configured Sources that implement
+// Lister.
+func (p *parser) keys() ([]string, error) {
+	return listKeys(p.ctx, p.sources)
+}
+
+// keysWithPrefix returns the sorted keys of all configured Sources that
+// implement Lister and start with prefix. The keys are listed once per parser
+// and indexed, so parsing many map fields doesn't scan the environment once
+// per field.
+func (p *parser) keysWithPrefix(prefix string) ([]string, error) {
+	if !p.keysListed {
+		keys, err := p.keys()
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(keys)
+		p.keyIndex = keys
+		p.keysListed = true
+	}
+
+	start := sort.SearchStrings(p.keyIndex, prefix)
+	end := start
+	for end < len(p.keyIndex) && strings.HasPrefix(p.keyIndex[end], prefix) {
+		end++
+	}
+
+	return p.keyIndex[start:end], nil
+}
+
+func listKeys(ctx context.Context, sources []Source) ([]string, error) {
+	seen := make(map[string]bool)
+	var keys []string
+	for _, s := range sources {
+		l, ok := s.(Lister)
+		if !ok {
+			continue
+		}
+
+		sk, err := l.Keys(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("list keys: %w", err)
+		}
+
+		for _, k := range sk {
+			if !seen[k] {
+				seen[k] = true
+				keys = append(keys, k)
+			}
+		}
+	}
+	return keys, nil
+}
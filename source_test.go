@@ -0,0 +1,354 @@
+package envi_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/bounoable/envi"
+	"github.com/google/go-cmp/cmp"
+)
+
+type blockingSource struct{}
+
+func (blockingSource) Lookup(ctx context.Context, key string) (string, bool, error) {
+	<-ctx.Done()
+	return "", false, ctx.Err()
+}
+
+type sourceEnv struct {
+	Host   string            `env:"SOURCE_HOST"`
+	Port   int               `env:"SOURCE_PORT"`
+	Labels map[string]string `env:"SOURCE_LABEL"`
+}
+
+// TestWithSource verifies that variables are looked up from the configured
+// sources in order, and that map fields are populated from sources that can
+// list their keys.
+func TestWithSource(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("SOURCE_HOST", "os")
+
+	e, err := envi.New[sourceEnv](envi.WithSource(
+		envi.Map{"SOURCE_HOST": "first", "SOURCE_LABEL_a": "1"},
+		envi.Map{"SOURCE_HOST": "second", "SOURCE_PORT": "8080", "SOURCE_LABEL_b": "2"},
+	))
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	want := sourceEnv{
+		Host:   "first",
+		Port:   8080,
+		Labels: map[string]string{"a": "1", "b": "2"},
+	}
+	if !cmp.Equal(want, e) {
+		t.Fatalf("env = %v, want = %v\n\n%s", e, want, cmp.Diff(want, e))
+	}
+
+	e, err = envi.New[sourceEnv](envi.WithSource(envi.OS()))
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	if e.Host != "os" {
+		t.Fatalf("Host = %q, want %q", e.Host, "os")
+	}
+}
+
+// TestParseEnviron verifies that variables are parsed from KEY=VALUE entries,
+// which are split at the first "=".
+func TestParseEnviron(t *testing.T) {
+	var e sourceEnv
+	err := envi.ParseEnviron(&e, []string{
+		"SOURCE_HOST=a=b==c",
+		"SOURCE_PORT=80",
+		"SOURCE_LABEL_query=x=1&y=2",
+		"=C:=C:\\",
+		"INVALID",
+		"SOURCE_PORT=8080",
+	})
+	if err != nil {
+		t.Fatalf("ParseEnviron() failed: %v", err)
+	}
+
+	want := sourceEnv{
+		Host:   "a=b==c",
+		Port:   8080,
+		Labels: map[string]string{"query": "x=1&y=2"},
+	}
+	if !cmp.Equal(want, e) {
+		t.Fatalf("env = %v, want = %v\n\n%s", e, want, cmp.Diff(want, e))
+	}
+
+	if env := envi.Environ([]string{"=C:=C:\\", "A="}); !cmp.Equal(env, envi.Map{"=C:": "C:\\", "A": ""}) {
+		t.Fatalf("Environ() = %v", env)
+	}
+}
+
+// countingSource is a Map that counts how often its keys are listed.
+type countingSource struct {
+	envi.Map
+	listed int
+}
+
+func (s *countingSource) Keys(ctx context.Context) ([]string, error) {
+	s.listed++
+	return s.Map.Keys(ctx)
+}
+
+// TestWithSource_maps verifies that the keys of the sources are listed only
+// once per Parse call, however many map fields the struct has.
+func TestWithSource_maps(t *testing.T) {
+	type mapsEnv struct {
+		Labels map[string]string `env:"LABEL"`
+		Limits map[string]int    `env:"LIMIT"`
+		Flags  map[string]bool   `env:"FLAG"`
+	}
+
+	src := &countingSource{Map: envi.Map{
+		"LABEL_TEAM": "core",
+		"LIMIT_CPU":  "2",
+		"LIMIT_MEM":  "512",
+		"FLAG_DEBUG": "true",
+		"LABELS":     "ignored",
+	}}
+
+	e, err := envi.New[mapsEnv](envi.WithSource(src))
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	want := mapsEnv{
+		Labels: map[string]string{"TEAM": "core"},
+		Limits: map[string]int{"CPU": 2, "MEM": 512},
+		Flags:  map[string]bool{"DEBUG": true},
+	}
+	if !cmp.Equal(want, e) {
+		t.Fatalf("env = %v, want = %v\n\n%s", e, want, cmp.Diff(want, e))
+	}
+
+	if src.listed != 1 {
+		t.Fatalf("Keys() called %d times, want 1", src.listed)
+	}
+}
+
+// TestParseContext verifies that ParseContext passes its context to the
+// sources and fails with the context's error once it is done.
+func TestParseContext(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	var e sourceEnv
+	err := envi.ParseContext(ctx, &e, envi.WithSource(blockingSource{}))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("ParseContext() should fail with %q; got %q", context.DeadlineExceeded, err)
+	}
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := envi.ParseContext(canceled, &e); !errors.Is(err, context.Canceled) {
+		t.Fatalf("ParseContext() should fail with %q; got %q", context.Canceled, err)
+	}
+}
+
+// TestSources verifies that a Chain falls through its sources in order of
+// precedence and records which source provided each variable.
+func TestSources(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("SOURCE_HOST", "os")
+
+	overrides := envi.Map{"SOURCE_PORT": "9090"}
+	defaults := envi.Map{"SOURCE_HOST": "localhost", "SOURCE_PORT": "8080", "SOURCE_LABEL_a": "1"}
+	chain := envi.Sources(envi.OS(), overrides, defaults)
+
+	e, err := envi.New[sourceEnv](envi.WithSource(chain))
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	want := sourceEnv{
+		Host:   "os",
+		Port:   9090,
+		Labels: map[string]string{"a": "1"},
+	}
+	if !cmp.Equal(want, e) {
+		t.Fatalf("env = %v, want = %v\n\n%s", e, want, cmp.Diff(want, e))
+	}
+
+	origins := map[string]envi.Source{
+		"SOURCE_HOST":    envi.OS(),
+		"SOURCE_PORT":    overrides,
+		"SOURCE_LABEL_a": defaults,
+	}
+	for key, want := range origins {
+		if got, ok := chain.Origin(key); !ok || !cmp.Equal(want, got) {
+			t.Fatalf("Origin(%q) = %v, %v; want %v", key, got, ok, want)
+		}
+	}
+
+	if _, ok := chain.Origin("SOURCE_MISSING"); ok {
+		t.Fatalf("Origin() should return false for unset variables")
+	}
+}
+
+// TestWithSourceTimeout verifies that lookups in a Source wrapped with
+// WithSourceTimeout fail once the timeout expires, even if the Source ignores
+// the context, while the remaining Sources are unaffected.
+func TestWithSourceTimeout(t *testing.T) {
+	fast := envi.WithSourceTimeout(envi.Map{"SOURCE_HOST": "fast", "SOURCE_LABEL_a": "1"}, time.Second)
+	e, err := envi.New[sourceEnv](envi.WithSource(fast))
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	if want := (sourceEnv{Host: "fast", Labels: map[string]string{"a": "1"}}); !cmp.Equal(want, e) {
+		t.Fatalf("env = %v, want = %v\n\n%s", e, want, cmp.Diff(want, e))
+	}
+
+	hanging := make(chan struct{})
+	defer close(hanging)
+
+	for name, src := range map[string]envi.Source{
+		"context": blockingSource{},
+		"hanging": hangingSource(hanging),
+	} {
+		start := time.Now()
+		_, err := envi.New[sourceEnv](envi.WithSource(
+			envi.Map{"SOURCE_HOST": "local"},
+			envi.WithSourceTimeout(src, 20*time.Millisecond),
+		))
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("[%s] New() should fail with %v; got %v", name, context.DeadlineExceeded, err)
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Fatalf("[%s] New() took %v", name, elapsed)
+		}
+	}
+}
+
+// hangingSource is a Source that ignores the context and blocks until the
+// channel is closed.
+type hangingSource chan struct{}
+
+func (s hangingSource) Lookup(context.Context, string) (string, bool, error) {
+	<-s
+	return "", false, nil
+}
+
+// flakySource is a Source that fails the first lookups with err.
+type flakySource struct {
+	envi.Map
+	failures int
+	err      error
+	calls    int
+}
+
+func (s *flakySource) Lookup(ctx context.Context, key string) (string, bool, error) {
+	s.calls++
+	if s.calls <= s.failures {
+		return "", false, s.err
+	}
+	return s.Map.Lookup(ctx, key)
+}
+
+// TestWithRetry verifies that failed lookups in a Source wrapped with
+// WithRetry are retried up to the configured number of attempts, and that
+// errors that are not retryable fail immediately.
+func TestWithRetry(t *testing.T) {
+	throttled := errors.New("throttled")
+	forbidden := errors.New("forbidden")
+	policy := envi.RetryPolicy{
+		Attempts:  3,
+		Delay:     time.Millisecond,
+		Retryable: func(err error) bool { return !errors.Is(err, forbidden) },
+	}
+
+	src := &flakySource{Map: envi.Map{"SOURCE_HOST": "vault"}, failures: 2, err: throttled}
+	var e struct {
+		Host string `env:"SOURCE_HOST"`
+	}
+	if err := envi.Parse(&e, envi.WithSource(envi.WithRetry(src, policy))); err != nil {
+		t.Fatalf("Parse() failed: %v", err)
+	}
+	if e.Host != "vault" || src.calls != 3 {
+		t.Fatalf("Host = %q after %d calls, want %q after 3 calls", e.Host, src.calls, "vault")
+	}
+
+	src = &flakySource{Map: envi.Map{"SOURCE_HOST": "vault"}, failures: 3, err: throttled}
+	if err := envi.Parse(&e, envi.WithSource(envi.WithRetry(src, policy))); !errors.Is(err, throttled) || src.calls != 3 {
+		t.Fatalf("Parse() should fail with %v after 3 calls; got %v after %d calls", throttled, err, src.calls)
+	}
+
+	src = &flakySource{Map: envi.Map{"SOURCE_HOST": "vault"}, failures: 1, err: forbidden}
+	if err := envi.Parse(&e, envi.WithSource(envi.WithRetry(src, policy))); !errors.Is(err, forbidden) || src.calls != 1 {
+		t.Fatalf("Parse() should fail with %v after 1 call; got %v after %d calls", forbidden, err, src.calls)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	src = &flakySource{Map: envi.Map{"SOURCE_HOST": "vault"}, failures: 1, err: throttled}
+	if err := envi.ParseContext(ctx, &e, envi.WithSource(envi.WithRetry(src, policy))); err == nil || src.calls > 1 {
+		t.Fatalf("ParseContext() should fail without retries for canceled context; got %v after %d calls", err, src.calls)
+	}
+}
+
+// cacheCounter is a Source that counts the lookups of each key and how
+// often its keys are listed.
+type cacheCounter struct {
+	envi.Map
+	lookups map[string]int
+	lists   int
+}
+
+func (s *cacheCounter) Lookup(ctx context.Context, key string) (string, bool, error) {
+	s.lookups[key]++
+	return s.Map.Lookup(ctx, key)
+}
+
+func (s *cacheCounter) Keys(ctx context.Context) ([]string, error) {
+	s.lists++
+	return s.Map.Keys(ctx)
+}
+
+// TestCached verifies that a Cache looks up variables only once until they
+// expire or are invalidated.
+func TestCached(t *testing.T) {
+	src := &cacheCounter{
+		Map:     envi.Map{"SOURCE_HOST": "vault", "SOURCE_LABEL_a": "1"},
+		lookups: make(map[string]int),
+	}
+	cache := envi.Cached(src, 50*time.Millisecond)
+
+	parse := func() sourceEnv {
+		t.Helper()
+		e, err := envi.New[sourceEnv](envi.WithSource(cache))
+		if err != nil {
+			t.Fatalf("New() failed: %v", err)
+		}
+		return e
+	}
+
+	parse()
+	src.Map["SOURCE_HOST"] = "rotated"
+	if e := parse(); e.Host != "vault" {
+		t.Fatalf("Host = %q, want cached %q", e.Host, "vault")
+	}
+	if src.lookups["SOURCE_HOST"] != 1 || src.lookups["SOURCE_PORT"] != 1 || src.lists != 1 {
+		t.Fatalf("%d lookups of SOURCE_HOST, %d of SOURCE_PORT and %d lists; want 1 each", src.lookups["SOURCE_HOST"], src.lookups["SOURCE_PORT"], src.lists)
+	}
+
+	cache.Invalidate("SOURCE_HOST")
+	if e := parse(); e.Host != "rotated" {
+		t.Fatalf("Host = %q, want %q after invalidation", e.Host, "rotated")
+	}
+	if src.lookups["SOURCE_PORT"] != 1 {
+		t.Fatalf("%d lookups of SOURCE_PORT, want 1", src.lookups["SOURCE_PORT"])
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	src.Map["SOURCE_PORT"] = "8080"
+	if e := parse(); e.Port != 8080 || src.lists != 2 {
+		t.Fatalf("Port = %d after %d lists, want 8080 after 2 lists once expired", e.Port, src.lists)
+	}
+}
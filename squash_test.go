@@ -0,0 +1,97 @@
+package envi_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/bounoable/envi"
+	"github.com/google/go-cmp/cmp"
+)
+
+type squashCommon struct {
+	Host string `env:"SQUASH_HOST" default:"localhost"`
+	Port int    `env:"SQUASH_PORT" required:"true"`
+}
+
+type squashEnv struct {
+	Common squashCommon `env:",squash"`
+	Debug  bool         `env:"SQUASH_DEBUG"`
+}
+
+// TestParse_squash tests that the fields of a struct field with an
+// `env:",squash"` tag are treated as fields of the enclosing struct.
+func TestParse_squash(t *testing.T) {
+	e, err := envi.New[squashEnv](envi.WithSource(envi.Map{
+		"SQUASH_PORT":  "8080",
+		"SQUASH_DEBUG": "true",
+	}))
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	want := squashEnv{Common: squashCommon{Host: "localhost", Port: 8080}, Debug: true}
+	if !cmp.Equal(want, e) {
+		t.Fatalf("env = %v, want = %v\n\n%s", e, want, cmp.Diff(want, e))
+	}
+
+	var fields []string
+	for _, v := range envi.Variables[squashEnv]() {
+		fields = append(fields, v.Field)
+	}
+	if want := []string{"Host", "Port", "Debug"}; !cmp.Equal(want, fields) {
+		t.Fatalf("Variables() fields = %v, want = %v", fields, want)
+	}
+
+	problems := envi.Check[squashEnv](envi.WithSource(envi.Map{}))
+	if len(problems) != 1 || problems[0].Field != "Port" || !errors.Is(problems[0].Err, envi.ErrRequired) {
+		t.Fatalf("Check() = %v, want a required problem for Port", problems)
+	}
+
+	e = squashEnv{Debug: true}
+	if err := envi.Parse(&e, envi.WithFields("Host"), envi.WithSource(envi.Map{"SQUASH_HOST": "example.com"})); err != nil {
+		t.Fatalf("Parse() failed: %v", err)
+	}
+	want = squashEnv{Common: squashCommon{Host: "example.com"}, Debug: true}
+	if !cmp.Equal(want, e) {
+		t.Fatalf("env = %v, want = %v\n\n%s", e, want, cmp.Diff(want, e))
+	}
+}
+
+// TestParse_tagOptions tests that unknown options of `env` tags and options
+// that don't apply to their field are reported.
+func TestParse_tagOptions(t *testing.T) {
+	type typo struct {
+		Common squashCommon `env:",sqaush"`
+	}
+	type restString struct {
+		Rest string `env:"APP,rest"`
+	}
+	type squashString struct {
+		Host string `env:",squash"`
+	}
+
+	src := envi.WithSource(envi.Map{"SQUASH_PORT": "8080"})
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"typo", parseErr[typo](src), `env tag of "Common" field: unknown option "sqaush"`},
+		{"rest", parseErr[restString](src), `env tag of "Rest" field: option "rest" requires a map field`},
+		{"squash", parseErr[squashString](src), `env tag of "Host" field: option "squash" requires a struct field`},
+	}
+	for _, tt := range tests {
+		if tt.err == nil || !strings.Contains(tt.err.Error(), tt.want) {
+			t.Fatalf("%s: New() should fail with %q; got %v", tt.name, tt.want, tt.err)
+		}
+	}
+
+	if problems := envi.Check[typo](src); len(problems) != 1 {
+		t.Fatalf("Check() = %v, want a problem for the env tag", problems)
+	}
+}
+
+func parseErr[Env any](opts ...envi.Option) error {
+	_, err := envi.New[Env](opts...)
+	return err
+}
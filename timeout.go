@@ -0,0 +1,85 @@
+package envi
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// WithSourceTimeout returns a Source that fails lookups in source that take
+// longer than timeout, so a slow secret store can't hang startup:
+//
+//	envi.WithSource(envi.OS(), envi.WithSourceTimeout(vault, 2*time.Second))
+//
+// The context passed to source has the timeout as its deadline. Lookups that
+// ignore the context are abandoned once the timeout expires. The returned
+// Source implements Lister if source does.
+func WithSourceTimeout(source Source, timeout time.Duration) Source {
+	return &timeoutSource{source: source, timeout: timeout}
+}
+
+type timeoutSource struct {
+	source  Source
+	timeout time.Duration
+}
+
+type timeoutResult struct {
+	value string
+	ok    bool
+	keys  []string
+	err   error
+}
+
+// Lookup implements Source.
+func (s *timeoutSource) Lookup(ctx context.Context, key string) (string, bool, error) {
+	res, err := s.do(ctx, func(ctx context.Context) timeoutResult {
+		v, ok, err := s.source.Lookup(ctx, key)
+		return timeoutResult{value: v, ok: ok, err: err}
+	})
+	if err != nil {
+		return "", false, err
+	}
+	return res.value, res.ok, res.err
+}
+
+// Keys implements Lister. It returns no keys if the wrapped Source does not
+// implement Lister.
+func (s *timeoutSource) Keys(ctx context.Context) ([]string, error) {
+	l, ok := s.source.(Lister)
+	if !ok {
+		return nil, nil
+	}
+	res, err := s.do(ctx, func(ctx context.Context) timeoutResult {
+		keys, err := l.Keys(ctx)
+		return timeoutResult{keys: keys, err: err}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.keys, res.err
+}
+
+// do calls fn with a context that expires after the timeout of s and returns
+// an error if fn doesn't return in time.
+func (s *timeoutSource) do(parent context.Context, fn func(context.Context) timeoutResult) (timeoutResult, error) {
+	ctx, cancel := context.WithTimeout(parent, s.timeout)
+	defer cancel()
+
+	done := make(chan timeoutResult, 1)
+	go func() { done <- fn(ctx) }()
+
+	var res timeoutResult
+	select {
+	case res = <-done:
+		if res.err == nil {
+			return res, nil
+		}
+	case <-ctx.Done():
+		res.err = ctx.Err()
+	}
+
+	if parent.Err() == nil && ctx.Err() == context.DeadlineExceeded {
+		return res, fmt.Errorf("source timed out after %v: %w", s.timeout, res.err)
+	}
+	return res, nil
+}
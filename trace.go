@@ -0,0 +1,175 @@
+package envi
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// redacted replaces the values of fields with a `secret` tag in traces,
+// reports and diffs.
+const redacted = "<redacted>"
+
+// TraceEvent describes how the value of a single field was resolved.
+type TraceEvent struct {
+	// Field is the path of the field, e.g. "Database.URL".
+	Field string
+
+	// Key is the environment variable that was looked up, or the prefix of
+	// the variables of a map field.
+	Key string
+
+	// From is the variable that provided the value if it is not Key, i.e. a
+	// deprecated alias of Key or a variable of the `defaultFrom` tag.
+	From string
+
+	// Set reports whether the variable, or one of the variables of the
+	// `defaultFrom` tag, was set in any Source. For map fields, it reports
+	// whether any variable with the prefix was found.
+	Set bool
+
+	// Source is the Source that provided the variable, or nil if the
+	// variable was not set or the field is a map.
+	Source Source
+
+	// Default reports whether the value of the field was taken from its
+	// `default` or `defaultExpr` tag.
+	Default bool
+
+	// Kind is the kind of the field.
+	Kind reflect.Kind
+
+	// Value is the formatted final value of the field. It is redacted for
+	// fields with a `secret` tag.
+	Value string
+}
+
+// WithTrace sets a function that is called with a TraceEvent for every field
+// that is resolved from a variable, to help diagnose why a field has a
+// certain value.
+func WithTrace(fn func(TraceEvent)) Option {
+	return func(p *parser) {
+		p.trace = fn
+	}
+}
+
+// fieldResult describes how the value of a field was resolved.
+type fieldResult struct {
+	// consulted reports whether the field was resolved from variables;
+	// deferred reports whether it still awaits its defaultExpr.
+	consulted bool
+	deferred  bool
+
+	key    string
+	set    bool
+	source Source
+	def    bool
+
+	// skipped reports whether the field was not parsed; see WithFields.
+	skipped bool
+
+	// found reports whether a non-empty value was found for the field, or
+	// for any field of a nested struct; see xorGroup.
+	found bool
+
+	// from is the key of the variable that provided the value, which is
+	// either key or one of the fallbacks, and raw is its unprocessed value.
+	from string
+	raw  string
+
+	// fallbacks are the keys of the `defaultFrom` tag that were consulted
+	// because the variable was not set.
+	fallbacks []string
+}
+
+// recordField passes how field, which has the final value v, was resolved to
+// the trace function and the report of the parser.
+func (p *parser) recordField(field *fieldSchema, res fieldResult, v reflect.Value) {
+	if p.trace == nil && p.report == nil {
+		return
+	}
+
+	name := field.name
+	if len(p.path) > 0 {
+		name = strings.Join(p.path, ".") + "." + name
+	}
+
+	value := formatValue(v)
+	if field.secret {
+		value = redacted
+	}
+
+	var from string
+	if res.set && res.from != res.key {
+		from = res.from
+	}
+
+	if p.trace != nil {
+		p.trace(TraceEvent{
+			Field:   name,
+			Key:     res.key,
+			From:    from,
+			Set:     res.set,
+			Source:  res.source,
+			Default: res.def,
+			Kind:    field.typ.Kind(),
+			Value:   value,
+		})
+	}
+
+	if p.report != nil {
+		p.report.Fields = append(p.report.Fields, FieldReport{
+			Field:   name,
+			Keys:    append([]string{res.key}, res.fallbacks...),
+			From:    from,
+			Found:   res.set,
+			Source:  res.source,
+			Default: res.def,
+			Value:   value,
+		})
+	}
+}
+
+// formatValue formats v for traces and reports. Nil pointers are formatted as
+// "<nil>" and other pointers as the value they point to.
+func formatValue(v reflect.Value) string {
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return "<nil>"
+		}
+		if loc, ok := v.Interface().(*time.Location); ok {
+			return loc.String()
+		}
+		v = v.Elem()
+	}
+	if v.Type() == rawMessageType {
+		return string(v.Bytes())
+	}
+	if name, ok := enumName(v); ok {
+		return name
+	}
+	if u, ok := v.Interface().(url.URL); ok {
+		return u.Redacted()
+	}
+	if r, ok := v.Interface().(interface{ Redacted() string }); ok {
+		return r.Redacted()
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+// origin resolves the Source that provided key if s is a Chain.
+func origin(s Source, key string) Source {
+	for {
+		c, ok := s.(*Chain)
+		if !ok {
+			return s
+		}
+		o, ok := c.Origin(key)
+		if !ok {
+			return s
+		}
+		s = o
+	}
+}
@@ -0,0 +1,96 @@
+package envi_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/bounoable/envi"
+	"github.com/google/go-cmp/cmp"
+)
+
+type traceEnv struct {
+	Host     string            `env:"TRACE_HOST" default:"localhost"`
+	Port     int               `env:"TRACE_PORT"`
+	Addr     string            `env:"TRACE_ADDR" defaultExpr:"{{.Host}}:{{.Port}}"`
+	Password string            `env:"TRACE_PASSWORD" secret:"true"`
+	Labels   map[string]string `env:"TRACE_LABEL"`
+	Database struct {
+		URL string `env:"TRACE_DATABASE_URL"`
+	}
+	Ignored string
+}
+
+// TestWithTrace verifies that a TraceEvent is emitted for every field that is
+// resolved from a variable, with the Source that provided it and redacted
+// secrets.
+func TestWithTrace(t *testing.T) {
+	defaults := envi.Map{"TRACE_PORT": "8080"}
+	overrides := envi.Map{
+		"TRACE_PASSWORD":     "hunter2",
+		"TRACE_LABEL_TEAM":   "core",
+		"TRACE_DATABASE_URL": "postgres://localhost",
+	}
+
+	var events []envi.TraceEvent
+	_, err := envi.New[traceEnv](
+		envi.WithSource(envi.Sources(overrides, defaults)),
+		envi.WithTrace(func(e envi.TraceEvent) { events = append(events, e) }),
+	)
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	want := []envi.TraceEvent{
+		{Field: "Host", Key: "TRACE_HOST", Default: true, Kind: reflect.String, Value: "localhost"},
+		{Field: "Port", Key: "TRACE_PORT", Set: true, Source: defaults, Kind: reflect.Int, Value: "8080"},
+		{Field: "Password", Key: "TRACE_PASSWORD", Set: true, Source: overrides, Kind: reflect.String, Value: "<redacted>"},
+		{Field: "Labels", Key: "TRACE_LABEL", Set: true, Kind: reflect.Map, Value: "map[TEAM:core]"},
+		{Field: "Database.URL", Key: "TRACE_DATABASE_URL", Set: true, Source: overrides, Kind: reflect.String, Value: "postgres://localhost"},
+		{Field: "Addr", Key: "TRACE_ADDR", Default: true, Kind: reflect.String, Value: "localhost:8080"},
+	}
+	if !cmp.Equal(want, events, sameSource) {
+		t.Fatalf("events = %v, want = %v\n\n%s", events, want, cmp.Diff(want, events, sameSource))
+	}
+}
+
+// sameSource compares Sources by identity, as Map Sources are not comparable.
+var sameSource = cmp.Comparer(func(a, b envi.Source) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return reflect.ValueOf(a).Pointer() == reflect.ValueOf(b).Pointer()
+})
+
+type secretTraceEnv struct {
+	Database struct {
+		User    string `env:"USER"`
+		Replica struct {
+			Password string `env:"REPLICA_PASSWORD"`
+		}
+	} `envPrefix:"DB_" secret:"true"`
+	Host string `env:"HOST"`
+}
+
+// TestWithTrace_nestedSecret verifies that the fields of structs with a
+// `secret` tag are redacted, including those of their nested structs.
+func TestWithTrace_nestedSecret(t *testing.T) {
+	vars := envi.Map{"DB_USER": "admin", "DB_REPLICA_PASSWORD": "hunter2", "HOST": "localhost"}
+
+	var events []envi.TraceEvent
+	_, err := envi.New[secretTraceEnv](
+		envi.WithSource(vars),
+		envi.WithTrace(func(e envi.TraceEvent) { events = append(events, e) }),
+	)
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	want := []envi.TraceEvent{
+		{Field: "Database.User", Key: "DB_USER", Set: true, Source: vars, Kind: reflect.String, Value: "<redacted>"},
+		{Field: "Database.Replica.Password", Key: "DB_REPLICA_PASSWORD", Set: true, Source: vars, Kind: reflect.String, Value: "<redacted>"},
+		{Field: "Host", Key: "HOST", Set: true, Source: vars, Kind: reflect.String, Value: "localhost"},
+	}
+	if !cmp.Equal(want, events, sameSource) {
+		t.Fatalf("events = %v, want = %v\n\n%s", events, want, cmp.Diff(want, events, sameSource))
+	}
+}
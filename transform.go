@@ -0,0 +1,70 @@
+package envi
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Transformer transforms the value of a variable before it is converted. arg
+// is the argument of the transformer in the `transform` tag, e.g. "v" for
+// `transform:"trimPrefix=v"`, or empty.
+type Transformer func(value, arg string) (string, error)
+
+// transformers are the built-in transformers of `transform` tags by name.
+var transformers = map[string]Transformer{
+	"trim":       func(v, _ string) (string, error) { return strings.TrimSpace(v), nil },
+	"lower":      func(v, _ string) (string, error) { return strings.ToLower(v), nil },
+	"upper":      func(v, _ string) (string, error) { return strings.ToUpper(v), nil },
+	"trimPrefix": func(v, arg string) (string, error) { return strings.TrimPrefix(v, arg), nil },
+	"trimSuffix": func(v, arg string) (string, error) { return strings.TrimSuffix(v, arg), nil },
+	"basename":   func(v, _ string) (string, error) { return filepath.Base(v), nil },
+}
+
+// customTransformers holds the transformers of RegisterTransformer, keyed by
+// name.
+var customTransformers sync.Map
+
+// RegisterTransformer registers a Transformer that can be used in `transform`
+// tags by name, in addition to the built-in transformers trim, lower, upper,
+// trimPrefix, trimSuffix and basename:
+//
+//	envi.RegisterTransformer("stripQuotes", func(v, _ string) (string, error) {
+//		return strings.Trim(v, `"'`), nil
+//	})
+//
+// Registering a name again, including the name of a built-in transformer,
+// replaces its Transformer.
+func RegisterTransformer(name string, fn Transformer) {
+	customTransformers.Store(name, fn)
+}
+
+// transform applies the transformers of the `transform` tag of field to its
+// non-empty value, in order.
+func transform(field *fieldSchema, value string) (string, error) {
+	if value == "" {
+		return value, nil
+	}
+
+	for _, t := range field.transforms {
+		fn, ok := transformer(t.name)
+		if !ok {
+			return "", fmt.Errorf("unknown transformer %q", t.name)
+		}
+
+		var err error
+		if value, err = fn(value, t.arg); err != nil {
+			return "", fmt.Errorf("%s: transform %s: %w", field.key, t.name, err)
+		}
+	}
+	return value, nil
+}
+
+func transformer(name string) (Transformer, bool) {
+	if fn, ok := customTransformers.Load(name); ok {
+		return fn.(Transformer), true
+	}
+	fn, ok := transformers[name]
+	return fn, ok
+}
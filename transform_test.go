@@ -0,0 +1,73 @@
+package envi_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/bounoable/envi"
+	"github.com/google/go-cmp/cmp"
+)
+
+func init() {
+	envi.RegisterTransformer("stripQuotes", func(v, _ string) (string, error) {
+		return strings.Trim(v, `"'`), nil
+	})
+	envi.RegisterTransformer("nonBlank", func(v, _ string) (string, error) {
+		if strings.TrimSpace(v) == "" {
+			return "", errors.New("value is blank")
+		}
+		return v, nil
+	})
+}
+
+// TestParse_transform verifies that the transformers of `transform` tags are
+// applied in order before values are validated and converted.
+func TestParse_transform(t *testing.T) {
+	type transformEnv struct {
+		Level   string   `env:"TRANSFORM_LEVEL" transform:"trim,lower"`
+		Region  string   `env:"TRANSFORM_REGION" transform:"upper" default:"eu-central-1"`
+		Version int      `env:"TRANSFORM_VERSION" transform:"trim,trimPrefix=v"`
+		Binary  string   `env:"TRANSFORM_BINARY" transform:"basename"`
+		Token   string   `env:"TRANSFORM_TOKEN" transform:"stripQuotes"`
+		Hosts   []string `env:"TRANSFORM_HOSTS" transform:"lower"`
+		Empty   string   `env:"TRANSFORM_EMPTY" transform:"basename"`
+	}
+
+	e, err := envi.New[transformEnv](envi.WithSource(envi.Map{
+		"TRANSFORM_LEVEL":   "  DEBUG ",
+		"TRANSFORM_VERSION": " v2",
+		"TRANSFORM_BINARY":  "/usr/local/bin/app",
+		"TRANSFORM_TOKEN":   `"abc"`,
+		"TRANSFORM_HOSTS":   "A.example.com,B.example.com",
+	}))
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	want := transformEnv{
+		Level:   "debug",
+		Region:  "EU-CENTRAL-1",
+		Version: 2,
+		Binary:  "app",
+		Token:   "abc",
+		Hosts:   []string{"a.example.com", "b.example.com"},
+	}
+	if !cmp.Equal(want, e) {
+		t.Fatalf("env = %v, want = %v\n\n%s", e, want, cmp.Diff(want, e))
+	}
+
+	var failing struct {
+		Name string `env:"TRANSFORM_NAME" transform:"nonBlank"`
+	}
+	if err := envi.Parse(&failing, envi.WithSource(envi.Map{"TRANSFORM_NAME": "  "})); err == nil || !strings.Contains(err.Error(), "value is blank") {
+		t.Fatalf("Parse() should fail with the error of the transformer; got %v", err)
+	}
+
+	var unknown struct {
+		Name string `env:"TRANSFORM_NAME" transform:"reverse"`
+	}
+	if err := envi.Parse(&unknown, envi.WithSource(envi.Map{"TRANSFORM_NAME": "x"})); err == nil || !strings.Contains(err.Error(), `unknown transformer "reverse"`) {
+		t.Fatalf("Parse() should fail for unknown transformer; got %v", err)
+	}
+}
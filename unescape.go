@@ -0,0 +1,31 @@
+package envi
+
+import "strings"
+
+// WithUnescape unescapes the escape sequences \n, \r, \t and \\ in values, so
+// multi-line content such as PEM blocks can be passed through single-line
+// variables. Other backslashes are kept as they are. An `unescape` tag
+// enables or disables unescaping for a single field, e.g. `unescape:"true"`.
+func WithUnescape() Option {
+	return func(p *parser) {
+		p.unescape = true
+	}
+}
+
+// unescapes reports whether the value of field is unescaped.
+func (p *parser) unescapes(field *fieldSchema) bool {
+	if field.hasUnescape {
+		return field.unescape
+	}
+	return p.unescape
+}
+
+var unescaper = strings.NewReplacer(`\\`, `\`, `\n`, "\n", `\r`, "\r", `\t`, "\t")
+
+// unescapeValue replaces the escape sequences in s.
+func unescapeValue(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	return unescaper.Replace(s)
+}
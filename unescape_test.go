@@ -0,0 +1,44 @@
+package envi_test
+
+import (
+	"testing"
+
+	"github.com/bounoable/envi"
+)
+
+// TestWithUnescape verifies that escape sequences are unescaped with
+// WithUnescape or an `unescape` tag.
+func TestWithUnescape(t *testing.T) {
+	type unescapeEnv struct {
+		Cert     string `env:"UNESCAPE_CERT"`
+		Template string `env:"UNESCAPE_TEMPLATE" unescape:"true"`
+		Pattern  string `env:"UNESCAPE_PATTERN" unescape:"false"`
+	}
+
+	src := envi.Map{
+		"UNESCAPE_CERT":     `-----BEGIN CERTIFICATE-----\nMIIB\n-----END CERTIFICATE-----`,
+		"UNESCAPE_TEMPLATE": `Hello,\t{{.Name}}\\n`,
+		"UNESCAPE_PATTERN":  `^\d+\n$`,
+	}
+
+	e, err := envi.New[unescapeEnv](envi.WithSource(src))
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	want := unescapeEnv{
+		Cert:     src["UNESCAPE_CERT"],
+		Template: "Hello,\t{{.Name}}\\n",
+		Pattern:  src["UNESCAPE_PATTERN"],
+	}
+	if e != want {
+		t.Fatalf("env = %+v, want %+v", e, want)
+	}
+
+	if e, err = envi.New[unescapeEnv](envi.WithSource(src), envi.WithUnescape()); err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	want.Cert = "-----BEGIN CERTIFICATE-----\nMIIB\n-----END CERTIFICATE-----"
+	if e != want {
+		t.Fatalf("env = %+v, want %+v", e, want)
+	}
+}
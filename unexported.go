@@ -0,0 +1,52 @@
+package envi
+
+import (
+	"errors"
+	"reflect"
+)
+
+// ErrUnexported is returned with WithStrictUnexported for unexported fields
+// that have an `env` tag.
+var ErrUnexported = errors.New("unexported field has an env tag")
+
+// WithStrictUnexported makes unexported fields with an `env` tag an error
+// wrapping ErrUnexported. Unexported fields can't be set and are ignored by
+// default, so a tag on them is usually a typo in the field name. The exported
+// fields of embedded structs of unexported types are parsed as usual.
+func WithStrictUnexported() Option {
+	return func(p *parser) {
+		p.strictUnexported = true
+	}
+}
+
+// settable reports whether field is parsed, which is the case for exported
+// fields and for embedded structs, whose exported fields are promoted, unless
+// they have an `env:"-"` tag.
+func (f *fieldSchema) settable() bool {
+	return !f.skip && (f.exported || (f.embedded && f.isStruct && !f.isPointer))
+}
+
+// checkUnexported returns an error wrapping ErrUnexported if strict handling
+// of unexported fields is enabled and the unexported field has an `env` tag.
+func (p *parser) checkUnexported(field *fieldSchema) error {
+	if !p.strictUnexported || !field.hasKey {
+		return nil
+	}
+	return ErrUnexported
+}
+
+// setExported sets dst to v. If dst or v is an embedded struct of an
+// unexported type, only its exported fields are set, recursively.
+func setExported(dst, v reflect.Value) {
+	if dst.CanSet() && v.CanInterface() {
+		dst.Set(v)
+		return
+	}
+
+	schema := schemaOf(dst.Type())
+	for n := range schema.fields {
+		if field := &schema.fields[n]; field.settable() {
+			setExported(dst.Field(n), v.Field(n))
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package envi_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/bounoable/envi"
+)
+
+type unexportedBase struct {
+	Port  int `env:"UNEXPORTED_PORT"`
+	debug bool
+}
+
+type unexportedEnv struct {
+	unexportedBase
+	Host  string `env:"UNEXPORTED_HOST"`
+	token string `env:"UNEXPORTED_TOKEN"`
+}
+
+// TestParse_unexported tests that unexported fields are ignored, that the
+// exported fields of embedded structs of unexported types are parsed, and
+// that WithStrictUnexported rejects unexported fields with an env tag.
+func TestParse_unexported(t *testing.T) {
+	source := envi.WithSource(envi.Map{
+		"UNEXPORTED_HOST":  "localhost",
+		"UNEXPORTED_PORT":  "8080",
+		"UNEXPORTED_TOKEN": "secret",
+	})
+
+	e, err := envi.New[unexportedEnv](source)
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	if e.Host != "localhost" || e.Port != 8080 || e.token != "" {
+		t.Fatalf("env = %+v, want Host and Port to be set and token to be empty", e)
+	}
+
+	e = unexportedEnv{token: "kept"}
+	e.Port = 80
+	e.debug = true
+	if err := envi.Merge(&e, envi.WithSource(envi.Map{"UNEXPORTED_HOST": "example.com"})); err != nil {
+		t.Fatalf("Merge() failed: %v", err)
+	}
+	if e.Host != "example.com" || e.Port != 80 || e.token != "kept" || !e.debug {
+		t.Fatalf("env = %+v, want merged Host and kept Port, token and debug", e)
+	}
+
+	if _, err := envi.New[unexportedEnv](source, envi.WithStrictUnexported()); !errors.Is(err, envi.ErrUnexported) {
+		t.Fatalf("New() should fail with %v; got %v", envi.ErrUnexported, err)
+	}
+}
@@ -0,0 +1,32 @@
+package envi
+
+import "reflect"
+
+// WithSkipUnsupported ignores fields whose types cannot be parsed from
+// variables, such as funcs, channels and interfaces, including pointers,
+// slices and maps of them, so structs can carry runtime values next to their
+// configuration. Without it, the variables of such fields are still looked
+// up and fail to parse if they are set. Decode hooks are not called for
+// ignored fields.
+func WithSkipUnsupported() Option {
+	return func(p *parser) {
+		p.skipUnsupported = true
+	}
+}
+
+// isUnsupported reports whether values of type t cannot be parsed from
+// variables.
+func isUnsupported(t reflect.Type) bool {
+	if isJSONType(t) {
+		return false
+	}
+
+	switch t.Kind() {
+	case reflect.Func, reflect.Chan, reflect.UnsafePointer, reflect.Interface:
+		return true
+	case reflect.Pointer, reflect.Slice, reflect.Array, reflect.Map:
+		return isUnsupported(t.Elem())
+	default:
+		return false
+	}
+}
@@ -0,0 +1,44 @@
+package envi_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/bounoable/envi"
+)
+
+// TestWithSkipUnsupported tests that fields of unsupported types, such as
+// funcs, channels and interfaces, are ignored with WithSkipUnsupported and
+// fail to parse without it.
+func TestWithSkipUnsupported(t *testing.T) {
+	type unsupportedEnv struct {
+		Host     string                  `env:"UNSUPPORTED_HOST"`
+		OnReload func()                  `env:"UNSUPPORTED_ON_RELOAD"`
+		Events   chan string             `env:"UNSUPPORTED_EVENTS"`
+		Logger   fmt.Stringer            `env:"UNSUPPORTED_LOGGER"`
+		Handlers map[string]func() error `env:"UNSUPPORTED_HANDLER"`
+	}
+
+	source := envi.WithSource(envi.Map{
+		"UNSUPPORTED_HOST":        "localhost",
+		"UNSUPPORTED_ON_RELOAD":   "reload",
+		"UNSUPPORTED_EVENTS":      "events",
+		"UNSUPPORTED_LOGGER":      "stdout",
+		"UNSUPPORTED_HANDLER_GET": "get",
+	})
+
+	if _, err := envi.New[unsupportedEnv](source); err == nil {
+		t.Fatalf("New() should fail for set variables of unsupported fields")
+	}
+
+	e, err := envi.New[unsupportedEnv](source, envi.WithSkipUnsupported())
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	if e.Host != "localhost" {
+		t.Fatalf("Host = %q, want %q", e.Host, "localhost")
+	}
+	if e.OnReload != nil || e.Events != nil || e.Logger != nil || e.Handlers != nil {
+		t.Fatalf("unsupported fields should be left untouched; got %+v", e)
+	}
+}
@@ -0,0 +1,173 @@
+package envi
+
+import (
+	"fmt"
+	"net/mail"
+	"net/url"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// validators are the rules of `validate` tags by name. A validator checks the
+// value of a variable, after defaults and expansion, against the argument of
+// the rule, e.g. "https" for `validate:"url=https"`.
+var validators = map[string]func(value, arg string) error{
+	"file":     validateFile,
+	"dir":      validateDir,
+	"url":      validateURL,
+	"port":     validatePort,
+	"hostname": validateHostname,
+	"email":    validateEmail,
+}
+
+// rule is a rule of a `validate` tag or a transformer of a `transform` tag.
+type rule struct {
+	name string
+	arg  string
+}
+
+// parseRules parses the comma-separated rules of a `validate` or `transform`
+// tag, e.g. "file" or "url=https".
+func parseRules(tag string) []rule {
+	var rules []rule
+	for _, r := range strings.Split(tag, ",") {
+		if r = strings.TrimSpace(r); r == "" {
+			continue
+		}
+		name, arg, _ := strings.Cut(r, "=")
+		rules = append(rules, rule{name: name, arg: arg})
+	}
+	return rules
+}
+
+// validate checks the non-empty value of field against the rules of its
+// `validate` tag. The elements of slices and arrays are checked separately.
+func (p *parser) validate(field *fieldSchema, value string) error {
+	if len(field.rules) == 0 {
+		return nil
+	}
+
+	values := []string{value}
+	if k := field.typ.Kind(); (k == reflect.Slice || k == reflect.Array) && field.typ != rawMessageType && !isJSONType(field.typ) {
+		var err error
+		if values, err = splitList(value, p.list.with(field.list)); err != nil {
+			return err
+		}
+	}
+
+	for _, r := range field.rules {
+		fn, ok := validators[r.name]
+		if !ok {
+			return fmt.Errorf("unknown validation rule %q", r.name)
+		}
+		for _, v := range values {
+			if err := fn(v, r.arg); err != nil {
+				return fmt.Errorf("%s: %w", field.key, err)
+			}
+		}
+	}
+	return nil
+}
+
+func validateFile(value, _ string) error {
+	info, err := os.Stat(value)
+	if err != nil {
+		return fmt.Errorf("file %q does not exist", value)
+	}
+	if info.IsDir() {
+		return fmt.Errorf("%q is a directory, not a file", value)
+	}
+	return nil
+}
+
+func validateDir(value, _ string) error {
+	info, err := os.Stat(value)
+	if err != nil {
+		return fmt.Errorf("directory %q does not exist", value)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%q is not a directory", value)
+	}
+	return nil
+}
+
+// validateURL requires an absolute URL. arg is an optional list of allowed
+// schemes separated by '|', e.g. "https|wss".
+func validateURL(value, arg string) error {
+	u, err := url.Parse(value)
+	if err != nil {
+		return fmt.Errorf("invalid URL %q: %w", value, err)
+	}
+	if u.Scheme == "" || (u.Host == "" && u.Opaque == "") {
+		return fmt.Errorf("%q is not an absolute URL", value)
+	}
+	if arg == "" {
+		return nil
+	}
+	for _, scheme := range strings.Split(arg, "|") {
+		if strings.EqualFold(u.Scheme, scheme) {
+			return nil
+		}
+	}
+	return fmt.Errorf("URL %q must use scheme %s", u.Redacted(), strings.ReplaceAll(arg, "|", " or "))
+}
+
+// validatePort requires a port number between 1 and 65535, or between 1024
+// and 65535 if arg is "unprivileged".
+func validatePort(value, arg string) error {
+	lowest := 1
+	switch arg {
+	case "":
+	case "unprivileged":
+		lowest = 1024
+	default:
+		return fmt.Errorf("invalid argument %q of port rule", arg)
+	}
+
+	port, err := strconv.Atoi(value)
+	if err != nil || port < lowest || port > 65535 {
+		return fmt.Errorf("invalid port %q, must be between %d and 65535", value, lowest)
+	}
+	return nil
+}
+
+// validateHostname requires a hostname as defined by RFC 1123: dot-separated
+// labels of 1 to 63 letters, digits and hyphens that do not start or end with
+// a hyphen, with at most 253 characters in total.
+func validateHostname(value, _ string) error {
+	name := strings.TrimSuffix(value, ".")
+	if name == "" || len(name) > 253 {
+		return fmt.Errorf("invalid hostname %q", value)
+	}
+	for _, label := range strings.Split(name, ".") {
+		if !validLabel(label) {
+			return fmt.Errorf("invalid hostname %q", value)
+		}
+	}
+	return nil
+}
+
+func validLabel(label string) bool {
+	if label == "" || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+		return false
+	}
+	for _, c := range label {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '-':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// validateEmail requires an email address as parsed by net/mail, e.g.
+// "alerts@example.com" or "Alerts <alerts@example.com>".
+func validateEmail(value, _ string) error {
+	if _, err := mail.ParseAddress(value); err != nil {
+		return fmt.Errorf("invalid email address %q: %w", value, err)
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package envi
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Value is a field type that records where its value came from, e.g. for
+// audit logs and error messages:
+//
+//	type Config struct {
+//		Port envi.Value[int] `env:"PORT" default:"8080"`
+//	}
+//
+//	log.Printf("listening on port %d (from %s)", cfg.Port.Value, cfg.Port.Key)
+//
+// The tags of the field apply to the wrapped value as usual.
+type Value[T any] struct {
+	// Value is the parsed value.
+	Value T
+
+	// Key is the variable that provided the value, which is the variable of
+	// the field or one of its `defaultFrom` variables. It is the variable of
+	// the field if no variable was set.
+	Key string
+
+	// Raw is the value of the variable as it was read from the Source,
+	// before defaults, unescaping and expansion were applied.
+	Raw string
+
+	// Source is the Source that provided the variable, or nil if no variable
+	// was set.
+	Source Source
+
+	// Set reports whether the variable was set in any Source, and Default
+	// reports whether the value was taken from the `default` tag.
+	Set     bool
+	Default bool
+}
+
+// String formats the parsed value.
+func (v Value[T]) String() string {
+	return fmt.Sprint(v.Value)
+}
+
+func (Value[T]) wrappedType() reflect.Type {
+	return reflect.TypeOf((*T)(nil)).Elem()
+}
+
+func (v *Value[T]) wrap(value reflect.Value, key, raw string, source Source, set, def bool) {
+	if value.IsValid() {
+		v.Value = value.Interface().(T)
+	}
+	v.Key, v.Raw, v.Source, v.Set, v.Default = key, raw, source, set, def
+}
+
+// wrapper is implemented by Value.
+type wrapper interface {
+	wrappedType() reflect.Type
+}
+
+var wrapperType = reflect.TypeOf((*wrapper)(nil)).Elem()
+
+// isWrapper reports whether t is a Value type.
+func isWrapper(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && t.Implements(wrapperType)
+}
+
+// parseWrapped parses the value of the Value field and records where it came
+// from.
+func (p *parser) parseWrapped(field *fieldSchema, res *fieldResult) (reflect.Value, bool, error) {
+	leaf := *field
+	leaf.typ = reflect.Zero(field.typ).Interface().(wrapper).wrappedType()
+	leaf.wrapper = false
+
+	v, _, err := p.parseField(&leaf, res, reflect.New(leaf.typ).Elem())
+	if err != nil || !res.consulted {
+		return reflect.Value{}, false, err
+	}
+
+	key := res.from
+	if key == "" {
+		key = res.key
+	}
+
+	w := reflect.New(field.typ)
+	w.Interface().(interface {
+		wrap(reflect.Value, string, string, Source, bool, bool)
+	}).wrap(v, key, res.raw, res.source, res.set, res.def)
+
+	return w.Elem(), true, nil
+}
@@ -0,0 +1,42 @@
+package envi_test
+
+import (
+	"testing"
+
+	"github.com/bounoable/envi"
+	"github.com/google/go-cmp/cmp"
+)
+
+// TestValue verifies that Value fields record the key, raw value and Source
+// that their value came from.
+func TestValue(t *testing.T) {
+	type valueEnv struct {
+		Host  envi.Value[string]   `env:"VALUE_HOST"`
+		Port  envi.Value[int]      `env:"VALUE_PORT" default:"8080"`
+		Token envi.Value[string]   `env:"VALUE_TOKEN" defaultFrom:"VALUE_LEGACY_TOKEN"`
+		Tags  envi.Value[[]string] `env:"VALUE_TAGS"`
+	}
+
+	defaults := envi.Map{"VALUE_LEGACY_TOKEN": "legacy"}
+	overrides := envi.Map{"VALUE_HOST": " example.com", "VALUE_TAGS": "a, b"}
+	chain := envi.Sources(overrides, defaults)
+
+	e, err := envi.New[valueEnv](envi.WithSource(chain))
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	want := valueEnv{
+		Host:  envi.Value[string]{Value: " example.com", Key: "VALUE_HOST", Raw: " example.com", Source: overrides, Set: true},
+		Port:  envi.Value[int]{Value: 8080, Key: "VALUE_PORT", Default: true},
+		Token: envi.Value[string]{Value: "legacy", Key: "VALUE_LEGACY_TOKEN", Raw: "legacy", Source: defaults, Set: true},
+		Tags:  envi.Value[[]string]{Value: []string{"a", "b"}, Key: "VALUE_TAGS", Raw: "a, b", Source: overrides, Set: true},
+	}
+	if !cmp.Equal(want, e) {
+		t.Fatalf("env = %v, want = %v\n\n%s", e, want, cmp.Diff(want, e))
+	}
+
+	if e.Port.String() != "8080" {
+		t.Fatalf("Port.String() = %q, want %q", e.Port.String(), "8080")
+	}
+}
@@ -0,0 +1,128 @@
+package envi
+
+import (
+	"reflect"
+	"strings"
+)
+
+// Variable describes an environment variable of a struct, e.g. to generate
+// documentation.
+type Variable struct {
+	// Key is the key of the variable. For map fields, Key is the prefix of
+	// the variables, and Map is true.
+	Key string
+	Map bool
+
+	// Field is the path of the field, e.g. "Database.URL".
+	Field string
+
+	// Type is the Go type of the field, e.g. "time.Duration".
+	Type string
+
+	// Default is the value of the `default` tag, if HasDefault is true.
+	// DefaultExpr is the value of the `defaultExpr` tag.
+	Default     string
+	HasDefault  bool
+	DefaultExpr string
+
+	// DefaultFrom are the keys of the `defaultFrom` tag.
+	DefaultFrom []string
+
+	// Aliases are the deprecated keys of the `aliases` tag.
+	Aliases []string
+
+	// Description is the value of the `desc` tag.
+	Description string
+
+	// Example is the value of the `example` tag, a sample value for
+	// templates and documentation.
+	Example string
+
+	// Required reports whether the field has a `required` tag. RequiredIf
+	// and RequiredUnless are the values of the `required_if` and
+	// `required_unless` tags.
+	Required       bool
+	RequiredIf     string
+	RequiredUnless string
+
+	Secret bool
+
+	// Deprecated is the value of the `deprecated` tag, if IsDeprecated is
+	// true.
+	Deprecated   string
+	IsDeprecated bool
+}
+
+// Variables returns a Variable for every field of Env with an `env` tag,
+// including the fields of nested structs, in the order of the fields. The
+// variables of a nested struct follow each other at the position of the
+// struct, so output generated from them is stable.
+func Variables[Env any]() []Variable {
+	var vars []Variable
+	if t := reflect.TypeOf((*Env)(nil)).Elem(); t.Kind() == reflect.Struct {
+		collectVariables(&vars, schemaOf(t), "")
+	}
+	return vars
+}
+
+// FieldInfo describes a field of a config struct; see Explain.
+type FieldInfo struct {
+	Variable
+
+	// Path are the names of the structs the field is nested in, followed by
+	// the name of the field, e.g. ["Database", "URL"].
+	Path []string
+}
+
+// Explain returns a FieldInfo for every field of Config with an `env` tag,
+// including the fields of nested structs, in the order of the fields. It
+// exposes the schema of a config, e.g. to build dashboards or admission
+// controllers.
+func Explain[Config any]() []FieldInfo {
+	vars := Variables[Config]()
+	infos := make([]FieldInfo, len(vars))
+	for i, v := range vars {
+		infos[i] = FieldInfo{Variable: v, Path: strings.Split(v.Field, ".")}
+	}
+	return infos
+}
+
+func collectVariables(vars *[]Variable, schema *structSchema, prefix string) {
+	for n := range schema.fields {
+		field := &schema.fields[n]
+		tag := field.tag
+
+		if field.recursive || !field.settable() {
+			continue
+		}
+		if field.isStruct {
+			collectVariables(vars, field.nested(), field.nestedPath(prefix))
+			continue
+		}
+
+		isMap := isPrefixMap(field.typ)
+		if !field.hasKey && !isMap {
+			continue
+		}
+
+		*vars = append(*vars, Variable{
+			Key:            field.key,
+			Map:            isMap,
+			Field:          prefix + field.name,
+			Type:           field.typ.String(),
+			Default:        field.def,
+			HasDefault:     field.hasDefault,
+			DefaultExpr:    tag.Get("defaultExpr"),
+			DefaultFrom:    field.defaultFrom,
+			Aliases:        field.aliases,
+			Description:    tag.Get("desc"),
+			Example:        tag.Get("example"),
+			Required:       field.required,
+			RequiredIf:     tag.Get("required_if"),
+			RequiredUnless: tag.Get("required_unless"),
+			Secret:         field.secret,
+			Deprecated:     field.deprecated,
+			IsDeprecated:   field.isDeprecated,
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package envi_test
+
+import (
+	"testing"
+
+	"github.com/bounoable/envi"
+	"github.com/google/go-cmp/cmp"
+)
+
+type variablesEnv struct {
+	Host     string            `env:"HOST" default:"localhost" desc:"Host to listen on."`
+	Port     int               `env:"PORT" required:"true" example:"8080"`
+	Addr     string            `env:"ADDR" defaultExpr:"{{.Host}}:{{.Port}}"`
+	Password string            `env:"PASSWORD" secret:"true"`
+	Legacy   string            `env:"LEGACY" deprecated:"use HOST instead"`
+	Labels   map[string]string `env:"LABEL"`
+	Database *struct {
+		URL string `env:"DATABASE_URL"`
+	}
+	Ignored string
+}
+
+// TestVariables verifies that Variables describes every variable of a struct,
+// including nested structs, in field order.
+func TestVariables(t *testing.T) {
+	want := []envi.Variable{
+		{Key: "HOST", Field: "Host", Type: "string", Default: "localhost", HasDefault: true, Description: "Host to listen on."},
+		{Key: "PORT", Field: "Port", Type: "int", Example: "8080", Required: true},
+		{Key: "ADDR", Field: "Addr", Type: "string", DefaultExpr: "{{.Host}}:{{.Port}}"},
+		{Key: "PASSWORD", Field: "Password", Type: "string", Secret: true},
+		{Key: "LEGACY", Field: "Legacy", Type: "string", Deprecated: "use HOST instead", IsDeprecated: true},
+		{Key: "LABEL", Map: true, Field: "Labels", Type: "map[string]string"},
+		{Key: "DATABASE_URL", Field: "Database.URL", Type: "string"},
+	}
+
+	vars := envi.Variables[variablesEnv]()
+	if !cmp.Equal(want, vars) {
+		t.Fatalf("Variables() = %v, want = %v\n\n%s", vars, want, cmp.Diff(want, vars))
+	}
+}
+
+// TestExplain verifies that Explain describes the fields of a config with
+// their nesting paths.
+func TestExplain(t *testing.T) {
+	infos := envi.Explain[variablesEnv]()
+	vars := envi.Variables[variablesEnv]()
+	if len(infos) != len(vars) {
+		t.Fatalf("Explain() returned %d fields, want %d", len(infos), len(vars))
+	}
+
+	for i, info := range infos {
+		if !cmp.Equal(vars[i], info.Variable) {
+			t.Fatalf("Explain()[%d].Variable = %v, want = %v", i, info.Variable, vars[i])
+		}
+	}
+
+	last := infos[len(infos)-1]
+	if want := []string{"Database", "URL"}; !cmp.Equal(want, last.Path) {
+		t.Fatalf("Path = %v, want %v", last.Path, want)
+	}
+	if last.Key != "DATABASE_URL" || last.Type != "string" {
+		t.Fatalf("Key, Type = %s, %s; want DATABASE_URL, string", last.Key, last.Type)
+	}
+}
@@ -0,0 +1,56 @@
+package envi
+
+import (
+	"fmt"
+	"log"
+)
+
+// Warning is a non-fatal issue that was detected while parsing, such as the
+// use of a deprecated variable.
+type Warning struct {
+	// Field is the name of the struct field the warning refers to.
+	Field string
+
+	// Key is the environment variable the warning refers to.
+	Key string
+
+	// Replacement is the key of the variable that replaces Key if Key is a
+	// deprecated alias; see WithAliases.
+	Replacement string
+
+	// Message describes the issue.
+	Message string
+}
+
+func (w Warning) String() string {
+	return fmt.Sprintf("%s (field %s): %s", w.Key, w.Field, w.Message)
+}
+
+// WithWarningHandler sets the function that is called with the warnings that
+// occur during parsing. By default, warnings are written to the standard
+// logger of the log package. Pass a function that does nothing to discard
+// them.
+func WithWarningHandler(fn func(Warning)) Option {
+	return func(p *parser) {
+		p.warn = fn
+	}
+}
+
+func logWarning(w Warning) {
+	log.Printf("envi: %s", w)
+}
+
+// warnDeprecated reports the use of the variable key of field if the field has
+// a `deprecated` tag, e.g. `deprecated:"use NEW_VAR instead"`.
+func (p *parser) warnDeprecated(field *fieldSchema, key string) {
+	if !field.isDeprecated {
+		return
+	}
+
+	msg := "deprecated"
+	if field.deprecated != "" {
+		msg += ": " + field.deprecated
+	}
+
+	p.warn(Warning{Field: field.name, Key: key, Message: msg})
+}
@@ -0,0 +1,51 @@
+package envi_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/bounoable/envi"
+	"github.com/google/go-cmp/cmp"
+)
+
+type deprecatedEnv struct {
+	Host   string            `env:"DEPRECATED_HOST" deprecated:"use HOST instead"`
+	Port   int               `env:"DEPRECATED_PORT" deprecated:""`
+	Debug  bool              `env:"DEPRECATED_DEBUG" deprecated:"use LOG_LEVEL instead"`
+	Labels map[string]string `env:"DEPRECATED_LABEL" deprecated:"use LABELS instead"`
+}
+
+// TestWithWarningHandler verifies that the use of variables with a
+// `deprecated` tag is reported to the warning handler without failing.
+func TestWithWarningHandler(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("DEPRECATED_HOST", "localhost")
+	os.Setenv("DEPRECATED_PORT", "8080")
+	os.Setenv("DEPRECATED_LABEL_TEAM", "core")
+
+	var warnings []envi.Warning
+	e, err := envi.New[deprecatedEnv](envi.WithWarningHandler(func(w envi.Warning) {
+		warnings = append(warnings, w)
+	}))
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	want := deprecatedEnv{
+		Host:   "localhost",
+		Port:   8080,
+		Labels: map[string]string{"TEAM": "core"},
+	}
+	if !cmp.Equal(want, e) {
+		t.Fatalf("env = %v, want = %v\n\n%s", e, want, cmp.Diff(want, e))
+	}
+
+	wantWarnings := []envi.Warning{
+		{Field: "Host", Key: "DEPRECATED_HOST", Message: "deprecated: use HOST instead"},
+		{Field: "Port", Key: "DEPRECATED_PORT", Message: "deprecated"},
+		{Field: "Labels", Key: "DEPRECATED_LABEL", Message: "deprecated: use LABELS instead"},
+	}
+	if !cmp.Equal(wantWarnings, warnings) {
+		t.Fatalf("warnings = %v, want = %v\n\n%s", warnings, wantWarnings, cmp.Diff(wantWarnings, warnings))
+	}
+}
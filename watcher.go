@@ -0,0 +1,138 @@
+package envi
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Watcher holds the current value of a Config that is parsed from the
+// environment, and re-parses it on demand or on an interval. Subscribers are
+// notified whenever the effective configuration changes.
+type Watcher[Config any] struct {
+	opts []Option
+
+	mux         sync.RWMutex
+	current     Config
+	subscribers map[int]func(old, new Config)
+	onError     []func(error)
+	nextID      int
+}
+
+// NewWatcher parses the initial Config using the provided options and returns
+// a Watcher for it. The same options are used for every reload.
+func NewWatcher[Config any](opts ...Option) (*Watcher[Config], error) {
+	cfg, err := New[Config](opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Watcher[Config]{
+		opts:        opts,
+		current:     cfg,
+		subscribers: make(map[int]func(old, new Config)),
+	}, nil
+}
+
+// Current returns the current Config.
+func (w *Watcher[Config]) Current() Config {
+	w.mux.RLock()
+	defer w.mux.RUnlock()
+	return w.current
+}
+
+// Subscribe registers fn to be called with the previous and the new Config
+// whenever a reload changes the configuration. The returned function removes
+// the subscription.
+func (w *Watcher[Config]) Subscribe(fn func(old, new Config)) (unsubscribe func()) {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+
+	id := w.nextID
+	w.nextID++
+	w.subscribers[id] = fn
+
+	return func() {
+		w.mux.Lock()
+		defer w.mux.Unlock()
+		delete(w.subscribers, id)
+	}
+}
+
+// OnError registers fn to be called with the errors of reloads that are
+// triggered by Run.
+func (w *Watcher[Config]) OnError(fn func(error)) {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+	w.onError = append(w.onError, fn)
+}
+
+// Reload re-parses the Config and reports whether it changed, comparing the
+// fields like Diff. If parsing fails, the current Config is kept and the
+// error is returned. Subscribers are called synchronously before Reload
+// returns.
+func (w *Watcher[Config]) Reload() (changed bool, err error) {
+	return w.ReloadContext(context.Background())
+}
+
+// ReloadContext is like Reload, but passes ctx to the configured sources like
+// ParseContext, so a reload that performs I/O can be canceled.
+func (w *Watcher[Config]) ReloadContext(ctx context.Context) (changed bool, err error) {
+	var cfg Config
+	if err := ParseContext(ctx, &cfg, w.opts...); err != nil {
+		return false, err
+	}
+
+	w.mux.Lock()
+	old := w.current
+	if len(Diff(old, cfg)) == 0 {
+		w.mux.Unlock()
+		return false, nil
+	}
+	w.current = cfg
+
+	subscribers := make([]func(old, new Config), 0, len(w.subscribers))
+	for id := 0; id < w.nextID; id++ {
+		if fn, ok := w.subscribers[id]; ok {
+			subscribers = append(subscribers, fn)
+		}
+	}
+	w.mux.Unlock()
+
+	for _, fn := range subscribers {
+		fn(old, cfg)
+	}
+
+	return true, nil
+}
+
+// Run reloads the Config every interval until ctx is canceled, passing ctx to
+// the sources of every reload. Reload errors are passed to the handlers
+// registered with OnError, except for those of a reload that is interrupted
+// by the cancellation of ctx.
+func (w *Watcher[Config]) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := w.ReloadContext(ctx); err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+
+				w.mux.RLock()
+				handlers := make([]func(error), len(w.onError))
+				copy(handlers, w.onError)
+				w.mux.RUnlock()
+
+				for _, fn := range handlers {
+					fn(err)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package envi_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/bounoable/envi"
+)
+
+type watchEnv struct {
+	Token string `env:"WATCH_TOKEN"`
+	Port  int    `env:"WATCH_PORT"`
+}
+
+// TestWatcher verifies that a Watcher only notifies subscribers when a reload
+// changes the configuration, and that failed reloads keep the current value.
+func TestWatcher(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("WATCH_TOKEN", "foo")
+
+	w, err := envi.NewWatcher[watchEnv]()
+	if err != nil {
+		t.Fatalf("NewWatcher() failed: %v", err)
+	}
+
+	if cfg := w.Current(); cfg.Token != "foo" {
+		t.Fatalf("Current().Token = %q, want %q", cfg.Token, "foo")
+	}
+
+	var calls []watchEnv
+	unsubscribe := w.Subscribe(func(old, new watchEnv) {
+		calls = append(calls, old, new)
+	})
+
+	if changed, err := w.Reload(); err != nil || changed {
+		t.Fatalf("Reload() = %v, %v; want false, <nil>", changed, err)
+	}
+
+	os.Setenv("WATCH_TOKEN", "bar")
+	if changed, err := w.Reload(); err != nil || !changed {
+		t.Fatalf("Reload() = %v, %v; want true, <nil>", changed, err)
+	}
+
+	if len(calls) != 2 || calls[0].Token != "foo" || calls[1].Token != "bar" {
+		t.Fatalf("subscriber should be called with old and new config; got %v", calls)
+	}
+
+	os.Setenv("WATCH_PORT", "invalid")
+	if _, err := w.Reload(); err == nil {
+		t.Fatalf("Reload() should fail")
+	}
+	if cfg := w.Current(); cfg.Token != "bar" {
+		t.Fatalf("Current().Token = %q, want %q", cfg.Token, "bar")
+	}
+
+	unsubscribe()
+	os.Setenv("WATCH_PORT", "80")
+	if _, err := w.Reload(); err != nil {
+		t.Fatalf("Reload() failed: %v", err)
+	}
+	if len(calls) != 2 {
+		t.Fatalf("unsubscribed subscriber should not be called")
+	}
+}
+
+// TestWatcher_Run verifies that Run reloads the configuration on an interval
+// and reports reload errors to the OnError handlers.
+func TestWatcher_Run(t *testing.T) {
+	os.Clearenv()
+
+	w, err := envi.NewWatcher[watchEnv]()
+	if err != nil {
+		t.Fatalf("NewWatcher() failed: %v", err)
+	}
+
+	changed := make(chan watchEnv, 1)
+	w.Subscribe(func(_, new watchEnv) {
+		select {
+		case changed <- new:
+		default:
+		}
+	})
+
+	errs := make(chan error, 1)
+	w.OnError(func(err error) {
+		select {
+		case errs <- err:
+		default:
+		}
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go w.Run(ctx, time.Millisecond)
+
+	os.Setenv("WATCH_PORT", "8080")
+	select {
+	case cfg := <-changed:
+		if cfg.Port != 8080 {
+			t.Fatalf("Port = %d, want %d", cfg.Port, 8080)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("subscriber was not called")
+	}
+
+	os.Setenv("WATCH_PORT", "invalid")
+	select {
+	case <-errs:
+	case <-time.After(time.Second):
+		t.Fatalf("error handler was not called")
+	}
+}
+
+// TestWatcher_ReloadContext verifies that reloads pass their context to the
+// sources and keep the current value if it is canceled.
+func TestWatcher_ReloadContext(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("WATCH_TOKEN", "foo")
+
+	w, err := envi.NewWatcher[watchEnv]()
+	if err != nil {
+		t.Fatalf("NewWatcher() failed: %v", err)
+	}
+
+	os.Setenv("WATCH_TOKEN", "bar")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if changed, err := w.ReloadContext(ctx); !errors.Is(err, context.Canceled) || changed {
+		t.Fatalf("ReloadContext() = %v, %v; want false, %v", changed, err, context.Canceled)
+	}
+	if cfg := w.Current(); cfg.Token != "foo" {
+		t.Fatalf("Current().Token = %q, want %q", cfg.Token, "foo")
+	}
+
+	if changed, err := w.ReloadContext(context.Background()); err != nil || !changed {
+		t.Fatalf("ReloadContext() = %v, %v; want true, <nil>", changed, err)
+	}
+}
+
+// TestWatcher_lazy verifies that reloads of configs with Lazy fields only
+// report changes if a variable changed.
+func TestWatcher_lazy(t *testing.T) {
+	type lazyWatchEnv struct {
+		Token envi.Lazy[string] `env:"WATCH_TOKEN"`
+		Port  int               `env:"WATCH_PORT"`
+	}
+
+	os.Clearenv()
+	os.Setenv("WATCH_TOKEN", "foo")
+
+	w, err := envi.NewWatcher[lazyWatchEnv]()
+	if err != nil {
+		t.Fatalf("NewWatcher() failed: %v", err)
+	}
+
+	var calls int
+	w.Subscribe(func(_, _ lazyWatchEnv) { calls++ })
+
+	if changed, err := w.Reload(); err != nil || changed {
+		t.Fatalf("Reload() = %v, %v; want false, <nil>", changed, err)
+	}
+	if calls != 0 {
+		t.Fatalf("subscriber should not be called without changes; got %d calls", calls)
+	}
+
+	os.Setenv("WATCH_PORT", "8080")
+	if changed, err := w.Reload(); err != nil || !changed {
+		t.Fatalf("Reload() = %v, %v; want true, <nil>", changed, err)
+	}
+}